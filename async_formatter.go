@@ -0,0 +1,171 @@
+package xlog
+
+import (
+	"sync"
+	"time"
+)
+
+// AsyncFormatter is a formatter wrapper, that queues entries
+// and formats them in a background goroutine,
+// so any formatter and sink combination does not block the logging calls.
+//
+// The caller can not be resolved from the background goroutine,
+// so the caller is not logged by the inner formatter.
+// The time of the entry is the time it is formatted.
+type AsyncFormatter struct {
+	inner Formatter
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan asyncEntry
+	flush  chan chan struct{}
+	done   chan struct{}
+}
+
+type asyncEntry struct {
+	pkg     string
+	level   LogLevel
+	kv      bool
+	entries []any
+}
+
+// NewAsyncFormatter returns AsyncFormatter.
+// depth controls the size of the queue, if the queue fills, the logging calls block.
+// flushInterval specifies how often the inner formatter is flushed,
+// you can pass zero to flush only when the queue is drained.
+// The caller must call Close to drain the queue and stop the background goroutine.
+func NewAsyncFormatter(inner Formatter, depth int, flushInterval time.Duration) *AsyncFormatter {
+	if depth < 0 {
+		depth = 0
+	}
+	f := &AsyncFormatter{
+		inner: inner.Options(FormatNoCaller),
+		queue: make(chan asyncEntry, depth),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	done := TrackGoroutine("xlog.AsyncFormatter")
+	go f.run(flushInterval, done)
+	return f
+}
+
+// Options allows to configure formatter behavior,
+// FormatWithCaller is ignored
+func (f *AsyncFormatter) Options(ops ...FormatterOption) Formatter {
+	list := make([]FormatterOption, 0, len(ops))
+	for _, op := range ops {
+		if op != FormatWithCaller {
+			list = append(list, op)
+		}
+	}
+	f.inner.Options(list...)
+	return f
+}
+
+// Settings applies the settings to the inner formatter
+func (f *AsyncFormatter) Settings(settings FormatterSettings) Formatter {
+	ApplySettings(f.inner, settings)
+	return f
+}
+
+// Format log entry string to the stream
+func (f *AsyncFormatter) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	f.enqueue(asyncEntry{pkg: pkg, level: l, entries: entries}, depth+1)
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (f *AsyncFormatter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	f.enqueue(asyncEntry{pkg: pkg, level: l, kv: true, entries: entries}, depth+1)
+}
+
+func (f *AsyncFormatter) enqueue(e asyncEntry, depth int) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.closed {
+		// the background goroutine is stopped, format directly
+		f.format(e, depth+1)
+		return
+	}
+	e.entries = append([]any{}, e.entries...)
+	f.queue <- e
+}
+
+// Flush blocks until the queued entries are formatted
+// and the inner formatter is flushed
+func (f *AsyncFormatter) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case f.flush <- flushed:
+		<-flushed
+	case <-f.done:
+		f.inner.Flush()
+	}
+}
+
+// Close drains the queue, and stops the background goroutine.
+// The subsequent entries are formatted directly.
+func (f *AsyncFormatter) Close() error {
+	f.lock.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.queue)
+	}
+	f.lock.Unlock()
+
+	<-f.done
+	return nil
+}
+
+// Queued returns the number of entries in the queue
+func (f *AsyncFormatter) Queued() int {
+	return len(f.queue)
+}
+
+func (f *AsyncFormatter) format(e asyncEntry, depth int) {
+	if e.kv {
+		f.inner.FormatKV(e.pkg, e.level, depth+1, e.entries...)
+	} else {
+		f.inner.Format(e.pkg, e.level, depth+1, e.entries...)
+	}
+}
+
+func (f *AsyncFormatter) run(flushInterval time.Duration, done func()) {
+	defer func() {
+		done()
+		close(f.done)
+	}()
+
+	var tick <-chan time.Time
+	if flushInterval > 0 {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case e, ok := <-f.queue:
+			if !ok {
+				f.inner.Flush()
+				return
+			}
+			f.format(e, 1)
+		case <-tick:
+			f.inner.Flush()
+		case flushed := <-f.flush:
+			// format the entries queued before the flush
+			for n := len(f.queue); n > 0; n-- {
+				e, ok := <-f.queue
+				if !ok {
+					break
+				}
+				f.format(e, 1)
+			}
+			f.inner.Flush()
+			close(flushed)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AsyncFormatter(t *testing.T) {
+	var b bytes.Buffer
+	f := xlog.NewAsyncFormatter(xlog.NewStringFormatter(&b), 16, time.Millisecond)
+	f.Options(xlog.FormatSkipTime, xlog.FormatWithCaller)
+
+	xlog.SetFormatter(f)
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.Info("msg")
+	logger.Debug("skipped")
+	f.Flush()
+
+	assert.Equal(t, "level=I pkg=xlog_test k=1\nlevel=I pkg=xlog_test \"msg\"\n", b.String())
+	assert.Equal(t, 0, f.Queued())
+	assert.Equal(t, 1, xlog.RunningGoroutines()["xlog.AsyncFormatter"])
+
+	require.NoError(t, f.Close())
+	require.NoError(t, f.Close())
+	assert.NoError(t, xlog.CheckGoroutines())
+
+	b.Reset()
+	logger.KV(xlog.INFO, "k", 2)
+	f.Flush()
+	assert.Equal(t, "level=I pkg=xlog_test k=2\n", b.String())
+}
+
+func Test_AsyncFormatterDrain(t *testing.T) {
+	dest := newBlockingWriter()
+	f := xlog.NewAsyncFormatter(xlog.NewStringFormatter(dest).Options(xlog.FormatSkipTime), 4, 0)
+
+	f.FormatKV("pkg", xlog.INFO, 1, "k", 0)
+	// wait until the first entry is being written
+	<-dest.started
+
+	// the logging calls do not block while the sink is blocked
+	for i := 1; i <= 3; i++ {
+		f.FormatKV("pkg", xlog.INFO, 1, "k", i)
+	}
+	assert.Equal(t, 3, f.Queued())
+
+	close(dest.release)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "level=I pkg=pkg k=0\nlevel=I pkg=pkg k=1\nlevel=I pkg=pkg k=2\nlevel=I pkg=pkg k=3\n", dest.String())
+}
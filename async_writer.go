@@ -0,0 +1,326 @@
+package xlog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy specifies the behavior of AsyncWriter when its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writer until there is space in the buffer
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the entry being written
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest entry in the buffer
+	OverflowDropOldest
+)
+
+// String returns the name of the policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	default:
+		return "block"
+	}
+}
+
+// AsyncConfig specifies configuration for AsyncWriter
+type AsyncConfig struct {
+	// Size specifies the number of entries in the ring buffer,
+	// 1024 by default
+	Size int
+	// Overflow specifies the behavior when the buffer is full
+	Overflow OverflowPolicy
+	// FlushInterval specifies how often the destination is flushed,
+	// if it has Flush() error method.
+	// The destination is also flushed when the buffer is drained.
+	FlushInterval time.Duration
+}
+
+// AsyncWriter is an io.Writer, that pushes formatted entries
+// onto a bounded ring buffer, and writes them to the destination
+// in a background goroutine, so logging does not block on I/O.
+//
+// The writes are grouped by lines, so a partially written entry
+// is never dropped or interleaved.
+type AsyncWriter struct {
+	dest io.Writer
+	cfg  AsyncConfig
+
+	lock    sync.Mutex
+	notFull *sync.Cond
+	idle    *sync.Cond
+	// turn is signaled when the write in progress is queued
+	turn     *sync.Cond
+	ring     [][]byte
+	head     int
+	count    int
+	pending  []byte
+	busy     bool
+	writing  bool
+	stopping bool
+	closed   bool
+	dropped  uint64
+	written  uint64
+
+	wake    chan struct{}
+	stopped chan struct{}
+}
+
+// NewAsyncWriter returns an instance of AsyncWriter,
+// the caller must call Close to drain the buffer and stop the background goroutine.
+func NewAsyncWriter(dest io.Writer, cfg AsyncConfig) *AsyncWriter {
+	if cfg.Size <= 0 {
+		cfg.Size = 1024
+	}
+	w := &AsyncWriter{
+		dest:    dest,
+		cfg:     cfg,
+		ring:    make([][]byte, cfg.Size),
+		wake:    make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	w.notFull = sync.NewCond(&w.lock)
+	w.idle = sync.NewCond(&w.lock)
+	w.turn = sync.NewCond(&w.lock)
+
+	done := TrackGoroutine("xlog.AsyncWriter")
+	go w.run(done)
+	return w
+}
+
+// Write implements io.Writer
+func (w *AsyncWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.waitTurn()
+	defer w.doneTurn()
+
+	if w.closed {
+		// the background goroutine is stopped, write directly
+		return w.dest.Write(b)
+	}
+
+	w.pending = append(w.pending, b...)
+	idx := bytes.LastIndexByte(w.pending, '\n')
+	if idx < 0 {
+		return len(b), nil
+	}
+	// the complete lines are taken out before push may wait for space,
+	// and the partial line is kept for the next write
+	lines := w.pending[: idx+1 : idx+1]
+	w.pending = append([]byte(nil), w.pending[idx+1:]...)
+	w.push(lines)
+	return len(b), nil
+}
+
+// waitTurn waits for the write in progress, that may wait for space in the buffer,
+// so the concurrent writes are queued in order and not merged with its partial line.
+// The caller must hold the lock, and call doneTurn.
+func (w *AsyncWriter) waitTurn() {
+	for w.writing {
+		w.turn.Wait()
+	}
+	w.writing = true
+}
+
+// doneTurn lets the next write to proceed,
+// the caller must hold the lock
+func (w *AsyncWriter) doneTurn() {
+	w.writing = false
+	w.turn.Signal()
+}
+
+// pushPending adds the partial line to the ring buffer,
+// the caller must hold the lock and the turn
+func (w *AsyncWriter) pushPending() {
+	if len(w.pending) > 0 {
+		b := w.pending
+		w.pending = nil
+		w.push(b)
+	}
+}
+
+// push adds the entry to the ring buffer,
+// the caller must hold the lock
+func (w *AsyncWriter) push(b []byte) {
+	size := len(w.ring)
+	if w.count == size {
+		switch w.cfg.Overflow {
+		case OverflowDropNewest:
+			w.dropped++
+			return
+		case OverflowDropOldest:
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % size
+			w.count--
+			w.dropped++
+		default:
+			// the background goroutine drains the buffer before stopping,
+			// so there will be space
+			for w.count == size {
+				w.notFull.Wait()
+			}
+		}
+	}
+	w.ring[(w.head+w.count)%size] = b
+	w.count++
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes the oldest entry from the ring buffer,
+// the caller must hold the lock
+func (w *AsyncWriter) pop() []byte {
+	b := w.ring[w.head]
+	w.ring[w.head] = nil
+	w.head = (w.head + 1) % len(w.ring)
+	w.count--
+	w.notFull.Signal()
+	return b
+}
+
+// Flush blocks until the buffered entries are written
+// and the destination is flushed
+func (w *AsyncWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.waitTurn()
+	if w.closed {
+		w.doneTurn()
+		if f, ok := w.dest.(flushable); ok {
+			_ = f.Flush()
+		}
+		return
+	}
+	w.pushPending()
+	w.doneTurn()
+
+	for w.count > 0 || w.busy {
+		w.idle.Wait()
+	}
+}
+
+// Close drains the buffer, and stops the background goroutine.
+// The subsequent writes are written directly to the destination.
+func (w *AsyncWriter) Close() error {
+	w.lock.Lock()
+	w.waitTurn()
+	if w.stopping {
+		w.doneTurn()
+		w.lock.Unlock()
+		<-w.stopped
+		return nil
+	}
+	w.pushPending()
+	w.stopping = true
+	w.doneTurn()
+	w.lock.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	<-w.stopped
+	return nil
+}
+
+// Dropped returns the number of dropped entries
+func (w *AsyncWriter) Dropped() uint64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.dropped
+}
+
+// Written returns the number of entries written to the destination
+func (w *AsyncWriter) Written() uint64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.written
+}
+
+// Queued returns the number of entries in the buffer
+func (w *AsyncWriter) Queued() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.count
+}
+
+func (w *AsyncWriter) run(done func()) {
+	defer func() {
+		done()
+		close(w.stopped)
+	}()
+
+	var tick <-chan time.Time
+	flusher, canFlush := w.dest.(flushable)
+	if canFlush && w.cfg.FlushInterval > 0 {
+		ticker := time.NewTicker(w.cfg.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			_ = flusher.Flush()
+			continue
+		case <-w.wake:
+		}
+
+		if closed := w.drain(flusher, canFlush); closed {
+			return
+		}
+	}
+}
+
+// drain writes all the buffered entries, and returns true if closed
+func (w *AsyncWriter) drain(flusher flushable, canFlush bool) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	dirty := false
+	for {
+		if w.count == 0 {
+			if dirty && canFlush {
+				dirty = false
+				w.busy = true
+				w.lock.Unlock()
+				_ = flusher.Flush()
+				w.lock.Lock()
+				continue
+			}
+			w.busy = false
+			w.idle.Broadcast()
+			if w.stopping {
+				// the subsequent writes go directly to the destination
+				w.closed = true
+			}
+			return w.closed
+		}
+
+		b := w.pop()
+		w.busy = true
+		dirty = true
+		w.lock.Unlock()
+		_, _ = w.dest.Write(b)
+		w.lock.Lock()
+		w.written++
+	}
+}
+
+type flushable interface {
+	Flush() error
+}
@@ -0,0 +1,176 @@
+package xlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks writes until released
+type blockingWriter struct {
+	lock    sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		release: make(chan struct{}),
+		started: make(chan struct{}),
+	}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *blockingWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func Test_AsyncWriter(t *testing.T) {
+	var b bytes.Buffer
+	dest := bufio.NewWriter(&b)
+	w := xlog.NewAsyncWriter(dest, xlog.AsyncConfig{FlushInterval: time.Millisecond})
+
+	xlog.SetFormatter(xlog.NewStringFormatter(w).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "k", strings.Repeat("a", 5000))
+	_, _ = w.Write([]byte("partial"))
+	w.Flush()
+
+	assert.Equal(t, "level=I pkg=xlog_test k=1\nlevel=I pkg=xlog_test k=\""+strings.Repeat("a", 1023)+"...\" truncated=true\npartial", b.String())
+	assert.Equal(t, uint64(3), w.Written())
+	assert.Equal(t, 0, w.Queued())
+	assert.Equal(t, 1, xlog.RunningGoroutines()["xlog.AsyncWriter"])
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	assert.NoError(t, xlog.CheckGoroutines())
+
+	b.Reset()
+	logger.KV(xlog.INFO, "k", 2)
+	w.Flush()
+	assert.Equal(t, "level=I pkg=xlog_test k=2\n", b.String())
+}
+
+func Test_AsyncWriterOverflow(t *testing.T) {
+	tcases := []struct {
+		policy   xlog.OverflowPolicy
+		name     string
+		expected string
+		dropped  uint64
+	}{
+		{xlog.OverflowDropNewest, "drop-newest", "0\n1\n2\n", 2},
+		{xlog.OverflowDropOldest, "drop-oldest", "0\n3\n4\n", 2},
+		{xlog.OverflowBlock, "block", "0\n1\n2\n3\n4\n", 0},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.name, tc.policy.String())
+
+			dest := newBlockingWriter()
+			w := xlog.NewAsyncWriter(dest, xlog.AsyncConfig{Size: 2, Overflow: tc.policy})
+
+			_, _ = w.Write([]byte("0\n"))
+			// wait until the first entry is being written
+			<-dest.started
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for _, s := range []string{"1\n", "2\n", "3\n", "4\n"} {
+					_, _ = w.Write([]byte(s))
+				}
+			}()
+
+			if tc.policy != xlog.OverflowBlock {
+				<-done
+			}
+			close(dest.release)
+			<-done
+
+			require.NoError(t, w.Close())
+			assert.Equal(t, tc.expected, dest.String())
+			assert.Equal(t, tc.dropped, w.Dropped())
+		})
+	}
+}
+
+func Test_AsyncWriterBlockConcurrent(t *testing.T) {
+	dest := newBlockingWriter()
+	w := xlog.NewAsyncWriter(dest, xlog.AsyncConfig{Size: 1, Overflow: xlog.OverflowBlock})
+
+	_, _ = w.Write([]byte("0\n"))
+	// wait until the first entry is being written, and fill the buffer
+	<-dest.started
+	_, _ = w.Write([]byte("1\n"))
+
+	// the write with the partial line waits for space,
+	// the flush and the next write are queued after the lines of the first one
+	var wg sync.WaitGroup
+	for _, fn := range []func(){
+		func() { _, _ = w.Write([]byte("2\n3\npar")) },
+		w.Flush,
+		func() { _, _ = w.Write([]byte("tial\n")) },
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(dest.release)
+	wg.Wait()
+	w.Flush()
+	assert.Equal(t, "0\n1\n2\n3\npartial\n", dest.String())
+
+	// the concurrent writers with multi-line entries, and flushes
+	const writers, entries = 8, 100
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < entries; j++ {
+				_, _ = fmt.Fprintf(w, "w%d e%d a\nw%d e%d b\n", i, j, i, j)
+				if j%10 == 0 {
+					w.Flush()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(dest.String(), "\n"), "\n")[5:]
+	require.Len(t, lines, writers*entries*2)
+	next := make([]int, writers)
+	for idx := 0; idx < len(lines); idx += 2 {
+		var i, j int
+		_, err := fmt.Sscanf(lines[idx], "w%d e%d a", &i, &j)
+		require.NoError(t, err, lines[idx])
+		assert.Equal(t, next[i], j, "out of order: %s", lines[idx])
+		assert.Equal(t, fmt.Sprintf("w%d e%d b", i, j), lines[idx+1])
+		next[i]++
+	}
+}
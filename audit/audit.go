@@ -0,0 +1,209 @@
+// Package audit provides the logger of audit events,
+// that writes tamper-evident records chained by hashes,
+// and emits the same events as structured entries to the logging pipeline.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// KeyAudit is the key of the pipeline entries of audit events
+const KeyAudit = "audit"
+
+// Record is the audit record
+type Record struct {
+	// Seq is the sequence number of the record in the chain, starting at 1
+	Seq uint64 `json:"seq"`
+	// Time of the event
+	Time time.Time `json:"time"`
+	// Action is the audited action, such as "user.login"
+	Action string `json:"action"`
+	// Actor is the identity performing the action
+	Actor string `json:"actor,omitempty"`
+	// Fields are the key-value entries of the event
+	Fields map[string]any `json:"fields,omitempty"`
+	// Prev is the hash of the previous record, empty for the first record
+	Prev string `json:"prev"`
+	// Hash is SHA-256 of the record without the hash
+	Hash string `json:"hash"`
+}
+
+// hashSuffix is the size of `,"hash":"<hex>"}` suffix of the record line
+const hashSuffix = len(`,"hash":""}`) + sha256.Size*2
+
+// Config specifies configuration for Logger
+type Config struct {
+	// Writer of the tamper-evident records, such as the file opened for append
+	Writer io.Writer
+	// Logger emits the events to the logging pipeline,
+	// with "audit=true" entry, if set
+	Logger xlog.KeyValueLogger
+	// Level of the pipeline entries, NOTICE by default
+	Level xlog.LogLevel
+	// Last specifies the last record of the existing file,
+	// to continue the chain, as returned by Verify
+	Last *Record
+}
+
+// Logger writes audit events
+type Logger struct {
+	cfg Config
+
+	lock sync.Mutex
+	seq  uint64
+	prev string
+}
+
+// New returns Logger
+func New(cfg Config) *Logger {
+	if cfg.Level == 0 {
+		cfg.Level = xlog.NOTICE
+	}
+	l := &Logger{cfg: cfg}
+	if cfg.Last != nil {
+		l.seq = cfg.Last.Seq
+		l.prev = cfg.Last.Hash
+	}
+	return l
+}
+
+// Log writes the event to the records, and then emits it to the pipeline,
+// so the pipeline entries are the copies of the persisted records.
+// The entries are key/value pairs.
+func (l *Logger) Log(ctx context.Context, action, actor string, entries ...any) (*Record, error) {
+	fields, values, err := toFields(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	r := &Record{
+		Seq:    l.seq + 1,
+		Time:   xlog.TimeNowFn().UTC(),
+		Action: action,
+		Actor:  actor,
+		Fields: fields,
+		Prev:   l.prev,
+	}
+	line, err := seal(r)
+	if err == nil {
+		_, err = l.cfg.Writer.Write(line)
+	}
+	if err != nil {
+		l.lock.Unlock()
+		return nil, errors.WithMessagef(err, "failed to write audit record %d", r.Seq)
+	}
+	l.seq = r.Seq
+	l.prev = r.Hash
+	l.lock.Unlock()
+
+	if l.cfg.Logger != nil {
+		kv := []any{
+			KeyAudit, true,
+			"action", r.Action,
+			"actor", r.Actor,
+			"seq", r.Seq,
+			"hash", r.Hash,
+		}
+		kv = append(kv, values...)
+		l.cfg.Logger.ContextKV(ctx, l.cfg.Level, kv...)
+	}
+	return r, nil
+}
+
+// seal computes the hash of the record,
+// and returns the record line
+func seal(r *Record) ([]byte, error) {
+	r.Hash = ""
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// remove `,"hash":""}`
+	body = body[:len(body)-len(`,"hash":""}`)]
+	body = append(body, '}')
+
+	h := sha256.Sum256(body)
+	r.Hash = hex.EncodeToString(h[:])
+
+	line := append(body[:len(body)-1], `,"hash":"`...)
+	line = append(line, r.Hash...)
+	line = append(line, "\"}\n"...)
+	return line, nil
+}
+
+// Verify reads the records, and verifies the hashes and the chain,
+// returning the last record to continue the chain
+func Verify(r io.Reader) (*Record, error) {
+	var last *Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		rec := new(Record)
+		if err := json.Unmarshal(line, rec); err != nil {
+			return last, errors.WithMessagef(err, "invalid record at line %d", n)
+		}
+		if len(line) < hashSuffix || string(line[len(line)-hashSuffix:]) != `,"hash":"`+rec.Hash+`"}` {
+			return last, errors.Errorf("invalid hash at line %d", n)
+		}
+		body := append(append([]byte{}, line[:len(line)-hashSuffix]...), '}')
+		h := sha256.Sum256(body)
+		if hex.EncodeToString(h[:]) != rec.Hash {
+			return last, errors.Errorf("record %d at line %d is modified", rec.Seq, n)
+		}
+
+		prevSeq, prevHash := uint64(0), ""
+		if last != nil {
+			prevSeq, prevHash = last.Seq, last.Hash
+		}
+		if rec.Prev != prevHash || (last != nil && rec.Seq != prevSeq+1) {
+			return last, errors.Errorf("chain is broken at line %d: record %d follows %d", n, rec.Seq, prevSeq)
+		}
+		last = rec
+	}
+	if err := scanner.Err(); err != nil {
+		return last, errors.WithStack(err)
+	}
+	return last, nil
+}
+
+// toFields returns the map of key/value pairs,
+// and the list of pairs with the values as recorded
+func toFields(entries []any) (map[string]any, []any, error) {
+	if len(entries) == 0 {
+		return nil, nil, nil
+	}
+	fields := make(map[string]any, len(entries)/2)
+	values := make([]any, 0, len(entries)+1)
+	for i := 0; i < len(entries); i += 2 {
+		k, ok := entries[i].(string)
+		if !ok {
+			return nil, nil, errors.Errorf("key is not a string: %v", xlog.EscapedString(entries[i]))
+		}
+		var v any
+		if i+1 < len(entries) {
+			v = entries[i+1]
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+		values = append(values, k, v)
+	}
+	return fields, values, nil
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "audit")
+
+func Test_Logger(t *testing.T) {
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	var pipeline bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&pipeline).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	var records bytes.Buffer
+	l := New(Config{Writer: &records, Logger: logger})
+
+	r1, err := l.Log(context.Background(), "user.login", "alice", "ip", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), r1.Seq)
+	assert.Empty(t, r1.Prev)
+
+	r2, err := l.Log(context.Background(), "user.delete", "alice", "user", "bob", "err", errors.New("not found"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), r2.Seq)
+	assert.Equal(t, r1.Hash, r2.Prev)
+
+	_, err = l.Log(context.Background(), "invalid", "alice", 1, 2)
+	assert.EqualError(t, err, "key is not a string: 1")
+
+	lines := strings.Split(strings.TrimSpace(records.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"seq":1,"time":"2021-04-01T00:00:00Z","action":"user.login","actor":"alice","fields":{"ip":"10.0.0.1"},"prev":"","hash":"`+r1.Hash+`"}`, lines[0])
+
+	// the pipeline entries are copies of the records
+	assert.Equal(t,
+		`{"action":"user.login","actor":"alice","audit":true,"hash":"`+r1.Hash+`","ip":"10.0.0.1","level":"N","pkg":"audit","seq":1}`+"\n"+
+			`{"action":"user.delete","actor":"alice","audit":true,"err":"not found","hash":"`+r2.Hash+`","level":"N","pkg":"audit","seq":2,"user":"bob"}`+"\n",
+		pipeline.String())
+
+	last, err := Verify(strings.NewReader(records.String()))
+	require.NoError(t, err)
+	assert.Equal(t, r2.Hash, last.Hash)
+
+	// continue the chain
+	l = New(Config{Writer: &records, Last: last})
+	r3, err := l.Log(context.Background(), "user.logout", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), r3.Seq)
+	assert.Equal(t, r2.Hash, r3.Prev)
+
+	last, err = Verify(strings.NewReader(records.String()))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last.Seq)
+}
+
+func Test_Verify(t *testing.T) {
+	var records bytes.Buffer
+	l := New(Config{Writer: &records})
+	for _, action := range []string{"a", "b", "c"} {
+		_, err := l.Log(context.Background(), action, "actor", "k", 1)
+		require.NoError(t, err)
+	}
+	lines := strings.SplitAfter(records.String(), "\n")
+
+	tcases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"modified", strings.Replace(records.String(), `"action":"b"`, `"action":"x"`, 1), "record 2 at line 2 is modified"},
+		{"removed", lines[0] + lines[2], "chain is broken at line 2: record 3 follows 1"},
+		{"truncated head", lines[1] + lines[2], "chain is broken at line 1: record 2 follows 0"},
+		{"reordered", lines[1] + lines[0] + lines[2], "chain is broken at line 1: record 2 follows 0"},
+		{"invalid", lines[0] + "{}\n", "invalid hash at line 2"},
+		{"not json", lines[0] + "x\n", "invalid record at line 2: invalid character 'x' looking for beginning of value"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tc.data))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	last, err := Verify(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Nil(t, last)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func Test_LoggerWriteError(t *testing.T) {
+	var pipeline bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&pipeline))
+
+	l := New(Config{Writer: failingWriter{}, Logger: logger})
+	_, err := l.Log(context.Background(), "a", "actor")
+	assert.EqualError(t, err, "failed to write audit record 1: disk full")
+	// not emitted to the pipeline
+	assert.Empty(t, pipeline.String())
+
+	// the sequence is not advanced
+	l.cfg.Writer = &pipeline
+	r, err := l.Log(context.Background(), "a", "actor")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), r.Seq)
+}
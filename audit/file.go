@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLocked is returned by OpenFile, if the file is locked by another writer
+var ErrLocked = errors.New("audit file is locked by another writer")
+
+// File is the append-only file of audit records,
+// exclusively locked with OS file lock while open,
+// so concurrent writers in this or other processes can not break the chain
+type File struct {
+	f    *os.File
+	last *Record
+}
+
+// OpenFile opens the audit file for append, creating it if needed,
+// and acquires the exclusive lock, or returns ErrLocked if the file is used by another writer.
+// The existing records are verified, and the file is refused if the chain is broken.
+// Use Last to continue the chain:
+//
+//	f, err := audit.OpenFile(path)
+//	...
+//	l := audit.New(audit.Config{Writer: f, Last: f.Last()})
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	last, err := Verify(f)
+	if err != nil {
+		_ = unlockFile(f)
+		_ = f.Close()
+		return nil, errors.WithMessagef(err, "failed to verify audit file: %s", path)
+	}
+	return &File{f: f, last: last}, nil
+}
+
+// Last returns the last record of the file when it was opened,
+// or nil if the file was empty
+func (f *File) Last() *Record {
+	return f.last
+}
+
+// Name returns the name of the file
+func (f *File) Name() string {
+	return f.f.Name()
+}
+
+// Write appends the record line
+func (f *File) Write(b []byte) (int, error) {
+	return f.f.Write(b)
+}
+
+// Sync commits the written records to the storage
+func (f *File) Sync() error {
+	return errors.WithStack(f.f.Sync())
+}
+
+// Close releases the lock and closes the file
+func (f *File) Close() error {
+	err := unlockFile(f.f)
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	return errors.WithStack(err)
+}
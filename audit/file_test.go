@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := OpenFile(path)
+	require.NoError(t, err)
+	assert.Nil(t, f.Last())
+	assert.Equal(t, path, f.Name())
+
+	// concurrent writer is refused
+	_, err = OpenFile(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	l := New(Config{Writer: f})
+	_, err = l.Log(context.Background(), "user.login", "alice")
+	require.NoError(t, err)
+	r2, err := l.Log(context.Background(), "user.logout", "alice")
+	require.NoError(t, err)
+	require.NoError(t, f.Sync())
+	require.NoError(t, f.Close())
+
+	// the chain is continued
+	f, err = OpenFile(path)
+	require.NoError(t, err)
+	require.NotNil(t, f.Last())
+	assert.Equal(t, *r2, *f.Last())
+
+	l = New(Config{Writer: f, Last: f.Last()})
+	r3, err := l.Log(context.Background(), "user.login", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), r3.Seq)
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	last, err := Verify(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, r3.Hash, last.Hash)
+
+	// tampered file is refused
+	tampered := []byte(string(b[:len(b)-3]) + "0\"}\n")
+	require.NoError(t, os.WriteFile(path, tampered, 0600))
+	_, err = OpenFile(path)
+	assert.ErrorContains(t, err, "failed to verify audit file: "+path)
+
+	// unlocked after the failure
+	require.NoError(t, os.WriteFile(path, b, 0600))
+	f, err = OpenFile(path)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = OpenFile(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package audit
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func lockFile(_ *os.File) error {
+	return errors.New("audit file locking is not supported on this platform")
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,184 @@
+// Package azure provides formatter for Azure Monitor and Log Analytics,
+// and the exporter to HTTP Data Collector API.
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// SeverityLevel is the severity level of Azure Monitor
+type SeverityLevel int
+
+// Severity levels of Azure Monitor
+const (
+	Verbose SeverityLevel = iota
+	Information
+	Warning
+	Error
+	Critical
+)
+
+var levelsToSeverity = map[xlog.LogLevel]SeverityLevel{
+	xlog.CRITICAL: Critical,
+	xlog.ERROR:    Error,
+	xlog.WARNING:  Warning,
+	xlog.NOTICE:   Information,
+	xlog.INFO:     Information,
+	xlog.TRACE:    Verbose,
+	xlog.DEBUG:    Verbose,
+}
+
+// SeverityFor returns Azure Monitor severity level for the log level
+func SeverityFor(l xlog.LogLevel) SeverityLevel {
+	if s, ok := levelsToSeverity[l]; ok {
+		return s
+	}
+	return Information
+}
+
+// TimeGeneratedField is the name of the time field
+const TimeGeneratedField = "TimeGenerated"
+
+// Record is the log record in the schema expected by Azure Monitor
+type Record struct {
+	TimeGenerated string         `json:"TimeGenerated,omitempty"`
+	SeverityLevel SeverityLevel  `json:"SeverityLevel"`
+	Message       string         `json:"Message,omitempty"`
+	Category      string         `json:"Category,omitempty"`
+	Function      string         `json:"Function,omitempty"`
+	Source        string         `json:"Source,omitempty"`
+	Dimensions    map[string]any `json:"customDimensions,omitempty"`
+}
+
+// formatter provides Azure Monitor logs format
+type formatter struct {
+	w io.Writer
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// NewFormatter returns Azure Monitor formatter, each entry is written
+// as a single JSON line with one Write call.
+// The key-value entries are emitted as custom dimensions.
+func NewFormatter(w io.Writer) xlog.Formatter {
+	return &formatter{
+		w:          w,
+		withCaller: true,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatSkipTime:
+			f.skipTime = true
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs, emitted as custom dimensions
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format log entry string to the stream
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are written immediately
+func (f *formatter) Flush() {}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	r := Record{
+		SeverityLevel: SeverityFor(l),
+		Category:      pkg,
+	}
+	if !f.skipTime {
+		r.TimeGenerated = xlog.TimeNowFn().UTC().Format(time.RFC3339Nano)
+	}
+	if f.withCaller {
+		caller, file, line := xlog.Caller(depth + 1)
+		r.Function = caller
+		r.Source = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if k == "msg" && msg == "" {
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if s, ok := val.(string); ok && s == "" && !f.printEmpty {
+			continue
+		}
+		if r.Dimensions == nil {
+			r.Dimensions = make(map[string]any)
+		}
+		r.Dimensions[k] = val
+	}
+	r.Message = strings.TrimSpace(msg)
+
+	f.buf.Reset()
+	encoder := json.NewEncoder(&f.buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r); err != nil {
+		return
+	}
+	_, _ = f.w.Write(f.buf.Bytes())
+	xlog.ObserveEntrySize(pkg, f.buf.Len())
+}
+
+// value returns the JSON value of the custom dimension
+func value(v any) any {
+	switch typ := v.(type) {
+	case string, bool:
+		return typ
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return typ
+	case error:
+		return typ.Error()
+	case time.Time:
+		return typ.UTC().Format(time.RFC3339)
+	case time.Duration:
+		return typ.String()
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
@@ -0,0 +1,45 @@
+package azure
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Formatter(t *testing.T) {
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	var b bytes.Buffer
+	f := NewFormatter(&b).Options(xlog.FormatNoCaller)
+
+	f.FormatKV("pkg", xlog.WARNING, 1, "msg", "request", "api", "get", "latency", 12, "err", errors.New("failed"), "ok", true, "nil", nil)
+	assert.Equal(t, `{"TimeGenerated":"2021-04-01T00:00:00Z","SeverityLevel":2,"Message":"request","Category":"pkg","customDimensions":{"api":"get","err":"failed","latency":12,"ok":true}}`+"\n", b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatSkipTime)
+	f.Format("", xlog.DEBUG, 1, "debug ", "message\n")
+	f.Flush()
+	assert.Equal(t, `{"SeverityLevel":0,"Message":"debug message"}`+"\n", b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatWithCaller)
+	f.Format("", xlog.CRITICAL, 1, "msg")
+	assert.Contains(t, b.String(), `"SeverityLevel":4`)
+	assert.Contains(t, b.String(), `"Function":"Test_Formatter","Source":"azure_test.go:`)
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+
+	assert.Equal(t, Information, SeverityFor(xlog.NOTICE))
+	assert.Equal(t, Error, SeverityFor(xlog.ERROR))
+}
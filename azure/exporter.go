@@ -0,0 +1,256 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxBatchSize is the maximum size of a post to HTTP Data Collector API
+	MaxBatchSize = 30 * 1024 * 1024
+	// apiVersion of HTTP Data Collector API
+	apiVersion = "2016-04-01"
+	// resource of HTTP Data Collector API, used in the signature
+	resource = "/api/logs"
+)
+
+// ExporterConfig specifies configuration for Exporter
+type ExporterConfig struct {
+	// WorkspaceID of Log Analytics workspace
+	WorkspaceID string
+	// SharedKey is the base64 encoded primary or secondary key of the workspace
+	SharedKey string
+	// LogType specifies the custom log type, the table name without "_CL" suffix
+	LogType string
+	// Endpoint specifies the URL of the API,
+	// "https://<WorkspaceID>.ods.opinsights.azure.com/api/logs" by default
+	Endpoint string
+	// HTTPClient specifies the client, http.DefaultClient by default
+	HTTPClient *http.Client
+	// FlushInterval specifies the interval to send batched records,
+	// 5 seconds by default
+	FlushInterval time.Duration
+	// MaxBatchSize specifies the maximum size of a post, MaxBatchSize by default
+	MaxBatchSize int
+	// MaxRetries specifies the number of retries of failed posts, 3 by default
+	MaxRetries int
+	// Backoff specifies the initial delay between retries,
+	// doubled on each retry, 200ms by default
+	Backoff time.Duration
+}
+
+// Exporter is io.Writer that batches records for HTTP Data Collector API,
+// each Write call is a single JSON record, as written by the formatter.
+// The requests are signed with HMAC-SHA256 of the shared key.
+type Exporter struct {
+	cfg ExporterConfig
+	key []byte
+
+	lock    sync.Mutex
+	records [][]byte
+	size    int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewExporter returns Exporter
+func NewExporter(cfg ExporterConfig) (*Exporter, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.SharedKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid shared key")
+	}
+	if cfg.WorkspaceID == "" || cfg.LogType == "" {
+		return nil, errors.New("workspace ID and log type are required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://%s.ods.opinsights.azure.com%s", cfg.WorkspaceID, resource)
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxBatchSize <= 0 || cfg.MaxBatchSize > MaxBatchSize {
+		cfg.MaxBatchSize = MaxBatchSize
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+
+	e := &Exporter{
+		cfg:  cfg,
+		key:  key,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// Write adds the record to the batch
+func (e *Exporter) Write(p []byte) (int, error) {
+	record := bytes.TrimSpace(p)
+	if len(record) == 0 {
+		return len(p), nil
+	}
+	record = append([]byte{}, record...)
+	size := len(record) + 1
+
+	e.lock.Lock()
+	var batch [][]byte
+	if e.size+size > e.cfg.MaxBatchSize {
+		batch = e.take()
+	}
+	e.records = append(e.records, record)
+	e.size += size
+	e.lock.Unlock()
+
+	if len(batch) > 0 {
+		if err := e.send(context.Background(), batch); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the batched records
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.lock.Lock()
+	batch := e.take()
+	e.lock.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return e.send(ctx, batch)
+}
+
+// Close stops the background flush, and sends the batched records
+func (e *Exporter) Close() error {
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+	})
+	return e.Flush(context.Background())
+}
+
+// take returns the batched records, the caller must hold the lock
+func (e *Exporter) take() [][]byte {
+	batch := e.records
+	e.records = nil
+	e.size = 0
+	return batch
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Flush(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "xlog: azure: %v\n", err)
+			}
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// send the records as JSON array, retrying with backoff
+// on throttling and server errors
+func (e *Exporter) send(ctx context.Context, records [][]byte) error {
+	body := make([]byte, 0, len(records)*256)
+	body = append(body, '[')
+	for i, r := range records {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, r...)
+	}
+	body = append(body, ']')
+
+	delay := e.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = e.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= e.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "failed to send %d records", len(records))
+		}
+		delay *= 2
+	}
+	return errors.WithMessagef(err, "failed to send %d records", len(records))
+}
+
+// post sends the request, and returns true if it can be retried
+func (e *Exporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint+"?api-version="+apiVersion, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Log-Type", e.cfg.LogType)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("time-generated-field", TimeGeneratedField)
+	req.Header.Set("Authorization", e.authorization(len(body), date))
+
+	resp, err := e.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, errors.Errorf("unexpected status: %s", resp.Status)
+}
+
+// authorization returns the SharedKey authorization header
+func (e *Exporter) authorization(contentLength int, date string) string {
+	return "SharedKey " + e.cfg.WorkspaceID + ":" + Signature(e.key, contentLength, date)
+}
+
+// Signature returns base64 encoded HMAC-SHA256 signature of the request
+// to HTTP Data Collector API
+func Signature(key []byte, contentLength int, date string) string {
+	s := "POST\n" + strconv.Itoa(contentLength) + "\napplication/json\nx-ms-date:" + date + "\n" + resource
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(s))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,115 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Signature(t *testing.T) {
+	key := []byte("secret")
+	assert.Equal(t, "xqrhoN4XFATm48tk/GhDabKaWyAF5JvpMtFJEGvDv5c=", Signature(key, 100, "Thu, 01 Apr 2021 00:00:00 GMT"))
+}
+
+func Test_Exporter(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("secret"))
+
+	var lock sync.Mutex
+	var bodies []string
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		assert.Equal(t, "/api/logs", r.URL.Path)
+		assert.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "App", r.Header.Get("Log-Type"))
+		assert.Equal(t, TimeGeneratedField, r.Header.Get("time-generated-field"))
+
+		body, _ := io.ReadAll(r.Body)
+		date := r.Header.Get("x-ms-date")
+		assert.Equal(t, strconv.Itoa(len(body)), r.Header.Get("Content-Length"))
+		assert.Equal(t, "SharedKey ws1:"+Signature([]byte("secret"), len(body), date), r.Header.Get("Authorization"))
+
+		bodies = append(bodies, string(body))
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status = statuses[0]
+			statuses = statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(ExporterConfig{
+		WorkspaceID:   "ws1",
+		SharedKey:     key,
+		LogType:       "App",
+		Endpoint:      srv.URL + "/api/logs",
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	_, err = e.Write([]byte(`{"Message":"1"}` + "\n"))
+	require.NoError(t, err)
+	_, err = e.Write([]byte(`{"Message":"2"}` + "\n"))
+	require.NoError(t, err)
+	_, err = e.Write([]byte("\n"))
+	require.NoError(t, err)
+	require.NoError(t, e.Flush(context.Background()))
+	require.NoError(t, e.Close())
+	require.NoError(t, e.Close())
+
+	lock.Lock()
+	assert.Equal(t, []string{`[{"Message":"1"},{"Message":"2"}]`, `[{"Message":"1"},{"Message":"2"}]`}, bodies)
+
+	// client errors are not retried
+	bodies = nil
+	statuses = []int{http.StatusForbidden}
+	lock.Unlock()
+
+	e, err = NewExporter(ExporterConfig{
+		WorkspaceID:   "ws1",
+		SharedKey:     key,
+		LogType:       "App",
+		Endpoint:      srv.URL + "/api/logs",
+		FlushInterval: time.Hour,
+		MaxBatchSize:  20,
+	})
+	require.NoError(t, err)
+	defer e.Close()
+
+	_, err = e.Write([]byte(`{"Message":"1"}`))
+	require.NoError(t, err)
+	_, err = e.Write([]byte(`{"Message":"2"}`))
+	assert.EqualError(t, err, "failed to send 1 records: unexpected status: 403 Forbidden")
+
+	lock.Lock()
+	assert.Len(t, bodies, 1)
+	lock.Unlock()
+}
+
+func Test_NewExporter(t *testing.T) {
+	_, err := NewExporter(ExporterConfig{WorkspaceID: "ws1", LogType: "App", SharedKey: "!"})
+	assert.EqualError(t, err, "invalid shared key: illegal base64 data at input byte 0")
+
+	_, err = NewExporter(ExporterConfig{SharedKey: "c2VjcmV0"})
+	assert.EqualError(t, err, "workspace ID and log type are required")
+
+	e, err := NewExporter(ExporterConfig{WorkspaceID: "ws1", LogType: "App", SharedKey: "c2VjcmV0"})
+	require.NoError(t, err)
+	defer e.Close()
+	assert.Equal(t, "https://ws1.ods.opinsights.azure.com/api/logs", e.cfg.Endpoint)
+	assert.Equal(t, "SharedKey ws1:"+Signature([]byte("secret"), 10, "d"), e.authorization(10, "d"))
+}
@@ -0,0 +1,132 @@
+package xlog
+
+import "context"
+
+// Batch collects entries of the package logger, and emits them at once,
+// with a single sink lock acquisition and a single writer flush.
+// It is useful for loops, that log many entries.
+// Batch is not safe for concurrent use.
+type Batch struct {
+	p       *PackageLogger
+	ctx     context.Context
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	t       entriesType
+	level   LogLevel
+	entries []any
+}
+
+// deferredFlusher is implemented by formatters,
+// that can defer flushing the writer while a batch is formatted
+type deferredFlusher interface {
+	setDeferFlush(deferred bool)
+}
+
+// Batch returns the builder of entries, emitted by Emit
+func (p *PackageLogger) Batch() *Batch {
+	return &Batch{p: p}
+}
+
+// WithContext sets the context of the entries,
+// see ContextKV
+func (b *Batch) WithContext(ctx context.Context) *Batch {
+	b.ctx = ctx
+	return b
+}
+
+// KV adds the entry in "key1=value1, ..., keyN=valueN" format
+func (b *Batch) KV(level LogLevel, entries ...any) *Batch {
+	b.entries = append(b.entries, batchEntry{t: kv, level: level, entries: b.p.withPrefix(entries)})
+	return b
+}
+
+// Log adds the entry with the values separated by space
+func (b *Batch) Log(level LogLevel, entries ...any) *Batch {
+	b.entries = append(b.entries, batchEntry{t: plain, level: level, entries: entries})
+	return b
+}
+
+// Len returns the number of collected entries
+func (b *Batch) Len() int {
+	return len(b.entries)
+}
+
+// Emit emits the collected entries in order, and resets the batch.
+// The entries are subject to the enablers and rate limits as logged individually.
+func (b *Batch) Emit() {
+	if len(b.entries) == 0 {
+		return
+	}
+	entries := b.entries
+	b.entries = nil
+
+	p := b.p
+	ctx := b.ctx
+	if ctx != nil {
+		values := append(append([]any{}, ContextEntries(ctx)...), TraceEntries(ctx)...)
+		if len(values) > 0 {
+			for i := range entries {
+				if entries[i].t == kv {
+					entries[i].entries = append(values[:len(values):len(values)], entries[i].entries...)
+				}
+			}
+		}
+	}
+
+	// the decisions are made before the sink is locked,
+	// as the enablers and OnError callback may log
+	selected := entries[:0]
+	for _, e := range entries {
+		if e.level == ERROR {
+			logger.reportError(p.repo, p.pkg)
+		}
+		if logger.decide(ctx, p.repo, p.pkg, p.levelFor(), e.level) {
+			selected = append(selected, e)
+		}
+	}
+	if len(selected) == 0 {
+		return
+	}
+
+	f := logger.lockedSinkFor(p.repo, p.pkg)
+	if f == nil {
+		return
+	}
+
+	// the entries dropped by the rate limit are reported after the sink is unlocked
+	var dropped []LogLevel
+	df, deferred := f.Formatter.(deferredFlusher)
+	if deferred {
+		df.setDeferFlush(true)
+	}
+	for _, e := range selected {
+		if logger.allowRate(f.Formatter, p.repo, p.pkg, e.level, calldepth) {
+			p.emit(ctx, f, e.t, calldepth, e.level, e.entries...)
+		} else {
+			dropped = append(dropped, e.level)
+		}
+	}
+	if deferred {
+		df.setDeferFlush(false)
+	}
+	f.Flush()
+	f.Unlock()
+
+	for _, level := range dropped {
+		logger.entryDropped(p.pkg, level)
+	}
+}
+
+func (s *StringFormatter) setDeferFlush(deferred bool) {
+	s.size.deferFlush = deferred
+}
+
+func (c *PrettyFormatter) setDeferFlush(deferred bool) {
+	c.size.deferFlush = deferred
+}
+
+func (c *JSONFormatter) setDeferFlush(deferred bool) {
+	c.size.deferFlush = deferred
+}
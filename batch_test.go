@@ -0,0 +1,110 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingWriter counts Write calls
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(b)
+}
+
+func Test_Batch(t *testing.T) {
+	var w countingWriter
+	xlog.SetFormatter(xlog.NewStringFormatter(&w).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetEntrySizeMetrics(true)
+	defer xlog.SetEntrySizeMetrics(false)
+	xlog.ResetEntrySizes()
+
+	b := logger.Batch()
+	for i := 0; i < 3; i++ {
+		b.KV(xlog.INFO, "i", i)
+	}
+	b.Log(xlog.WARNING, "done")
+	b.KV(xlog.DEBUG, "skipped", true)
+	assert.Equal(t, 5, b.Len())
+	assert.Empty(t, w.String())
+
+	b.Emit()
+	assert.Equal(t,
+		"level=I pkg=xlog_test i=0\n"+
+			"level=I pkg=xlog_test i=1\n"+
+			"level=I pkg=xlog_test i=2\n"+
+			"level=W pkg=xlog_test \"done\"\n",
+		w.String())
+	// single flush
+	assert.Equal(t, 1, w.writes)
+	assert.Equal(t, 0, b.Len())
+
+	// the entry sizes are observed per entry
+	sizes := xlog.EntrySizes()["xlog_test"]
+	assert.Equal(t, uint64(4), sizes.Count)
+	assert.Equal(t, uint64(w.Len()), sizes.Sum)
+
+	// the formatter flushes each entry after the batch
+	w.Reset()
+	w.writes = 0
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	assert.Equal(t, 2, w.writes)
+
+	// empty batch
+	w.Reset()
+	b.Emit()
+	assert.Empty(t, w.String())
+}
+
+func Test_BatchRateLimit(t *testing.T) {
+	m := newCountingSink()
+	xlog.SetMetricsSink(m)
+	defer xlog.SetMetricsSink(nil)
+	defer xlog.WithRateLimit(testRepo, "xlog_test", xlog.WARNING, 0)
+
+	var w bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&w).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	// the entries dropped by the rate limit are reported as with PackageLogger
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.WARNING, 1)
+	logger.Batch().
+		KV(xlog.WARNING, "k", 1).
+		KV(xlog.WARNING, "k", 2).
+		KV(xlog.WARNING, "k", 3).
+		KV(xlog.INFO, "k", 4).
+		Emit()
+
+	assert.Equal(t, "level=W pkg=xlog_test k=1\n"+
+		"level=I pkg=xlog_test k=4\n", w.String())
+	assert.Equal(t, map[xlog.LogLevel]int{xlog.WARNING: 2}, m.dropped)
+}
+
+func Test_BatchContext(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.WARNING)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetEnablers()
+
+	xlog.AddEnabler(xlog.ContextLevelEnabler)
+	ctx := xlog.ContextWithKV(xlog.ContextWithLevel(context.Background(), xlog.INFO), "req", "r1")
+
+	logger.WithPrefix("p").(*xlog.PackageLogger).Batch().
+		WithContext(ctx).
+		KV(xlog.INFO, "k", 1).
+		KV(xlog.DEBUG, "k", 2).
+		Emit()
+
+	assert.Equal(t, `{"level":"I","p.k":1,"pkg":"xlog_test","req":"r1"}`+"\n", b.String())
+}
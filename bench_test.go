@@ -0,0 +1,168 @@
+package xlog_test
+
+import (
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/effective-security/xlog"
+)
+
+const benchRepo = "github.com/effective-security/xlog/bench"
+
+func BenchmarkLogKV(b *testing.B) {
+	xlog.SetFormatter(xlog.NewJSONFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	l := xlog.NewPackageLogger(benchRepo, "kv")
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.KV(xlog.INFO, "k1", 1, "k2", "value")
+		}
+	})
+}
+
+// BenchmarkLogKVString measures the text formatters,
+// which write the entry from the pooled buffer
+func BenchmarkLogKVString(b *testing.B) {
+	formatters := map[string]xlog.Formatter{
+		"string": xlog.NewStringFormatter(io.Discard),
+		"pretty": xlog.NewPrettyFormatter(io.Discard),
+	}
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	for name, f := range formatters {
+		b.Run(name, func(b *testing.B) {
+			xlog.SetFormatter(f)
+			l := xlog.NewPackageLogger(benchRepo, "kv")
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.KV(xlog.INFO, "k1", 1, "k2", "value", "k3", true, "k4", 3.14)
+			}
+		})
+	}
+}
+
+// BenchmarkLogString measures the formatted messages of the text formatter
+func BenchmarkLogString(b *testing.B) {
+	xlog.SetFormatter(xlog.NewStringFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	l := xlog.NewPackageLogger(benchRepo, "string")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("request ", i, " completed")
+	}
+}
+
+// BenchmarkLogKVDisabled measures the cost of the level check,
+// which does not acquire any lock
+func BenchmarkLogKVDisabled(b *testing.B) {
+	xlog.SetFormatter(xlog.NewJSONFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	l := xlog.NewPackageLogger(benchRepo, "disabled")
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.KV(xlog.DEBUG, "k1", 1, "k2", "value")
+		}
+	})
+}
+
+// BenchmarkLogKVPerPackageSink measures parallel logging by packages
+// with their own formatters, which do not contend on a shared lock
+func BenchmarkLogKVPerPackageSink(b *testing.B) {
+	xlog.SetFormatter(xlog.NewJSONFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	const count = 16
+	loggers := make([]*xlog.PackageLogger, count)
+	for i := range loggers {
+		loggers[i] = xlog.NewPackageLogger(benchRepo, "sink"+strconv.Itoa(i))
+		loggers[i].SetFormatter(xlog.NewJSONFormatter(io.Discard))
+	}
+	defer func() {
+		for _, l := range loggers {
+			l.SetFormatter(nil)
+		}
+	}()
+
+	var next atomic.Int32
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		l := loggers[int(next.Add(1))%count]
+		for pb.Next() {
+			l.KV(xlog.INFO, "k1", 1, "k2", "value")
+		}
+	})
+}
+
+// BenchmarkCaller measures the caller resolution,
+// used by the formatters with FormatWithCaller option
+func BenchmarkCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = xlog.Caller(1)
+	}
+}
+
+func BenchmarkEscapedString(b *testing.B) {
+	values := map[string]any{
+		"string":  "value",
+		"escaped": "line1\nline2 \"quoted\"",
+		"int":     12345,
+		"int32":   int32(-12345),
+		"float":   3.1415,
+		"struct":  struct{ A, B int }{1, 2},
+	}
+	for name, v := range values {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = xlog.EscapedString(v)
+			}
+		})
+	}
+}
+
+// BenchmarkFanout compares FanoutWriter, that copies the entry once for all the sinks,
+// with io.MultiWriter of AsyncWriters, that copy the entry per sink
+func BenchmarkFanout(b *testing.B) {
+	const sinks = 16
+	line := []byte(`{"level":"I","pkg":"bench","k1":1,"k2":"value"}` + "\n")
+
+	b.Run("FanoutWriter", func(b *testing.B) {
+		dests := make([]io.Writer, sinks)
+		for i := range dests {
+			dests[i] = io.Discard
+		}
+		w := xlog.NewFanoutWriter(xlog.FanoutConfig{}, dests...)
+		defer w.Close()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = w.Write(line)
+		}
+		w.Flush()
+	})
+	b.Run("MultiWriter", func(b *testing.B) {
+		dests := make([]io.Writer, sinks)
+		for i := range dests {
+			aw := xlog.NewAsyncWriter(io.Discard, xlog.AsyncConfig{Size: 4096})
+			defer aw.Close()
+			dests[i] = aw
+		}
+		w := io.MultiWriter(dests...)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = w.Write(line)
+		}
+	})
+}
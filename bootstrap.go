@@ -0,0 +1,125 @@
+package xlog
+
+import "time"
+
+// KeyLoggedAt is the key of the time, when the replayed entry was logged
+const KeyLoggedAt = "logged_at"
+
+// bootstrapFormatter retains the entries logged before a formatter is set,
+// to replay them to the formatter set by SetFormatter or SwapFormatter.
+// The sink lock protects the state.
+type bootstrapFormatter struct {
+	size    int
+	entries []bootstrapEntry
+	dropped int
+}
+
+type bootstrapEntry struct {
+	t       entriesType
+	pkg     string
+	level   LogLevel
+	time    time.Time
+	entries []any
+}
+
+// SetBootstrapBuffer retains up to size entries logged before the formatter is set,
+// and replays them once a formatter is installed by SetFormatter or SwapFormatter,
+// so the startup diagnostics are not lost.
+// The oldest entries are dropped when the buffer is full.
+// It has no effect if the formatter is already set.
+// Pass zero to discard the buffer.
+// The buffer can also be enabled by XLOG_BOOTSTRAP_BUFFER environment variable.
+//
+// The replayed entries have the logged_at field with the time they were logged,
+// and the caller is resolved to the caller of SetFormatter.
+func SetBootstrapBuffer(size int) {
+	var old *sink
+	logger.updateSinks(func(s *sinks) {
+		if s.formatter != nil {
+			if _, ok := s.formatter.Formatter.(*bootstrapFormatter); !ok {
+				return
+			}
+			old = s.formatter
+			s.formatter = nil
+		}
+		if size > 0 {
+			s.formatter = &sink{Formatter: &bootstrapFormatter{size: size}}
+		}
+	})
+	if old != nil {
+		// the retained entries are discarded
+		_ = retireBootstrap(old)
+	}
+}
+
+// Format retains the entry
+func (b *bootstrapFormatter) Format(pkg string, level LogLevel, _ int, entries ...any) {
+	b.add(bootstrapEntry{t: plain, pkg: pkg, level: level, entries: entries})
+}
+
+// FormatKV retains the entry
+func (b *bootstrapFormatter) FormatKV(pkg string, level LogLevel, _ int, entries ...any) {
+	b.add(bootstrapEntry{t: kv, pkg: pkg, level: level, entries: entries})
+}
+
+func (b *bootstrapFormatter) add(e bootstrapEntry) {
+	e.time = TimeNowFn()
+	e.entries = append([]any(nil), e.entries...)
+	if len(b.entries) >= b.size {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+		b.dropped++
+	}
+	b.entries = append(b.entries, e)
+}
+
+// Flush is a no-op
+func (b *bootstrapFormatter) Flush() {}
+
+// Options is a no-op
+func (b *bootstrapFormatter) Options(_ ...FormatterOption) Formatter {
+	return b
+}
+
+// retireBootstrap retires the bootstrap sink,
+// and returns the retained entries,
+// the loggers with the previous sinks snapshot retry with the current one
+func retireBootstrap(old *sink) *bootstrapFormatter {
+	b, ok := old.Formatter.(*bootstrapFormatter)
+	if !ok {
+		return nil
+	}
+	old.Lock()
+	defer old.Unlock()
+	old.retired = true
+	res := &bootstrapFormatter{entries: b.entries, dropped: b.dropped}
+	b.entries = nil
+	b.dropped = 0
+	return res
+}
+
+// replayBootstrap replays the entries retained by the bootstrap sink to the formatter
+func replayBootstrap(old, to *sink) {
+	b := retireBootstrap(old)
+	if b == nil || to == nil || (len(b.entries) == 0 && b.dropped == 0) {
+		return
+	}
+
+	to.Lock()
+	defer to.Unlock()
+	if b.dropped > 0 {
+		to.FormatKV("xlog", WARNING, bootstrapDepth, "reason", "bootstrap_buffer_full", "dropped", b.dropped)
+	}
+	for _, e := range b.entries {
+		loggedAt := e.time.UTC().Format(time.RFC3339Nano)
+		if e.t == kv {
+			to.FormatKV(e.pkg, e.level, bootstrapDepth, append(e.entries, KeyLoggedAt, loggedAt)...)
+		} else {
+			to.Format(e.pkg, e.level, bootstrapDepth, append(flatten(false, nil, KeyLoggedAt, loggedAt), e.entries...)...)
+		}
+	}
+	to.Flush()
+}
+
+// bootstrapDepth resolves the caller of SetFormatter or SwapFormatter
+const bootstrapDepth = 3
@@ -0,0 +1,66 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BootstrapBuffer(t *testing.T) {
+	defer func(fn func() time.Time) {
+		xlog.TimeNowFn = fn
+	}(xlog.TimeNowFn)
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	xlog.SetFormatter(nil)
+	xlog.SetBootstrapBuffer(3)
+	assert.Nil(t, xlog.GetFormatter())
+
+	logger.KV(xlog.INFO, "dropped", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.Info("starting")
+	logger.KV(xlog.DEBUG, "skipped", true)
+	logger.KV(xlog.WARNING, "k", 3)
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime))
+	assert.Equal(t,
+		"level=W pkg=xlog func=Test_BootstrapBuffer reason=\"bootstrap_buffer_full\" dropped=1\n"+
+			"level=I pkg=xlog_test func=Test_BootstrapBuffer k=2 logged_at=\"2024-01-02T03:04:05Z\"\n"+
+			"level=I pkg=xlog_test func=Test_BootstrapBuffer \"logged_at=\\\"2024-01-02T03:04:05Z\\\"\" \"starting\"\n"+
+			"level=W pkg=xlog_test func=Test_BootstrapBuffer k=3 logged_at=\"2024-01-02T03:04:05Z\"\n",
+		b.String())
+
+	// replayed once
+	logger.KV(xlog.INFO, "k", 4)
+	b.Reset()
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	assert.Empty(t, b.String())
+
+	// not installed when the formatter is set
+	xlog.SetBootstrapBuffer(10)
+	logger.KV(xlog.INFO, "k", 5)
+	assert.Equal(t, "level=I pkg=xlog_test k=5\n", b.String())
+
+	// discarded
+	xlog.SetFormatter(nil)
+	xlog.SetBootstrapBuffer(10)
+	logger.KV(xlog.INFO, "k", 6)
+	xlog.SetBootstrapBuffer(0)
+	b.Reset()
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	assert.Empty(t, b.String())
+
+	// replayed by SwapFormatter
+	xlog.SetFormatter(nil)
+	xlog.SetBootstrapBuffer(10)
+	logger.KV(xlog.INFO, "k", 7)
+	assert.NoError(t, xlog.SwapFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller)))
+	assert.Equal(t, "level=I pkg=xlog_test k=7 logged_at=\"2024-01-02T03:04:05Z\"\n", b.String())
+}
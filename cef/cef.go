@@ -0,0 +1,300 @@
+// Package cef provides formatter for SIEM ingestion,
+// in ArcSight Common Event Format (CEF) or IBM QRadar Log Event Extended Format (LEEF).
+package cef
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// Format of the events
+type Format int
+
+const (
+	// CEF is ArcSight Common Event Format, version 0
+	CEF Format = iota
+	// LEEF is QRadar Log Event Extended Format, version 1.0
+	LEEF
+)
+
+var levelsToSeverity = map[xlog.LogLevel]int{
+	xlog.CRITICAL: 10,
+	xlog.ERROR:    8,
+	xlog.WARNING:  6,
+	xlog.NOTICE:   4,
+	xlog.INFO:     3,
+	xlog.TRACE:    1,
+	xlog.DEBUG:    0,
+}
+
+// SeverityFor returns the event severity from 0 to 10 for the log level
+func SeverityFor(l xlog.LogLevel) int {
+	if s, ok := levelsToSeverity[l]; ok {
+		return s
+	}
+	return 3
+}
+
+// Config specifies configuration for the formatter
+type Config struct {
+	// Format of the events, CEF by default
+	Format Format
+	// Vendor, Product and Version of the device in the event header
+	Vendor  string
+	Product string
+	Version string
+	// EventIDKey specifies the key-value entry used as the event class ID,
+	// "event" by default. If the entry is not present, the package is used.
+	EventIDKey string
+	// Mapping specifies the extension keys for the key-value entries,
+	// such as "client_ip" to "src", the other keys are emitted as is
+	Mapping map[string]string
+}
+
+// formatter provides CEF and LEEF logs format
+type formatter struct {
+	w   io.Writer
+	cfg Config
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// NewFormatter returns an instance of CEF or LEEF formatter,
+// each entry is written with a single Write call
+func NewFormatter(w io.Writer, cfg Config) xlog.Formatter {
+	if cfg.EventIDKey == "" {
+		cfg.EventIDKey = "event"
+	}
+	return &formatter{
+		w:          w,
+		cfg:        cfg,
+		withCaller: true,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatSkipTime:
+			f.skipTime = true
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs, emitted as extensions
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format log entry string to the stream
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are written immediately
+func (f *formatter) Flush() {}
+
+type extension struct {
+	key string
+	val string
+}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	eventID := pkg
+	var ext []extension
+	if !f.skipTime {
+		ext = append(ext, extension{f.timeKey(), strconv.FormatInt(xlog.TimeNowFn().UnixMilli(), 10)})
+	}
+	if pkg != "" {
+		ext = append(ext, extension{"cat", pkg})
+	}
+	if f.withCaller {
+		caller, _, _ := xlog.Caller(depth + 1)
+		ext = append(ext, extension{f.key("func"), caller})
+	}
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if k == "msg" && msg == "" {
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if k == f.cfg.EventIDKey && val != "" {
+			eventID = val
+			continue
+		}
+		if val == "" && !f.printEmpty {
+			continue
+		}
+		ext = append(ext, extension{f.key(k), val})
+	}
+	msg = strings.TrimSpace(msg)
+
+	b := &f.buf
+	b.Reset()
+	if f.cfg.Format == LEEF {
+		f.writeLEEF(b, l, eventID, msg, ext)
+	} else {
+		f.writeCEF(b, l, eventID, msg, ext)
+	}
+	b.WriteByte('\n')
+
+	_, _ = f.w.Write(b.Bytes())
+	xlog.ObserveEntrySize(pkg, b.Len())
+}
+
+// writeCEF writes the event:
+// CEF:0|Vendor|Product|Version|EventClassID|Name|Severity|Extension
+func (f *formatter) writeCEF(b *bytes.Buffer, l xlog.LogLevel, eventID, msg string, ext []extension) {
+	name := msg
+	if name == "" {
+		name = eventID
+	}
+
+	b.WriteString("CEF:0|")
+	for _, h := range []string{f.cfg.Vendor, f.cfg.Product, f.cfg.Version, eventID, name} {
+		writeHeader(b, h)
+		b.WriteByte('|')
+	}
+	b.WriteString(strconv.Itoa(SeverityFor(l)))
+	b.WriteByte('|')
+
+	if msg != "" {
+		ext = append(ext, extension{"msg", msg})
+	}
+	for i, e := range ext {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(e.key)
+		b.WriteByte('=')
+		for _, r := range e.val {
+			switch r {
+			case '\\', '=':
+				b.WriteByte('\\')
+				b.WriteRune(r)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+}
+
+// writeLEEF writes the event, with tab delimited attributes:
+// LEEF:1.0|Vendor|Product|Version|EventID|Attributes
+func (f *formatter) writeLEEF(b *bytes.Buffer, l xlog.LogLevel, eventID, msg string, ext []extension) {
+	b.WriteString("LEEF:1.0|")
+	for _, h := range []string{f.cfg.Vendor, f.cfg.Product, f.cfg.Version, eventID} {
+		writeHeader(b, h)
+		b.WriteByte('|')
+	}
+
+	ext = append(ext, extension{"sev", strconv.Itoa(SeverityFor(l))})
+	if msg != "" {
+		ext = append(ext, extension{"msg", msg})
+	}
+	for i, e := range ext {
+		if i > 0 {
+			b.WriteByte('\t')
+		}
+		b.WriteString(e.key)
+		b.WriteByte('=')
+		for _, r := range e.val {
+			switch r {
+			case '\t', '\n', '\r':
+				b.WriteByte(' ')
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+}
+
+// timeKey returns the key of the event time
+func (f *formatter) timeKey() string {
+	if f.cfg.Format == LEEF {
+		return "devTime"
+	}
+	return "rt"
+}
+
+// key returns the extension key for the entry key,
+// the extension keys are limited to alphanumeric characters
+func (f *formatter) key(k string) string {
+	if mapped, ok := f.cfg.Mapping[k]; ok {
+		k = mapped
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// writeHeader writes the header field, escaping '|' and '\'
+func writeHeader(b *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '\\', '|':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n', '\r':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// value returns the string value of the extension
+func value(v any) string {
+	switch typ := v.(type) {
+	case string:
+		return typ
+	case error:
+		return typ.Error()
+	case time.Time:
+		return typ.UTC().Format(time.RFC3339)
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
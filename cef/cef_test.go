@@ -0,0 +1,87 @@
+package cef
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CEF(t *testing.T) {
+	var b bytes.Buffer
+	f := NewFormatter(&b, Config{
+		Vendor:  "Acme|Corp",
+		Product: "api",
+		Version: "1.0",
+		Mapping: map[string]string{"client_ip": "src"},
+	}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime)
+
+	f.FormatKV("auth", xlog.WARNING, 1, "event", "login_failed", "msg", "invalid password", "client_ip", "10.0.0.1",
+		"user", `a=b\c`, "nil", nil, "empty", "", "err", errors.New("line1\nline2"))
+	assert.Equal(t, `CEF:0|Acme\|Corp|api|1.0|login_failed|invalid password|6|cat=auth src=10.0.0.1 user=a\=b\\c err=line1\nline2 msg=invalid password`+"\n", b.String())
+
+	b.Reset()
+	f.Format("auth", xlog.CRITICAL, 1, "plain ", "message")
+	assert.Equal(t, "CEF:0|Acme\\|Corp|api|1.0|auth|plain message|10|cat=auth msg=plain message\n", b.String())
+
+	b.Reset()
+	f.FormatKV("auth", xlog.INFO, 1, "k.1", 1)
+	assert.Equal(t, "CEF:0|Acme\\|Corp|api|1.0|auth|auth|3|cat=auth k_1=1\n", b.String())
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	b.Reset()
+	f = NewFormatter(&b, Config{Vendor: "acme", Product: "api", Version: "1"})
+	f.FormatKV("", xlog.DEBUG, 1, "k", "v")
+	f.Flush()
+	assert.Equal(t, "CEF:0|acme|api|1|||0|rt=1617235200000 func=Test_CEF k=v\n", b.String())
+}
+
+func Test_LEEF(t *testing.T) {
+	var b bytes.Buffer
+	f := NewFormatter(&b, Config{
+		Format:     LEEF,
+		Vendor:     "acme",
+		Product:    "api",
+		Version:    "1.0",
+		EventIDKey: "action",
+	}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime)
+
+	f.FormatKV("auth", xlog.ERROR, 1, "action", "logout", "msg", "done", "note", "a\tb")
+	assert.Equal(t, "LEEF:1.0|acme|api|1.0|logout|cat=auth\tnote=a b\tsev=8\tmsg=done\n", b.String())
+
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	b.Reset()
+	f = NewFormatter(&b, Config{Format: LEEF, Vendor: "acme", Product: "api", Version: "1"})
+	f.Format("auth", xlog.INFO, 1, "hello")
+	assert.Equal(t, "LEEF:1.0|acme|api|1|auth|devTime=1617235200000\tcat=auth\tfunc=Test_LEEF\tsev=3\tmsg=hello\n", b.String())
+}
+
+func Test_Severity(t *testing.T) {
+	assert.Equal(t, 10, SeverityFor(xlog.CRITICAL))
+	assert.Equal(t, 8, SeverityFor(xlog.ERROR))
+	assert.Equal(t, 6, SeverityFor(xlog.WARNING))
+	assert.Equal(t, 4, SeverityFor(xlog.NOTICE))
+	assert.Equal(t, 3, SeverityFor(xlog.INFO))
+	assert.Equal(t, 1, SeverityFor(xlog.TRACE))
+	assert.Equal(t, 0, SeverityFor(xlog.DEBUG))
+	assert.Equal(t, 3, SeverityFor(xlog.LogLevel(100)))
+}
@@ -0,0 +1,248 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// Limits of PutLogEvents API
+const (
+	// MaxBatchCount is the maximum number of events in a batch
+	MaxBatchCount = 10000
+	// MaxBatchSize is the maximum size of a batch,
+	// calculated as the sum of messages size plus 26 bytes per event
+	MaxBatchSize = 1048576
+	// MaxEventSize is the maximum size of an event, including the overhead
+	MaxEventSize = 262144
+	// eventOverhead is the size added to each event
+	eventOverhead = 26
+)
+
+// InputLogEvent is a log event to be sent
+type InputLogEvent struct {
+	// Message of the event
+	Message string
+	// Timestamp of the event in milliseconds since the Unix epoch
+	Timestamp int64
+}
+
+// PutLogEventsInput is the request of PutLogEvents
+type PutLogEventsInput struct {
+	LogGroupName  string
+	LogStreamName string
+	LogEvents     []InputLogEvent
+	// SequenceToken is the token returned by the previous call,
+	// nil for the first call
+	SequenceToken *string
+}
+
+// PutLogEventsOutput is the response of PutLogEvents
+type PutLogEventsOutput struct {
+	NextSequenceToken *string
+}
+
+// Client sends log events to CloudWatch Logs,
+// an adapter over AWS SDK client, such as cwlogs.Client
+// in the github.com/effective-security/xlog/cloudwatch/cwlogs module
+type Client interface {
+	PutLogEvents(ctx context.Context, input *PutLogEventsInput) (*PutLogEventsOutput, error)
+}
+
+// InvalidSequenceTokenError is returned by Client,
+// if the sequence token is not valid
+type InvalidSequenceTokenError struct {
+	// ExpectedSequenceToken is the token to be used
+	ExpectedSequenceToken *string
+}
+
+func (e *InvalidSequenceTokenError) Error() string {
+	return "invalid sequence token"
+}
+
+// BatcherConfig specifies configuration for Batcher
+type BatcherConfig struct {
+	Client        Client
+	LogGroupName  string
+	LogStreamName string
+	// FlushInterval specifies the interval to send batched events,
+	// 5 seconds by default
+	FlushInterval time.Duration
+	// MaxRetries specifies the number of retries of failed calls, 3 by default
+	MaxRetries int
+	// Backoff specifies the initial delay between retries,
+	// doubled on each retry, 200ms by default
+	Backoff time.Duration
+}
+
+// Batcher is io.Writer that batches events for PutLogEvents calls,
+// each Write call is a single event.
+// The batch is sent when the PutLogEvents limits are reached,
+// on the flush interval, or on Flush and Close calls.
+type Batcher struct {
+	cfg BatcherConfig
+
+	lock   sync.Mutex
+	events []InputLogEvent
+	size   int
+
+	// sendLock serializes calls, as required by the sequence token
+	sendLock sync.Mutex
+	token    *string
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewBatcher returns Batcher
+func NewBatcher(cfg BatcherConfig) *Batcher {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+	b := &Batcher{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Write adds the event to the batch
+func (b *Batcher) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, "\n"))
+	if len(msg)+eventOverhead > MaxEventSize {
+		msg = msg[:MaxEventSize-eventOverhead]
+	}
+	if msg == "" {
+		return len(p), nil
+	}
+	event := InputLogEvent{
+		Message:   msg,
+		Timestamp: xlog.TimeNowFn().UnixMilli(),
+	}
+	size := len(msg) + eventOverhead
+
+	b.lock.Lock()
+	var batch []InputLogEvent
+	if len(b.events) >= MaxBatchCount || b.size+size > MaxBatchSize {
+		batch = b.take()
+	}
+	b.events = append(b.events, event)
+	b.size += size
+	b.lock.Unlock()
+
+	if len(batch) > 0 {
+		if err := b.send(context.Background(), batch); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the batched events
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.lock.Lock()
+	batch := b.take()
+	b.lock.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return b.send(ctx, batch)
+}
+
+// Close stops the background flush, and sends the batched events
+func (b *Batcher) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	return b.Flush(context.Background())
+}
+
+// take returns the batched events, the caller must hold the lock
+func (b *Batcher) take() []InputLogEvent {
+	batch := b.events
+	b.events = nil
+	b.size = 0
+	return batch
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Flush(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "xlog: cloudwatch: %v\n", err)
+			}
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// send the events, retrying with backoff,
+// and updating the sequence token on InvalidSequenceTokenError
+func (b *Batcher) send(ctx context.Context, events []InputLogEvent) error {
+	// PutLogEvents requires the events in chronological order
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+
+	delay := b.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var out *PutLogEventsOutput
+		out, err = b.cfg.Client.PutLogEvents(ctx, &PutLogEventsInput{
+			LogGroupName:  b.cfg.LogGroupName,
+			LogStreamName: b.cfg.LogStreamName,
+			LogEvents:     events,
+			SequenceToken: b.token,
+		})
+		if err == nil {
+			if out != nil {
+				b.token = out.NextSequenceToken
+			}
+			return nil
+		}
+
+		var tokenErr *InvalidSequenceTokenError
+		if errors.As(err, &tokenErr) {
+			b.token = tokenErr.ExpectedSequenceToken
+		}
+		if attempt >= b.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "failed to put %d log events", len(events))
+		}
+		delay *= 2
+	}
+	return errors.WithMessagef(err, "failed to put %d log events", len(events))
+}
@@ -0,0 +1,134 @@
+package cloudwatch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	lock   sync.Mutex
+	calls  []PutLogEventsInput
+	errs   []error
+	tokens int
+}
+
+func (c *mockClient) PutLogEvents(_ context.Context, input *PutLogEventsInput) (*PutLogEventsOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.calls = append(c.calls, *input)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.tokens++
+	token := strings.Repeat("t", c.tokens)
+	return &PutLogEventsOutput{NextSequenceToken: &token}, nil
+}
+
+func (c *mockClient) Calls() []PutLogEventsInput {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]PutLogEventsInput{}, c.calls...)
+}
+
+func Test_Batcher(t *testing.T) {
+	client := &mockClient{}
+	b := NewBatcher(BatcherConfig{
+		Client:        client,
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+
+	_, err := b.Write([]byte("event1\n"))
+	require.NoError(t, err)
+	_, err = b.Write([]byte("event2\n"))
+	require.NoError(t, err)
+	_, err = b.Write([]byte("\n"))
+	require.NoError(t, err)
+	assert.Empty(t, client.Calls())
+
+	require.NoError(t, b.Flush(context.Background()))
+	require.NoError(t, b.Flush(context.Background()))
+	calls := client.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "group", calls[0].LogGroupName)
+	assert.Equal(t, "stream", calls[0].LogStreamName)
+	assert.Nil(t, calls[0].SequenceToken)
+	require.Len(t, calls[0].LogEvents, 2)
+	assert.Equal(t, "event1", calls[0].LogEvents[0].Message)
+	assert.Equal(t, "event2", calls[0].LogEvents[1].Message)
+
+	// retry with the expected sequence token
+	expected := "expected"
+	client.errs = []error{&InvalidSequenceTokenError{ExpectedSequenceToken: &expected}, errors.New("throttled")}
+	_, err = b.Write([]byte("event3"))
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+	require.NoError(t, b.Close())
+
+	calls = client.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, "t", *calls[1].SequenceToken)
+	assert.Equal(t, "expected", *calls[2].SequenceToken)
+	assert.Equal(t, "expected", *calls[3].SequenceToken)
+}
+
+func Test_BatcherLimits(t *testing.T) {
+	client := &mockClient{}
+	b := NewBatcher(BatcherConfig{
+		Client:        client,
+		FlushInterval: time.Hour,
+		MaxRetries:    1,
+		Backoff:       time.Millisecond,
+	})
+	defer b.Close()
+
+	large := strings.Repeat("a", MaxEventSize)
+	for i := 0; i < 5; i++ {
+		_, err := b.Write([]byte(large))
+		require.NoError(t, err)
+	}
+	calls := client.Calls()
+	require.Len(t, calls, 1)
+	assert.Len(t, calls[0].LogEvents, 4)
+	assert.Len(t, calls[0].LogEvents[0].Message, MaxEventSize-eventOverhead)
+
+	client.errs = []error{errors.New("throttled"), errors.New("throttled")}
+	err := b.Flush(context.Background())
+	assert.EqualError(t, err, "failed to put 1 log events: throttled")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.errs = []error{errors.New("throttled")}
+	_, _ = b.Write([]byte("event"))
+	err = b.Flush(ctx)
+	assert.EqualError(t, err, "failed to put 1 log events: context canceled")
+}
+
+func Test_BatcherInterval(t *testing.T) {
+	client := &mockClient{}
+	b := NewBatcher(BatcherConfig{
+		Client:        client,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer b.Close()
+
+	_, err := b.Write([]byte("event"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(client.Calls()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,217 @@
+// Package cloudwatch provides formatter for AWS CloudWatch Logs,
+// compatible with Embedded Metric Format (EMF),
+// and the writer to batch PutLogEvents calls.
+package cloudwatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// Metric specifies the entry key to be emitted as EMF metric
+type Metric struct {
+	// Name of the metric, and the entry key with the value
+	Name string `json:"Name"`
+	// Unit of the metric, such as "Milliseconds", "Bytes" or "Count"
+	Unit string `json:"Unit,omitempty"`
+}
+
+// Config specifies configuration for the CloudWatch formatter
+type Config struct {
+	// Namespace specifies the CloudWatch namespace of the metrics
+	Namespace string
+	// Metrics specifies the entry keys to be extracted as metrics,
+	// if an entry has numeric values for any of them.
+	// If empty, the entries are emitted as plain JSON.
+	Metrics []Metric
+	// Dimensions specifies the entry keys to be used as metric dimensions,
+	// only the keys present in the entry are used
+	Dimensions []string
+}
+
+// formatter provides CloudWatch logs format
+type formatter struct {
+	w   io.Writer
+	cfg Config
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// NewFormatter returns CloudWatch formatter, each entry is written
+// as a single JSON line with one Write call
+func NewFormatter(w io.Writer, cfg Config) xlog.Formatter {
+	return &formatter{
+		w:          w,
+		cfg:        cfg,
+		withCaller: true,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatSkipTime:
+			f.skipTime = true
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs, emitted as top-level JSON fields
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format log entry string to the stream
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are written immediately
+func (f *formatter) Flush() {}
+
+// metadata is EMF metadata of the entry
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+type metricDirective struct {
+	Namespace  string     `json:"Namespace"`
+	Dimensions [][]string `json:"Dimensions"`
+	Metrics    []Metric   `json:"Metrics"`
+}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := xlog.TimeNowFn()
+	m := map[string]any{
+		"level": l.String(),
+	}
+	if !f.skipTime {
+		m["time"] = now.UTC().Format(time.RFC3339Nano)
+	}
+	if pkg != "" {
+		m["pkg"] = pkg
+	}
+	if f.withCaller {
+		caller, file, line := xlog.Caller(depth + 1)
+		m["func"] = caller
+		m["src"] = fmt.Sprintf("%s:%d", file, line)
+	}
+	if msg = strings.TrimSpace(msg); msg != "" {
+		m["msg"] = msg
+	}
+
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if s, ok := val.(string); ok && s == "" && !f.printEmpty {
+			continue
+		}
+		m[k] = val
+	}
+
+	if md := f.metadata(m, now); md != nil {
+		m["_aws"] = md
+	}
+
+	f.buf.Reset()
+	encoder := json.NewEncoder(&f.buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(m); err != nil {
+		return
+	}
+	_, _ = f.w.Write(f.buf.Bytes())
+	xlog.ObserveEntrySize(pkg, f.buf.Len())
+}
+
+// metadata returns EMF metadata for the metrics present in the entry,
+// or nil if the entry has no metrics
+func (f *formatter) metadata(m map[string]any, now time.Time) *metadata {
+	var metrics []Metric
+	for _, metric := range f.cfg.Metrics {
+		if isNumber(m[metric.Name]) {
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	dimensions := []string{}
+	for _, d := range f.cfg.Dimensions {
+		if _, ok := m[d]; ok {
+			dimensions = append(dimensions, d)
+		}
+	}
+
+	return &metadata{
+		Timestamp: now.UnixMilli(),
+		CloudWatchMetrics: []metricDirective{
+			{
+				Namespace:  f.cfg.Namespace,
+				Dimensions: [][]string{dimensions},
+				Metrics:    metrics,
+			},
+		},
+	}
+}
+
+// value returns the JSON value of the field
+func value(v any) any {
+	switch typ := v.(type) {
+	case string, bool:
+		return typ
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return typ
+	case error:
+		return typ.Error()
+	case time.Time:
+		return typ.UTC().Format(time.RFC3339)
+	case time.Duration:
+		return typ.String()
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Formatter(t *testing.T) {
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	var b bytes.Buffer
+	f := NewFormatter(&b, Config{
+		Namespace:  "svc",
+		Metrics:    []Metric{{Name: "latency", Unit: "Milliseconds"}, {Name: "count"}},
+		Dimensions: []string{"api", "region"},
+	}).Options(xlog.FormatNoCaller)
+
+	f.FormatKV("pkg", xlog.INFO, 1, "msg", "request", "api", "get", "latency", 12, "err", errors.New("failed"), "ok", true, "nil", nil)
+	assert.Equal(t, `{"_aws":{"Timestamp":1617235200000,"CloudWatchMetrics":[{"Namespace":"svc","Dimensions":[["api"]],"Metrics":[{"Name":"latency","Unit":"Milliseconds"}]}]},"api":"get","err":"failed","latency":12,"level":"INFO","msg":"request","ok":true,"pkg":"pkg","time":"2021-04-01T00:00:00Z"}`+"\n", b.String())
+
+	b.Reset()
+	f.FormatKV("pkg", xlog.INFO, 1, "latency", "slow")
+	assert.Equal(t, `{"latency":"slow","level":"INFO","pkg":"pkg","time":"2021-04-01T00:00:00Z"}`+"\n", b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatSkipTime)
+	f.Format("", xlog.ERROR, 1, "failed ", "request\n")
+	f.Flush()
+	assert.Equal(t, `{"level":"ERROR","msg":"failed request"}`+"\n", b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatWithCaller)
+	f.Format("", xlog.INFO, 1, "msg")
+	assert.Contains(t, b.String(), `"func":"Test_Formatter"`)
+	assert.Contains(t, b.String(), `"src":"cloudwatch_test.go:`)
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+}
@@ -0,0 +1,93 @@
+// Package cwlogs provides cloudwatch.Client, that sends the log events
+// with AWS SDK v2 CloudWatch Logs client.
+//
+// The package is a separate module, so the core module does not depend on AWS SDK.
+package cwlogs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/effective-security/xlog/cloudwatch"
+	"github.com/pkg/errors"
+)
+
+// API is the subset of cloudwatchlogs.Client used by Client
+type API interface {
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+// Client sends the log events with AWS SDK client
+type Client struct {
+	api API
+}
+
+var _ cloudwatch.Client = (*Client)(nil)
+
+// New returns Client
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	...
+//	b := cloudwatch.NewBatcher(cloudwatch.BatcherConfig{
+//		Client:        cwlogs.New(cloudwatchlogs.NewFromConfig(cfg)),
+//		LogGroupName:  "/svc/api",
+//		LogStreamName: hostname,
+//	})
+func New(api API) *Client {
+	return &Client{api: api}
+}
+
+// PutLogEvents sends the events,
+// InvalidSequenceTokenException is returned as cloudwatch.InvalidSequenceTokenError.
+// The events rejected by CloudWatch as too old or too new are not retried,
+// and are reported to stderr.
+func (c *Client) PutLogEvents(ctx context.Context, input *cloudwatch.PutLogEventsInput) (*cloudwatch.PutLogEventsOutput, error) {
+	out, err := c.api.PutLogEvents(ctx, ToInput(input))
+	if err != nil {
+		var tokenErr *types.InvalidSequenceTokenException
+		if errors.As(err, &tokenErr) {
+			return nil, &cloudwatch.InvalidSequenceTokenError{
+				ExpectedSequenceToken: tokenErr.ExpectedSequenceToken,
+			}
+		}
+		return nil, errors.WithStack(err)
+	}
+	if info := out.RejectedLogEventsInfo; info != nil {
+		fmt.Fprintf(os.Stderr, "xlog: cloudwatch: rejected log events: too_new_start=%d too_old_end=%d expired_end=%d\n",
+			index(info.TooNewLogEventStartIndex),
+			index(info.TooOldLogEventEndIndex),
+			index(info.ExpiredLogEventEndIndex))
+	}
+	return &cloudwatch.PutLogEventsOutput{
+		NextSequenceToken: out.NextSequenceToken,
+	}, nil
+}
+
+// ToInput returns the SDK request of the input
+func ToInput(input *cloudwatch.PutLogEventsInput) *cloudwatchlogs.PutLogEventsInput {
+	events := make([]types.InputLogEvent, len(input.LogEvents))
+	for i, e := range input.LogEvents {
+		events[i] = types.InputLogEvent{
+			Message:   aws.String(e.Message),
+			Timestamp: aws.Int64(e.Timestamp),
+		}
+	}
+	return &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(input.LogGroupName),
+		LogStreamName: aws.String(input.LogStreamName),
+		LogEvents:     events,
+		SequenceToken: input.SequenceToken,
+	}
+}
+
+// index returns the index of rejected events, or -1 if not set
+func index(i *int32) int32 {
+	if i == nil {
+		return -1
+	}
+	return *i
+}
@@ -0,0 +1,205 @@
+package cwlogs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/cloudwatch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request is PutLogEvents request on the wire
+type request struct {
+	LogGroupName  string  `json:"logGroupName"`
+	LogStreamName string  `json:"logStreamName"`
+	SequenceToken *string `json:"sequenceToken"`
+	LogEvents     []struct {
+		Message   string `json:"message"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"logEvents"`
+}
+
+// logsServer is the fake CloudWatch Logs, that keeps the requests,
+// and checks the sequence token as the service does
+type logsServer struct {
+	lock     sync.Mutex
+	requests []request
+	tokens   int
+	// failToken fails the next request with InvalidSequenceTokenException
+	failToken bool
+}
+
+func (s *logsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "Logs_20140328.PutLogEvents" {
+		http.Error(w, "unexpected target: "+target, http.StatusBadRequest)
+		return
+	}
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, req)
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	expected := s.token()
+	if s.failToken || ptr(req.SequenceToken) != ptr(expected) {
+		s.failToken = false
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"__type":                "InvalidSequenceTokenException",
+			"message":               "The given sequenceToken is invalid",
+			"expectedSequenceToken": expected,
+		})
+		return
+	}
+	s.tokens++
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"nextSequenceToken": s.token(),
+	})
+}
+
+// token returns the expected sequence token, nil for the first call
+func (s *logsServer) token() *string {
+	if s.tokens == 0 {
+		return nil
+	}
+	return aws.String(fmt.Sprintf("token%d", s.tokens))
+}
+
+func (s *logsServer) Requests() []request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+func ptr(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
+
+func newClient(t *testing.T, srv *logsServer) *Client {
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	return New(cloudwatchlogs.New(cloudwatchlogs.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(ts.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		Retryer:      aws.NopRetryer{},
+	}))
+}
+
+func TestPutLogEvents(t *testing.T) {
+	srv := new(logsServer)
+	c := newClient(t, srv)
+	ctx := context.Background()
+
+	input := &cloudwatch.PutLogEventsInput{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		LogEvents: []cloudwatch.InputLogEvent{
+			{Message: "event1", Timestamp: 1000},
+			{Message: "event2", Timestamp: 2000},
+		},
+	}
+	out, err := c.PutLogEvents(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "token1", ptr(out.NextSequenceToken))
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "group", reqs[0].LogGroupName)
+	assert.Equal(t, "stream", reqs[0].LogStreamName)
+	assert.Nil(t, reqs[0].SequenceToken)
+	require.Len(t, reqs[0].LogEvents, 2)
+	assert.Equal(t, "event1", reqs[0].LogEvents[0].Message)
+	assert.Equal(t, int64(1000), reqs[0].LogEvents[0].Timestamp)
+	assert.Equal(t, "event2", reqs[0].LogEvents[1].Message)
+	assert.Equal(t, int64(2000), reqs[0].LogEvents[1].Timestamp)
+
+	// the previous token is not valid anymore
+	_, err = c.PutLogEvents(ctx, input)
+	var tokenErr *cloudwatch.InvalidSequenceTokenError
+	require.True(t, errors.As(err, &tokenErr), "unexpected error: %v", err)
+	assert.Equal(t, "token1", ptr(tokenErr.ExpectedSequenceToken))
+
+	input.SequenceToken = tokenErr.ExpectedSequenceToken
+	out, err = c.PutLogEvents(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "token2", ptr(out.NextSequenceToken))
+
+	reqs = srv.Requests()
+	require.Len(t, reqs, 3)
+	assert.Equal(t, "token1", ptr(reqs[2].SequenceToken))
+}
+
+func TestBatcher(t *testing.T) {
+	defer func(fn func() time.Time) { xlog.TimeNowFn = fn }(xlog.TimeNowFn)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+
+	srv := new(logsServer)
+	b := cloudwatch.NewBatcher(cloudwatch.BatcherConfig{
+		Client:        newClient(t, srv),
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	defer b.Close()
+
+	// 4 events of the maximum size fill the batch,
+	// and the oversized events are truncated
+	for i := 0; i < 6; i++ {
+		msg := strings.Repeat(fmt.Sprintf("%d", i), cloudwatch.MaxEventSize)
+		_, err := b.Write([]byte(msg + "\n"))
+		require.NoError(t, err)
+	}
+
+	// the sequence token is renewed on the next call
+	srv.lock.Lock()
+	srv.failToken = true
+	srv.lock.Unlock()
+	require.NoError(t, b.Flush(context.Background()))
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 3)
+	assert.Nil(t, reqs[0].SequenceToken)
+	assert.Equal(t, "token1", ptr(reqs[1].SequenceToken))
+	assert.Equal(t, "token1", ptr(reqs[2].SequenceToken))
+	assert.Equal(t, reqs[1].LogEvents, reqs[2].LogEvents)
+
+	var n int
+	for _, req := range []request{reqs[0], reqs[2]} {
+		var size int
+		for _, e := range req.LogEvents {
+			assert.Len(t, e.Message, cloudwatch.MaxEventSize-26)
+			assert.Equal(t, strings.Repeat(fmt.Sprintf("%d", n), len(e.Message)), e.Message)
+			assert.Equal(t, now.UnixMilli(), e.Timestamp)
+			size += len(e.Message) + 26
+			n++
+		}
+		assert.LessOrEqual(t, size, cloudwatch.MaxBatchSize)
+		assert.LessOrEqual(t, len(req.LogEvents), cloudwatch.MaxBatchCount)
+	}
+	assert.Len(t, reqs[0].LogEvents, 4)
+	assert.Equal(t, 6, n)
+}
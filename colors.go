@@ -0,0 +1,165 @@
+package xlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Color is the ANSI terminal color
+type Color uint8
+
+// Colors of the terminal 16 colors palette
+const (
+	// ColorDefault keeps the color of the terminal or the entry
+	ColorDefault Color = iota
+	ColorBlack
+	ColorRed
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagenta
+	ColorCyan
+	ColorWhite
+	ColorBrightBlack
+	ColorBrightRed
+	ColorBrightGreen
+	ColorBrightYellow
+	ColorBrightBlue
+	ColorBrightMagenta
+	ColorBrightCyan
+	ColorBrightWhite
+)
+
+// code returns SGR parameter of the color, with offset 0 for foreground, 10 for background
+func (c Color) code(offset int) string {
+	if c <= ColorWhite {
+		return strconv.Itoa(29 + int(c) + offset)
+	}
+	return strconv.Itoa(81 + int(c) + offset)
+}
+
+// Style is the ANSI terminal style of the level or the fields
+type Style struct {
+	Foreground Color
+	Background Color
+	Bold       bool
+	Dim        bool
+	Underline  bool
+}
+
+// IsZero returns true if the style is not set
+func (s Style) IsZero() bool {
+	return s == Style{}
+}
+
+// Code returns ANSI escape sequence of the style,
+// or empty string if the style is not set
+func (s Style) Code() string {
+	if s.IsZero() {
+		return ""
+	}
+	params := []string{"0"}
+	if s.Bold {
+		params = append(params, "1")
+	}
+	if s.Dim {
+		params = append(params, "2")
+	}
+	if s.Underline {
+		params = append(params, "4")
+	}
+	if s.Foreground != ColorDefault {
+		params = append(params, s.Foreground.code(0))
+	}
+	if s.Background != ColorDefault {
+		params = append(params, s.Background.code(10))
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// ColorScheme specifies the styles of PrettyFormatter entries,
+// when configured with FormatWithColor option
+type ColorScheme struct {
+	// Levels specifies the style of the entries per level,
+	// LevelColors are used for the levels not in the map
+	Levels map[LogLevel]Style
+	// Keys specifies the style of the keys of the fields
+	Keys Style
+	// Values specifies the style of the values of the fields,
+	// the values have the style of the level if not set
+	Values Style
+	// Errors specifies the style of the error values
+	Errors Style
+}
+
+// DefaultColorScheme has dim keys, and red errors
+var DefaultColorScheme = ColorScheme{
+	Levels: map[LogLevel]Style{
+		CRITICAL: {Foreground: ColorBrightRed, Bold: true},
+	},
+	Keys:   Style{Dim: true},
+	Errors: Style{Foreground: ColorBrightRed},
+}
+
+// colorCodes are the escape sequences of ColorScheme
+type colorCodes struct {
+	levels map[LogLevel]string
+	keys   string
+	values string
+	errors string
+}
+
+func newColorCodes(s *ColorScheme) *colorCodes {
+	if s == nil {
+		return nil
+	}
+	c := &colorCodes{
+		levels: map[LogLevel]string{},
+		keys:   s.Keys.Code(),
+		values: s.Values.Code(),
+		errors: s.Errors.Code(),
+	}
+	for l, style := range s.Levels {
+		c.levels[l] = style.Code()
+	}
+	return c
+}
+
+// level returns the escape sequence of the level
+func (c *colorCodes) level(l LogLevel) string {
+	if c != nil {
+		if code, ok := c.levels[l]; ok {
+			return code
+		}
+	}
+	return string(LevelColors[l])
+}
+
+// field returns the field with the key and value styles,
+// restoring the level style after each of them
+func (c *colorCodes) field(level string, k string, v any, val string) string {
+	var b strings.Builder
+	if c.keys != "" {
+		b.WriteString(c.keys)
+		b.WriteString(k)
+		b.Write(ColorOff)
+		b.WriteString(level)
+	} else {
+		b.WriteString(k)
+	}
+	b.WriteByte('=')
+
+	code := c.values
+	if _, ok := v.(error); ok && c.errors != "" {
+		code = c.errors
+	}
+	if code != "" {
+		b.WriteString(code)
+		b.WriteString(val)
+		b.Write(ColorOff)
+		b.WriteString(level)
+	} else {
+		b.WriteString(val)
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package xlog_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StyleCode(t *testing.T) {
+	assert.Equal(t, "", xlog.Style{}.Code())
+	assert.True(t, xlog.Style{}.IsZero())
+	assert.Equal(t, "\033[0;91m", xlog.Style{Foreground: xlog.ColorBrightRed}.Code())
+	assert.Equal(t, "\033[0;30m", xlog.Style{Foreground: xlog.ColorBlack}.Code())
+	assert.Equal(t, "\033[0;97m", xlog.Style{Foreground: xlog.ColorBrightWhite}.Code())
+	assert.Equal(t, "\033[0;1;2;4;37;44m", xlog.Style{
+		Foreground: xlog.ColorWhite,
+		Background: xlog.ColorBlue,
+		Bold:       true,
+		Dim:        true,
+		Underline:  true,
+	}.Code())
+	assert.Equal(t, "\033[0;100m", xlog.Style{Background: xlog.ColorBrightBlack}.Code())
+}
+
+func Test_ColorScheme(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{
+		Colors: &xlog.DefaultColorScheme,
+	}).Options(xlog.FormatSkipTime, xlog.FormatNoCaller, xlog.FormatWithColor))
+
+	info := "\033[0;96m"
+	off := "\033[0m"
+	logger.KV(xlog.INFO, "k1", 1)
+	assert.Equal(t, info+"I | pkg=xlog_test, \033[0;2mk1"+off+info+"=1"+off+"\n", b.String())
+
+	b.Reset()
+	logger.KV(xlog.ERROR, "err", fmt.Errorf("failed"))
+	red := "\033[0;91m"
+	assert.Equal(t, red+"E | pkg=xlog_test, \033[0;2merr"+off+red+"="+red+"\"failed\""+off+red+off+"\n", b.String())
+
+	b.Reset()
+	logger.Log(xlog.CRITICAL, "fatal")
+	assert.Equal(t, "\033[0;1;91mC | pkg=xlog_test, \"fatal\""+off+"\n", b.String())
+
+	// level override and value style
+	b.Reset()
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{
+		Colors: &xlog.ColorScheme{
+			Levels: map[xlog.LogLevel]xlog.Style{
+				xlog.INFO: {Foreground: xlog.ColorGreen, Underline: true},
+			},
+			Values: xlog.Style{Bold: true},
+		},
+	}).Options(xlog.FormatSkipTime, xlog.FormatNoCaller, xlog.FormatWithColor))
+	logger.KV(xlog.INFO, "k1", 1)
+	green := "\033[0;4;32m"
+	assert.Equal(t, green+"I | pkg=xlog_test, k1=\033[0;1m1"+off+green+off+"\n", b.String())
+
+	// no colors without FormatWithColor
+	b.Reset()
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{
+		Colors: &xlog.DefaultColorScheme,
+	}).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	logger.KV(xlog.INFO, "k1", 1)
+	assert.Equal(t, "I | pkg=xlog_test, k1=1\n", b.String())
+}
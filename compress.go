@@ -0,0 +1,180 @@
+package xlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CompressedPrefix is the marker of compressed values
+const CompressedPrefix = "gzip+base64:"
+
+// CompressConfig specifies configuration for Compressor
+type CompressConfig struct {
+	// Threshold specifies the size of the entry,
+	// above which the large values are compressed, 64KB by default
+	Threshold int
+	// MinValueSize specifies the minimum size of the value to compress,
+	// 1KB by default
+	MinValueSize int
+}
+
+// Compressor is a formatter wrapper, that compresses the large values
+// of the entries exceeding the threshold, so sinks with line-size limits
+// still accept them. The compressed values are gzipped and base64 encoded,
+// with "gzip+base64:" prefix, and can be decoded by Decompress.
+type Compressor struct {
+	inner Formatter
+	cfg   CompressConfig
+}
+
+// NewCompressor returns Compressor
+func NewCompressor(inner Formatter, cfg CompressConfig) *Compressor {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 64 * 1024
+	}
+	if cfg.MinValueSize <= 0 {
+		cfg.MinValueSize = 1024
+	}
+	return &Compressor{
+		inner: inner,
+		cfg:   cfg,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (c *Compressor) Options(ops ...FormatterOption) Formatter {
+	c.inner.Options(ops...)
+	return c
+}
+
+// Settings applies the settings to the inner formatter
+func (c *Compressor) Settings(settings FormatterSettings) Formatter {
+	ApplySettings(c.inner, settings)
+	return c
+}
+
+// Format log entry string to the stream
+func (c *Compressor) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	msg := fmt.Sprint(entries...)
+	if len(msg) > c.cfg.Threshold {
+		entries = []any{Compress(msg)}
+	}
+	c.inner.Format(pkg, l, depth+1, entries...)
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (c *Compressor) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	c.inner.FormatKV(pkg, l, depth+1, c.compress(entries)...)
+}
+
+// Flush the logs
+func (c *Compressor) Flush() {
+	c.inner.Flush()
+}
+
+// compress returns the entries with the largest values compressed,
+// until the estimated size is below the threshold
+func (c *Compressor) compress(entries []any) []any {
+	type field struct {
+		idx int
+		val string
+	}
+	var large []field
+	total := 0
+	for i := 0; i < len(entries); i++ {
+		s, ok := entries[i].(string)
+		if !ok {
+			s = fmt.Sprint(entries[i])
+		}
+		total += len(s) + 2
+		if i%2 == 1 && len(s) >= c.cfg.MinValueSize && !IsCompressed(s) {
+			large = append(large, field{idx: i, val: s})
+		}
+	}
+	if total <= c.cfg.Threshold || len(large) == 0 {
+		return entries
+	}
+
+	sort.SliceStable(large, func(i, j int) bool {
+		return len(large[i].val) > len(large[j].val)
+	})
+
+	list := append([]any{}, entries...)
+	for _, f := range large {
+		z := Compress(f.val)
+		list[f.idx] = z
+		total -= len(f.val) - len(z)
+		if total <= c.cfg.Threshold {
+			break
+		}
+	}
+	return list
+}
+
+// Compress returns gzipped and base64 encoded value with "gzip+base64:" prefix
+func Compress(s string) string {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, _ = zw.Write([]byte(s))
+	_ = zw.Close()
+	return CompressedPrefix + base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+// IsCompressed returns true if the value is compressed
+func IsCompressed(s string) bool {
+	return strings.HasPrefix(s, CompressedPrefix)
+}
+
+func isCompressedValue(v any) bool {
+	s, ok := v.(string)
+	return ok && IsCompressed(s)
+}
+
+// Decompress returns the original value of the compressed value,
+// or the value as is, if it is not compressed
+func Decompress(s string) (string, error) {
+	if !IsCompressed(s) {
+		return s, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(s[len(CompressedPrefix):])
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to decode value")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to decompress value")
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to decompress value")
+	}
+	return string(b), nil
+}
+
+// Decompress replaces the compressed message and field values with the original values
+func (e *Entry) Decompress() error {
+	msg, err := Decompress(e.Message)
+	if err != nil {
+		return err
+	}
+	e.Message = msg
+	for i := 1; i < len(e.Fields); i += 2 {
+		if s, ok := e.Fields[i].(string); ok && IsCompressed(s) {
+			v, err := Decompress(s)
+			if err != nil {
+				return errors.WithMessagef(err, "invalid field %v", e.Fields[i-1])
+			}
+			e.Fields[i] = v
+		}
+	}
+	return nil
+}
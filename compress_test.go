@@ -0,0 +1,78 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Compress(t *testing.T) {
+	v := strings.Repeat("stack frame\n", 100)
+	z := xlog.Compress(v)
+	assert.True(t, xlog.IsCompressed(z))
+	assert.Less(t, len(z), len(v))
+
+	d, err := xlog.Decompress(z)
+	require.NoError(t, err)
+	assert.Equal(t, v, d)
+
+	d, err = xlog.Decompress("plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", d)
+
+	_, err = xlog.Decompress(xlog.CompressedPrefix + "!!!")
+	assert.EqualError(t, err, "failed to decode value: illegal base64 data at input byte 0")
+	_, err = xlog.Decompress(xlog.CompressedPrefix + "YWJj")
+	assert.EqualError(t, err, "failed to decompress value: unexpected EOF")
+}
+
+func Test_Compressor(t *testing.T) {
+	var b bytes.Buffer
+	c := xlog.NewCompressor(xlog.NewJSONFormatter(&b), xlog.CompressConfig{
+		Threshold:    2048,
+		MinValueSize: 512,
+	})
+	xlog.SetFormatter(c.Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	small := strings.Repeat("a", 600)
+	large := strings.Repeat("stack frame\n", 200)
+
+	// below the threshold
+	logger.KV(xlog.INFO, "small", small)
+	assert.Equal(t, `{"level":"I","pkg":"xlog_test","small":"`+small+`"}`+"\n", b.String())
+
+	// only the largest value is compressed
+	b.Reset()
+	logger.KV(xlog.INFO, "small", small, "stack", large, "k", 1)
+	assert.Less(t, b.Len(), 2048)
+	assert.Contains(t, b.String(), `"small":"`+small+`"`)
+	assert.Contains(t, b.String(), `"stack":"gzip+base64:`)
+
+	r, err := xlog.NewReader(strings.NewReader(b.String()), nil)
+	require.NoError(t, err)
+	e, err := r.Next()
+	require.NoError(t, err)
+	require.NoError(t, e.Decompress())
+	stack, _ := e.Field("stack")
+	assert.Equal(t, large, stack)
+
+	b.Reset()
+	logger.Info(large)
+	c.Flush()
+	assert.Contains(t, b.String(), `"msg":"gzip+base64:`)
+
+	r, err = xlog.NewReader(strings.NewReader(b.String()), nil)
+	require.NoError(t, err)
+	e, err = r.Next()
+	require.NoError(t, err)
+	require.NoError(t, e.Decompress())
+	assert.Equal(t, large, e.Message)
+
+	e.Fields = []any{"k", xlog.CompressedPrefix + "!!!"}
+	assert.EqualError(t, e.Decompress(), "invalid field k: failed to decode value: illegal base64 data at input byte 0")
+}
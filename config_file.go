@@ -0,0 +1,388 @@
+package xlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig specifies the logger configuration loaded by ConfigureFromFile
+type FileConfig struct {
+	// Formatter specifies the type of the global formatter:
+	// pretty (default), string, json or nil
+	Formatter string `json:"formatter,omitempty" yaml:"formatter,omitempty"`
+	// Options specifies the formatter options, such as NoCaller or SkipTime
+	Options []string `json:"options,omitempty" yaml:"options,omitempty"`
+	// Settings specifies the formatter settings, such as pkg_key or max_value_len
+	Settings *FormatterSettings `json:"settings,omitempty" yaml:"settings,omitempty"`
+	// Output specifies stderr (default), stdout, or the file path
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// Rotation specifies the rotation of the output file
+	Rotation *RotationConfig `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	// Levels specifies the log levels per repo and package
+	Levels []RepoLogLevel `json:"levels,omitempty" yaml:"levels,omitempty"`
+}
+
+// RotationConfig specifies the rotation of the output file
+type RotationConfig struct {
+	// MaxSize specifies the maximum size in megabytes of the file before it is rotated
+	MaxSize int `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	// MaxAge specifies the maximum number of days to retain old files
+	MaxAge int `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	// MaxBackups specifies the maximum number of old files to retain
+	MaxBackups int `json:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+	// Compress specifies if the rotated files are compressed with gzip
+	Compress bool `json:"compress,omitempty" yaml:"compress,omitempty"`
+}
+
+// fileOutput is the output opened by the applied file config
+var fileOutput struct {
+	sync.Mutex
+	closer io.Closer
+	// levels are the levels of the applied file config
+	levels []RepoLogLevel
+}
+
+// LoadConfigFile returns the configuration from YAML or JSON file,
+// the format is detected by .yaml or .yml extension
+func LoadConfigFile(path string) (*FileConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseConfigFile(path, b)
+}
+
+// parseConfigFile returns the configuration from the content of the file
+func parseConfigFile(path string, b []byte) (*FileConfig, error) {
+	var err error
+	cfg := new(FileConfig)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+		if err == io.EOF {
+			err = nil
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config: %s", path)
+	}
+	return cfg, nil
+}
+
+// ConfigureFromFile loads the configuration from YAML or JSON file, and applies it.
+// The configuration is validated before applied,
+// so the logger is not changed if the file is invalid.
+func ConfigureFromFile(path string) error {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	return Configure(cfg)
+}
+
+// Configure applies the configuration:
+// sets the global formatter and the levels.
+// The output file opened by the previous configuration is closed,
+// after the in-flight entries are written to it.
+// The levels of the previous configuration, that are removed,
+// are reset to INFO, so the file stays the source of truth.
+func Configure(cfg *FileConfig) error {
+	options, err := parseFormatterOptions(cfg.Options)
+	if err != nil {
+		return err
+	}
+	levels, err := parseConfigLevels(cfg.Levels)
+	if err != nil {
+		return err
+	}
+	newFormatter, err := formatterByType(cfg.Formatter)
+	if err != nil {
+		return err
+	}
+
+	fileOutput.Lock()
+	defer fileOutput.Unlock()
+
+	w, closer, err := openOutput(cfg.Output, cfg.Rotation)
+	if err != nil {
+		return err
+	}
+
+	f := newFormatter(w)
+	if len(options) > 0 {
+		f = f.Options(options...)
+	}
+	if cfg.Settings != nil {
+		f = ApplySettings(f, *cfg.Settings)
+	}
+	err = SwapFormatter(f)
+
+	for _, ll := range fileOutput.levels {
+		if !hasRepoLevel(levels, ll) {
+			ll.Level = INFO.String()
+			SetRepoLevel(ll)
+		}
+	}
+	SetRepoLevels(levels)
+	fileOutput.levels = levels
+
+	// the previous sink is retired by SwapFormatter,
+	// so the output is not used anymore
+	if fileOutput.closer != nil {
+		_ = fileOutput.closer.Close()
+	}
+	fileOutput.closer = closer
+	return err
+}
+
+// hasRepoLevel returns true, if the list has the level of the same repo and package
+func hasRepoLevel(list []RepoLogLevel, ll RepoLogLevel) bool {
+	for _, l := range list {
+		if l.Repo == ll.Repo && packageName(l.Package) == packageName(ll.Package) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageName returns the package of the level, "*" for all packages
+func packageName(pkg string) string {
+	if pkg == "" {
+		return "*"
+	}
+	return pkg
+}
+
+func formatterByType(typ string) (func(io.Writer) Formatter, error) {
+	switch strings.ToLower(typ) {
+	case "", "pretty", "default":
+		return NewPrettyFormatter, nil
+	case "string":
+		return NewStringFormatter, nil
+	case "json":
+		return NewJSONFormatter, nil
+	case "nil":
+		return func(io.Writer) Formatter { return NewNilFormatter() }, nil
+	}
+	return nil, errors.Errorf("unsupported formatter: %q", typ)
+}
+
+func openOutput(output string, rotation *RotationConfig) (io.Writer, io.Closer, error) {
+	switch strings.ToLower(output) {
+	case "", "stderr":
+		return os.Stderr, nil, nil
+	case "stdout":
+		return os.Stdout, nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if rotation != nil {
+		w := &lumberjack.Logger{
+			Filename:   output,
+			MaxSize:    rotation.MaxSize,
+			MaxAge:     rotation.MaxAge,
+			MaxBackups: rotation.MaxBackups,
+			Compress:   rotation.Compress,
+		}
+		return w, w, nil
+	}
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return file, file, nil
+}
+
+// parseConfigLevels returns the levels in canonical format,
+// ordered to apply the global and repo levels before the package levels
+func parseConfigLevels(list []RepoLogLevel) ([]RepoLogLevel, error) {
+	levels := make([]RepoLogLevel, 0, len(list))
+	for _, ll := range list {
+		l, err := ParseLevel(strings.ToUpper(ll.Level))
+		if err != nil {
+			return nil, err
+		}
+		ll.Level = l.String()
+		if ll.Repo == "" {
+			ll.Repo = "*"
+		}
+		levels = append(levels, ll)
+	}
+	rank := func(ll RepoLogLevel) int {
+		switch {
+		case ll.Repo == "*":
+			return 0
+		case ll.Package == "" || ll.Package == "*":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		return rank(levels[i]) < rank(levels[j])
+	})
+	return levels, nil
+}
+
+func parseFormatterOptions(names []string) ([]FormatterOption, error) {
+	var options []FormatterOption
+	for _, name := range names {
+		o, err := ParseFormatterOption(name)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+// ParseFormatterOption returns the option by name, as returned by FormatterOption.String
+func ParseFormatterOption(name string) (FormatterOption, error) {
+	for o := FormatWithCaller; o <= FormatDuplicateKeysSuffix; o++ {
+		if strings.EqualFold(o.String(), name) {
+			return o, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported formatter option: %q", name)
+}
+
+// ConfigWatcher reapplies the configuration file when it is changed
+type ConfigWatcher struct {
+	path    string
+	delay   time.Duration
+	watcher *fsnotify.Watcher
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	// hash is the hash of the applied content
+	hash [sha256.Size]byte
+}
+
+// WatchConfigFile applies the configuration file, and returns the watcher
+// that reapplies the configuration when the content of the file is changed.
+// The parent directory is watched with fsnotify, so the file replaced by rename,
+// as editors and Kubernetes ConfigMap volumes do, is reloaded.
+// The events are coalesced for the delay, 100 milliseconds by default.
+// The errors of the reload are reported with ReportError,
+// and the previous configuration remains active.
+func WatchConfigFile(path string, delay time.Duration) (*ConfigWatcher, error) {
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = fw.Add(filepath.Dir(path)); err != nil {
+		_ = fw.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	w := &ConfigWatcher{
+		path:    path,
+		delay:   delay,
+		watcher: fw,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if _, err = w.reload(); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+
+	done := TrackGoroutine("xlog.ConfigWatcher")
+	go w.run(done)
+	return w, nil
+}
+
+// Close stops watching the file, the applied configuration remains active
+func (w *ConfigWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.stopped
+		_ = w.watcher.Close()
+	})
+	return nil
+}
+
+func (w *ConfigWatcher) run(done func()) {
+	defer done()
+	defer close(w.stopped)
+
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-w.stop:
+			return
+		case _, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			// any event in the directory schedules the check,
+			// as the file may be replaced by the rename of a symlink,
+			// and the content is reapplied only if changed
+			fire = time.After(w.delay)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			ReportError("xlog")
+			fmt.Fprintf(os.Stderr, "xlog: config watcher: %v\n", err)
+		case <-fire:
+			fire = nil
+			if _, err := w.reload(); err != nil {
+				ReportError("xlog")
+				fmt.Fprintf(os.Stderr, "xlog: config: %v\n", err)
+			}
+		}
+	}
+}
+
+// reload applies the configuration, if the content of the file is changed,
+// and returns true if it was applied.
+// The missing file is not an error after the first load,
+// as it is removed while being replaced.
+func (w *ConfigWatcher) reload() (bool, error) {
+	b, err := os.ReadFile(w.path)
+	if err != nil {
+		if w.hash != ([sha256.Size]byte{}) && os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	hash := sha256.Sum256(b)
+	if hash == w.hash {
+		return false, nil
+	}
+	// the hash is updated for the invalid content as well,
+	// so the error is reported once
+	w.hash = hash
+
+	cfg, err := parseConfigFile(w.path, b)
+	if err != nil {
+		return false, err
+	}
+	return true, Configure(cfg)
+}
@@ -0,0 +1,199 @@
+package xlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ConfigureFromFile(t *testing.T) {
+	const repo = "github.com/effective-security/xlog"
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+	defer func() {
+		require.NoError(t, xlog.Configure(&xlog.FileConfig{Formatter: "nil"}))
+	}()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "logs", "app.log")
+	path := filepath.Join(dir, "xlog.yaml")
+	err := os.WriteFile(path, []byte(`
+formatter: json
+options: [NoCaller, skiptime]
+settings:
+  pkg_key: logger
+output: `+output+`
+rotation:
+  max_size: 1
+  max_backups: 2
+levels:
+  - repo: `+repo+`
+    package: xlog_test
+    level: debug
+  - repo: "*"
+    level: WARNING
+`), 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, xlog.ConfigureFromFile(path))
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	logger.KV(xlog.DEBUG, "k", 1)
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, `{"k":1,"level":"D","logger":"xlog_test"}`+"\n", string(b))
+
+	cfg := xlog.EffectiveConfig()
+	assert.Equal(t, "*xlog.JSONFormatter", cfg.Formatter.Type)
+	assert.Equal(t, []string{"SkipTime"}, cfg.Formatter.Options)
+	assert.Equal(t, &xlog.FormatterSettings{PkgKey: "logger"}, cfg.Formatter.Settings)
+
+	jsonPath := filepath.Join(dir, "xlog.json")
+	tcases := []struct {
+		cfg string
+		err string
+	}{
+		{`{"formatter":"xml"}`, `unsupported formatter: "xml"`},
+		{`{"options":["Loud"]}`, `unsupported formatter option: "Loud"`},
+		{`{"levels":[{"repo":"*","level":"LOUD"}]}`, "unable to parse log level: LOUD"},
+		{`{"format":"json"}`, `failed to parse config: ` + jsonPath + `: json: unknown field "format"`},
+	}
+	for _, tc := range tcases {
+		require.NoError(t, os.WriteFile(jsonPath, []byte(tc.cfg), 0644))
+		assert.EqualError(t, xlog.ConfigureFromFile(jsonPath), tc.err)
+	}
+	// not changed
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+	assert.Equal(t, "*xlog.JSONFormatter", xlog.EffectiveConfig().Formatter.Type)
+
+	err = xlog.ConfigureFromFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func Test_ConfigureReload(t *testing.T) {
+	const repo = "github.com/effective-security/xlog"
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+	defer func() {
+		require.NoError(t, xlog.Configure(&xlog.FileConfig{Formatter: "nil"}))
+	}()
+
+	// the levels removed from the file are reset
+	require.NoError(t, xlog.Configure(&xlog.FileConfig{
+		Formatter: "nil",
+		Levels: []xlog.RepoLogLevel{
+			{Repo: repo, Package: "xlog_test", Level: "DEBUG"},
+			{Repo: "*", Level: "ERROR"},
+		},
+	}))
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	require.NoError(t, xlog.Configure(&xlog.FileConfig{
+		Formatter: "nil",
+		Levels:    []xlog.RepoLogLevel{{Repo: "*", Level: "WARNING"}},
+	}))
+	assert.True(t, logger.LevelAt(xlog.WARNING))
+	assert.False(t, logger.LevelAt(xlog.NOTICE))
+
+	require.NoError(t, xlog.Configure(&xlog.FileConfig{Formatter: "nil"}))
+	assert.True(t, logger.LevelAt(xlog.INFO))
+	assert.False(t, logger.LevelAt(xlog.DEBUG))
+
+	// the entries written during the reload are not lost
+	dir := t.TempDir()
+	outputs := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	cfg := func(i int) *xlog.FileConfig {
+		return &xlog.FileConfig{
+			Formatter: "string",
+			Options:   []string{"NoCaller", "SkipTime"},
+			Output:    outputs[i%2],
+		}
+	}
+	require.NoError(t, xlog.Configure(cfg(0)))
+
+	// the writers keep logging until the reloads are done
+	var written atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				logger.KV(xlog.INFO, "n", written.Add(1))
+			}
+		}()
+	}
+	for i := 1; i < 100; i++ {
+		require.NoError(t, xlog.Configure(cfg(i)))
+	}
+	close(stop)
+	wg.Wait()
+	require.NoError(t, xlog.Configure(&xlog.FileConfig{Formatter: "nil"}))
+
+	var lines int
+	for _, output := range outputs {
+		b, err := os.ReadFile(output)
+		require.NoError(t, err)
+		lines += strings.Count(string(b), "\n")
+	}
+	assert.Equal(t, int(written.Load()), lines)
+}
+
+func Test_WatchConfigFile(t *testing.T) {
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+	defer func() {
+		require.NoError(t, xlog.Configure(&xlog.FileConfig{Formatter: "nil"}))
+	}()
+
+	path := filepath.Join(t.TempDir(), "xlog.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"formatter":"nil","levels":[{"repo":"*","level":"INFO"}]}`), 0644))
+
+	w, err := xlog.WatchConfigFile(path, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer w.Close()
+	assert.False(t, logger.LevelAt(xlog.DEBUG))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"formatter":"nil","levels":[{"repo":"*","level":"DEBUG"}]}`), 0644))
+	assert.Eventually(t, func() bool {
+		return logger.LevelAt(xlog.DEBUG)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the file replaced by rename, with the same size
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(`{"formatter":"nil","levels":[{"repo":"*","level":"ERROR"}]}`), 0644))
+	require.NoError(t, os.Rename(tmp, path))
+	assert.Eventually(t, func() bool {
+		return !logger.LevelAt(xlog.WARNING)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the edit in place with the same size
+	require.NoError(t, os.WriteFile(path, []byte(`{"formatter":"nil","levels":[{"repo":"*","level":"DEBUG"}]}`), 0644))
+	assert.Eventually(t, func() bool {
+		return logger.LevelAt(xlog.DEBUG)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the invalid content keeps the previous configuration
+	require.NoError(t, os.WriteFile(path, []byte(`{"formatter":`), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, []byte(`{"formatter":"nil","levels":[{"repo":"*","level":"ERROR"}]}`), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	_, err = xlog.WatchConfigFile(filepath.Join(t.TempDir(), "missing.json"), 0)
+	assert.Error(t, err)
+}
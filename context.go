@@ -1,39 +1,121 @@
 package xlog
 
-import "context"
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 type contextKey int
 
 const (
 	keyContext contextKey = iota
+	keyWorkerID
+)
+
+const (
+	// KeyTraceID is the key used to log OpenTelemetry trace ID
+	KeyTraceID = "trace_id"
+	// KeySpanID is the key used to log OpenTelemetry span ID
+	KeySpanID = "span_id"
+	// KeyTraceSampled is the key used to log OpenTelemetry sampled flag,
+	// added only for the sampled spans
+	KeyTraceSampled = "trace_sampled"
+	// KeyWorkerID is the key used to log the worker ID, set by ContextWithWorkerID
+	KeyWorkerID = "worker"
 )
 
 // contextLogs represents extra data in the Context that will be added to logs, in key=value format
 type contextLogs struct {
 	entries []any
+	// providers is true if any value is func() any
+	providers bool
 }
 
 // ContextWithKV returns context with values to be added to logs,
 // entries in "key1=value1, ..., keyN=valueN" format.
+// A value can be a provider function of func() any type,
+// evaluated each time an entry is logged, such as the current retry attempt.
 func ContextWithKV(ctx context.Context, entries ...any) context.Context {
 	v := ctx.Value(keyContext)
 	if v == nil {
 		rctx := &contextLogs{
-			entries: entries,
+			entries:   entries,
+			providers: hasProviders(entries),
 		}
 		ctx = context.WithValue(ctx, keyContext, rctx)
 	} else {
 		rctx := v.(*contextLogs)
 		rctx.entries = append(rctx.entries, entries...)
+		rctx.providers = rctx.providers || hasProviders(entries)
 	}
 	return ctx
 }
 
-// ContextEntries returns log entries
+// ContextWithWorkerID returns context with the worker ID to be added to logs as worker field,
+// to identify the entries of the concurrent workers, such as the goroutines of a pool.
+// Unlike ContextWithKV, the parent context is not modified.
+func ContextWithWorkerID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyWorkerID, id)
+}
+
+// WorkerIDFromContext returns the worker ID, set by ContextWithWorkerID
+func WorkerIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyWorkerID).(string)
+	return id
+}
+
+// ContextEntries returns log entries, including the worker ID,
+// with the values of provider functions evaluated
 func ContextEntries(ctx context.Context) []any {
+	worker := WorkerIDFromContext(ctx)
 	v := ctx.Value(keyContext)
 	if v == nil {
+		if worker == "" {
+			return nil
+		}
+		return []any{KeyWorkerID, worker}
+	}
+	rctx := v.(*contextLogs)
+	if !rctx.providers && worker == "" {
+		return rctx.entries
+	}
+	entries := make([]any, 0, len(rctx.entries)+2)
+	if worker != "" {
+		entries = append(entries, KeyWorkerID, worker)
+	}
+	for i, e := range rctx.entries {
+		if fn, ok := e.(func() any); ok && i%2 == 1 {
+			e = fn()
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// hasProviders returns true if any value of the entries is func() any
+func hasProviders(entries []any) bool {
+	for i := 1; i < len(entries); i += 2 {
+		if _, ok := entries[i].(func() any); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceEntries returns trace_id and span_id entries, and trace_sampled for the sampled span,
+// if ctx has a valid OpenTelemetry span context
+func TraceEntries(ctx context.Context) []any {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
 		return nil
 	}
-	return v.(*contextLogs).entries
+	entries := []any{
+		KeyTraceID, sc.TraceID().String(),
+		KeySpanID, sc.SpanID().String(),
+	}
+	if sc.IsSampled() {
+		entries = append(entries, KeyTraceSampled, true)
+	}
+	return entries
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/effective-security/xlog"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Test_ContextWithLog(t *testing.T) {
@@ -38,3 +39,84 @@ func Test_WithContext(t *testing.T) {
 	assert.Equal(t, "2021-04-01 00:00:00.000000 \x1b[0;96mI | pkg=xlog_test, func=Test_WithContext, key1=1, key2=\"val2\", k3=3\x1b[0m\n", result)
 	b.Reset()
 }
+
+func Test_ContextWithTrace(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetFormatter(xlog.NewPrettyFormatter(writer).Options(xlog.FormatNoCaller))
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	assert.Empty(t, xlog.TraceEntries(context.Background()))
+	assert.Len(t, xlog.TraceEntries(ctx), 4)
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	assert.Equal(t, []any{
+		xlog.KeyTraceID, "0102030405060708090a0b0c0d0e0f10",
+		xlog.KeySpanID, "0102030405060708",
+		xlog.KeyTraceSampled, true,
+	}, xlog.TraceEntries(sampled))
+
+	ctx = xlog.ContextWithKV(ctx, "key1", 1)
+	logger.ContextKV(ctx, xlog.INFO, "k2", 2)
+	assert.Equal(t, "2021-04-01 00:00:00.000000 I | pkg=xlog_test, key1=1, trace_id=\"0102030405060708090a0b0c0d0e0f10\", span_id=\"0102030405060708\", k2=2\n", b.String())
+	b.Reset()
+
+	xlog.SetFormatter(xlog.NewJSONFormatter(writer).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	logger.ContextKV(ctx, xlog.INFO, "k2", 2)
+	assert.Equal(t, `{"k2":2,"key1":1,"level":"I","pkg":"xlog_test","span_id":"0102030405060708","trace_id":"0102030405060708090a0b0c0d0e0f10"}`+"\n", b.String())
+}
+
+func Test_ContextProviders(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	attempt := 0
+	ctx := xlog.ContextWithKV(context.Background(), "op", "sync")
+	ctx = xlog.ContextWithKV(ctx, "attempt", func() any { return attempt })
+	for attempt = 1; attempt <= 2; attempt++ {
+		logger.ContextKV(ctx, xlog.INFO, "status", "retry")
+	}
+	assert.Equal(t,
+		"level=I pkg=xlog_test op=\"sync\" attempt=1 status=\"retry\"\n"+
+			"level=I pkg=xlog_test op=\"sync\" attempt=2 status=\"retry\"\n",
+		b.String())
+
+	// the stored entries are not changed
+	attempt = 3
+	entries := xlog.ContextEntries(ctx)
+	assert.Equal(t, []any{"op", "sync", "attempt", 3}, entries)
+	entries[3] = 0
+	assert.Equal(t, 3, xlog.ContextEntries(ctx)[3])
+}
+
+func Test_ContextWithWorkerID(t *testing.T) {
+	ctx := xlog.ContextWithKV(context.Background(), "cid", 123)
+	assert.Empty(t, xlog.WorkerIDFromContext(ctx))
+
+	w1 := xlog.ContextWithWorkerID(ctx, "w1")
+	w2 := xlog.ContextWithWorkerID(ctx, "w2")
+	assert.Equal(t, "w1", xlog.WorkerIDFromContext(w1))
+	assert.Equal(t, []any{"worker", "w1", "cid", 123}, xlog.ContextEntries(w1))
+	assert.Equal(t, []any{"worker", "w2", "cid", 123}, xlog.ContextEntries(w2))
+	// the parent context is not modified
+	assert.Equal(t, []any{"cid", 123}, xlog.ContextEntries(ctx))
+	assert.Equal(t, []any{"worker", "w3"}, xlog.ContextEntries(xlog.ContextWithWorkerID(context.Background(), "w3")))
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.ContextKV(w1, xlog.INFO, "k", 1)
+	assert.Equal(t, "level=I pkg=xlog_test worker=\"w1\" cid=123 k=1\n", b.String())
+}
@@ -0,0 +1,33 @@
+package xlog
+
+import (
+	"context"
+	"time"
+)
+
+// Canonical keys, to keep the field names consistent across services and dashboards.
+// KeyTraceID and KeySpanID are populated by ContextKV from OpenTelemetry span context.
+const (
+	// KeyError is the key of the error, used by Err
+	KeyError = "err"
+	// KeyDuration is the key of the duration of the operation, used by Elapsed
+	KeyDuration = "duration"
+	// KeyRequestID is the key of the request ID, used by RequestID and ContextWithRequestID
+	KeyRequestID = "request_id"
+)
+
+// Elapsed returns the duration field with the time elapsed since start
+func Elapsed(start time.Time) Field {
+	return Duration(KeyDuration, TimeNowFn().Sub(start))
+}
+
+// RequestID returns the request ID field
+func RequestID(id string) Field {
+	return String(KeyRequestID, id)
+}
+
+// ContextWithRequestID returns context with the request ID,
+// to be added to the entries logged with ContextKV
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return ContextWithKV(ctx, KeyRequestID, id)
+}
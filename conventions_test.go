@@ -0,0 +1,36 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Conventions(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fn := xlog.TimeNowFn
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = fn
+	}()
+
+	elapsed := xlog.Elapsed(now.Add(-2 * time.Second))
+	assert.Equal(t, xlog.KeyDuration, elapsed.Key)
+	assert.Equal(t, 2*time.Second, elapsed.Value())
+	requestID := xlog.RequestID("r1")
+	assert.Equal(t, xlog.KeyRequestID, requestID.Key)
+	assert.Equal(t, "r1", requestID.Value())
+
+	ctx := xlog.ContextWithRequestID(context.Background(), "r2")
+	logger.ContextKV(ctx, xlog.INFO, xlog.Elapsed(now.Add(-time.Millisecond)), xlog.Err(fmt.Errorf("failed")))
+	assert.Equal(t, "level=I pkg=xlog_test request_id=\"r2\" duration=1ms err=\"failed\"\n", b.String())
+}
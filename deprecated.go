@@ -0,0 +1,87 @@
+package xlog
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// KeyDeprecated is the key used to log the deprecated feature
+	KeyDeprecated = "deprecated"
+	// DeprecatedMessage is the message of the deprecation entries
+	DeprecatedMessage = "deprecated feature used"
+)
+
+// DeprecationInfo describes the deprecation that fired
+type DeprecationInfo struct {
+	// Feature is the name of the deprecated feature
+	Feature string `json:"feature"`
+	// Pkg is the package that reported the deprecation
+	Pkg string `json:"pkg"`
+	// Source is the location of the first use, in "file:line" format
+	Source string `json:"source"`
+	// Time of the first use
+	Time time.Time `json:"time"`
+}
+
+var deprecations struct {
+	sync.Mutex
+	fired map[string]DeprecationInfo
+}
+
+// Deprecated logs the use of the deprecated feature at WARNING level,
+// once per process per feature, with "deprecated" key set to the feature.
+// The fired deprecations can be queried with Deprecations.
+func (p *PackageLogger) Deprecated(feature string, entries ...any) {
+	_, source := callerInfo(calldepth)
+	if !markDeprecated(feature, p.pkg, source) {
+		return
+	}
+	kvList := append([]any{"msg", DeprecatedMessage, KeyDeprecated, feature}, p.withPrefix(entries)...)
+	p.internalLog(nil, kv, calldepth, WARNING, kvList...)
+}
+
+// markDeprecated records the deprecation,
+// and returns false if the feature has already fired
+func markDeprecated(feature, pkg, source string) bool {
+	deprecations.Lock()
+	defer deprecations.Unlock()
+
+	if _, ok := deprecations.fired[feature]; ok {
+		return false
+	}
+	if deprecations.fired == nil {
+		deprecations.fired = make(map[string]DeprecationInfo)
+	}
+	deprecations.fired[feature] = DeprecationInfo{
+		Feature: feature,
+		Pkg:     pkg,
+		Source:  source,
+		Time:    TimeNowFn(),
+	}
+	return true
+}
+
+// Deprecations returns the deprecations fired in the process, sorted by feature
+func Deprecations() []DeprecationInfo {
+	deprecations.Lock()
+	defer deprecations.Unlock()
+
+	list := make([]DeprecationInfo, 0, len(deprecations.fired))
+	for _, d := range deprecations.fired {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Feature < list[j].Feature
+	})
+	return list
+}
+
+// ResetDeprecations clears the fired deprecations,
+// so each feature is logged again on the next use
+func ResetDeprecations() {
+	deprecations.Lock()
+	defer deprecations.Unlock()
+	deprecations.fired = nil
+}
@@ -0,0 +1,49 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Deprecated(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.ResetDeprecations()
+	defer xlog.ResetDeprecations()
+
+	for i := 0; i < 3; i++ {
+		logger.Deprecated("v1_api", "path", "/v1/users")
+	}
+	logger.WithPrefix("req").Deprecated("legacy_token", "id", 1)
+	assert.Equal(t,
+		"level=W pkg=xlog_test msg=\"deprecated feature used\" deprecated=\"v1_api\" path=\"/v1/users\"\n"+
+			"level=W pkg=xlog_test msg=\"deprecated feature used\" deprecated=\"legacy_token\" req.id=1\n",
+		b.String())
+
+	list := xlog.Deprecations()
+	require.Len(t, list, 2)
+	assert.Equal(t, "legacy_token", list[0].Feature)
+	assert.Equal(t, "v1_api", list[1].Feature)
+	assert.Equal(t, "xlog_test", list[1].Pkg)
+	assert.True(t, strings.HasPrefix(list[1].Source, "deprecated_test.go:"), list[1].Source)
+	assert.False(t, list[1].Time.IsZero())
+
+	// fired even if WARNING is disabled
+	b.Reset()
+	xlog.SetGlobalLogLevel(xlog.ERROR)
+	logger.Deprecated("disabled")
+	assert.Empty(t, b.String())
+	assert.Len(t, xlog.Deprecations(), 3)
+
+	xlog.ResetDeprecations()
+	assert.Empty(t, xlog.Deprecations())
+
+	xlog.NewNilLogger().(xlog.ExtendedKeyValueLogger).Deprecated("nil")
+	assert.Empty(t, xlog.Deprecations())
+}
@@ -0,0 +1,82 @@
+package xlog
+
+import "strconv"
+
+// duplicateKeysPolicy specifies how the formatters resolve the duplicate keys
+type duplicateKeysPolicy int
+
+const (
+	// keepDuplicateKeys is the default: the text formatters print all pairs,
+	// and JSON formatter prints the last value
+	keepDuplicateKeys duplicateKeysPolicy = iota
+	lastKeyWins
+	firstKeyWins
+	suffixDuplicateKeys
+)
+
+// hasDuplicateKeys returns true if any string key is repeated
+func hasDuplicateKeys(kvList []any) bool {
+	if len(kvList) < 4 {
+		return false
+	}
+	for i := 2; i < len(kvList); i += 2 {
+		k, ok := kvList[i].(string)
+		if !ok {
+			continue
+		}
+		for j := 0; j < i; j += 2 {
+			if s, ok := kvList[j].(string); ok && s == k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveDuplicateKeys returns the key-value pairs with the duplicate keys resolved by the policy:
+// the last or first value is kept at the position of the first key,
+// or the repeated keys are renamed to key_2, key_3 and so on.
+// The keys that are not strings are kept as is
+func (p duplicateKeysPolicy) resolveDuplicateKeys(kvList []any) []any {
+	if p == keepDuplicateKeys {
+		return kvList
+	}
+	kvList = expandFields(kvList)
+	if !hasDuplicateKeys(kvList) {
+		return kvList
+	}
+
+	list := make([]any, 0, len(kvList))
+	index := make(map[string]int, len(kvList)/2)
+	for i := 0; i < len(kvList); i += 2 {
+		var v any
+		if i+1 < len(kvList) {
+			v = kvList[i+1]
+		}
+		k, ok := kvList[i].(string)
+		if !ok {
+			list = append(list, kvList[i], v)
+			continue
+		}
+		pos, seen := index[k]
+		if !seen {
+			index[k] = len(list)
+			list = append(list, k, v)
+			continue
+		}
+		switch p {
+		case lastKeyWins:
+			list[pos+1] = v
+		case suffixDuplicateKeys:
+			for n := 2; ; n++ {
+				key := k + "_" + strconv.Itoa(n)
+				if _, ok := index[key]; !ok {
+					index[key] = len(list)
+					list = append(list, key, v)
+					break
+				}
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,72 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DuplicateKeys(t *testing.T) {
+	tcases := []struct {
+		opt    xlog.FormatterOption
+		name   string
+		str    string
+		pretty string
+		json   string
+	}{
+		{
+			name:   "default",
+			str:    "level=I pkg=xlog_test k=1 id=2 k=3 k=4\n",
+			pretty: "I | pkg=xlog_test, k=1, id=2, k=3, k=4\n",
+			json:   `{"id":2,"k":4,"level":"I","pkg":"xlog_test"}` + "\n",
+		},
+		{
+			opt:    xlog.FormatDuplicateKeysLastWins,
+			name:   "DuplicateKeysLastWins",
+			str:    "level=I pkg=xlog_test k=4 id=2\n",
+			pretty: "I | pkg=xlog_test, k=4, id=2\n",
+			json:   `{"id":2,"k":4,"level":"I","pkg":"xlog_test"}` + "\n",
+		},
+		{
+			opt:    xlog.FormatDuplicateKeysFirstWins,
+			name:   "DuplicateKeysFirstWins",
+			str:    "level=I pkg=xlog_test k=1 id=2\n",
+			pretty: "I | pkg=xlog_test, k=1, id=2\n",
+			json:   `{"id":2,"k":1,"level":"I","pkg":"xlog_test"}` + "\n",
+		},
+		{
+			opt:    xlog.FormatDuplicateKeysSuffix,
+			name:   "DuplicateKeysSuffix",
+			str:    "level=I pkg=xlog_test k=1 id=2 k_2=3 k_3=4\n",
+			pretty: "I | pkg=xlog_test, k=1, id=2, k_2=3, k_3=4\n",
+			json:   `{"id":2,"k":1,"k_2":3,"k_3":4,"level":"I","pkg":"xlog_test"}` + "\n",
+		},
+	}
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.opt != 0 {
+				assert.Equal(t, tc.name, tc.opt.String())
+				o, err := xlog.ParseFormatterOption(tc.name)
+				require.NoError(t, err)
+				assert.Equal(t, tc.opt, o)
+			}
+
+			var str, pretty, js bytes.Buffer
+			xlog.SetFormatter(xlog.NewMultiFormatter(
+				xlog.NewStringFormatter(&str),
+				xlog.NewPrettyFormatter(&pretty),
+				xlog.NewJSONFormatter(&js),
+			).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, tc.opt))
+
+			logger.WithValues("k", 1, "id", 2).KV(xlog.INFO, "k", 3, "k", 4)
+			assert.Equal(t, tc.str, str.String())
+			assert.Equal(t, tc.pretty, pretty.String())
+			assert.Equal(t, tc.json, js.String())
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package xlog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FormatterInfo describes a formatter
+type FormatterInfo struct {
+	// Type of the formatter
+	Type string `json:"type"`
+	// Options of the formatter, if supported
+	Options []string `json:"options,omitempty"`
+	// Settings of the formatter, if supported and specified
+	Settings *FormatterSettings `json:"settings,omitempty"`
+}
+
+// ConfigInfo describes the active logger configuration
+type ConfigInfo struct {
+	// Formatter is the global formatter
+	Formatter *FormatterInfo `json:"formatter,omitempty"`
+	// RepoFormatters specifies formatters per repo
+	RepoFormatters map[string]*FormatterInfo `json:"repo_formatters,omitempty"`
+	// PackageFormatters specifies formatters per package,
+	// in "repo:pkg" format
+	PackageFormatters map[string]*FormatterInfo `json:"package_formatters,omitempty"`
+	// Hooks specifies the types of installed hooks
+	Hooks []string `json:"hooks,omitempty"`
+	// Enablers specifies the types of installed enablers
+	Enablers []string `json:"enablers,omitempty"`
+	// Levels specifies the log level per package
+	Levels []RepoLogLevel `json:"levels,omitempty"`
+	// NamedLevels specifies the log level per named loggers subtree
+	NamedLevels map[string]string `json:"named_levels,omitempty"`
+	// LevelLimit specifies the maximum level, if limited by the memory watchdog
+	LevelLimit string `json:"level_limit,omitempty"`
+	// RateLimits specifies the configured rate limits
+	RateLimits []RateLimitStats `json:"rate_limits,omitempty"`
+	// Redaction specifies if a redactor is set
+	Redaction bool `json:"redaction,omitempty"`
+	// GlobalFields specifies the fields set by SetGlobalFields,
+	// the values are printed with %v, so the secrets remain masked
+	GlobalFields map[string]string `json:"global_fields,omitempty"`
+	// FieldFuncs specifies the number of functions added by AddFieldFunc
+	FieldFuncs int `json:"field_funcs,omitempty"`
+	// FieldsProviders specifies the TTL of the providers added by AddGlobalFieldsProvider
+	FieldsProviders []string `json:"fields_providers,omitempty"`
+}
+
+// formatterOptions is implemented by formatters with options
+type formatterOptions interface {
+	optionNames() []string
+	formatterSettings() FormatterSettings
+}
+
+// EffectiveConfig returns the active logger configuration
+func EffectiveConfig() *ConfigInfo {
+	cfg := &ConfigInfo{
+		Levels:     GetRepoLevels(),
+		RateLimits: GetRateLimits(),
+		Redaction:  GetRedactor() != nil,
+	}
+	sort.Slice(cfg.Levels, func(i, j int) bool {
+		if cfg.Levels[i].Repo == cfg.Levels[j].Repo {
+			return cfg.Levels[i].Package < cfg.Levels[j].Package
+		}
+		return cfg.Levels[i].Repo < cfg.Levels[j].Repo
+	})
+	if levels := logger.namedLevels.Load(); levels != nil {
+		cfg.NamedLevels = make(map[string]string, len(*levels))
+		for name, l := range *levels {
+			cfg.NamedLevels[name] = l.String()
+		}
+	}
+	if limit := logger.levelLimit.Load(); limit != noLevelLimit {
+		cfg.LevelLimit = LogLevel(limit).String()
+	}
+
+	if s := logger.sinks.Load(); s != nil {
+		if s.formatter != nil {
+			cfg.Formatter = newFormatterInfo(s.formatter.Formatter)
+		}
+		if len(s.repo) > 0 {
+			cfg.RepoFormatters = make(map[string]*FormatterInfo)
+			for repo, f := range s.repo {
+				cfg.RepoFormatters[repo] = newFormatterInfo(f.Formatter)
+			}
+		}
+		if len(s.pkg) > 0 {
+			cfg.PackageFormatters = make(map[string]*FormatterInfo)
+			for key, f := range s.pkg {
+				cfg.PackageFormatters[key.repo+":"+key.pkg] = newFormatterInfo(f.Formatter)
+			}
+		}
+	}
+
+	if hooks := logger.hooks.Load(); hooks != nil {
+		for _, h := range *hooks {
+			cfg.Hooks = append(cfg.Hooks, fmt.Sprintf("%T", h.hook))
+		}
+	}
+	if enablers := logger.enablers.Load(); enablers != nil {
+		for _, e := range *enablers {
+			cfg.Enablers = append(cfg.Enablers, fmt.Sprintf("%T", e))
+		}
+	}
+
+	if fields := logger.globalFields.Load(); fields != nil {
+		list := *fields
+		cfg.GlobalFields = make(map[string]string, len(list)/2)
+		for i := 0; i+1 < len(list); i += 2 {
+			cfg.GlobalFields[fmt.Sprint(list[i])] = fmt.Sprint(list[i+1])
+		}
+	}
+	if funcs := logger.fieldFuncs.Load(); funcs != nil {
+		cfg.FieldFuncs = len(*funcs)
+	}
+	if providers := logger.fieldsProviders.Load(); providers != nil {
+		for _, p := range *providers {
+			cfg.FieldsProviders = append(cfg.FieldsProviders, "ttl="+p.ttl.String())
+		}
+	}
+	return cfg
+}
+
+func newFormatterInfo(f Formatter) *FormatterInfo {
+	fi := &FormatterInfo{
+		Type: fmt.Sprintf("%T", f),
+	}
+	if o, ok := f.(formatterOptions); ok {
+		fi.Options = o.optionNames()
+		if s := o.formatterSettings(); !s.IsZero() {
+			fi.Settings = &s
+		}
+	}
+	return fi
+}
+
+// optionNames returns the names of enabled options
+func (c *config) optionNames() []string {
+	var list []string
+	if c.withCaller {
+		list = append(list, FormatWithCaller.String())
+	}
+	if c.skipTime {
+		list = append(list, FormatSkipTime.String())
+	}
+	if c.skipLevel {
+		list = append(list, FormatSkipLevel.String())
+	}
+	if c.withLocation {
+		list = append(list, FormatWithLocation.String())
+	}
+	if c.color {
+		list = append(list, FormatWithColor.String())
+	}
+	if c.printEmpty {
+		list = append(list, FormatPrintEmpty.String())
+	}
+	if c.redact {
+		list = append(list, FormatWithRedaction.String())
+	}
+	if c.skipPkg {
+		list = append(list, FormatSkipPkg.String())
+	}
+	if c.monotonic {
+		list = append(list, FormatWithMonotonic.String())
+	}
+	if c.errorChain {
+		list = append(list, FormatWithErrorChain.String())
+	}
+	if c.stackTrace {
+		list = append(list, FormatWithStackTrace.String())
+	}
+	if c.goroutineID {
+		list = append(list, FormatWithGoroutineID.String())
+	}
+	if c.orderedKeys {
+		list = append(list, FormatWithOrderedKeys.String())
+	}
+	switch c.duplicates {
+	case lastKeyWins:
+		list = append(list, FormatDuplicateKeysLastWins.String())
+	case firstKeyWins:
+		list = append(list, FormatDuplicateKeysFirstWins.String())
+	case suffixDuplicateKeys:
+		list = append(list, FormatDuplicateKeysSuffix.String())
+	}
+	switch c.pkgPath {
+	case pkgRelativePath:
+		list = append(list, FormatWithPkgPath.String())
+	case pkgFullPath:
+		list = append(list, FormatWithPkgFullPath.String())
+	}
+	return list
+}
+
+// String returns the name of the option
+func (o FormatterOption) String() string {
+	switch o {
+	case FormatWithCaller:
+		return "WithCaller"
+	case FormatNoCaller:
+		return "NoCaller"
+	case FormatSkipTime:
+		return "SkipTime"
+	case FormatSkipLevel:
+		return "SkipLevel"
+	case FormatWithLocation:
+		return "WithLocation"
+	case FormatWithColor:
+		return "WithColor"
+	case FormatPrintEmpty:
+		return "PrintEmpty"
+	case FormatWithRedaction:
+		return "WithRedaction"
+	case FormatSkipPkg:
+		return "SkipPkg"
+	case FormatWithMonotonic:
+		return "WithMonotonic"
+	case FormatWithErrorChain:
+		return "WithErrorChain"
+	case FormatWithStackTrace:
+		return "WithStackTrace"
+	case FormatWithPkgPath:
+		return "WithPkgPath"
+	case FormatWithPkgFullPath:
+		return "WithPkgFullPath"
+	case FormatWithGoroutineID:
+		return "WithGoroutineID"
+	case FormatWithOrderedKeys:
+		return "WithOrderedKeys"
+	case FormatDuplicateKeysLastWins:
+		return "DuplicateKeysLastWins"
+	case FormatDuplicateKeysFirstWins:
+		return "DuplicateKeysFirstWins"
+	case FormatDuplicateKeysSuffix:
+		return "DuplicateKeysSuffix"
+	}
+	return fmt.Sprintf("FormatterOption(%d)", int(o))
+}
@@ -0,0 +1,89 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EffectiveConfig(t *testing.T) {
+	const repo = "github.com/effective-security/xlog/config"
+	pkgA := xlog.NewPackageLogger(repo, "a")
+	xlog.NewPackageLogger(repo, "b")
+	defer func() {
+		xlog.SetRepoFormatter(repo, nil)
+		xlog.ResetHooks()
+	}()
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetRepoFormatter(repo, xlog.NewNilFormatter())
+	pkgA.SetFormatter(xlog.NewPrettyFormatter(&b).Options(xlog.FormatWithLocation, xlog.FormatWithRedaction))
+	xlog.SetRepoLogLevel(repo, xlog.NOTICE)
+	xlog.SetPackageLogLevel(repo, "b", xlog.DEBUG)
+	xlog.AddHook(xlog.HookFunc(func(*xlog.Entry) error { return nil }))
+
+	cfg := xlog.EffectiveConfig()
+	require.NotNil(t, cfg.Formatter)
+	assert.Equal(t, "*xlog.JSONFormatter", cfg.Formatter.Type)
+	assert.Equal(t, []string{"SkipTime"}, cfg.Formatter.Options)
+	assert.Equal(t, &xlog.FormatterInfo{Type: "*xlog.NilFormatter"}, cfg.RepoFormatters[repo])
+	assert.Equal(t, &xlog.FormatterInfo{
+		Type:    "*xlog.PrettyFormatter",
+		Options: []string{"WithCaller", "WithLocation", "WithRedaction"},
+	}, cfg.PackageFormatters[repo+":a"])
+	assert.Equal(t, []string{"xlog.HookFunc"}, cfg.Hooks)
+	assert.Empty(t, cfg.LevelLimit)
+	assert.False(t, cfg.Redaction)
+
+	var levels []xlog.RepoLogLevel
+	for _, l := range cfg.Levels {
+		if l.Repo == repo {
+			levels = append(levels, l)
+		}
+	}
+	assert.Equal(t, []xlog.RepoLogLevel{
+		{Repo: repo, Package: "a", Level: "NOTICE"},
+		{Repo: repo, Package: "b", Level: "DEBUG"},
+	}, levels)
+
+	_, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "NoCaller", xlog.FormatNoCaller.String())
+	assert.Equal(t, "FormatterOption(100)", xlog.FormatterOption(100).String())
+}
+
+func Test_EffectiveConfigFields(t *testing.T) {
+	defer func() {
+		xlog.SetGlobalFields()
+		xlog.ResetFieldFuncs()
+		xlog.ResetGlobalFieldsProviders()
+	}()
+
+	cfg := xlog.EffectiveConfig()
+	assert.Nil(t, cfg.GlobalFields)
+	assert.Zero(t, cfg.FieldFuncs)
+	assert.Nil(t, cfg.FieldsProviders)
+
+	xlog.SetGlobalFields("service", "api", "pid", 42, "apikey", xlog.Secret("key123"))
+	xlog.AddFieldFunc(func(context.Context, xlog.LogLevel, string) (string, any) { return "k", 1 })
+	xlog.AddFieldFunc(func(context.Context, xlog.LogLevel, string) (string, any) { return "", nil })
+	xlog.AddGlobalFieldsProvider(func() []any { return []any{"sha", "abc"} }, 0)
+	xlog.AddGlobalFieldsProvider(func() []any { return []any{"color", "blue"} }, time.Minute)
+
+	cfg = xlog.EffectiveConfig()
+	assert.Equal(t, map[string]string{"service": "api", "pid": "42", "apikey": "*****"}, cfg.GlobalFields)
+	assert.Equal(t, 2, cfg.FieldFuncs)
+	assert.Equal(t, []string{"ttl=0s", "ttl=1m0s"}, cfg.FieldsProviders)
+
+	js, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(js), `"global_fields":{"apikey":"*****","pid":"42","service":"api"},"field_funcs":2,"fields_providers":["ttl=0s","ttl=1m0s"]`)
+}
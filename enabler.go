@@ -0,0 +1,104 @@
+package xlog
+
+import "context"
+
+// Decision describes the entry to be evaluated by enablers
+type Decision struct {
+	// Ctx is the context of the entry, or nil if logged without context
+	Ctx context.Context
+	// Repo of the package logger
+	Repo string
+	// Pkg of the package logger
+	Pkg string
+	// Level of the entry
+	Level LogLevel
+	// PkgLevel is the current level of the package logger
+	PkgLevel LogLevel
+}
+
+// Enabler decides whether the entry should be emitted.
+// Enablers are evaluated in the order they are added,
+// starting with the decision of the package level and the level limit,
+// so each policy can either veto the entry, or enable the entry
+// that is disabled by the package level.
+// Enabler is called for each log call, including disabled ones,
+// so it must be fast and safe for concurrent use.
+type Enabler interface {
+	Enabled(d *Decision, enabled bool) bool
+}
+
+// EnablerFunc is an adapter to use a function as Enabler
+type EnablerFunc func(d *Decision, enabled bool) bool
+
+// Enabled calls f(d, enabled)
+func (f EnablerFunc) Enabled(d *Decision, enabled bool) bool {
+	return f(d, enabled)
+}
+
+// AddEnabler adds the enabler to the decision chain,
+// enablers are evaluated in the order they are added
+func AddEnabler(e Enabler) {
+	logger.Lock()
+	defer logger.Unlock()
+
+	var list []Enabler
+	if enablers := logger.enablers.Load(); enablers != nil {
+		list = append(list, *enablers...)
+	}
+	list = append(list, e)
+	logger.enablers.Store(&list)
+}
+
+// ResetEnablers removes all enablers
+func ResetEnablers() {
+	logger.enablers.Store(nil)
+}
+
+// decide returns true if the entry must be emitted
+func (l *loggerStruct) decide(ctx context.Context, repo, pkg string, pkgLevel, level LogLevel) bool {
+	enabled := l.enabled(pkgLevel, level)
+	enablers := l.enablers.Load()
+	if enablers == nil {
+		return enabled
+	}
+
+	d := &Decision{
+		Ctx:      ctx,
+		Repo:     repo,
+		Pkg:      pkg,
+		Level:    level,
+		PkgLevel: pkgLevel,
+	}
+	for _, e := range *enablers {
+		enabled = e.Enabled(d, enabled)
+	}
+	return enabled
+}
+
+type levelContextKey struct{}
+
+// ContextWithLevel returns context with the log level override,
+// applied to the entries logged with ContextKV when ContextLevelEnabler is added.
+// This allows to enable TRACE logs for a specific request.
+func ContextWithLevel(ctx context.Context, level LogLevel) context.Context {
+	return context.WithValue(ctx, levelContextKey{}, level)
+}
+
+// ContextLevel returns the log level override from the context
+func ContextLevel(ctx context.Context) (LogLevel, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(levelContextKey{}).(LogLevel)
+	return level, ok
+}
+
+// ContextLevelEnabler is the Enabler that applies the log level override
+// from the context, set by ContextWithLevel.
+// The override does not bypass the level limit set by Watchdog.
+var ContextLevelEnabler Enabler = EnablerFunc(func(d *Decision, enabled bool) bool {
+	if level, ok := ContextLevel(d.Ctx); ok {
+		return d.Level == CRITICAL || (level >= d.Level && logger.withinLimit(d.Level))
+	}
+	return enabled
+})
@@ -0,0 +1,59 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Enablers(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetEnablers()
+
+	var decisions []xlog.Decision
+	xlog.AddEnabler(xlog.EnablerFunc(func(d *xlog.Decision, enabled bool) bool {
+		decisions = append(decisions, *d)
+		return enabled
+	}))
+	xlog.AddEnabler(xlog.ContextLevelEnabler)
+	xlog.AddEnabler(xlog.EnablerFunc(func(d *xlog.Decision, enabled bool) bool {
+		return enabled && d.Level != xlog.NOTICE
+	}))
+
+	ctx := context.Background()
+	dctx := xlog.ContextWithLevel(ctx, xlog.TRACE)
+
+	logger.KV(xlog.DEBUG, "msg", "disabled")
+	logger.ContextKV(ctx, xlog.DEBUG, "msg", "disabled")
+	logger.ContextKV(dctx, xlog.TRACE, "msg", "enabled")
+	logger.ContextKV(dctx, xlog.DEBUG, "msg", "disabled")
+	logger.KV(xlog.NOTICE, "msg", "vetoed")
+	logger.KV(xlog.INFO, "msg", "info")
+	assert.Equal(t, "level=T pkg=xlog_test msg=\"enabled\"\nlevel=I pkg=xlog_test msg=\"info\"\n", b.String())
+
+	assert.Len(t, decisions, 6)
+	assert.Nil(t, decisions[0].Ctx)
+	assert.Equal(t, dctx, decisions[2].Ctx)
+	assert.Equal(t, "github.com/effective-security/xlog", decisions[2].Repo)
+	assert.Equal(t, "xlog_test", decisions[2].Pkg)
+	assert.Equal(t, xlog.TRACE, decisions[2].Level)
+	assert.Equal(t, xlog.INFO, decisions[2].PkgLevel)
+
+	assert.Equal(t, []string{"xlog.EnablerFunc", "xlog.EnablerFunc", "xlog.EnablerFunc"}, xlog.EffectiveConfig().Enablers)
+
+	level, ok := xlog.ContextLevel(dctx)
+	assert.True(t, ok)
+	assert.Equal(t, xlog.TRACE, level)
+	_, ok = xlog.ContextLevel(ctx)
+	assert.False(t, ok)
+
+	xlog.ResetEnablers()
+	b.Reset()
+	logger.ContextKV(dctx, xlog.TRACE, "msg", "disabled")
+	assert.Empty(t, b.String())
+}
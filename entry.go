@@ -0,0 +1,45 @@
+package xlog
+
+import "time"
+
+// Entry represents a structured log entry
+type Entry struct {
+	// Time of the entry
+	Time time.Time `json:"time,omitempty"`
+	// Monotonic is the monotonic clock reading since the process start,
+	// if logged with FormatWithMonotonic option
+	Monotonic time.Duration `json:"mono,omitempty"`
+	// Level of the entry
+	Level LogLevel `json:"level"`
+	// Pkg is the package name
+	Pkg string `json:"pkg,omitempty"`
+	// Caller is the caller function name
+	Caller string `json:"func,omitempty"`
+	// Source is the caller location, in "file:line" format
+	Source string `json:"src,omitempty"`
+	// Message of the entry, if any
+	Message string `json:"msg,omitempty"`
+	// Fields are key-value pairs of the entry,
+	// in "key1, value1, ..., keyN, valueN" format
+	Fields []any `json:"fields,omitempty"`
+	// Tags of the entry
+	Tags Tags `json:"tags,omitempty"`
+}
+
+// Field returns the value of the field by key
+func (e *Entry) Field(key string) (any, bool) {
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if k, ok := e.Fields[i].(string); ok && k == key {
+			return e.Fields[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// values returns the fields, including tags
+func (e *Entry) values() []any {
+	if len(e.Tags) == 0 {
+		return e.Fields
+	}
+	return append(append([]any{}, e.Fields...), KeyTags, e.Tags)
+}
@@ -0,0 +1,180 @@
+package xlog
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// EntrySizeBuckets specifies upper bounds of the entry size histogram buckets,
+// in bytes. The buckets are exponential, from 64 bytes to 1MB,
+// the last bucket of EntrySizeStats counts the entries above 1MB.
+var EntrySizeBuckets = func() []int {
+	var list []int
+	for i := minSizeBucketBits; i <= maxSizeBucketBits; i++ {
+		list = append(list, 1<<i)
+	}
+	return list
+}()
+
+const (
+	minSizeBucketBits = 6
+	maxSizeBucketBits = 20
+	sizeBucketsCount  = maxSizeBucketBits - minSizeBucketBits + 2
+)
+
+// EntrySizeStats provides the distribution of formatted entry sizes for a package
+type EntrySizeStats struct {
+	// Count is the number of observed entries
+	Count uint64 `json:"count"`
+	// Sum is the total size of observed entries, in bytes
+	Sum uint64 `json:"sum"`
+	// Buckets contains the number of entries per bucket, not cumulative,
+	// with upper bounds defined by EntrySizeBuckets.
+	// The last bucket counts the entries above the largest bound.
+	Buckets []uint64 `json:"buckets"`
+}
+
+type sizeHistogram struct {
+	count   atomic.Uint64
+	sum     atomic.Uint64
+	buckets [sizeBucketsCount]atomic.Uint64
+}
+
+func (h *sizeHistogram) observe(size int) {
+	h.count.Add(1)
+	h.sum.Add(uint64(size))
+	h.buckets[sizeBucket(size)].Add(1)
+}
+
+func (h *sizeHistogram) stats() EntrySizeStats {
+	s := EntrySizeStats{
+		Count:   h.count.Load(),
+		Sum:     h.sum.Load(),
+		Buckets: make([]uint64, sizeBucketsCount),
+	}
+	for i := range h.buckets {
+		s.Buckets[i] = h.buckets[i].Load()
+	}
+	return s
+}
+
+// sizeBucket returns the index of the bucket for the size
+func sizeBucket(size int) int {
+	if size <= 1<<minSizeBucketBits {
+		return 0
+	}
+	// number of bits to represent size-1 is the power of 2 upper bound
+	idx := bits.Len(uint(size-1)) - minSizeBucketBits
+	if idx > sizeBucketsCount-1 {
+		idx = sizeBucketsCount - 1
+	}
+	return idx
+}
+
+var (
+	entrySizesEnabled atomic.Bool
+	entrySizes        sync.Map // pkg => *sizeHistogram
+)
+
+// SetEntrySizeMetrics enables or disables tracking of formatted entry sizes per package.
+// This is useful to find which packages dominate log volume.
+func SetEntrySizeMetrics(enabled bool) {
+	entrySizesEnabled.Store(enabled)
+}
+
+// ObserveEntrySize records the size of a formatted entry for the package,
+// if entry size metrics are enabled.
+// Custom formatters should call it after writing an entry.
+func ObserveEntrySize(pkg string, size int) {
+	if !entrySizesEnabled.Load() {
+		return
+	}
+	v, ok := entrySizes.Load(pkg)
+	if !ok {
+		v, _ = entrySizes.LoadOrStore(pkg, new(sizeHistogram))
+	}
+	v.(*sizeHistogram).observe(size)
+}
+
+// EntrySizes returns the distribution of formatted entry sizes per package
+func EntrySizes() map[string]EntrySizeStats {
+	m := make(map[string]EntrySizeStats)
+	entrySizes.Range(func(k, v any) bool {
+		m[k.(string)] = v.(*sizeHistogram).stats()
+		return true
+	})
+	return m
+}
+
+// ResetEntrySizes resets collected entry sizes
+func ResetEntrySizes() {
+	entrySizes.Range(func(k, _ any) bool {
+		entrySizes.Delete(k)
+		return true
+	})
+}
+
+// sizeWriter counts bytes written to the underlying writer
+type sizeWriter struct {
+	w io.Writer
+	// lw is set if w is LevelWriter
+	lw LevelWriter
+	// level is the level of the current entry
+	level LogLevel
+	n     int
+	// start is the position of the current entry
+	start int
+	// deferFlush is set while a batch is formatted,
+	// so the entries are flushed once at the end of the batch
+	deferFlush bool
+}
+
+// begin marks the start of the entry with the level written to bw
+func (s *sizeWriter) begin(bw *bufio.Writer, level LogLevel) {
+	if s.lw != nil && level != s.level {
+		// the deferred entries are written with their level
+		_ = bw.Flush()
+		s.level = level
+	}
+	s.start = s.n + bw.Buffered()
+}
+
+// end flushes the entry written to bw, unless the flush is deferred,
+// and returns the size of the entry
+func (s *sizeWriter) end(bw *bufio.Writer) int {
+	size := s.n + bw.Buffered() - s.start
+	if !s.deferFlush {
+		_ = bw.Flush()
+		s.Flush()
+	}
+	return size
+}
+
+func (s *sizeWriter) Write(b []byte) (int, error) {
+	var n int
+	var err error
+	if s.lw != nil {
+		n, err = s.lw.WriteLevel(s.level, b)
+	} else {
+		n, err = s.w.Write(b)
+	}
+	s.n += n
+	return n, err
+}
+
+// Flush flushes the underlying writer, if it's buffered
+func (s *sizeWriter) Flush() {
+	if bw, ok := s.w.(*bufio.Writer); ok {
+		_ = bw.Flush()
+	}
+}
+
+// newSizeWriter returns buffered writer, that counts bytes written to w
+func newSizeWriter(w io.Writer) (*bufio.Writer, *sizeWriter) {
+	sw := &sizeWriter{w: w}
+	sw.lw, _ = w.(LevelWriter)
+	return bufio.NewWriter(sw), sw
+}
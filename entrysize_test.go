@@ -0,0 +1,46 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EntrySizes(t *testing.T) {
+	xlog.SetEntrySizeMetrics(true)
+	defer func() {
+		xlog.SetEntrySizeMetrics(false)
+		xlog.ResetEntrySizes()
+	}()
+	xlog.ResetEntrySizes()
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	small := b.Len()
+	b.Reset()
+	logger.KV(xlog.INFO, "k", strings.Repeat("a", 200))
+	large := b.Len()
+
+	stats := xlog.EntrySizes()
+	require.Contains(t, stats, "xlog_test")
+	s := stats["xlog_test"]
+	assert.Equal(t, uint64(2), s.Count)
+	assert.Equal(t, uint64(small+large), s.Sum)
+	require.Len(t, s.Buckets, len(xlog.EntrySizeBuckets)+1)
+	assert.Equal(t, uint64(1), s.Buckets[0])
+	assert.Equal(t, uint64(1), s.Buckets[2])
+
+	xlog.ObserveEntrySize("custom", 2<<20)
+	assert.Equal(t, uint64(1), xlog.EntrySizes()["custom"].Buckets[len(xlog.EntrySizeBuckets)])
+
+	xlog.SetEntrySizeMetrics(false)
+	xlog.ObserveEntrySize("custom", 10)
+	assert.Equal(t, uint64(1), xlog.EntrySizes()["custom"].Count)
+}
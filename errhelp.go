@@ -0,0 +1,125 @@
+package xlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KeyErrHelp is the key of the documentation URL for the logged error
+const KeyErrHelp = "err_help"
+
+// ErrorCoder is implemented by errors with a catalog code
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// RegisterErrorHelp maps the error code or fingerprint to the documentation URL,
+// such as a runbook. ERROR and CRITICAL entries with the matching error
+// have "err_help" field with the URL.
+// The code is returned by ErrorCoder in the error chain,
+// the fingerprint is returned by ErrorFingerprint.
+func RegisterErrorHelp(codeOrFingerprint, url string) {
+	logger.Lock()
+	defer logger.Unlock()
+
+	catalog := map[string]string{}
+	if current := logger.errHelp.Load(); current != nil {
+		for k, v := range *current {
+			catalog[k] = v
+		}
+	}
+	catalog[codeOrFingerprint] = url
+	logger.errHelp.Store(&catalog)
+}
+
+// ResetErrorHelp removes all documentation URLs
+func ResetErrorHelp() {
+	logger.errHelp.Store(nil)
+}
+
+// ErrorHelp returns the documentation URL for the error,
+// or empty string if the error is not in the catalog.
+// For multi-errors, the URL of the first matching error is returned.
+func ErrorHelp(err error) string {
+	catalog := logger.errHelp.Load()
+	if catalog == nil || err == nil {
+		return ""
+	}
+	return errorHelp(*catalog, err)
+}
+
+func errorHelp(catalog map[string]string, err error) string {
+	var coder ErrorCoder
+	if errors.As(err, &coder) {
+		if url, ok := catalog[coder.ErrorCode()]; ok {
+			return url
+		}
+	}
+	if list := Errors(err); len(list) > 0 {
+		for _, e := range list {
+			if e != nil {
+				if url := errorHelp(catalog, e); url != "" {
+					return url
+				}
+			}
+		}
+		return ""
+	}
+	return catalog[ErrorFingerprint(err)]
+}
+
+// ErrorFingerprint returns the identity of the error,
+// that does not change with the wrapping messages and numbers in the message:
+// the hash of the type and the message of the root cause
+func ErrorFingerprint(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+
+	// replace numbers, such as IDs and durations
+	var sb strings.Builder
+	digits := false
+	for _, r := range err.Error() {
+		isDigit := r >= '0' && r <= '9'
+		if !isDigit {
+			_, _ = sb.WriteRune(r)
+		} else if !digits {
+			_ = sb.WriteByte('#')
+		}
+		digits = isDigit
+	}
+
+	h := sha256.Sum256([]byte(fmt.Sprintf("%T:%s", err, sb.String())))
+	return hex.EncodeToString(h[:6])
+}
+
+// errorHelpFor returns the documentation URL for the first error in the entries,
+// if the level is ERROR or more severe
+func (l *loggerStruct) errorHelpFor(level LogLevel, entries []any) string {
+	if level > ERROR {
+		return ""
+	}
+	catalog := l.errHelp.Load()
+	if catalog == nil {
+		return ""
+	}
+	for _, e := range entries {
+		if f, ok := e.(Field); ok {
+			e = f.any
+		}
+		if err, ok := e.(error); ok && err != nil {
+			if url := errorHelp(*catalog, err); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
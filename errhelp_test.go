@@ -0,0 +1,60 @@
+package xlog_test
+
+import (
+	"bytes"
+	goerrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type codedError struct {
+	code string
+}
+
+func (e *codedError) Error() string     { return "coded error " + e.code }
+func (e *codedError) ErrorCode() string { return e.code }
+
+func Test_ErrorHelp(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetErrorHelp()
+
+	timeout := goerrors.New("timeout after 5s")
+	xlog.RegisterErrorHelp("DB001", "https://runbooks/db001")
+	xlog.RegisterErrorHelp(xlog.ErrorFingerprint(timeout), "https://runbooks/timeout")
+
+	// the fingerprint does not depend on wrapping and numbers
+	wrapped := errors.WithMessage(fmt.Errorf("call: %w", goerrors.New("timeout after 30s")), "failed")
+	assert.Equal(t, xlog.ErrorFingerprint(timeout), xlog.ErrorFingerprint(wrapped))
+	assert.NotEqual(t, xlog.ErrorFingerprint(timeout), xlog.ErrorFingerprint(goerrors.New("refused")))
+
+	coded := errors.WithStack(&codedError{code: "DB001"})
+	assert.Equal(t, "https://runbooks/db001", xlog.ErrorHelp(coded))
+	assert.Equal(t, "https://runbooks/timeout", xlog.ErrorHelp(wrapped))
+	assert.Equal(t, "https://runbooks/timeout", xlog.ErrorHelp(goerrors.Join(goerrors.New("other"), wrapped)))
+	assert.Empty(t, xlog.ErrorHelp(goerrors.New("other")))
+	assert.Empty(t, xlog.ErrorHelp(nil))
+
+	logger.KV(xlog.ERROR, "err", &codedError{code: "DB001"})
+	logger.Errorf("failed: %v", timeout)
+	logger.Error("failed:", timeout)
+	// not added for other levels and errors
+	logger.KV(xlog.WARNING, "err", &codedError{code: "DB001"})
+	logger.KV(xlog.ERROR, "err", goerrors.New("other"))
+
+	assert.Equal(t,
+		"level=E pkg=xlog_test err=\"coded error DB001\" err_help=\"https://runbooks/db001\"\n"+
+			"level=E pkg=xlog_test \"err_help=\\\"https://runbooks/timeout\\\"\" [\"failed: timeout after 5s\"]\n"+
+			"level=E pkg=xlog_test \"err_help=\\\"https://runbooks/timeout\\\"\" \"failed:\" \"timeout after 5s\"\n"+
+			"level=W pkg=xlog_test err=\"coded error DB001\"\n"+
+			"level=E pkg=xlog_test err=\"other\"\n",
+		b.String())
+
+	xlog.ResetErrorHelp()
+	assert.Empty(t, xlog.ErrorHelp(coded))
+}
@@ -0,0 +1,116 @@
+package xlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorFormat specifies how the errors are logged
+type ErrorFormat int32
+
+const (
+	// ErrorFormatFull logs the errors in %+v format,
+	// with the stack trace if the error has it
+	ErrorFormatFull ErrorFormat = iota
+	// ErrorFormatMessage logs the errors with Error() message only
+	ErrorFormatMessage
+)
+
+// String returns the name of the format
+func (f ErrorFormat) String() string {
+	switch f {
+	case ErrorFormatFull:
+		return "full"
+	case ErrorFormatMessage:
+		return "message"
+	}
+	return fmt.Sprintf("ErrorFormat(%d)", int32(f))
+}
+
+// SetErrorFormat sets how the errors are logged by all formatters,
+// ErrorFormatFull by default
+func SetErrorFormat(f ErrorFormat) {
+	logger.errorFormat.Store(int32(f))
+}
+
+// GetErrorFormat returns how the errors are logged
+func GetErrorFormat() ErrorFormat {
+	return ErrorFormat(logger.errorFormat.Load())
+}
+
+// Suffixes of the fields of the expanded errors chain
+const (
+	KeyErrorMessage = ".message"
+	KeyErrorCause   = ".cause"
+	KeyErrorStack   = ".stack"
+)
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// ErrorChainFields returns the fields of the wrapped errors chain,
+// logged by the formatters with FormatWithErrorChain option:
+// "key.message" with the error message, "key.cause" with the message of the root cause,
+// and "key.stack" with the stack trace of the root cause, if the error has it,
+// and the errors are logged with ErrorFormatFull.
+// Multi-errors are returned as a single field with the list of values.
+func ErrorChainFields(key string, err error) []any {
+	if err == nil {
+		return nil
+	}
+	if len(Errors(err)) > 0 {
+		return []any{key, ErrorValue(err)}
+	}
+
+	fields := []any{key + KeyErrorMessage, err.Error()}
+
+	cause := err
+	wrapped := false
+	var st stackTracer
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		cause, wrapped = e, true
+		if s, ok := e.(stackTracer); ok {
+			st = s
+		}
+	}
+	if st == nil {
+		st, _ = err.(stackTracer)
+	}
+	if wrapped {
+		fields = append(fields, key+KeyErrorCause, cause.Error())
+	}
+	if st != nil && GetErrorFormat() == ErrorFormatFull {
+		fields = append(fields, key+KeyErrorStack, strings.TrimSpace(fmt.Sprintf("%+v", st.StackTrace())))
+	}
+	return fields
+}
+
+// ExpandErrorChains returns the key-value pairs with the error values
+// expanded by ErrorChainFields, or the same list if there are no errors
+func ExpandErrorChains(kvList []any) []any {
+	for i := 1; i < len(kvList); i += 2 {
+		if _, ok := kvList[i].(error); !ok {
+			continue
+		}
+		list := make([]any, 0, len(kvList)+4)
+		list = append(list, kvList[:i-1]...)
+		for j := i - 1; j < len(kvList); j += 2 {
+			if j+1 < len(kvList) {
+				if err, ok := kvList[j+1].(error); ok {
+					if k, ok := kvList[j].(string); ok {
+						list = append(list, ErrorChainFields(k, err)...)
+						continue
+					}
+				}
+				list = append(list, kvList[j], kvList[j+1])
+			} else {
+				list = append(list, kvList[j])
+			}
+		}
+		return list
+	}
+	return kvList
+}
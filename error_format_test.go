@@ -0,0 +1,71 @@
+package xlog_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ErrorFormat(t *testing.T) {
+	defer xlog.SetErrorFormat(xlog.ErrorFormatFull)
+
+	err := errors.New("failed")
+	assert.Equal(t, xlog.ErrorFormatFull, xlog.GetErrorFormat())
+	assert.True(t, strings.HasPrefix(xlog.EscapedString(err), `"failed\n`))
+
+	xlog.SetErrorFormat(xlog.ErrorFormatMessage)
+	assert.Equal(t, xlog.ErrorFormatMessage, xlog.GetErrorFormat())
+	assert.Equal(t, `"failed"`, xlog.EscapedString(err))
+	assert.Equal(t, `["failed","other"]`, xlog.EscapedString(stderrors.Join(err, errors.New("other"))))
+
+	assert.Equal(t, "full", xlog.ErrorFormatFull.String())
+	assert.Equal(t, "message", xlog.ErrorFormatMessage.String())
+	assert.Equal(t, "ErrorFormat(5)", xlog.ErrorFormat(5).String())
+}
+
+func Test_ErrorChainFields(t *testing.T) {
+	defer xlog.SetErrorFormat(xlog.ErrorFormatFull)
+
+	assert.Nil(t, xlog.ErrorChainFields("err", nil))
+	assert.Equal(t, []any{"err.message", "plain"}, xlog.ErrorChainFields("err", stderrors.New("plain")))
+
+	wrapped := fmt.Errorf("query: %w", stderrors.New("refused"))
+	assert.Equal(t, []any{"err.message", "query: refused", "err.cause", "refused"}, xlog.ErrorChainFields("err", wrapped))
+
+	joined := stderrors.Join(stderrors.New("e1"), stderrors.New("e2"))
+	assert.Equal(t, []any{"err", []any{"e1", "e2"}}, xlog.ErrorChainFields("err", joined))
+
+	withStack := errors.Wrap(errors.New("refused"), "query")
+	fields := xlog.ErrorChainFields("error", withStack)
+	require.Len(t, fields, 6)
+	assert.Equal(t, []any{"error.message", "query: refused", "error.cause", "refused", "error.stack"}, fields[:5])
+	assert.Contains(t, fields[5], "Test_ErrorChainFields")
+
+	xlog.SetErrorFormat(xlog.ErrorFormatMessage)
+	assert.Len(t, xlog.ErrorChainFields("error", withStack), 4)
+}
+
+func Test_FormatWithErrorChain(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller, xlog.FormatWithErrorChain))
+
+	err := fmt.Errorf("query: %w", stderrors.New("refused"))
+	logger.KV(xlog.WARNING, "k", 1, "err", err)
+	assert.Equal(t, `{"err.cause":"refused","err.message":"query: refused","k":1,"level":"W","pkg":"xlog_test"}`+"\n", b.String())
+
+	b.Reset()
+	logger.KV(xlog.WARNING, "k", 1)
+	assert.Equal(t, `{"k":1,"level":"W","pkg":"xlog_test"}`+"\n", b.String())
+
+	o, perr := xlog.ParseFormatterOption("WithErrorChain")
+	require.NoError(t, perr)
+	assert.Equal(t, xlog.FormatWithErrorChain, o)
+}
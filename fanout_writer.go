@@ -0,0 +1,183 @@
+package xlog
+
+import (
+	"io"
+	"sync"
+)
+
+// FanoutConfig specifies configuration for FanoutWriter
+type FanoutConfig struct {
+	// Size specifies the maximum number of buffered bytes,
+	// not yet written to the slowest destination, 1MB by default.
+	// The writer blocks when the buffer is full.
+	Size int
+}
+
+// FanoutWriter is an io.Writer, that writes the same formatted entries
+// to several destinations in background goroutines.
+// The written bytes are copied once to a shared append-only buffer,
+// and each destination writes the buffer from its own offset,
+// instead of copying the bytes per destination.
+type FanoutWriter struct {
+	cfg   FanoutConfig
+	sinks []*fanoutSink
+
+	lock    sync.Mutex
+	changed *sync.Cond
+	// buf holds the bytes from base to end offsets,
+	// the bytes are never modified once written,
+	// so the destinations write them outside of the lock
+	buf     []byte
+	base    int64
+	end     int64
+	closed  bool
+	stopped sync.WaitGroup
+}
+
+type fanoutSink struct {
+	dest   io.Writer
+	offset int64
+	busy   bool
+}
+
+// NewFanoutWriter returns an instance of FanoutWriter,
+// the caller must call Close to drain the buffer and stop the background goroutines.
+func NewFanoutWriter(cfg FanoutConfig, dests ...io.Writer) *FanoutWriter {
+	if cfg.Size <= 0 {
+		cfg.Size = 1024 * 1024
+	}
+	w := &FanoutWriter{
+		cfg: cfg,
+	}
+	w.changed = sync.NewCond(&w.lock)
+	for _, dest := range dests {
+		s := &fanoutSink{dest: dest}
+		w.sinks = append(w.sinks, s)
+		w.stopped.Add(1)
+		done := TrackGoroutine("xlog.FanoutWriter")
+		go w.run(s, done)
+	}
+	return w
+}
+
+// Write implements io.Writer
+func (w *FanoutWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		// the background goroutines are stopped, write directly
+		for _, s := range w.sinks {
+			_, _ = s.dest.Write(b)
+		}
+		return len(b), nil
+	}
+
+	// a write larger than the buffer waits for the buffer to be drained
+	for w.end > w.base && int(w.end-w.base)+len(b) > w.cfg.Size {
+		w.changed.Wait()
+	}
+	w.buf = append(w.buf, b...)
+	w.end += int64(len(b))
+	w.changed.Broadcast()
+	return len(b), nil
+}
+
+// Flush blocks until the buffered bytes are written to all destinations,
+// and flushes the destinations that have Flush() error method
+func (w *FanoutWriter) Flush() {
+	w.lock.Lock()
+	for !w.drained() {
+		w.changed.Wait()
+	}
+	w.lock.Unlock()
+
+	for _, s := range w.sinks {
+		if f, ok := s.dest.(flushable); ok {
+			_ = f.Flush()
+		}
+	}
+}
+
+// Close drains the buffer, and stops the background goroutines.
+// The subsequent writes are written directly to the destinations.
+func (w *FanoutWriter) Close() error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		w.changed.Broadcast()
+	}
+	w.lock.Unlock()
+
+	w.stopped.Wait()
+	return nil
+}
+
+// Buffered returns the number of bytes not yet written to the slowest destination
+func (w *FanoutWriter) Buffered() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return int(w.end - w.base)
+}
+
+// drained returns true if all destinations wrote the buffer,
+// the caller must hold the lock
+func (w *FanoutWriter) drained() bool {
+	for _, s := range w.sinks {
+		if s.offset < w.end || s.busy {
+			return false
+		}
+	}
+	return true
+}
+
+// release discards the bytes written to all destinations,
+// the caller must hold the lock
+func (w *FanoutWriter) release() {
+	min := w.end
+	for _, s := range w.sinks {
+		if s.offset < min {
+			min = s.offset
+		}
+	}
+	if min == w.base {
+		return
+	}
+	if min == w.end {
+		// the buffer may still be written by a destination outside of the lock,
+		// so it is not reused
+		w.buf = nil
+	} else {
+		w.buf = w.buf[min-w.base:]
+	}
+	w.base = min
+}
+
+func (w *FanoutWriter) run(s *fanoutSink, done func()) {
+	defer func() {
+		done()
+		w.stopped.Done()
+	}()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for {
+		for s.offset == w.end && !w.closed {
+			w.changed.Wait()
+		}
+		if s.offset == w.end {
+			// closed and drained
+			return
+		}
+
+		b := w.buf[s.offset-w.base : w.end-w.base : w.end-w.base]
+		s.busy = true
+		w.lock.Unlock()
+		_, _ = s.dest.Write(b)
+		w.lock.Lock()
+		s.busy = false
+		s.offset += int64(len(b))
+		w.release()
+		w.changed.Broadcast()
+	}
+}
@@ -0,0 +1,131 @@
+package xlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is bytes.Buffer safe for concurrent use
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *lockedBuffer) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *lockedBuffer) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func Test_FanoutWriter(t *testing.T) {
+	var b1 bytes.Buffer
+	dest1 := bufio.NewWriter(&b1)
+	dest2 := &lockedBuffer{}
+	w := xlog.NewFanoutWriter(xlog.FanoutConfig{}, dest1, dest2)
+
+	xlog.SetFormatter(xlog.NewStringFormatter(w).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	w.Flush()
+
+	expected := "level=I pkg=xlog_test k=1\nlevel=I pkg=xlog_test k=2\n"
+	assert.Equal(t, expected, b1.String())
+	assert.Equal(t, expected, dest2.String())
+	assert.Equal(t, 0, w.Buffered())
+	assert.Equal(t, 2, xlog.RunningGoroutines()["xlog.FanoutWriter"])
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	assert.NoError(t, xlog.CheckGoroutines())
+
+	logger.KV(xlog.INFO, "k", 3)
+	w.Flush()
+	assert.Equal(t, expected+"level=I pkg=xlog_test k=3\n", b1.String())
+	assert.Equal(t, expected+"level=I pkg=xlog_test k=3\n", dest2.String())
+}
+
+func Test_FanoutWriterSlowSink(t *testing.T) {
+	slow := newBlockingWriter()
+	fast := &lockedBuffer{}
+	w := xlog.NewFanoutWriter(xlog.FanoutConfig{Size: 10}, slow, fast)
+
+	_, err := w.Write([]byte("12345"))
+	require.NoError(t, err)
+	<-slow.started
+
+	// the fast sink is not blocked by the slow one,
+	// while the bytes are retained for the slow sink
+	assert.Eventually(t, func() bool { return fast.String() == "12345" }, time.Second, time.Millisecond)
+	assert.Equal(t, 5, w.Buffered())
+
+	_, err = w.Write([]byte("67890"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, w.Buffered())
+
+	// the buffer is full, the write blocks until the slow sink is released
+	written := make(chan struct{})
+	go func() {
+		_, _ = w.Write([]byte("abc"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("write must block when the buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(slow.release)
+	<-written
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "1234567890abc", slow.String())
+	assert.Equal(t, "1234567890abc", fast.String())
+	assert.Equal(t, 0, w.Buffered())
+}
+
+func Test_FanoutWriterConcurrent(t *testing.T) {
+	dests := make([]*lockedBuffer, 8)
+	writers := make([]io.Writer, len(dests))
+	for i := range dests {
+		dests[i] = &lockedBuffer{}
+		writers[i] = dests[i]
+	}
+	w := xlog.NewFanoutWriter(xlog.FanoutConfig{Size: 256}, writers...)
+
+	line := strings.Repeat("x", 31) + "\n"
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_, _ = w.Write([]byte(line))
+			}
+		}()
+	}
+	wg.Wait()
+	w.Flush()
+
+	expected := strings.Repeat(line, 400)
+	for _, d := range dests {
+		assert.Equal(t, expected, d.String())
+	}
+	require.NoError(t, w.Close())
+}
@@ -0,0 +1,166 @@
+package xlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FieldFunc computes a field of the entry, such as memory usage,
+// active request count or shard ID.
+// The ctx is context.Background for entries logged without context.
+// If the returned key is empty, the field is not added.
+type FieldFunc func(ctx context.Context, level LogLevel, pkg string) (key string, value any)
+
+// AddFieldFunc registers the function to compute a field for each entry.
+// The functions are evaluated in the order they are added,
+// only for entries that will be emitted, after the level and enablers check.
+func AddFieldFunc(fn FieldFunc) {
+	logger.Lock()
+	defer logger.Unlock()
+
+	var list []FieldFunc
+	if funcs := logger.fieldFuncs.Load(); funcs != nil {
+		list = append(list, *funcs...)
+	}
+	list = append(list, fn)
+	logger.fieldFuncs.Store(&list)
+}
+
+// SetGlobalFields sets the static key-value pairs, such as host, pid or service name,
+// added to every entry of all loggers and formatters.
+// It is intended to be called once at startup, the call replaces the previous fields,
+// and the call without arguments removes them.
+func SetGlobalFields(keysAndValues ...any) {
+	if len(keysAndValues) == 0 {
+		logger.globalFields.Store(nil)
+		return
+	}
+	list := appendKV(make([]any, 0, len(keysAndValues)), "", keysAndValues)
+	list = list[:len(list):len(list)]
+	logger.globalFields.Store(&list)
+}
+
+// GlobalFields returns the fields set by SetGlobalFields
+func GlobalFields() []any {
+	if fields := logger.globalFields.Load(); fields != nil {
+		return append([]any{}, *fields...)
+	}
+	return nil
+}
+
+// ResetFieldFuncs removes all field functions
+func ResetFieldFuncs() {
+	logger.fieldFuncs.Store(nil)
+}
+
+// FieldsProvider returns the key-value pairs of the dynamic global fields,
+// such as build SHA, deployment color or feature flags snapshot
+type FieldsProvider func() []any
+
+// fieldsProvider caches the fields returned by the provider
+type fieldsProvider struct {
+	fn  FieldsProvider
+	ttl time.Duration
+	// refresh is held by the goroutine evaluating the provider,
+	// the other goroutines use the cached fields meanwhile
+	refresh sync.Mutex
+	cached  atomic.Pointer[cachedFields]
+}
+
+type cachedFields struct {
+	fields []any
+	// expires is the MonotonicNowFn reading, when the fields must be refreshed
+	expires time.Duration
+}
+
+// AddGlobalFieldsProvider registers the provider of the global fields added to every entry,
+// after the fields set by SetGlobalFields.
+// With zero ttl the provider is evaluated for each emitted entry,
+// otherwise the returned fields are cached and refreshed when the ttl expires,
+// while the refresh is in progress the other entries are logged with the previous fields.
+func AddGlobalFieldsProvider(fn FieldsProvider, ttl time.Duration) {
+	logger.Lock()
+	defer logger.Unlock()
+
+	var list []*fieldsProvider
+	if providers := logger.fieldsProviders.Load(); providers != nil {
+		list = append(list, *providers...)
+	}
+	list = append(list, &fieldsProvider{fn: fn, ttl: ttl})
+	logger.fieldsProviders.Store(&list)
+}
+
+// ResetGlobalFieldsProviders removes all global fields providers
+func ResetGlobalFieldsProviders() {
+	logger.fieldsProviders.Store(nil)
+}
+
+// fields returns the fields of the provider, evaluated or cached
+func (p *fieldsProvider) fields() []any {
+	if p.ttl <= 0 {
+		return p.evaluate()
+	}
+	c := p.cached.Load()
+	if c != nil && MonotonicNowFn() < c.expires {
+		return c.fields
+	}
+	if c != nil && !p.refresh.TryLock() {
+		return c.fields
+	}
+	if c == nil {
+		p.refresh.Lock()
+	}
+	defer p.refresh.Unlock()
+	// the fields may be refreshed while waiting for the lock
+	if c = p.cached.Load(); c != nil && MonotonicNowFn() < c.expires {
+		return c.fields
+	}
+	fields := p.evaluate()
+	p.cached.Store(&cachedFields{
+		fields:  fields,
+		expires: MonotonicNowFn() + p.ttl,
+	})
+	return fields
+}
+
+// evaluate calls the provider, and expands the typed fields
+func (p *fieldsProvider) evaluate() []any {
+	kv := p.fn()
+	if hasFields(kv) {
+		kv = appendKV(make([]any, 0, len(kv)), "", kv)
+	}
+	return kv[:len(kv):len(kv)]
+}
+
+// computeFields returns the global and computed fields for the entry
+func (l *loggerStruct) computeFields(ctx context.Context, level LogLevel, pkg string) []any {
+	var global []any
+	if fields := l.globalFields.Load(); fields != nil {
+		global = *fields
+	}
+	providers := l.fieldsProviders.Load()
+	funcs := l.fieldFuncs.Load()
+	if funcs == nil && providers == nil {
+		return global
+	}
+
+	list := append([]any{}, global...)
+	if providers != nil {
+		for _, p := range *providers {
+			list = append(list, p.fields()...)
+		}
+	}
+	if funcs != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		for _, fn := range *funcs {
+			if k, v := fn(ctx, level, pkg); k != "" {
+				list = append(list, k, v)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,146 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type shardKey struct{}
+
+func Test_FieldFuncs(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetFieldFuncs()
+
+	calls := 0
+	xlog.AddFieldFunc(func(ctx context.Context, level xlog.LogLevel, pkg string) (string, any) {
+		calls++
+		if v := ctx.Value(shardKey{}); v != nil {
+			return "shard", v
+		}
+		return "", nil
+	})
+	xlog.AddFieldFunc(func(_ context.Context, level xlog.LogLevel, pkg string) (string, any) {
+		return "active", pkg + ":" + level.Char()
+	})
+
+	ctx := context.WithValue(context.Background(), shardKey{}, 7)
+	logger.WithValues("v", 1).(xlog.ExtendedKeyValueLogger).WithTags("t1").ContextKV(ctx, xlog.INFO, "k", 2)
+	logger.KV(xlog.WARNING, "k", 3)
+	logger.Infof("formatted %d", 4)
+	logger.KV(xlog.DEBUG, "k", 5)
+
+	assert.Equal(t,
+		"level=I pkg=xlog_test v=1 shard=7 active=\"xlog_test:I\" tags=[\"t1\"] k=2\n"+
+			"level=W pkg=xlog_test active=\"xlog_test:W\" k=3\n"+
+			"level=I pkg=xlog_test \"active=\\\"xlog_test:I\\\"\" [\"formatted 4\"]\n",
+		b.String())
+	// not evaluated for disabled entries
+	assert.Equal(t, 3, calls)
+
+	// hooks see the computed fields
+	var fields []any
+	xlog.AddHook(xlog.HookFunc(func(e *xlog.Entry) error {
+		fields = e.Fields
+		return nil
+	}))
+	defer xlog.ResetHooks()
+
+	logger.KV(xlog.INFO, "k", 6)
+	assert.Equal(t, []any{"active", "xlog_test:I", "k", 6}, fields)
+}
+
+func Test_GlobalFields(t *testing.T) {
+	var str, js bytes.Buffer
+	xlog.SetFormatter(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&str),
+		xlog.NewJSONFormatter(&js),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	xlog.SetGlobalFields("host", "h1", xlog.Int("pid", 123), "service", "svc")
+	defer xlog.SetGlobalFields()
+	assert.Equal(t, []any{"host", "h1", "pid", 123, "service", "svc"}, xlog.GlobalFields())
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.Infof("started %d", 2)
+	assert.Equal(t,
+		"level=I pkg=xlog_test host=\"h1\" pid=123 service=\"svc\" k=1\n"+
+			"level=I pkg=xlog_test \"host=\\\"h1\\\"\" \"pid=123\" \"service=\\\"svc\\\"\" [\"started 2\"]\n",
+		str.String())
+	assert.Equal(t, `{"host":"h1","k":1,"level":"I","pid":123,"pkg":"xlog_test","service":"svc"}`+"\n"+
+		`{"level":"I","msg":"host=\"h1\"pid=123service=\"svc\"[started 2]","pkg":"xlog_test"}`+"\n", js.String())
+
+	// the global fields are before the computed fields
+	xlog.AddFieldFunc(func(_ context.Context, level xlog.LogLevel, pkg string) (string, any) {
+		return "active", 1
+	})
+	defer xlog.ResetFieldFuncs()
+
+	str.Reset()
+	logger.KV(xlog.INFO, "k", 3)
+	assert.Equal(t, "level=I pkg=xlog_test host=\"h1\" pid=123 service=\"svc\" active=1 k=3\n", str.String())
+
+	xlog.SetGlobalFields()
+	assert.Nil(t, xlog.GlobalFields())
+	str.Reset()
+	logger.KV(xlog.INFO, "k", 4)
+	assert.Equal(t, "level=I pkg=xlog_test active=1 k=4\n", str.String())
+}
+
+func Test_GlobalFieldsProvider(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetGlobalFieldsProviders()
+
+	prevNow := xlog.MonotonicNowFn
+	defer func() { xlog.MonotonicNowFn = prevNow }()
+	now := time.Duration(0)
+	xlog.MonotonicNowFn = func() time.Duration { return now }
+
+	color := "blue"
+	evaluated := 0
+	xlog.AddGlobalFieldsProvider(func() []any {
+		evaluated++
+		return []any{"color", color}
+	}, 0)
+	flags := 0
+	xlog.AddGlobalFieldsProvider(func() []any {
+		flags++
+		return []any{xlog.Int("flags", flags)}
+	}, time.Minute)
+
+	xlog.SetGlobalFields("service", "svc")
+	defer xlog.SetGlobalFields()
+
+	logger.KV(xlog.INFO, "k", 1)
+	color = "green"
+	now += 30 * time.Second
+	logger.KV(xlog.INFO, "k", 2)
+	// not evaluated for disabled entries
+	logger.KV(xlog.DEBUG, "k", 3)
+	now += time.Minute
+	logger.KV(xlog.INFO, "k", 4)
+
+	assert.Equal(t,
+		"level=I pkg=xlog_test service=\"svc\" color=\"blue\" flags=1 k=1\n"+
+			"level=I pkg=xlog_test service=\"svc\" color=\"green\" flags=1 k=2\n"+
+			"level=I pkg=xlog_test service=\"svc\" color=\"green\" flags=2 k=4\n",
+		b.String())
+	assert.Equal(t, 3, evaluated)
+	assert.Equal(t, 2, flags)
+
+	xlog.ResetGlobalFieldsProviders()
+	b.Reset()
+	logger.KV(xlog.INFO, "k", 5)
+	assert.Equal(t, "level=I pkg=xlog_test service=\"svc\" k=5\n", b.String())
+}
@@ -0,0 +1,102 @@
+package xlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Filter is the Hook, that drops entries by the package or field values.
+// If include matchers are specified, only the entries matching any of them are logged,
+// and the entries matching any of exclude matchers are dropped.
+type Filter struct {
+	include []filterMatcher
+	exclude []filterMatcher
+}
+
+// filterMatcher matches the package name, or the field value if key is set
+type filterMatcher struct {
+	pkg   string
+	key   string
+	value string
+}
+
+// ParseFilter returns Filter from comma-separated include and exclude matchers,
+// each matcher is the package name, or the field in key=value format:
+//
+//	xlog.ParseFilter("", "health,path=/metrics")
+//
+// It returns nil if no matchers are specified.
+func ParseFilter(include, exclude string) (*Filter, error) {
+	inc, err := parseFilterMatchers(include)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid include")
+	}
+	exc, err := parseFilterMatchers(exclude)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid exclude")
+	}
+	if len(inc) == 0 && len(exc) == 0 {
+		return nil, nil
+	}
+	return &Filter{
+		include: inc,
+		exclude: exc,
+	}, nil
+}
+
+func parseFilterMatchers(s string) ([]filterMatcher, error) {
+	var list []filterMatcher
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			list = append(list, filterMatcher{pkg: item})
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, errors.Errorf("missing key in %q", item)
+		}
+		list = append(list, filterMatcher{key: key, value: strings.TrimSpace(value)})
+	}
+	return list, nil
+}
+
+// Fire returns ErrDropEntry if the entry is filtered out
+func (f *Filter) Fire(e *Entry) error {
+	if len(f.include) > 0 && !matchesAny(f.include, e) {
+		return ErrDropEntry
+	}
+	if matchesAny(f.exclude, e) {
+		return ErrDropEntry
+	}
+	return nil
+}
+
+func matchesAny(list []filterMatcher, e *Entry) bool {
+	for _, m := range list {
+		if m.matches(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m filterMatcher) matches(e *Entry) bool {
+	if m.key == "" {
+		return m.pkg == e.Pkg
+	}
+	v, ok := e.Field(m.key)
+	if !ok {
+		return false
+	}
+	if s, ok := v.(string); ok {
+		return s == m.value
+	}
+	return fmt.Sprint(v) == m.value
+}
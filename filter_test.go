@@ -0,0 +1,61 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseFilter(t *testing.T) {
+	f, err := xlog.ParseFilter("", " ")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	_, err = xlog.ParseFilter("=v", "")
+	assert.EqualError(t, err, `invalid include: missing key in "=v"`)
+	_, err = xlog.ParseFilter("", "pkg, =v")
+	assert.EqualError(t, err, `invalid exclude: missing key in "=v"`)
+
+	f, err = xlog.ParseFilter("xlog_test, tenant=t1", "path=/metrics,code=200")
+	require.NoError(t, err)
+	assert.NotNil(t, f)
+}
+
+func Test_Filter(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetHooks()
+
+	other := xlog.NewPackageLogger("github.com/effective-security/xlog", "filter_other")
+
+	f, err := xlog.ParseFilter("", "path=/metrics,code=200,filter_other")
+	require.NoError(t, err)
+	xlog.AddHook(f)
+
+	logger.KV(xlog.INFO, "path", "/metrics")
+	logger.KV(xlog.INFO, "code", 200)
+	logger.WithValues("path", "/metrics").KV(xlog.INFO, "msg", "values")
+	other.KV(xlog.INFO, "path", "/api")
+	assert.Empty(t, b.String())
+
+	logger.KV(xlog.INFO, "path", "/api", "code", 500)
+	assert.Equal(t, "level=I pkg=xlog_test path=\"/api\" code=500\n", b.String())
+
+	xlog.ResetHooks()
+	f, err = xlog.ParseFilter("tenant=t1,filter_other", "code=500")
+	require.NoError(t, err)
+	xlog.AddHook(f)
+
+	b.Reset()
+	logger.KV(xlog.INFO, "tenant", "t2")
+	logger.KV(xlog.INFO, "tenant", "t1", "code", 500)
+	assert.Empty(t, b.String())
+
+	logger.KV(xlog.INFO, "tenant", "t1")
+	other.Info("included")
+	assert.Equal(t, "level=I pkg=xlog_test tenant=\"t1\"\nlevel=I pkg=filter_other \"included\"\n", b.String())
+}
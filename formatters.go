@@ -21,11 +21,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"path"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -47,8 +49,107 @@ const (
 	FormatWithColor
 	// FormatPrintEmpty allows to print empty values
 	FormatPrintEmpty
+	// FormatWithRedaction allows to redact values with the redactor set by SetRedactor
+	FormatWithRedaction
+	// FormatSkipPkg allows to configure skipping the pkg log
+	FormatSkipPkg
+	// FormatWithMonotonic allows to print the monotonic clock reading with each log,
+	// to detect the wall clock jumps and to order the entries of the process
+	FormatWithMonotonic
+	// FormatWithErrorChain allows to log the error values as the fields of the wrapped errors chain,
+	// returned by ErrorChainFields
+	FormatWithErrorChain
+	// FormatWithStackTrace allows to print the stack trace of the current goroutine
+	// as "stack" field of ERROR and CRITICAL entries, see SetStackTraceDepth
+	FormatWithStackTrace
+	// FormatWithPkgPath allows to print the package path relative to the repo in the pkg field,
+	// for example pkg=internal/server/http, to disambiguate the packages with the same name
+	FormatWithPkgPath
+	// FormatWithPkgFullPath allows to print the full import path of the package in the pkg field,
+	// for example pkg=github.com/org/repo/internal/server/http
+	FormatWithPkgFullPath
+	// FormatWithGoroutineID allows to print the ID of the logging goroutine as goid field,
+	// to debug the interleaved entries of concurrent goroutines.
+	// AsyncFormatter writes the entries from its own goroutine, see ContextWithWorkerID
+	FormatWithGoroutineID
+	// FormatWithOrderedKeys allows JSON formatter to print the keys in the stable order:
+	// time, monotonic, level, goid, pkg, src and func first, then the remaining keys sorted
+	FormatWithOrderedKeys
+	// FormatDuplicateKeysLastWins allows to print the last value of the repeated key,
+	// for example the KV value overrides the WithValues value of the same key
+	FormatDuplicateKeysLastWins
+	// FormatDuplicateKeysFirstWins allows to print the first value of the repeated key
+	FormatDuplicateKeysFirstWins
+	// FormatDuplicateKeysSuffix allows to print the values of the repeated key
+	// with the key_2, key_3 keys
+	FormatDuplicateKeysSuffix
 )
 
+// KeyTruncated is the key of the marker field,
+// added to the entries with the truncated values or message
+const KeyTruncated = "truncated"
+
+// DefaultMaxLen is the default maximum length of the values of the text formatters,
+// and of the message of JSON formatter
+const DefaultMaxLen = 1024
+
+// KeyGoroutineID is the key of the goroutine ID, printed with FormatWithGoroutineID
+const KeyGoroutineID = "goid"
+
+// KeyMonotonic is the key of the monotonic clock reading,
+// in nanoseconds since the process start
+const KeyMonotonic = "mono"
+
+// FormatterSettings specifies the formatter settings with values,
+// the zero fields keep the current values
+type FormatterSettings struct {
+	// PkgKey specifies the key of the pkg field, pkg by default
+	PkgKey string `json:"pkg_key,omitempty" yaml:"pkg_key,omitempty"`
+	// MaxValueLen specifies the maximum length of the values, the longer values are truncated.
+	// DefaultMaxLen by default for the text formatters, and not limited for JSON formatter.
+	// Negative value disables the truncation.
+	MaxValueLen int `json:"max_value_len,omitempty" yaml:"max_value_len,omitempty"`
+	// MaxMessageLen specifies the maximum length of the message of JSON formatter,
+	// DefaultMaxLen by default. Negative value disables the truncation.
+	MaxMessageLen int `json:"max_message_len,omitempty" yaml:"max_message_len,omitempty"`
+}
+
+// IsZero returns true if no settings are specified
+func (s FormatterSettings) IsZero() bool {
+	return s == FormatterSettings{}
+}
+
+// merge returns the settings with the non-zero values of o applied
+func (s FormatterSettings) merge(o FormatterSettings) FormatterSettings {
+	if o.PkgKey != "" {
+		s.PkgKey = o.PkgKey
+	}
+	if o.MaxValueLen != 0 {
+		s.MaxValueLen = o.MaxValueLen
+	}
+	if o.MaxMessageLen != 0 {
+		s.MaxMessageLen = o.MaxMessageLen
+	}
+	return s
+}
+
+// SettingsFormatter is implemented by the formatters that support FormatterSettings,
+// the wrapping formatters pass the settings to the wrapped ones
+type SettingsFormatter interface {
+	Formatter
+	// Settings applies the non-zero settings
+	Settings(s FormatterSettings) Formatter
+}
+
+// ApplySettings applies the settings to the formatter if it implements SettingsFormatter,
+// and returns the formatter
+func ApplySettings(f Formatter, s FormatterSettings) Formatter {
+	if sf, ok := f.(SettingsFormatter); ok {
+		return sf.Settings(s)
+	}
+	return f
+}
+
 // Formatter defines an interface for formatting logs
 type Formatter interface {
 	// Format log entry string to the stream,
@@ -66,10 +167,21 @@ type Formatter interface {
 // TimeNowFn to override in unit tests
 var TimeNowFn = time.Now
 
+// processStart is the base of the monotonic clock readings
+var processStart = time.Now()
+
+// MonotonicNowFn returns the monotonic clock reading since the process start,
+// not affected by the wall clock changes. Override in unit tests.
+var MonotonicNowFn = func() time.Duration {
+	return time.Since(processStart)
+}
+
 // NewStringFormatter returns string-based formatter
 func NewStringFormatter(w io.Writer) Formatter {
+	bw, size := newSizeWriter(w)
 	return &StringFormatter{
-		w: bufio.NewWriter(w),
+		w:    bw,
+		size: size,
 		config: config{
 			withCaller: true,
 			skipTime:   false,
@@ -80,7 +192,12 @@ func NewStringFormatter(w io.Writer) Formatter {
 // StringFormatter defines string-based formatter
 type StringFormatter struct {
 	config
-	w *bufio.Writer
+	w    *bufio.Writer
+	size *sizeWriter
+}
+
+func (s *StringFormatter) writesFields() bool {
+	return true
 }
 
 // Options allows to configure formatter behavior
@@ -89,113 +206,254 @@ func (s *StringFormatter) Options(ops ...FormatterOption) Formatter {
 	return s
 }
 
+// Settings applies the non-zero settings
+func (s *StringFormatter) Settings(settings FormatterSettings) Formatter {
+	s.config.settings(settings)
+	return s
+}
+
 // FormatKV log entry string to the stream,
 // the entries are key/value pairs
 func (s *StringFormatter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
-	s.format(pkg, l, depth+1, false, flatten(s.config.printEmpty, entries...)...)
+	if hasGroups(entries) {
+		entries = flattenGroups("", entries)
+	}
+	entries = s.duplicates.resolveDuplicateKeys(entries)
+	s.format(pkg, l, depth+1, kvEntries, entries...)
 }
 
 // Format log entry string to the stream
 func (s *StringFormatter) Format(pkg string, l LogLevel, depth int, entries ...any) {
-	s.format(pkg, l, depth+1, true, entries...)
+	s.format(pkg, l, depth+1, escapedEntries, entries...)
 }
 
-func (s *StringFormatter) format(pkg string, l LogLevel, depth int, escape bool, entries ...any) {
+func (s *StringFormatter) format(pkg string, l LogLevel, depth int, format entriesFormat, entries ...any) {
+	s.size.begin(s.w, l)
+	eb := getEntryBuffer()
+	b := eb.b
 	if !s.skipTime {
-		now := TimeNowFn().UTC()
-		_, _ = s.w.WriteString("time=")
-		_, _ = s.w.WriteString(now.Format(time.RFC3339))
-		_ = s.w.WriteByte(' ')
+		b = append(b, "time="...)
+		b = TimeNowFn().UTC().AppendFormat(b, time.RFC3339)
+		b = append(b, ' ')
+	}
+	if s.monotonic {
+		b = append(b, KeyMonotonic+"="...)
+		b = strconv.AppendInt(b, int64(MonotonicNowFn()), 10)
+		b = append(b, ' ')
 	}
 	if !s.skipLevel {
-		_, _ = s.w.WriteString("level=")
-		_, _ = s.w.WriteString(l.Char())
-		_ = s.w.WriteByte(' ')
+		b = append(b, "level="...)
+		b = append(b, l.Char()...)
+		b = append(b, ' ')
 	}
 
 	params := writeEntriesParams{
-		pkg:          pkg,
+		pkg:          s.pkgName(pkg, depth+1),
+		pkgKey:       s.pkgField(),
+		goroutineID:  s.goroutineID,
 		separator:    " ",
 		depth:        depth + 1,
 		withCaller:   s.withCaller,
 		withLocation: s.withLocation,
-		escape:       escape,
+		format:       format,
+		redactor:     s.redactor(),
+		maxValueLen:  truncateLen(s.maxValueLen, DefaultMaxLen),
 		printEmpty:   s.printEmpty,
+		stackTrace:   s.stackTrace && l <= ERROR,
 	}
-	writeEntries(s.w, &params, entries...)
-	s.Flush()
+	b = appendEntries(b, &params, entries...)
+	_, _ = s.w.Write(b)
+	putEntryBuffer(eb, b)
+	ObserveEntrySize(pkg, s.size.end(s.w))
 }
 
+// entriesFormat specifies how the text formatters write the entries
+type entriesFormat int
+
+const (
+	// escapedEntries are the values, written escaped
+	escapedEntries entriesFormat = iota
+	// flattenedEntries are the formatted strings, written as is
+	flattenedEntries
+	// kvEntries are key/value pairs, written as key=value
+	kvEntries
+)
+
 type writeEntriesParams struct {
 	pkg          string
+	pkgKey       string
+	goroutineID  bool
 	separator    string
+	segments     []PrettySegment
 	depth        int
 	withCaller   bool
 	withLocation bool
-	escape       bool
-	colorOff     bool
-	printEmpty   bool
+	format       entriesFormat
+	// redactor of kvEntries
+	redactor *Redactor
+	// maxValueLen is the truncation length of kvEntries values, zero disables the truncation
+	maxValueLen int
+	colorOff    bool
+	printEmpty  bool
+	// stackTrace specifies to print the stack trace
+	stackTrace bool
 }
 
-func writeEntries(w *bufio.Writer, p *writeEntriesParams, entries ...any) {
-	if p.pkg != "" {
-		_, _ = w.WriteString("pkg=")
-		_, _ = w.WriteString(p.pkg)
-		_, _ = w.WriteString(p.separator)
+// defaultSegments is the default order of the segments
+var defaultSegments = []PrettySegment{SegmentGoroutine, SegmentPkg, SegmentSrc, SegmentFunc}
+
+// appendEntries appends the segments and the entries,
+// the entry is terminated by new line
+func appendEntries(b []byte, p *writeEntriesParams, entries ...any) []byte {
+	segments := p.segments
+	if segments == nil {
+		segments = defaultSegments
 	}
 
+	var caller, file string
+	var line int
 	if p.withLocation || p.withCaller {
-		caller, file, line := Caller(p.depth + 1)
+		caller, file, line = Caller(p.depth + 1)
+	}
 
-		if p.withLocation {
-			_, _ = w.WriteString("src=")
-			// It's always the same number of frames to the user's call.
-			_, _ = w.WriteString(fmt.Sprintf("%s:%d", file, line))
-			_, _ = w.WriteString(p.separator)
+	for _, segment := range segments {
+		switch segment {
+		case SegmentGoroutine:
+			if p.goroutineID {
+				b = append(b, KeyGoroutineID+"="...)
+				b = strconv.AppendUint(b, GoroutineID(), 10)
+				b = append(b, p.separator...)
+			}
+		case SegmentPkg:
+			if p.pkg != "" && p.pkgKey != "" {
+				b = append(b, p.pkgKey...)
+				b = append(b, '=')
+				b = append(b, p.pkg...)
+				b = append(b, p.separator...)
+			}
+		case SegmentSrc:
+			if p.withLocation {
+				// It's always the same number of frames to the user's call.
+				b = append(b, "src="...)
+				b = append(b, file...)
+				b = append(b, ':')
+				b = strconv.AppendInt(b, int64(line), 10)
+				b = append(b, p.separator...)
+			}
+		case SegmentFunc:
+			if p.withCaller {
+				b = append(b, "func="...)
+				b = append(b, caller...)
+				b = append(b, p.separator...)
+			}
 		}
+	}
 
-		if p.withCaller {
-			_, _ = w.WriteString("func=")
-			_, _ = w.WriteString(caller)
-			_, _ = w.WriteString(p.separator)
+	// last is the start of the last entry
+	last := len(b)
+	count := len(entries)
+	if p.format == kvEntries {
+		b, count = appendFields(b, p.printEmpty, p.redactor, p.maxValueLen, p.separator, entries...)
+		last = len(b)
+	} else {
+		for i := 0; i < count; i++ {
+			last = len(b)
+			if p.format == escapedEntries {
+				b = appendEscaped(b, entries[i])
+			} else if s, ok := entries[i].(string); ok {
+				b = append(b, s...)
+			} else {
+				b = fmt.Append(b, entries[i])
+			}
+			if (len(b) > last || p.printEmpty) && i+1 < count {
+				b = append(b, p.separator...)
+			}
 		}
 	}
+	endsInNL := len(b) > last && b[len(b)-1] == '\n'
 
-	var str string
-	for i, count := 0, len(entries); i < count; i++ {
-		if p.escape {
-			str = EscapedString(entries[i])
-		} else {
-			str = fmt.Sprint(entries[i])
-		}
-		if str != "" || p.printEmpty {
-			_, _ = w.WriteString(str)
-			if i+1 < count {
-				_, _ = w.WriteString(p.separator)
-			}
+	if p.stackTrace {
+		if count > 0 {
+			b = append(b, p.separator...)
 		}
+		b = append(b, KeyStack+"="...)
+		b = appendEscaped(b, StackTrace(p.depth+1))
+		endsInNL = false
 	}
 
 	if p.colorOff {
-		_, _ = w.Write(ColorOff)
+		b = append(b, ColorOff...)
 	}
 
-	l := len(str)
-	endsInNL := l > 0 && str[l-1] == '\n'
 	if !endsInNL {
-		_ = w.WriteByte('\n')
+		b = append(b, '\n')
 	}
+	return b
 }
 
 // Flush the logs
 func (s *StringFormatter) Flush() {
 	s.w.Flush()
+	s.size.Flush()
+}
+
+func (s *StringFormatter) writer() (*bufio.Writer, *sizeWriter) {
+	return s.w, s.size
+}
+
+// PrettySegment is the segment of PrettyFormatter entry
+type PrettySegment string
+
+const (
+	// SegmentPkg is the pkg=<package> segment
+	SegmentPkg PrettySegment = "pkg"
+	// SegmentSrc is the src=<file:line> segment, printed with FormatWithLocation
+	SegmentSrc PrettySegment = "src"
+	// SegmentFunc is the func=<caller> segment, printed with FormatWithCaller
+	SegmentFunc PrettySegment = "func"
+	// SegmentGoroutine is the goid=<goroutine ID> segment, printed with FormatWithGoroutineID
+	SegmentGoroutine PrettySegment = "goid"
+)
+
+// PrettyLayout specifies the delimiters and the order of segments of PrettyFormatter,
+// to match existing log parsing rules
+type PrettyLayout struct {
+	// Delimiter between the level and the entries, " | " by default
+	Delimiter string
+	// Separator between the entries, ", " by default
+	Separator string
+	// Segments specifies the order of the segments printed before the entries,
+	// the segments not in the list are not printed.
+	// SegmentGoroutine, SegmentPkg, SegmentSrc, SegmentFunc by default
+	Segments []PrettySegment
+	// Colors specifies the styles of the levels and the fields
+	// with FormatWithColor option, LevelColors are used by default
+	Colors *ColorScheme
 }
 
 // NewPrettyFormatter returns an instance of PrettyFormatter
 func NewPrettyFormatter(w io.Writer) Formatter {
+	return NewPrettyFormatterWithLayout(w, PrettyLayout{})
+}
+
+// NewPrettyFormatterWithLayout returns an instance of PrettyFormatter
+// with custom layout
+func NewPrettyFormatterWithLayout(w io.Writer, layout PrettyLayout) Formatter {
+	if layout.Delimiter == "" {
+		layout.Delimiter = " | "
+	}
+	if layout.Separator == "" {
+		layout.Separator = ", "
+	}
+	if layout.Segments == nil {
+		layout.Segments = defaultSegments
+	}
+	bw, size := newSizeWriter(w)
 	return &PrettyFormatter{
-		w: bufio.NewWriter(w),
+		w:      bw,
+		size:   size,
+		layout: layout,
+		colors: newColorCodes(layout.Colors),
 		config: config{
 			withCaller:   true,
 			skipTime:     false,
@@ -208,7 +466,14 @@ func NewPrettyFormatter(w io.Writer) Formatter {
 // PrettyFormatter provides default logs format
 type PrettyFormatter struct {
 	config
-	w *bufio.Writer
+	w      *bufio.Writer
+	size   *sizeWriter
+	layout PrettyLayout
+	colors *colorCodes
+}
+
+func (c *PrettyFormatter) writesFields() bool {
+	return true
 }
 
 // Options allows to configure formatter behavior
@@ -217,52 +482,90 @@ func (c *PrettyFormatter) Options(ops ...FormatterOption) Formatter {
 	return c
 }
 
+// Settings applies the non-zero settings
+func (c *PrettyFormatter) Settings(settings FormatterSettings) Formatter {
+	c.config.settings(settings)
+	return c
+}
+
 // FormatKV log entry string to the stream,
 // the entries are key/value pairs
 func (c *PrettyFormatter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
-	c.format(pkg, l, depth+1, false, flatten(c.printEmpty, entries...)...)
+	if hasGroups(entries) {
+		entries = flattenGroups("", entries)
+	}
+	entries = c.duplicates.resolveDuplicateKeys(entries)
+	if c.color && c.colors != nil {
+		level := c.colors.level(l)
+		join := func(k string, v any, val string) string {
+			return c.colors.field(level, k, v, val)
+		}
+		c.format(pkg, l, depth+1, flattenedEntries, flattenFn(c.printEmpty, c.redactor(), truncateLen(c.maxValueLen, DefaultMaxLen), join, entries...)...)
+		return
+	}
+	c.format(pkg, l, depth+1, kvEntries, entries...)
 }
 
 // Format log entry string to the stream
 func (c *PrettyFormatter) Format(pkg string, l LogLevel, depth int, entries ...any) {
-	c.format(pkg, l, depth+1, true, entries...)
+	c.format(pkg, l, depth+1, escapedEntries, entries...)
 }
 
 // Format log entry string to the stream
-func (c *PrettyFormatter) format(pkg string, l LogLevel, depth int, escape bool, entries ...any) {
+func (c *PrettyFormatter) format(pkg string, l LogLevel, depth int, format entriesFormat, entries ...any) {
+	c.size.begin(c.w, l)
+	eb := getEntryBuffer()
+	b := eb.b
 	if !c.skipTime {
-		now := TimeNowFn()
-		ts := now.Format("2006-01-02 15:04:05")
-		_, _ = c.w.WriteString(ts)
-		ms := now.Nanosecond() / 1000
-		_, _ = c.w.WriteString(fmt.Sprintf(".%06d ", ms))
+		b = TimeNowFn().AppendFormat(b, "2006-01-02 15:04:05.000000 ")
+	}
+	if c.monotonic {
+		mono := MonotonicNowFn()
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(mono/time.Second), 10)
+		b = append(b, '.')
+		b = appendPadded(b, int64(mono%time.Second), 9)
+		b = append(b, "] "...)
 	}
 	if c.color {
-		_, _ = c.w.Write(LevelColors[l])
+		b = append(b, c.colors.level(l)...)
 	}
 	if !c.skipLevel {
-		_, _ = c.w.WriteString(l.Char())
-		_, _ = c.w.WriteString(" | ")
+		b = append(b, l.Char()...)
+		b = append(b, c.layout.Delimiter...)
 	}
 	params := writeEntriesParams{
-		pkg:          pkg,
-		separator:    ", ",
+		pkg:          c.pkgName(pkg, depth+1),
+		pkgKey:       c.pkgField(),
+		goroutineID:  c.goroutineID,
+		separator:    c.layout.Separator,
+		segments:     c.layout.Segments,
 		depth:        depth + 1,
 		withCaller:   c.withCaller,
 		withLocation: c.withLocation,
-		escape:       escape,
+		format:       format,
+		redactor:     c.redactor(),
+		maxValueLen:  truncateLen(c.maxValueLen, DefaultMaxLen),
 		colorOff:     c.color,
 		printEmpty:   c.printEmpty,
+		stackTrace:   c.stackTrace && l <= ERROR,
 	}
 
-	writeEntries(c.w, &params, entries...)
+	b = appendEntries(b, &params, entries...)
+	_, _ = c.w.Write(b)
+	putEntryBuffer(eb, b)
 
-	c.Flush()
+	ObserveEntrySize(pkg, c.size.end(c.w))
 }
 
 // Flush the logs
 func (c *PrettyFormatter) Flush() {
 	c.w.Flush()
+	c.size.Flush()
+}
+
+func (c *PrettyFormatter) writer() (*bufio.Writer, *sizeWriter) {
+	return c.w, c.size
 }
 
 // color pallete map
@@ -323,44 +626,130 @@ func (*NilFormatter) Flush() {
 	// noop
 }
 
-func flatten(printEmpty bool, kvList ...any) []any {
+func flatten(printEmpty bool, r *Redactor, kvList ...any) []any {
+	return flattenFn(printEmpty, r, DefaultMaxLen, nil, kvList...)
+}
+
+// flattenFn returns key=value entries, joined by the join function if provided,
+// the values longer than maxLen are truncated, unless maxLen is zero
+func flattenFn(printEmpty bool, r *Redactor, maxLen int, join func(k string, v any, val string) string, kvList ...any) []any {
 	size := len(kvList)
 	list := make([]any, 0, size/2)
+	truncated := false
 
-	for i, j := 0, 0; i < size; i += 2 {
-		k, ok := kvList[i].(string)
+	for i := 0; i < size; {
+		k, v, next, ok := kvValue(printEmpty, r, i, kvList)
+		i = next
 		if !ok {
-			panic(fmt.Sprintf("key is not a string: %v", EscapedString(kvList[i])))
-		}
-		var v any
-		if i+1 < size {
-			v = kvList[i+1]
-		}
-		if v == nil && !printEmpty {
 			continue
 		}
 		val := EscapedString(v)
 		if val != `""` || printEmpty {
-			if len(val) > 1024 {
-				val = val[:1024] + "...\""
+			if maxLen > 0 && len(val) > maxLen && !isCompressedValue(v) {
+				val = string(truncateEscaped([]byte(val), 0, maxLen))
+				truncated = true
+			}
+			if join != nil {
+				list = append(list, join(k, v, val))
+			} else {
+				list = append(list, k+"="+val)
 			}
-			list = append(list, k+"="+val)
-			j++
+		}
+	}
+	if truncated {
+		if join != nil {
+			list = append(list, join(KeyTruncated, true, "true"))
+		} else {
+			list = append(list, KeyTruncated+"=true")
 		}
 	}
 	return list
 }
 
+// appendFields appends key=value entries separated by the separator,
+// as returned by flattenFn, and returns the number of the appended entries
+func appendFields(b []byte, printEmpty bool, r *Redactor, maxLen int, separator string, kvList ...any) ([]byte, int) {
+	count := 0
+	truncated := false
+	for i := 0; i < len(kvList); {
+		var k string
+		var v any
+		var field Field
+		typed := false
+		if f, ok := kvList[i].(Field); ok && r == nil {
+			// the typed field is written without boxing its value
+			i++
+			if f.isEmpty() && !printEmpty {
+				continue
+			}
+			k, field, typed = f.Key, f, true
+		} else {
+			var ok bool
+			k, v, i, ok = kvValue(printEmpty, r, i, kvList)
+			if !ok {
+				continue
+			}
+		}
+		start := len(b)
+		if count > 0 {
+			b = append(b, separator...)
+		}
+		b = append(b, k...)
+		b = append(b, '=')
+		valStart := len(b)
+		if typed {
+			b = field.appendValue(b)
+		} else {
+			b = appendEscaped(b, v)
+		}
+		val := b[valStart:]
+		if !printEmpty && string(val) == `""` {
+			b = b[:start]
+			continue
+		}
+		if maxLen > 0 && len(val) > maxLen {
+			compressed := isCompressedValue(v)
+			if typed {
+				compressed = field.isCompressed()
+			}
+			if !compressed {
+				b = truncateEscaped(b, valStart, maxLen)
+				truncated = true
+			}
+		}
+		count++
+	}
+	if truncated {
+		b = append(b, separator...)
+		b = append(b, KeyTruncated+"=true"...)
+		count++
+	}
+	return b, count
+}
+
+// kvValue returns the key and the redacted value at i, and the index of the next entry,
+// false is returned if nil value is not printed
+func kvValue(printEmpty bool, r *Redactor, i int, kvList []any) (string, any, int, bool) {
+	key, val, next := kvAt(kvList, i)
+	k, ok := key.(string)
+	if !ok {
+		panic(fmt.Sprintf("key is not a string: %v", EscapedString(key)))
+	}
+	v := r.Redact(k, val)
+	return k, v, next, v != nil || printEmpty
+}
+
 // EscapedString returns string value stuitable for logging
 func EscapedString(value any) string {
 	switch typ := value.(type) {
+	case SecretValue:
+		value = typ.String()
 	case error:
-		value = fmt.Sprintf("%+v", typ)
+		value = ErrorValue(typ)
 	case time.Duration:
 		return typ.String()
 	case string:
-		value = strings.TrimSpace(typ)
-		// pass through for encoding
+		return quoteString(strings.TrimSpace(typ))
 	case uint64:
 		return strconv.FormatUint(typ, 10)
 	case uint:
@@ -369,6 +758,28 @@ func EscapedString(value any) string {
 		return strconv.FormatInt(typ, 10)
 	case int:
 		return strconv.FormatInt(int64(typ), 10)
+	case int32:
+		return strconv.FormatInt(int64(typ), 10)
+	case int16:
+		return strconv.FormatInt(int64(typ), 10)
+	case int8:
+		return strconv.FormatInt(int64(typ), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(typ), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(typ), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(typ), 10)
+	case float64:
+		if s, ok := formatFloat(typ, 64); ok {
+			return s
+		}
+	case float32:
+		if s, ok := formatFloat(float64(typ), 32); ok {
+			return s
+		}
+	case nil:
+		return "null"
 	case bool:
 		if typ {
 			return "true"
@@ -392,45 +803,220 @@ func EscapedString(value any) string {
 	default:
 		// keep as is to json.Encode
 	}
+	if s, ok := value.(string); ok {
+		return quoteString(s)
+	}
 
-	buffer := &bytes.Buffer{}
-	encoder := json.NewEncoder(buffer)
-	encoder.SetEscapeHTML(false)
-	_ = encoder.Encode(value)
-	return strings.TrimSpace(buffer.String())
+	e := encoderPool.Get().(*pooledEncoder)
+	e.buf.Reset()
+	_ = e.enc.Encode(value)
+	res := string(bytes.TrimSpace(e.buf.Bytes()))
+	// do not retain large buffers
+	if e.buf.Cap() <= maxPooledBuffer {
+		encoderPool.Put(e)
+	}
+	return res
 }
 
-// Caller returns caller function name, and location
-func Caller(depth int) (name string, file string, line int) {
-	pc, file, line, ok := runtime.Caller(depth)
+// appendEscaped appends the value as returned by EscapedString,
+// the common types are appended without the intermediate string
+func appendEscaped(b []byte, value any) []byte {
+	switch typ := value.(type) {
+	case string:
+		return appendQuoted(b, strings.TrimSpace(typ))
+	case int:
+		return strconv.AppendInt(b, int64(typ), 10)
+	case int64:
+		return strconv.AppendInt(b, typ, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(typ), 10)
+	case uint64:
+		return strconv.AppendUint(b, typ, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(typ), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(typ), 10)
+	case bool:
+		return strconv.AppendBool(b, typ)
+	case float64:
+		if r, ok := appendFloat(b, typ, 64); ok {
+			return r
+		}
+	case float32:
+		if r, ok := appendFloat(b, float64(typ), 32); ok {
+			return r
+		}
+	case nil:
+		return append(b, "null"...)
+	case time.Time:
+		return typ.UTC().AppendFormat(b, time.RFC3339)
+	}
+	return append(b, EscapedString(value)...)
+}
 
+// maxPooledBuffer is the maximum capacity of the buffer returned to the pool
+const maxPooledBuffer = 64 * 1024
+
+// pooledEncoder is json.Encoder with its buffer,
+// reused by EscapedString for the values without the fast path
+type pooledEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		e := &pooledEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		e.enc.SetEscapeHTML(false)
+		return e
+	},
+}
+
+// formatFloat returns the float as encoded by json.Encoder,
+// or false for NaN and infinity values, which are not supported by JSON
+func formatFloat(f float64, bits int) (string, bool) {
+	var buf [32]byte
+	b, ok := appendFloat(buf[:0], f, bits)
 	if !ok {
-		file = "???"
-		line = 1
-	} else {
-		slash := strings.LastIndex(file, "/")
-		if slash >= 0 {
-			file = file[slash+1:]
+		return "", false
+	}
+	return string(b), true
+}
+
+// appendFloat appends the float as encoded by json.Encoder,
+// or returns false for NaN and infinity values, which are not supported by JSON
+func appendFloat(b []byte, f float64, bits int) ([]byte, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
 		}
 	}
-	if line < 0 {
-		line = 0 // not a real line number
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
 	}
+	return b, true
+}
+
+// appendPadded appends the non-negative number padded with zeros to the width
+func appendPadded(b []byte, n int64, width int) []byte {
+	var buf [20]byte
+	d := strconv.AppendInt(buf[:0], n, 10)
+	for i := len(d); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, d...)
+}
 
-	details := runtime.FuncForPC(pc)
-	if ok && details != nil {
-		name := path.Base(details.Name())
-		name = removePart(name, "[", "]")
-		name = removePart(name, "(", ")")
+// entryBuffer is the pooled buffer of the text formatters,
+// the entry is written to the destination with a single write
+type entryBuffer struct {
+	b []byte
+}
 
-		// remove package name
-		idx := strings.Index(name, ".")
-		if idx >= 0 {
-			name = strings.TrimLeft(name[idx+1:], ".")
-		}
-		return name, file, line
+var entryBufferPool = sync.Pool{
+	New: func() any {
+		return &entryBuffer{b: make([]byte, 0, 1024)}
+	},
+}
+
+func getEntryBuffer() *entryBuffer {
+	eb := entryBufferPool.Get().(*entryBuffer)
+	eb.b = eb.b[:0]
+	return eb
+}
+
+// putEntryBuffer returns the buffer to the pool,
+// b is the grown buffer; large buffers are not retained
+func putEntryBuffer(eb *entryBuffer, b []byte) {
+	if cap(b) <= maxPooledBuffer {
+		eb.b = b
+		entryBufferPool.Put(eb)
 	}
-	return "func", file, line
+}
+
+// callerEntry is the resolved caller, cached by the program counter
+type callerEntry struct {
+	name    string
+	file    string
+	line    int
+	pkgPath string
+}
+
+// callerCache is the cache of the resolved callers by the program counter,
+// the program counter identifies the call site, including the inlined frames
+var callerCache sync.Map
+
+// unknownCaller is returned when the stack is not deep enough
+var unknownCaller = &callerEntry{name: "func", file: "???", line: 1}
+
+// Caller returns caller function name, and location
+func Caller(depth int) (name string, file string, line int) {
+	e := resolveCaller(depth + 1)
+	return e.name, e.file, e.line
+}
+
+// CallerPkgPath returns the import path of the caller's package,
+// or empty string if the caller is unknown
+func CallerPkgPath(depth int) string {
+	return resolveCaller(depth + 1).pkgPath
+}
+
+// resolveCaller returns the caller at the depth relative to its caller,
+// the callers are cached by the program counter
+func resolveCaller(depth int) *callerEntry {
+	var pcs [1]uintptr
+	// skip runtime.Callers and resolveCaller frames, as runtime.Caller does
+	if runtime.Callers(depth+1, pcs[:]) == 0 {
+		return unknownCaller
+	}
+	if c, ok := callerCache.Load(pcs[0]); ok {
+		return c.(*callerEntry)
+	}
+
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	e := &callerEntry{
+		name:    funcName(frame.Function),
+		file:    frame.File,
+		line:    frame.Line,
+		pkgPath: funcPkgPath(frame.Function),
+	}
+	if slash := strings.LastIndex(e.file, "/"); slash >= 0 {
+		e.file = e.file[slash+1:]
+	}
+	if e.line < 0 {
+		e.line = 0 // not a real line number
+	}
+	callerCache.Store(pcs[0], e)
+	return e
+}
+
+// funcName returns the function name without the package name and type parameters
+func funcName(function string) string {
+	if function == "" {
+		return "func"
+	}
+	name := path.Base(function)
+	name = removePart(name, "[", "]")
+	name = removePart(name, "(", ")")
+
+	// remove package name
+	idx := strings.Index(name, ".")
+	if idx >= 0 {
+		name = strings.TrimLeft(name[idx+1:], ".")
+	}
+	return name
 }
 
 func removePart(val, open, close string) string {
@@ -452,6 +1038,61 @@ type config struct {
 	printEmpty   bool
 	withLocation bool
 	color        bool
+	redact       bool
+	skipPkg      bool
+	monotonic    bool
+	errorChain   bool
+	stackTrace   bool
+	pkgKey       string
+	pkgPath      pkgPathFormat
+	goroutineID  bool
+	orderedKeys  bool
+	duplicates   duplicateKeysPolicy
+	// maxValueLen and maxMessageLen are the truncation lengths,
+	// zero is the formatter default, and negative disables the truncation
+	maxValueLen   int
+	maxMessageLen int
+}
+
+// settings applies the non-zero settings
+func (c *config) settings(s FormatterSettings) {
+	cur := c.formatterSettings().merge(s)
+	c.pkgKey = cur.PkgKey
+	c.maxValueLen = cur.MaxValueLen
+	c.maxMessageLen = cur.MaxMessageLen
+}
+
+// formatterSettings returns the current settings
+func (c *config) formatterSettings() FormatterSettings {
+	return FormatterSettings{
+		PkgKey:        c.pkgKey,
+		MaxValueLen:   c.maxValueLen,
+		MaxMessageLen: c.maxMessageLen,
+	}
+}
+
+// truncateLen returns the truncation length of the configured value,
+// or zero if the truncation is disabled
+func truncateLen(configured, def int) int {
+	switch {
+	case configured == 0:
+		return def
+	case configured < 0:
+		return 0
+	}
+	return configured
+}
+
+// pkgField returns the key for the package name,
+// or empty string if the package must be skipped
+func (c *config) pkgField() string {
+	if c.skipPkg {
+		return ""
+	}
+	if c.pkgKey != "" {
+		return c.pkgKey
+	}
+	return "pkg"
 }
 
 // Options allows to configure formatter behavior
@@ -472,6 +1113,30 @@ func (c *config) options(ops []FormatterOption) {
 			c.color = true
 		case FormatPrintEmpty:
 			c.printEmpty = true
+		case FormatWithRedaction:
+			c.redact = true
+		case FormatSkipPkg:
+			c.skipPkg = true
+		case FormatWithMonotonic:
+			c.monotonic = true
+		case FormatWithErrorChain:
+			c.errorChain = true
+		case FormatWithStackTrace:
+			c.stackTrace = true
+		case FormatWithPkgPath:
+			c.pkgPath = pkgRelativePath
+		case FormatWithPkgFullPath:
+			c.pkgPath = pkgFullPath
+		case FormatWithGoroutineID:
+			c.goroutineID = true
+		case FormatWithOrderedKeys:
+			c.orderedKeys = true
+		case FormatDuplicateKeysLastWins:
+			c.duplicates = lastKeyWins
+		case FormatDuplicateKeysFirstWins:
+			c.duplicates = firstKeyWins
+		case FormatDuplicateKeysSuffix:
+			c.duplicates = suffixDuplicateKeys
 		}
 	}
 }
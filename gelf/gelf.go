@@ -0,0 +1,179 @@
+// Package gelf provides GELF 1.1 formatter for Graylog,
+// with UDP chunked and TCP transports.
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/syslog"
+)
+
+// Version of GELF specification
+const Version = "1.1"
+
+// formatter provides GELF 1.1 logs format
+type formatter struct {
+	w    io.Writer
+	host string
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// NewFormatter returns GELF formatter, each entry is written
+// as a single JSON message with one Write call.
+// If host is empty, os.Hostname is used.
+func NewFormatter(w io.Writer, host string) xlog.Formatter {
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	return &formatter{
+		w:          w,
+		host:       host,
+		withCaller: true,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatSkipTime:
+			f.skipTime = true
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs, emitted as additional fields
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format log entry string to the stream
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are written immediately
+func (f *formatter) Flush() {}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	m := map[string]any{
+		"version": Version,
+		"host":    f.host,
+		"level":   int(syslog.SeverityFor(l)),
+	}
+	if !f.skipTime {
+		now := xlog.TimeNowFn()
+		m["timestamp"] = float64(now.UnixMilli()) / 1000
+	}
+	if pkg != "" {
+		m["_pkg"] = pkg
+	}
+	if f.withCaller {
+		caller, file, line := xlog.Caller(depth + 1)
+		m["_func"] = caller
+		m["_file"] = file
+		m["_line"] = line
+	}
+
+	var text []string
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if k == "msg" && msg == "" {
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if s, ok := val.(string); ok && s == "" && !f.printEmpty {
+			continue
+		}
+		m[FieldName(k)] = val
+		text = append(text, k+"="+xlog.EscapedString(v))
+	}
+
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		msg = strings.Join(text, " ")
+	}
+	if short, _, ok := strings.Cut(msg, "\n"); ok {
+		m["short_message"] = short
+		m["full_message"] = msg
+	} else {
+		m["short_message"] = msg
+	}
+
+	f.buf.Reset()
+	encoder := json.NewEncoder(&f.buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(m); err != nil {
+		return
+	}
+	b := bytes.TrimSuffix(f.buf.Bytes(), []byte{'\n'})
+	_, _ = f.w.Write(b)
+	xlog.ObserveEntrySize(pkg, len(b))
+}
+
+// value returns the value of the additional field,
+// GELF allows only numbers and strings
+func value(v any) any {
+	switch typ := v.(type) {
+	case string:
+		return typ
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return typ
+	case error:
+		return typ.Error()
+	case time.Time:
+		return typ.UTC().Format(time.RFC3339)
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
+
+var invalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+// FieldName returns the name of the additional field for the key,
+// prefixed with underscore
+func FieldName(key string) string {
+	name := "_" + invalidFieldChars.ReplaceAllString(key, "_")
+	if name == "_id" {
+		// _id is reserved
+		name = "_id_"
+	}
+	return name
+}
@@ -0,0 +1,45 @@
+package gelf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Formatter(t *testing.T) {
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 500000000, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	var b bytes.Buffer
+	f := NewFormatter(&b, "host1").Options(xlog.FormatNoCaller)
+
+	f.FormatKV("pkg", xlog.WARNING, 1, "msg", "hello", "id", 1, "user id", "u1", "err", errors.New("failed"), "nil", nil, "ok", true)
+	assert.Equal(t, `{"_err":"failed","_id_":1,"_ok":"true","_pkg":"pkg","_user_id":"u1","host":"host1","level":4,"short_message":"hello","timestamp":1617235200.5,"version":"1.1"}`, b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatSkipTime)
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v")
+	assert.Equal(t, `{"_k":"v","_pkg":"pkg","host":"host1","level":6,"short_message":"k=\"v\"","version":"1.1"}`, b.String())
+
+	b.Reset()
+	f.Format("", xlog.ERROR, 1, "line1\nline2")
+	f.Flush()
+	assert.Equal(t, `{"full_message":"line1\nline2","host":"host1","level":3,"short_message":"line1","version":"1.1"}`, b.String())
+
+	b.Reset()
+	f.Options(xlog.FormatWithCaller)
+	f.Format("", xlog.INFO, 1, "msg")
+	assert.Contains(t, b.String(), `"_file":"gelf_test.go","_func":"Test_Formatter","_line":`)
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+}
@@ -0,0 +1,184 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultChunkSize is the default size of UDP chunks
+	DefaultChunkSize = 1420
+	// maxChunks is the maximum number of chunks allowed by GELF
+	maxChunks = 128
+	// chunkHeaderSize is the size of the chunk header:
+	// magic bytes, message ID, sequence number and count
+	chunkHeaderSize = 12
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// UDPConfig specifies configuration for UDP writer
+type UDPConfig struct {
+	// ChunkSize specifies the maximum size of UDP datagram, DefaultChunkSize by default
+	ChunkSize int
+	// Compress specifies to gzip the messages
+	Compress bool
+}
+
+// UDPWriter sends GELF messages over UDP,
+// the messages larger than the chunk size are chunked
+type UDPWriter struct {
+	cfg  UDPConfig
+	lock sync.Mutex
+	conn net.Conn
+}
+
+// NewUDPWriter returns UDPWriter
+func NewUDPWriter(addr string, cfg UDPConfig) (*UDPWriter, error) {
+	if cfg.ChunkSize <= chunkHeaderSize {
+		cfg.ChunkSize = DefaultChunkSize
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to connect to GELF server: %s", addr)
+	}
+	return &UDPWriter{
+		cfg:  cfg,
+		conn: conn,
+	}, nil
+}
+
+// Write sends the message, each call must contain a single message
+func (w *UDPWriter) Write(b []byte) (int, error) {
+	msg := b
+	if w.cfg.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(b)
+		_ = zw.Close()
+		msg = buf.Bytes()
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(msg) <= w.cfg.ChunkSize {
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return len(b), nil
+	}
+
+	size := w.cfg.ChunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > maxChunks {
+		return 0, errors.Errorf("message is too large: %d bytes", len(b))
+	}
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	chunk := make([]byte, 0, w.cfg.ChunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = chunk[:0]
+		chunk = append(chunk, chunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*size:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	return len(b), nil
+}
+
+// Close the connection
+func (w *UDPWriter) Close() error {
+	return w.conn.Close()
+}
+
+// TCPWriter sends GELF messages over TCP,
+// the messages are delimited by null byte
+type TCPWriter struct {
+	addr    string
+	timeout time.Duration
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewTCPWriter returns TCPWriter
+func NewTCPWriter(addr string) (*TCPWriter, error) {
+	w := &TCPWriter{
+		addr:    addr,
+		timeout: 5 * time.Second,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *TCPWriter) connect() error {
+	if w.conn != nil {
+		_ = w.conn.Close()
+	}
+	conn, err := net.DialTimeout("tcp", w.addr, w.timeout)
+	if err != nil {
+		w.conn = nil
+		return errors.WithMessagef(err, "failed to connect to GELF server: %s", w.addr)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends the message, reconnecting once on failure,
+// each call must contain a single message
+func (w *TCPWriter) Write(b []byte) (int, error) {
+	msg := make([]byte, 0, len(b)+1)
+	msg = append(msg, b...)
+	msg = append(msg, 0)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, errors.New("GELF writer is closed")
+	}
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(b), nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return len(b), nil
+}
+
+// Close the connection
+func (w *TCPWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,110 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UDPWriter(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	read := func() []byte {
+		buf := make([]byte, 2048)
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		return buf[:n]
+	}
+
+	w, err := NewUDPWriter(pc.LocalAddr().String(), UDPConfig{ChunkSize: 100})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"short_message":"small"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"short_message":"small"}`, string(read()))
+
+	large := strings.Repeat("a", 200)
+	_, err = w.Write([]byte(large))
+	require.NoError(t, err)
+
+	var msg []byte
+	var id []byte
+	for i := 0; i < 3; i++ {
+		chunk := read()
+		assert.Equal(t, chunkMagic, chunk[:2])
+		if id == nil {
+			id = chunk[2:10]
+		}
+		assert.Equal(t, id, chunk[2:10])
+		assert.Equal(t, byte(i), chunk[10])
+		assert.Equal(t, byte(3), chunk[11])
+		msg = append(msg, chunk[12:]...)
+	}
+	assert.Equal(t, large, string(msg))
+
+	_, err = w.Write([]byte(strings.Repeat("a", 100*maxChunks)))
+	assert.EqualError(t, err, "message is too large: 12800 bytes")
+
+	wz, err := NewUDPWriter(pc.LocalAddr().String(), UDPConfig{Compress: true})
+	require.NoError(t, err)
+	defer wz.Close()
+
+	_, err = wz.Write([]byte(large))
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(read()))
+	require.NoError(t, err)
+	unzipped, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(unzipped))
+}
+
+func Test_TCPWriter(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			received <- msg
+		}
+	}()
+
+	w, err := NewTCPWriter(l.Addr().String())
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte(`{"short_message":"1"}`))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`{"short_message":"2"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"short_message\":\"1\"}\x00", <-received)
+	assert.Equal(t, "{\"short_message\":\"2\"}\x00", <-received)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("x"))
+	assert.EqualError(t, err, "GELF writer is closed")
+
+	_, err = NewTCPWriter("127.0.0.1:1")
+	assert.Error(t, err)
+}
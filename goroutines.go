@@ -0,0 +1,88 @@
+package xlog
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var goroutines = struct {
+	sync.Mutex
+	running map[string]int
+}{
+	running: make(map[string]int),
+}
+
+// TrackGoroutine registers a running background goroutine of a logging component,
+// and returns the function to be called when the goroutine stops.
+// This allows to verify that all logging goroutines stopped after shutdown.
+func TrackGoroutine(name string) (done func()) {
+	goroutines.Lock()
+	goroutines.running[name]++
+	goroutines.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			goroutines.Lock()
+			defer goroutines.Unlock()
+			if goroutines.running[name] <= 1 {
+				delete(goroutines.running, name)
+			} else {
+				goroutines.running[name]--
+			}
+		})
+	}
+}
+
+// RunningGoroutines returns the number of running background goroutines
+// of logging components, by name
+func RunningGoroutines() map[string]int {
+	goroutines.Lock()
+	defer goroutines.Unlock()
+
+	m := make(map[string]int, len(goroutines.running))
+	for k, v := range goroutines.running {
+		m[k] = v
+	}
+	return m
+}
+
+// CheckGoroutines returns an error if background goroutines
+// of logging components are still running
+func CheckGoroutines() error {
+	running := RunningGoroutines()
+	if len(running) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(running))
+	for k := range running {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return errors.Errorf("logging goroutines are still running: %s", strings.Join(names, ","))
+}
+
+// GoroutineID returns the ID of the current goroutine, parsed from the stack header,
+// or zero if the ID can not be parsed.
+// The ID is for debugging the logs only, and must not be used as goroutine local storage.
+func GoroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	b := buf[:n]
+	const prefix = "goroutine "
+	if len(b) < len(prefix) || string(b[:len(prefix)]) != prefix {
+		return 0
+	}
+	var id uint64
+	for _, c := range b[len(prefix):] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
@@ -0,0 +1,83 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TrackGoroutine(t *testing.T) {
+	assert.NoError(t, xlog.CheckGoroutines())
+
+	done1 := xlog.TrackGoroutine("test")
+	done2 := xlog.TrackGoroutine("test")
+	assert.Equal(t, map[string]int{"test": 2}, xlog.RunningGoroutines())
+	assert.EqualError(t, xlog.CheckGoroutines(), "logging goroutines are still running: test")
+
+	done1()
+	done1()
+	assert.Equal(t, map[string]int{"test": 1}, xlog.RunningGoroutines())
+	done2()
+	assert.Empty(t, xlog.RunningGoroutines())
+
+	w := xlog.NewMemoryWatchdog(xlog.MemoryWatchdogConfig{
+		Threshold: 1 << 50,
+		Interval:  time.Millisecond,
+	})
+	assert.False(t, w.Started())
+	w.Start()
+	assert.True(t, w.Started())
+	assert.Eventually(t, func() bool {
+		return xlog.RunningGoroutines()["xlog.MemoryWatchdog"] == 1
+	}, time.Second, time.Millisecond)
+	w.Stop()
+	assert.False(t, w.Started())
+	assert.NoError(t, xlog.CheckGoroutines())
+}
+
+func Test_GoroutineID(t *testing.T) {
+	id := xlog.GoroutineID()
+	assert.NotZero(t, id)
+	assert.Equal(t, id, xlog.GoroutineID())
+
+	other := make(chan uint64)
+	go func() {
+		other <- xlog.GoroutineID()
+	}()
+	assert.NotEqual(t, id, <-other)
+}
+
+func Test_FormatWithGoroutineID(t *testing.T) {
+	assert.Equal(t, "WithGoroutineID", xlog.FormatWithGoroutineID.String())
+	o, err := xlog.ParseFormatterOption("WithGoroutineID")
+	require.NoError(t, err)
+	assert.Equal(t, xlog.FormatWithGoroutineID, o)
+
+	var str, pretty, custom, js bytes.Buffer
+	xlog.SetFormatter(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&str),
+		xlog.NewPrettyFormatter(&pretty),
+		xlog.NewPrettyFormatterWithLayout(&custom, xlog.PrettyLayout{
+			Segments: []xlog.PrettySegment{xlog.SegmentPkg, xlog.SegmentGoroutine},
+		}),
+		xlog.NewJSONFormatter(&js),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithGoroutineID))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	goid := strconv.FormatUint(xlog.GoroutineID(), 10)
+	assert.Equal(t, "level=I goid="+goid+" pkg=xlog_test k=1\n", str.String())
+	assert.Equal(t, "I | goid="+goid+", pkg=xlog_test, k=1\n", pretty.String())
+	assert.Equal(t, "I | pkg=xlog_test, goid="+goid+", k=1\n", custom.String())
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(js.Bytes(), &m))
+	assert.Equal(t, float64(xlog.GoroutineID()), m[xlog.KeyGoroutineID])
+}
@@ -0,0 +1,96 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// GroupValue is the value of Group field, the key-value pairs of the group
+type GroupValue []any
+
+// Group returns the field with the key-value pairs grouped under the key,
+// logged as nested object by JSON formatter, and as "key.subkey=value" by the text formatters:
+//
+//	logger.KV(xlog.INFO, xlog.Group("http", "method", "GET", "status", 200))
+//	// {"http":{"method":"GET","status":200}}
+//
+// The empty group is not logged.
+func Group(key string, keysAndValues ...any) Field {
+	return Any(key, GroupValue(appendKV(make([]any, 0, len(keysAndValues)), "", keysAndValues)))
+}
+
+// MarshalJSON returns the group as JSON object, with the keys in order
+func (g GroupValue) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i := 0; i < len(g); i += 2 {
+		k, ok := g[i].(string)
+		if !ok {
+			continue
+		}
+		var v any
+		if i+1 < len(g) {
+			v = g[i+1]
+		}
+		if err, ok := v.(error); ok {
+			v = ErrorValue(err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(appendQuoted(nil, k))
+		buf.WriteByte(':')
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		// remove the new line added by the encoder
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// hasGroups returns true if any value of the key-value pairs is a group
+func hasGroups(kvList []any) bool {
+	for i := 0; i < len(kvList); {
+		var v any
+		if f, ok := kvList[i].(Field); ok {
+			// the typed field is a single entry
+			v = f.any
+			i++
+		} else {
+			if i+1 < len(kvList) {
+				v = kvList[i+1]
+			}
+			i += 2
+		}
+		if _, ok := v.(GroupValue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenGroups returns the key-value pairs with the groups expanded,
+// the keys of the group are prefixed with the group key
+func flattenGroups(prefix string, kvList []any) []any {
+	list := make([]any, 0, len(kvList))
+	for i := 0; i < len(kvList); {
+		var k, v any
+		k, v, i = kvAt(kvList, i)
+		if s, ok := k.(string); ok && prefix != "" {
+			k = prefix + "." + s
+		}
+		if g, ok := v.(GroupValue); ok {
+			if s, ok := k.(string); ok {
+				list = append(list, flattenGroups(s, g)...)
+				continue
+			}
+		}
+		list = append(list, k, v)
+	}
+	return list
+}
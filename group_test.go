@@ -0,0 +1,58 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Group(t *testing.T) {
+	var str, pretty, js bytes.Buffer
+	xlog.SetFormatter(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&str),
+		xlog.NewPrettyFormatter(&pretty),
+		xlog.NewJSONFormatter(&js),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO,
+		xlog.Group("http", "method", "GET", "status", 200, xlog.Group("client", "ip", "10.0.0.1")),
+		xlog.Group("empty"),
+		"k", 1)
+	assert.Equal(t, `level=I pkg=xlog_test http.method="GET" http.status=200 http.client.ip="10.0.0.1" k=1`+"\n", str.String())
+	assert.Equal(t, `I | pkg=xlog_test, http.method="GET", http.status=200, http.client.ip="10.0.0.1", k=1`+"\n", pretty.String())
+	assert.Equal(t, `{"http":{"client":{"ip":"10.0.0.1"},"method":"GET","status":200},"k":1,"level":"I","pkg":"xlog_test"}`+"\n", js.String())
+
+	// the prefix applies to the group key
+	str.Reset()
+	js.Reset()
+	logger.WithPrefix("req").KV(xlog.INFO, xlog.Group("http", "status", 200))
+	assert.Equal(t, `level=I pkg=xlog_test req.http.status=200`+"\n", str.String())
+	assert.Equal(t, `{"level":"I","pkg":"xlog_test","req.http":{"status":200}}`+"\n", js.String())
+}
+
+func Test_GroupTruncated(t *testing.T) {
+	var js bytes.Buffer
+	xlog.SetFormatter(xlog.ApplySettings(xlog.NewJSONFormatter(&js).Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{MaxValueLen: 3}))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, xlog.Group("g", "k", "abcdef"))
+	assert.Equal(t, `{"g":{"k":"abc..."},"level":"I","pkg":"xlog_test","truncated":true}`+"\n", js.String())
+}
+
+func Test_GroupValueJSON(t *testing.T) {
+	g := xlog.Group("g", "b", 1, "a", errors.New("failed"), xlog.Group("n", "s", "<x>")).Value()
+	b, err := json.Marshal(g)
+	require.NoError(t, err)
+	// json.Marshal escapes HTML
+	assert.Equal(t, `{"b":1,"a":"failed","n":{"s":"\u003cx\u003e"}}`, string(b))
+	assert.Equal(t, `{"b":1,"a":"failed","n":{"s":"<x>"}}`, xlog.EscapedString(g))
+	assert.Equal(t, `{}`, xlog.EscapedString(xlog.Group("e").Value()))
+}
@@ -0,0 +1,131 @@
+package xlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDropEntry can be returned by a Hook to drop the entry
+var ErrDropEntry = errors.New("drop entry")
+
+// Hook is invoked for each enabled entry before formatting.
+// Hook can modify the entry to redact or enrich the fields,
+// or return ErrDropEntry to drop the entry.
+// Other errors are reported to stderr, and the entry is logged.
+type Hook interface {
+	Fire(e *Entry) error
+}
+
+// HookFunc is an adapter to use a function as Hook
+type HookFunc func(e *Entry) error
+
+// Fire calls f(e)
+func (f HookFunc) Fire(e *Entry) error {
+	return f(e)
+}
+
+// registeredHook is the hook with the scope
+type registeredHook struct {
+	repo string
+	pkg  string
+	hook Hook
+}
+
+// matches returns true if the entry of the package is in the scope
+func (h *registeredHook) matches(repo, pkg string) bool {
+	return h.repo == "" || (h.repo == repo && (h.pkg == "" || h.pkg == pkg))
+}
+
+// AddHook adds the hook to the pipeline,
+// hooks are invoked in the order they are added
+func AddHook(h Hook) {
+	logger.addHook(&registeredHook{hook: h})
+}
+
+// AddRepoHook adds the hook invoked only for the entries of the packages in the repo,
+// and returns the function to remove the hook
+func AddRepoHook(repo string, h Hook) (remove func()) {
+	return AddPackageHook(repo, "*", h)
+}
+
+// AddPackageHook adds the hook invoked only for the entries of the package in the repo,
+// use "*" as pkg for all packages in the repo.
+// It returns the function to remove the hook.
+func AddPackageHook(repo, pkg string, h Hook) (remove func()) {
+	if pkg == "*" {
+		pkg = ""
+	}
+	rh := &registeredHook{repo: repo, pkg: pkg, hook: h}
+	logger.addHook(rh)
+	return func() {
+		logger.removeHook(rh)
+	}
+}
+
+func (l *loggerStruct) addHook(h *registeredHook) {
+	l.Lock()
+	defer l.Unlock()
+
+	var list []*registeredHook
+	if hooks := l.hooks.Load(); hooks != nil {
+		list = append(list, *hooks...)
+	}
+	list = append(list, h)
+	l.hooks.Store(&list)
+}
+
+func (l *loggerStruct) removeHook(h *registeredHook) {
+	l.Lock()
+	defer l.Unlock()
+
+	hooks := l.hooks.Load()
+	if hooks == nil {
+		return
+	}
+	var list []*registeredHook
+	for _, v := range *hooks {
+		if v != h {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		l.hooks.Store(nil)
+		return
+	}
+	l.hooks.Store(&list)
+}
+
+// ResetHooks removes all hooks
+func ResetHooks() {
+	logger.hooks.Store(nil)
+}
+
+// fireHooks invokes hooks for the entry of the package in the repo,
+// and returns false if the entry must be dropped
+func (l *loggerStruct) fireHooks(repo string, e *Entry, depth int) bool {
+	hooks := l.hooks.Load()
+	if hooks == nil {
+		return true
+	}
+
+	e.Caller, e.Source = callerInfo(depth + 1)
+	for _, h := range *hooks {
+		if !h.matches(repo, e.Pkg) {
+			continue
+		}
+		if err := h.hook.Fire(e); err != nil {
+			if errors.Is(err, ErrDropEntry) {
+				return false
+			}
+			fmt.Fprintf(os.Stderr, "xlog: hook failed: %v\n", err)
+		}
+	}
+	return true
+}
+
+func callerInfo(depth int) (string, string) {
+	caller, file, line := Caller(depth + 1)
+	return caller, fmt.Sprintf("%s:%d", file, line)
+}
@@ -0,0 +1,82 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Hooks(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetHooks()
+
+	var fired []*xlog.Entry
+	xlog.AddHook(xlog.HookFunc(func(e *xlog.Entry) error {
+		fired = append(fired, e)
+		for i := 0; i+1 < len(e.Fields); i += 2 {
+			if e.Fields[i] == "password" {
+				e.Fields[i+1] = "[REDACTED]"
+			}
+		}
+		return nil
+	}))
+	xlog.AddHook(xlog.HookFunc(func(e *xlog.Entry) error {
+		if e.Message == "drop" {
+			return ErrDrop
+		}
+		if e.Message == "fail" {
+			return errors.New("failed")
+		}
+		if strings.HasPrefix(e.Message, "enrich") {
+			e.Message = "enriched"
+			e.Fields = append(e.Fields, "host", "h1")
+		}
+		return nil
+	}))
+
+	l := logger.WithValues("ctx", 1).(xlog.ExtendedKeyValueLogger).WithTags("t1")
+	l.KV(xlog.INFO, "password", "secret", "k", 2)
+	assert.Equal(t, "level=I pkg=xlog_test ctx=1 password=\"[REDACTED]\" k=2 tags=[\"t1\"]\n", b.String())
+	require.Len(t, fired, 1)
+	assert.Equal(t, xlog.INFO, fired[0].Level)
+	assert.Equal(t, "xlog_test", fired[0].Pkg)
+	assert.Equal(t, "Test_Hooks", fired[0].Caller)
+	assert.Contains(t, fired[0].Source, "hooks_test.go:")
+	assert.Equal(t, xlog.Tags{"t1"}, fired[0].Tags)
+
+	b.Reset()
+	logger.Info("drop")
+	logger.Infof("dr%s", "op")
+	assert.Empty(t, b.String())
+
+	logger.Info("fail")
+	assert.Equal(t, "level=I pkg=xlog_test \"fail\"\n", b.String())
+
+	b.Reset()
+	logger.Info("enrich", 1)
+	assert.Equal(t, "level=I pkg=xlog_test \"host\" \"h1\" \"enriched\"\n", b.String())
+
+	b.Reset()
+	logger.Infof("enrich %d", 2)
+	assert.Equal(t, "level=I pkg=xlog_test \"host=\\\"h1\\\"\" [\"enriched\"]\n", b.String())
+
+	b.Reset()
+	logger.Debug("enrich")
+	assert.Empty(t, b.String())
+	assert.Len(t, fired, 6)
+
+	xlog.ResetHooks()
+	logger.Info("drop")
+	assert.Equal(t, "level=I pkg=xlog_test \"drop\"\n", b.String())
+	assert.Len(t, fired, 6)
+}
+
+// ErrDrop wraps xlog.ErrDropEntry
+var ErrDrop = errors.WithMessage(xlog.ErrDropEntry, "test")
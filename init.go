@@ -18,8 +18,10 @@
 package xlog
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -49,6 +51,15 @@ func init() {
 	case "NIL":
 		SetFormatter(NewNilFormatter())
 	}
+	if size, err := strconv.Atoi(os.Getenv("XLOG_BOOTSTRAP_BUFFER")); err == nil {
+		SetBootstrapBuffer(size)
+	}
+	filter, err := ParseFilter(os.Getenv("XLOG_INCLUDE"), os.Getenv("XLOG_EXCLUDE"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xlog: filter: %v\n", err)
+	} else if filter != nil {
+		AddHook(filter)
+	}
 }
 
 // NewDefaultFormatter returns an instance of default formatter
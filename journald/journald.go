@@ -0,0 +1,235 @@
+// Package journald provides the formatter, that sends entries to systemd journal
+// via the journald native protocol.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/syslog"
+	"github.com/pkg/errors"
+)
+
+// SocketPath is the default path of journald socket
+const SocketPath = "/run/systemd/journal/socket"
+
+// Config specifies configuration for journald formatter
+type Config struct {
+	// SocketPath specifies the path of journald socket, SocketPath by default
+	SocketPath string
+	// Identifier specifies SYSLOG_IDENTIFIER, the executable name by default
+	Identifier string
+}
+
+// formatter sends entries to journald
+type formatter struct {
+	conn       *net.UnixConn
+	addr       *net.UnixAddr
+	identifier string
+
+	lock       sync.Mutex
+	withCaller bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// Available returns true if journald socket exists at the path,
+// or at the default SocketPath, if path is empty
+func Available(path string) bool {
+	if path == "" {
+		path = SocketPath
+	}
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
+
+// NewFormatter returns the formatter, that sends entries to journald
+func NewFormatter(cfg Config) (xlog.Formatter, error) {
+	if cfg.SocketPath == "" {
+		cfg.SocketPath = SocketPath
+	}
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create journald socket")
+	}
+	return &formatter{
+		conn:       conn,
+		addr:       &net.UnixAddr{Name: cfg.SocketPath, Net: "unixgram"},
+		identifier: cfg.Identifier,
+		withCaller: true,
+	}, nil
+}
+
+// New returns journald formatter, if journald is available,
+// otherwise returns the fallback formatter, or the string formatter to stderr
+func New(cfg Config, fallback xlog.Formatter) xlog.Formatter {
+	if Available(cfg.SocketPath) {
+		if f, err := NewFormatter(cfg); err == nil {
+			return f
+		}
+	}
+	if fallback == nil {
+		fallback = xlog.NewStringFormatter(os.Stderr)
+	}
+	return fallback
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV sends the entry to journald,
+// the entries are key/value pairs, sent as custom fields
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format sends the entry to journald
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are sent immediately
+func (f *formatter) Flush() {}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	b := &f.buf
+	b.Reset()
+
+	writeField(b, "PRIORITY", strconv.Itoa(int(syslog.SeverityFor(l))))
+	writeField(b, "SYSLOG_IDENTIFIER", f.identifier)
+	if pkg != "" {
+		writeField(b, "PKG", pkg)
+	}
+	if f.withCaller {
+		caller, file, line := xlog.Caller(depth + 1)
+		writeField(b, "CODE_FILE", file)
+		writeField(b, "CODE_LINE", strconv.Itoa(line))
+		writeField(b, "CODE_FUNC", caller)
+	}
+
+	var text []string
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if k == "msg" && msg == "" {
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if val == "" && !f.printEmpty {
+			continue
+		}
+		name := FieldName(k)
+		if reserved[name] {
+			name = "F_" + name
+		}
+		writeField(b, name, val)
+		text = append(text, k+"="+xlog.EscapedString(v))
+	}
+	if msg == "" {
+		msg = strings.Join(text, " ")
+	}
+	writeField(b, "MESSAGE", strings.TrimSpace(msg))
+
+	_, _ = f.conn.WriteToUnix(b.Bytes(), f.addr)
+	xlog.ObserveEntrySize(pkg, b.Len())
+}
+
+// reserved are the fields set by the formatter
+var reserved = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"PKG":               true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// value returns the string value of the field
+func value(v any) string {
+	switch typ := v.(type) {
+	case string:
+		return typ
+	case error:
+		return fmt.Sprintf("%+v", typ)
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
+
+// writeField writes the field in the native protocol format,
+// values with new lines are written with the size prefix
+func writeField(b *bytes.Buffer, name, val string) {
+	b.WriteString(name)
+	if !strings.ContainsRune(val, '\n') {
+		b.WriteByte('=')
+		b.WriteString(val)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(val)))
+	b.WriteString(val)
+	b.WriteByte('\n')
+}
+
+// FieldName returns journald field name for the key:
+// upper case letters, digits and underscores,
+// not starting with underscore, up to 64 characters
+func FieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,73 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Formatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	assert.False(t, Available(path))
+
+	pc, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer pc.Close()
+	assert.True(t, Available(path))
+
+	f := New(Config{SocketPath: path, Identifier: "app"}, nil)
+	_, ok := f.(*formatter)
+	require.True(t, ok)
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	f.Options(xlog.FormatNoCaller)
+	f.FormatKV("pkg", xlog.WARNING, 1, "msg", "hello", "user-id", 1, "priority", "high", "nil", nil)
+	assert.Equal(t, "PRIORITY=4\nSYSLOG_IDENTIFIER=app\nPKG=pkg\nUSER_ID=1\nF_PRIORITY=high\nMESSAGE=hello\n", read())
+
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v", "n", 2)
+	assert.Equal(t, "PRIORITY=6\nSYSLOG_IDENTIFIER=app\nPKG=pkg\nK=v\nN=2\nMESSAGE=k=\"v\" n=2\n", read())
+
+	var exp bytes.Buffer
+	exp.WriteString("PRIORITY=3\nSYSLOG_IDENTIFIER=app\nPKG=pkg\nMESSAGE\n")
+	_ = binary.Write(&exp, binary.LittleEndian, uint64(11))
+	exp.WriteString("line1\nline2\n")
+	f.Format("pkg", xlog.ERROR, 1, "line1\nline2")
+	assert.Equal(t, exp.String(), read())
+
+	f.Options(xlog.FormatWithCaller)
+	f.Format("", xlog.DEBUG, 1, "msg")
+	assert.Contains(t, read(), "CODE_FILE=journald_test.go\nCODE_LINE=")
+	f.Flush()
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+}
+
+func Test_Fallback(t *testing.T) {
+	var b bytes.Buffer
+	fb := xlog.NewStringFormatter(&b)
+	assert.Equal(t, fb, New(Config{SocketPath: filepath.Join(t.TempDir(), "none")}, fb))
+	assert.IsType(t, &xlog.StringFormatter{}, New(Config{SocketPath: filepath.Join(t.TempDir(), "none")}, nil))
+}
+
+func Test_FieldName(t *testing.T) {
+	assert.Equal(t, "USER_ID", FieldName("user-id"))
+	assert.Equal(t, "KEY", FieldName("_key"))
+	assert.Equal(t, "F_1ST", FieldName("1st"))
+	assert.Equal(t, "F_", FieldName("__"))
+	assert.Len(t, FieldName(strings.Repeat("a", 100)), 64)
+}
@@ -16,16 +16,22 @@ package xlog
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // NewJSONFormatter returns an instance of JsonFormatter
 func NewJSONFormatter(w io.Writer) Formatter {
+	bw, size := newSizeWriter(w)
 	return &JSONFormatter{
-		w: bufio.NewWriter(w),
+		w:    bw,
+		size: size,
 		config: config{
 			withCaller:   true,
 			skipTime:     false,
@@ -38,7 +44,12 @@ func NewJSONFormatter(w io.Writer) Formatter {
 // JSONFormatter provides default logs format
 type JSONFormatter struct {
 	config
-	w *bufio.Writer
+	w    *bufio.Writer
+	size *sizeWriter
+}
+
+func (c *JSONFormatter) writesFields() bool {
+	return true
 }
 
 // Options allows to configure formatter behavior
@@ -47,10 +58,20 @@ func (c *JSONFormatter) Options(ops ...FormatterOption) Formatter {
 	return c
 }
 
+// Settings applies the non-zero settings
+func (c *JSONFormatter) Settings(settings FormatterSettings) Formatter {
+	c.config.settings(settings)
+	return c
+}
+
 // FormatKV log entry string to the stream,
 // the entries are key/value pairs
 func (c *JSONFormatter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
-	m := kvToMap(entries...)
+	if c.errorChain {
+		entries = ExpandErrorChains(expandFields(entries))
+	}
+	entries = c.duplicates.resolveDuplicateKeys(entries)
+	m := kvToMap(c.redactor(), truncateLen(c.maxValueLen, 0), entries...)
 	c.format(pkg, l, depth+1, false, m)
 }
 
@@ -61,15 +82,22 @@ func (c *JSONFormatter) Format(pkg string, l LogLevel, depth int, entries ...any
 
 // Format log entry string to the stream
 func (c *JSONFormatter) format(pkg string, l LogLevel, depth int, escape bool, kv map[string]any, entries ...any) {
+	c.size.begin(c.w, l)
 	if !c.skipTime {
 		now := TimeNowFn().UTC()
 		kv["time"] = now.Format(time.RFC3339)
 	}
+	if c.monotonic {
+		kv[KeyMonotonic] = int64(MonotonicNowFn())
+	}
 	if !c.skipLevel {
 		kv["level"] = l.Char()
 	}
-	if pkg != "" {
-		kv["pkg"] = pkg
+	if c.goroutineID {
+		kv[KeyGoroutineID] = GoroutineID()
+	}
+	if key := c.pkgField(); pkg != "" && key != "" {
+		kv[key] = c.pkgName(pkg, depth+1)
 	}
 
 	if l == ERROR || c.withLocation || c.withCaller {
@@ -82,44 +110,144 @@ func (c *JSONFormatter) format(pkg string, l LogLevel, depth int, escape bool, k
 		}
 	}
 
+	if c.stackTrace && l <= ERROR {
+		kv[KeyStack] = StackTrace(depth + 1)
+	}
+
 	if len(entries) > 0 {
 		msg := fmt.Sprint(entries...)
-		if len(msg) > 1024 {
-			msg = msg[:1024] + "...\""
+		if n := truncateLen(c.maxMessageLen, DefaultMaxLen); n > 0 && len(msg) > n && !IsCompressed(msg) {
+			msg = TruncateString(msg, n)
+			kv[KeyTruncated] = true
 		}
 		kv["msg"] = msg
 	}
 
-	encoder := json.NewEncoder(c.w)
-	encoder.SetEscapeHTML(false)
-	_ = encoder.Encode(kv)
+	if c.orderedKeys {
+		if b, err := orderedJSON(kv, c.headKeys()); err == nil {
+			_, _ = c.w.Write(b)
+		}
+	} else {
+		encoder := json.NewEncoder(c.w)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(kv)
+	}
 
-	c.Flush()
+	ObserveEntrySize(pkg, c.size.end(c.w))
 }
 
 // Flush the logs
 func (c *JSONFormatter) Flush() {
 	c.w.Flush()
+	c.size.Flush()
+}
+
+func (c *JSONFormatter) writer() (*bufio.Writer, *sizeWriter) {
+	return c.w, c.size
+}
+
+// headKeys returns the keys printed first with FormatWithOrderedKeys
+func (c *JSONFormatter) headKeys() []string {
+	return []string{"time", KeyMonotonic, "level", KeyGoroutineID, c.pkgField(), "src", "func"}
+}
+
+// orderedJSON returns the map as JSON object with the new line,
+// the head keys are first, and the remaining keys are sorted
+func orderedJSON(kv map[string]any, head []string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	add := func(k string, v any) error {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(appendQuoted(nil, k))
+		buf.WriteByte(':')
+		if err := enc.Encode(v); err != nil {
+			return errors.WithStack(err)
+		}
+		// remove the new line added by the encoder
+		buf.Truncate(buf.Len() - 1)
+		return nil
+	}
+
+	buf.WriteByte('{')
+	isHead := make(map[string]bool, len(head))
+	for _, k := range head {
+		v, ok := kv[k]
+		if k == "" || isHead[k] || !ok {
+			continue
+		}
+		isHead[k] = true
+		if err := add(k, v); err != nil {
+			return nil, err
+		}
+	}
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		if !isHead[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := add(k, kv[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
 }
 
-func kvToMap(kvList ...any) map[string]any {
-	size := len(kvList)
+// kvToMap returns the map of the entries, the values longer than maxLen are truncated,
+// unless maxLen is zero: the strings are cut at the rune boundary,
+// and the objects and arrays are replaced with {"truncated":true,"len":N} summary
+func kvToMap(r *Redactor, maxLen int, kvList ...any) map[string]any {
 	m := make(map[string]any)
+	if appendToMap(m, r, maxLen, kvList) {
+		m[KeyTruncated] = true
+	}
+	return m
+}
 
-	for i := 0; i < size; i += 2 {
-		k, ok := kvList[i].(string)
+// appendToMap adds the key-value pairs to the map, with the groups as nested maps,
+// and returns true if any value is truncated
+func appendToMap(m map[string]any, r *Redactor, maxLen int, kvList []any) (truncated bool) {
+	for i := 0; i < len(kvList); {
+		key, val, next := kvAt(kvList, i)
+		i = next
+		k, ok := key.(string)
 		if !ok {
-			panic(fmt.Sprintf("key is not a string: %v", EscapedString(kvList[i])))
-		}
-		var v any
-		if i+1 < size {
-			v = kvList[i+1]
+			panic(fmt.Sprintf("key is not a string: %v", EscapedString(key)))
 		}
+		v := r.Redact(k, val)
 		switch typ := v.(type) {
 		case error:
-			v = fmt.Sprintf("%+v", typ)
+			v = ErrorValue(typ)
+		case string:
+			if maxLen > 0 && len(typ) > maxLen && !IsCompressed(typ) {
+				v = TruncateString(typ, maxLen)
+				truncated = true
+			}
+		case GroupValue:
+			if len(typ) == 0 {
+				continue
+			}
+			group := make(map[string]any, len(typ)/2)
+			if appendToMap(group, r, maxLen, typ) {
+				truncated = true
+			}
+			v = group
+		default:
+			if maxLen > 0 && v != nil {
+				if s := EscapedString(v); len(s) > maxLen && (s[0] == '{' || s[0] == '[') {
+					v = truncatedValue{Truncated: true, Len: len(s)}
+					truncated = true
+				}
+			}
 		}
 		m[k] = v
 	}
-	return m
+	return truncated
 }
@@ -0,0 +1,63 @@
+// Package franz provides kafka.Producer, that sends the messages
+// with franz-go client.
+//
+// The package is a separate module, so the core module does not depend on the Kafka client.
+package franz
+
+import (
+	"context"
+
+	"github.com/effective-security/xlog/kafka"
+	"github.com/pkg/errors"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Producer sends the messages with franz-go client
+type Producer struct {
+	client *kgo.Client
+}
+
+var _ kafka.Producer = (*Producer)(nil)
+
+// New returns Producer,
+// the client is owned by the caller and must be closed after the writer
+//
+//	client, err := kgo.NewClient(kgo.SeedBrokers("kafka:9092"))
+//	...
+//	w := kafka.NewWriter(kafka.Config{Producer: franz.New(client), Topic: "logs"})
+func New(client *kgo.Client) *Producer {
+	return &Producer{client: client}
+}
+
+// Produce sends the messages and waits for the acknowledgement,
+// the error is returned if any of the messages failed
+func (p *Producer) Produce(ctx context.Context, msgs []kafka.Message) error {
+	records := make([]*kgo.Record, len(msgs))
+	for i, m := range msgs {
+		records[i] = ToRecord(m)
+	}
+
+	var failed int
+	var firstErr error
+	for _, res := range p.client.ProduceSync(ctx, records...) {
+		if res.Err != nil {
+			if firstErr == nil {
+				firstErr = res.Err
+			}
+			failed++
+		}
+	}
+	if firstErr != nil {
+		return errors.WithMessagef(firstErr, "failed to produce %d of %d messages", failed, len(msgs))
+	}
+	return nil
+}
+
+// ToRecord returns the record of the message
+func ToRecord(m kafka.Message) *kgo.Record {
+	return &kgo.Record{
+		Topic: m.Topic,
+		Key:   m.Key,
+		Value: m.Value,
+	}
+}
@@ -0,0 +1,242 @@
+package franz
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/kversion"
+)
+
+// broker is the fake single node Kafka, that keeps the produced messages
+type broker struct {
+	lis  net.Listener
+	host string
+	port int32
+
+	lock      sync.Mutex
+	messages  []kafka.Message
+	errorCode int16
+}
+
+func newBroker(t *testing.T) *broker {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	b := &broker{lis: lis, host: host, port: int32(p)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// setError sets the error code of the next produce responses
+func (b *broker) setError(code int16) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.errorCode = code
+}
+
+func (b *broker) produced() []kafka.Message {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]kafka.Message(nil), b.messages...)
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size int32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		// header v1: key, version, correlation ID, client ID
+		key := int16(binary.BigEndian.Uint16(body))
+		version := int16(binary.BigEndian.Uint16(body[2:]))
+		corr := binary.BigEndian.Uint32(body[4:])
+		clientLen := int16(binary.BigEndian.Uint16(body[8:]))
+		offset := 10
+		if clientLen > 0 {
+			offset += int(clientLen)
+		}
+
+		req := kmsg.RequestForKey(key)
+		if req == nil {
+			return
+		}
+		req.SetVersion(version)
+		if err := req.ReadFrom(body[offset:]); err != nil {
+			return
+		}
+		resp := b.handle(req)
+		if resp == nil {
+			// acks=0 produce has no response
+			continue
+		}
+		resp.SetVersion(version)
+
+		out := binary.BigEndian.AppendUint32(make([]byte, 4), corr)
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func (b *broker) handle(req kmsg.Request) kmsg.Response {
+	switch req := req.(type) {
+	case *kmsg.ApiVersionsRequest:
+		resp := req.ResponseKind().(*kmsg.ApiVersionsResponse)
+		for _, key := range []int16{0, 3, 18} {
+			maxVersion := kmsg.RequestForKey(key).MaxVersion()
+			if v, ok := kversion.V2_0_0().LookupMaxKeyVersion(key); ok {
+				maxVersion = v
+			}
+			resp.ApiKeys = append(resp.ApiKeys, kmsg.ApiVersionsResponseApiKey{ApiKey: key, MaxVersion: maxVersion})
+		}
+		return resp
+	case *kmsg.MetadataRequest:
+		resp := req.ResponseKind().(*kmsg.MetadataResponse)
+		resp.Brokers = []kmsg.MetadataResponseBroker{{NodeID: 0, Host: b.host, Port: b.port}}
+		for _, rt := range req.Topics {
+			partition := kmsg.NewMetadataResponseTopicPartition()
+			partition.Replicas = []int32{0}
+			partition.ISR = []int32{0}
+			topic := kmsg.NewMetadataResponseTopic()
+			topic.Topic = rt.Topic
+			topic.Partitions = []kmsg.MetadataResponseTopicPartition{partition}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		return resp
+	case *kmsg.ProduceRequest:
+		resp := req.ResponseKind().(*kmsg.ProduceResponse)
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		for _, rt := range req.Topics {
+			topic := kmsg.ProduceResponseTopic{Topic: rt.Topic}
+			for _, rp := range rt.Partitions {
+				if b.errorCode == 0 {
+					b.messages = append(b.messages, readRecords(rt.Topic, rp.Records)...)
+				}
+				topic.Partitions = append(topic.Partitions, kmsg.ProduceResponseTopicPartition{
+					Partition: rp.Partition,
+					ErrorCode: b.errorCode,
+				})
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		if req.Acks == 0 {
+			return nil
+		}
+		return resp
+	}
+	return nil
+}
+
+// readRecords returns the messages of the uncompressed record batches
+func readRecords(topic string, src []byte) []kafka.Message {
+	var list []kafka.Message
+	for len(src) > 0 {
+		var batch kmsg.RecordBatch
+		if err := batch.ReadFrom(src); err != nil {
+			return list
+		}
+		src = src[12+int(batch.Length):]
+
+		records := batch.Records
+		for i := int32(0); i < batch.NumRecords; i++ {
+			var r kmsg.Record
+			if err := r.ReadFrom(records); err != nil {
+				return list
+			}
+			_, n := binary.Varint(records)
+			records = records[n+int(r.Length):]
+			list = append(list, kafka.Message{Topic: topic, Key: r.Key, Value: r.Value})
+		}
+	}
+	return list
+}
+
+func newClient(t *testing.T, b *broker) *kgo.Client {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(b.lis.Addr().String()),
+		kgo.MaxVersions(kversion.V2_0_0()),
+		kgo.DisableIdempotentWrite(),
+		kgo.RequiredAcks(kgo.LeaderAck()),
+		kgo.ProducerBatchCompression(kgo.NoCompression()),
+		kgo.RecordRetries(1),
+	)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestProducer(t *testing.T) {
+	b := newBroker(t)
+	p := New(newClient(t, b))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msgs := []kafka.Message{
+		{Topic: "logs", Key: []byte("api"), Value: []byte(`{"msg":"one"}`)},
+		{Topic: "logs", Value: []byte(`{"msg":"two"}`)},
+	}
+	require.NoError(t, p.Produce(ctx, msgs))
+	assert.Equal(t, msgs, b.produced())
+
+	b.setError(kerr.MessageTooLarge.Code)
+	err := p.Produce(ctx, msgs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to produce 2 of 2 messages")
+	assert.ErrorIs(t, err, kerr.MessageTooLarge)
+	assert.Len(t, b.produced(), 2)
+}
+
+func TestWriter(t *testing.T) {
+	b := newBroker(t)
+	w := kafka.NewWriter(kafka.Config{
+		Producer: New(newClient(t, b)),
+		Topic:    "logs",
+		KeyField: "pkg",
+	})
+
+	_, err := w.Write([]byte(`{"pkg":"api","msg":"hello"}` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []kafka.Message{
+		{Topic: "logs", Key: []byte("api"), Value: []byte(`{"pkg":"api","msg":"hello"}`)},
+	}, b.produced())
+}
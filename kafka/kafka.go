@@ -0,0 +1,242 @@
+// Package kafka provides the writer, that batches formatted JSON entries
+// into a Kafka topic.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// ErrorPkg is the package reported to xlog.OnError callback on delivery errors
+const ErrorPkg = "kafka"
+
+// Message is Kafka message
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer sends the messages to Kafka,
+// an adapter over Kafka client, such as franz.Producer
+// in the github.com/effective-security/xlog/kafka/franz module
+type Producer interface {
+	Produce(ctx context.Context, msgs []Message) error
+}
+
+// Config specifies configuration for Writer
+type Config struct {
+	Producer Producer
+	// Topic of the messages
+	Topic string
+	// KeyField specifies the field of JSON entry used as the message key,
+	// such as "pkg" or a key-value entry. If empty, the messages have no key.
+	KeyField string
+	// BatchSize specifies the maximum number of messages in a batch, 100 by default
+	BatchSize int
+	// BatchBytes specifies the maximum size of a batch, 1MB by default
+	BatchBytes int
+	// FlushInterval specifies the interval to send batched messages,
+	// 1 second by default
+	FlushInterval time.Duration
+	// QueueSize specifies the number of entries queued for the producer,
+	// Write blocks when the queue is full, 1000 by default
+	QueueSize int
+	// OnError is called when the messages failed to deliver,
+	// in addition to xlog.OnError callback with ErrorPkg
+	OnError func(err error, msgs []Message)
+}
+
+// Writer is io.Writer, that batches entries for Kafka producer asynchronously,
+// each Write call is a single entry.
+// The batch is sent when it is full, on the flush interval,
+// or on Flush and Close calls.
+type Writer struct {
+	cfg Config
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan []byte
+	flush  chan chan struct{}
+	done   chan struct{}
+
+	failed atomic.Uint64
+}
+
+// NewWriter returns Writer
+func NewWriter(cfg Config) *Writer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = 1024 * 1024
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	w := &Writer{
+		cfg:   cfg,
+		queue: make(chan []byte, cfg.QueueSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues the entry, the trailing newline is removed
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return 0, errors.New("kafka writer is closed")
+	}
+	entry := bytes.TrimRight(p, "\n")
+	if len(entry) > 0 {
+		w.queue <- append([]byte{}, entry...)
+	}
+	return len(p), nil
+}
+
+// Flush sends the queued entries, and waits for the delivery
+func (w *Writer) Flush(ctx context.Context) error {
+	w.lock.RLock()
+	closed := w.closed
+	w.lock.RUnlock()
+	if closed {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case w.flush <- flushed:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, and drains the queue
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// Failed returns the number of messages failed to deliver
+func (w *Writer) Failed() uint64 {
+	return w.failed.Load()
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Message
+	size := 0
+	send := func() {
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = nil
+			size = 0
+		}
+	}
+	add := func(entry []byte) {
+		batch = append(batch, Message{
+			Topic: w.cfg.Topic,
+			Key:   w.key(entry),
+			Value: entry,
+		})
+		size += len(entry)
+		if len(batch) >= w.cfg.BatchSize || size >= w.cfg.BatchBytes {
+			send()
+		}
+	}
+
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				send()
+				return
+			}
+			add(entry)
+		case <-ticker.C:
+			send()
+		case flushed := <-w.flush:
+			// drain the entries queued before the flush
+			for n := len(w.queue); n > 0; n-- {
+				entry, ok := <-w.queue
+				if !ok {
+					break
+				}
+				add(entry)
+			}
+			send()
+			close(flushed)
+		}
+	}
+}
+
+// send the batch, and reports delivery errors
+func (w *Writer) send(batch []Message) {
+	err := w.cfg.Producer.Produce(context.Background(), batch)
+	if err == nil {
+		return
+	}
+	w.failed.Add(uint64(len(batch)))
+	xlog.ReportError(ErrorPkg)
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(err, batch)
+	} else {
+		fmt.Fprintf(os.Stderr, "xlog: kafka: failed to deliver %d messages: %v\n", len(batch), err)
+	}
+}
+
+// key returns the message key from the JSON entry
+func (w *Writer) key(entry []byte) []byte {
+	if w.cfg.KeyField == "" {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(entry, &m); err != nil {
+		return nil
+	}
+	raw, ok := m[w.cfg.KeyField]
+	if !ok {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []byte(s)
+	}
+	return raw
+}
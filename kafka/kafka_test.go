@@ -0,0 +1,123 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockProducer struct {
+	lock    sync.Mutex
+	batches [][]Message
+	err     error
+}
+
+func (p *mockProducer) Produce(_ context.Context, msgs []Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.batches = append(p.batches, msgs)
+	return p.err
+}
+
+func (p *mockProducer) Batches() [][]Message {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([][]Message{}, p.batches...)
+}
+
+func Test_Writer(t *testing.T) {
+	p := &mockProducer{}
+	w := NewWriter(Config{
+		Producer:      p,
+		Topic:         "logs",
+		KeyField:      "pkg",
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+
+	for _, entry := range []string{
+		`{"pkg":"a","msg":"1"}` + "\n",
+		`{"pkg":"b","msg":"2"}` + "\n",
+		`{"msg":"3"}` + "\n",
+		`{"pkg":"a","msg":"4"}` + "\n",
+		"\n",
+	} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Flush(context.Background()))
+
+	batches := p.Batches()
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 3)
+	assert.Equal(t, Message{Topic: "logs", Key: []byte("a"), Value: []byte(`{"pkg":"a","msg":"1"}`)}, batches[0][0])
+	assert.Equal(t, []byte("b"), batches[0][1].Key)
+	assert.Nil(t, batches[0][2].Key)
+	require.Len(t, batches[1], 1)
+	assert.Equal(t, `{"pkg":"a","msg":"4"}`, string(batches[1][0].Value))
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Flush(context.Background()))
+	_, err := w.Write([]byte("x"))
+	assert.EqualError(t, err, "kafka writer is closed")
+}
+
+func Test_WriterErrors(t *testing.T) {
+	var reported []string
+	xlog.OnError(func(pkg string) {
+		reported = append(reported, pkg)
+	})
+	defer xlog.OnError(nil)
+
+	var failed []Message
+	p := &mockProducer{err: errors.New("broker unavailable")}
+	w := NewWriter(Config{
+		Producer:      p,
+		Topic:         "logs",
+		KeyField:      "shard",
+		FlushInterval: time.Hour,
+		OnError: func(err error, msgs []Message) {
+			assert.EqualError(t, err, "broker unavailable")
+			failed = append(failed, msgs...)
+		},
+	})
+
+	_, err := w.Write([]byte(`{"shard":7}`))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`not json`))
+	require.NoError(t, err)
+	// Close drains the queue
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, uint64(2), w.Failed())
+	require.Len(t, failed, 2)
+	assert.Equal(t, []byte("7"), failed[0].Key)
+	assert.Nil(t, failed[1].Key)
+	assert.Equal(t, []string{ErrorPkg}, reported)
+}
+
+func Test_WriterInterval(t *testing.T) {
+	p := &mockProducer{}
+	w := NewWriter(Config{
+		Producer:      p,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer w.Close()
+
+	_, err := w.Write([]byte(`{"msg":"1"}`))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(p.Batches()) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, w.Flush(ctx))
+}
@@ -0,0 +1,55 @@
+package xlog
+
+// LazyValue is the value evaluated only when the entry is emitted,
+// after the level and enablers checks
+type LazyValue func() any
+
+// Lazy returns the value to be evaluated only when the entry is emitted,
+// so costly serialization does not run for the disabled levels:
+//
+//	logger.KV(xlog.DEBUG, "state", xlog.Lazy(func() any { return dump(state) }))
+func Lazy(fn func() any) LazyValue {
+	return LazyValue(fn)
+}
+
+// resolveLazy returns the entries with the lazy values evaluated,
+// or the same entries if there are no lazy values
+func resolveLazy(entries []any) []any {
+	for i, e := range entries {
+		if !isLazy(e) {
+			continue
+		}
+		res := make([]any, len(entries))
+		copy(res, entries[:i])
+		for j := i; j < len(entries); j++ {
+			res[j] = resolveValue(entries[j])
+		}
+		return res
+	}
+	return entries
+}
+
+// isLazy returns true if the entry is the lazy value, or the field with the lazy value
+func isLazy(e any) bool {
+	if f, ok := e.(Field); ok {
+		e = f.any
+	}
+	_, ok := e.(LazyValue)
+	return ok
+}
+
+// resolveValue returns the entry with the lazy value evaluated
+func resolveValue(e any) any {
+	switch typ := e.(type) {
+	case LazyValue:
+		if typ == nil {
+			return nil
+		}
+		return typ()
+	case Field:
+		if fn, ok := typ.any.(LazyValue); ok {
+			return Any(typ.Key, resolveValue(fn))
+		}
+	}
+	return e
+}
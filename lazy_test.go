@@ -0,0 +1,43 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lazy(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	calls := 0
+	lazy := xlog.Lazy(func() any {
+		calls++
+		return "dump"
+	})
+
+	logger.KV(xlog.DEBUG, "state", lazy)
+	logger.Debug(lazy)
+	logger.Debugf("state: %v", lazy)
+	logger.WithValues("state", lazy).KV(xlog.DEBUG, "k", 1)
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, b.String())
+
+	logger.KV(xlog.INFO, "state", lazy, xlog.Any("field", lazy))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "level=I pkg=xlog_test state=\"dump\" field=\"dump\"\n", b.String())
+
+	b.Reset()
+	logger.Info("state: ", lazy)
+	logger.Infof("state: %v", lazy)
+	logger.WithValues("state", lazy).KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "nil", xlog.LazyValue(nil))
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, "level=I pkg=xlog_test \"state:\" \"dump\"\n"+
+		"level=I pkg=xlog_test \"state: dump\"\n"+
+		"level=I pkg=xlog_test state=\"dump\" k=1\n"+
+		"level=I pkg=xlog_test \n", b.String())
+}
@@ -0,0 +1,70 @@
+package xlog
+
+import "sync/atomic"
+
+// LevelFilter is a formatter wrapper, that drops entries
+// above its minimum level. It is evaluated after the package level check,
+// so with NewMultiFormatter each sink can have its own verbosity,
+// for example console WARNING and above, file DEBUG and above.
+// The package level must allow the most verbose sink.
+type LevelFilter struct {
+	inner Formatter
+	level atomic.Int32
+}
+
+// NewLevelFilter returns LevelFilter,
+// forwarding entries at the level and more severe to the inner formatter
+func NewLevelFilter(inner Formatter, level LogLevel) *LevelFilter {
+	f := &LevelFilter{
+		inner: inner,
+	}
+	f.level.Store(int32(level))
+	return f
+}
+
+// SetLevel changes the minimum level of the sink
+func (f *LevelFilter) SetLevel(level LogLevel) {
+	f.level.Store(int32(level))
+}
+
+// Level returns the minimum level of the sink
+func (f *LevelFilter) Level() LogLevel {
+	return LogLevel(f.level.Load())
+}
+
+// Enabled returns true if the entries at the level are forwarded
+func (f *LevelFilter) Enabled(l LogLevel) bool {
+	return l == CRITICAL || int32(l) <= f.level.Load()
+}
+
+// Options allows to configure formatter behavior
+func (f *LevelFilter) Options(ops ...FormatterOption) Formatter {
+	f.inner.Options(ops...)
+	return f
+}
+
+// Settings applies the settings to the inner formatter
+func (f *LevelFilter) Settings(settings FormatterSettings) Formatter {
+	ApplySettings(f.inner, settings)
+	return f
+}
+
+// Format log entry string to the stream
+func (f *LevelFilter) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	if f.Enabled(l) {
+		f.inner.Format(pkg, l, depth+1, entries...)
+	}
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (f *LevelFilter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	if f.Enabled(l) {
+		f.inner.FormatKV(pkg, l, depth+1, entries...)
+	}
+}
+
+// Flush the logs
+func (f *LevelFilter) Flush() {
+	f.inner.Flush()
+}
@@ -0,0 +1,49 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LevelFilter(t *testing.T) {
+	var console, file, remote bytes.Buffer
+
+	consoleSink := xlog.NewLevelFilter(xlog.NewStringFormatter(&console), xlog.WARNING)
+	f := xlog.NewMultiFormatter(
+		consoleSink,
+		xlog.NewLevelFilter(xlog.NewStringFormatter(&file), xlog.DEBUG),
+		xlog.NewLevelFilter(xlog.NewStringFormatter(&remote), xlog.INFO),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime)
+	xlog.SetFormatter(f)
+	xlog.SetGlobalLogLevel(xlog.DEBUG)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.DEBUG, "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.KV(xlog.WARNING, "k", 3)
+	logger.Info("msg")
+	f.Flush()
+
+	assert.Equal(t, "level=W pkg=xlog_test k=3\n", console.String())
+	assert.Equal(t, "level=D pkg=xlog_test k=1\nlevel=I pkg=xlog_test k=2\nlevel=W pkg=xlog_test k=3\nlevel=I pkg=xlog_test \"msg\"\n", file.String())
+	assert.Equal(t, "level=I pkg=xlog_test k=2\nlevel=W pkg=xlog_test k=3\nlevel=I pkg=xlog_test \"msg\"\n", remote.String())
+
+	console.Reset()
+	consoleSink.SetLevel(xlog.ERROR)
+	assert.Equal(t, xlog.ERROR, consoleSink.Level())
+	assert.False(t, consoleSink.Enabled(xlog.WARNING))
+	assert.True(t, consoleSink.Enabled(xlog.CRITICAL))
+
+	logger.KV(xlog.WARNING, "k", 4)
+	logger.KV(xlog.ERROR, "k", 5)
+	assert.Equal(t, "level=E pkg=xlog_test k=5\n", console.String())
+
+	// package level is evaluated first
+	file.Reset()
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	logger.KV(xlog.DEBUG, "k", 6)
+	assert.Empty(t, file.String())
+}
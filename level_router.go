@@ -0,0 +1,85 @@
+package xlog
+
+// LevelRouter dispatches log entries to formatters by level
+type LevelRouter struct {
+	routes     map[LogLevel]Formatter
+	def        Formatter
+	formatters []Formatter
+}
+
+// NewLevelRouter returns an instance of Formatter,
+// that dispatches log entries to formatters by level,
+// for example WARNING and above to stderr, and INFO and below to a file.
+// Entries with levels not in routes are sent to the default formatter,
+// if def is nil, such entries are dropped.
+func NewLevelRouter(routes map[LogLevel]Formatter, def Formatter) Formatter {
+	r := &LevelRouter{
+		routes: make(map[LogLevel]Formatter, len(routes)),
+		def:    def,
+	}
+	for l, f := range routes {
+		r.routes[l] = f
+		r.add(f)
+	}
+	r.add(def)
+	return r
+}
+
+// add appends a unique formatter to the list of formatters
+func (r *LevelRouter) add(f Formatter) {
+	if f == nil {
+		return
+	}
+	for _, existing := range r.formatters {
+		if existing == f {
+			return
+		}
+	}
+	r.formatters = append(r.formatters, f)
+}
+
+func (r *LevelRouter) route(l LogLevel) Formatter {
+	if f, ok := r.routes[l]; ok {
+		return f
+	}
+	return r.def
+}
+
+// Options allows to configure formatter behavior,
+// the options are applied to all formatters
+func (r *LevelRouter) Options(ops ...FormatterOption) Formatter {
+	for _, f := range r.formatters {
+		f.Options(ops...)
+	}
+	return r
+}
+
+// Settings applies the settings to the formatters
+func (r *LevelRouter) Settings(settings FormatterSettings) Formatter {
+	for _, f := range r.formatters {
+		ApplySettings(f, settings)
+	}
+	return r
+}
+
+// Format log entry string to the stream
+func (r *LevelRouter) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	if f := r.route(l); f != nil {
+		f.Format(pkg, l, depth+1, entries...)
+	}
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (r *LevelRouter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	if f := r.route(l); f != nil {
+		f.FormatKV(pkg, l, depth+1, entries...)
+	}
+}
+
+// Flush the logs
+func (r *LevelRouter) Flush() {
+	for _, f := range r.formatters {
+		f.Flush()
+	}
+}
@@ -0,0 +1,45 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LevelRouter(t *testing.T) {
+	var stderr, file bytes.Buffer
+
+	errs := xlog.NewStringFormatter(&stderr)
+	f := xlog.NewLevelRouter(map[xlog.LogLevel]xlog.Formatter{
+		xlog.CRITICAL: errs,
+		xlog.ERROR:    errs,
+		xlog.WARNING:  errs,
+	}, xlog.NewStringFormatter(&file)).Options(xlog.FormatSkipTime)
+	xlog.SetFormatter(f)
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.WARNING, "k", 1)
+	logger.Error("err")
+	logger.KV(xlog.INFO, "k", 2)
+	logger.Info("info")
+	f.Flush()
+
+	assert.Equal(t, "level=W pkg=xlog_test func=Test_LevelRouter k=1\n"+
+		"level=E pkg=xlog_test func=Test_LevelRouter \"err\"\n", stderr.String())
+	assert.Equal(t, "level=I pkg=xlog_test func=Test_LevelRouter k=2\n"+
+		"level=I pkg=xlog_test func=Test_LevelRouter \"info\"\n", file.String())
+
+	stderr.Reset()
+	file.Reset()
+
+	f = xlog.NewLevelRouter(map[xlog.LogLevel]xlog.Formatter{
+		xlog.ERROR: errs,
+	}, nil)
+	xlog.SetFormatter(f)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.Info("info")
+	assert.Empty(t, stderr.String())
+	assert.Empty(t, file.String())
+}
@@ -0,0 +1,36 @@
+package xlog
+
+import "io"
+
+// LevelWriter is implemented by the writers, that route the formatted entries by level,
+// such as ERROR entries to a separate file.
+// The formatters writing directly to LevelWriter call WriteLevel with the level of the entry,
+// the entries with different levels are never written in one call.
+type LevelWriter interface {
+	io.Writer
+	// WriteLevel writes the formatted entry with the level
+	WriteLevel(level LogLevel, p []byte) (int, error)
+}
+
+// LevelWriterFunc returns LevelWriter, that writes the entries to the writer returned by route
+func LevelWriterFunc(route func(level LogLevel) io.Writer, def io.Writer) LevelWriter {
+	return &levelWriterFunc{route: route, def: def}
+}
+
+type levelWriterFunc struct {
+	route func(level LogLevel) io.Writer
+	def   io.Writer
+}
+
+// Write writes the data without the level to the default writer
+func (w *levelWriterFunc) Write(p []byte) (int, error) {
+	return w.def.Write(p)
+}
+
+// WriteLevel writes the entry to the writer for the level
+func (w *levelWriterFunc) WriteLevel(level LogLevel, p []byte) (int, error) {
+	if dest := w.route(level); dest != nil {
+		return dest.Write(p)
+	}
+	return w.def.Write(p)
+}
@@ -0,0 +1,66 @@
+package xlog_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+// levelCountingWriter counts the writes per level
+type levelCountingWriter struct {
+	bytes.Buffer
+	levels []xlog.LogLevel
+}
+
+func (w *levelCountingWriter) WriteLevel(level xlog.LogLevel, p []byte) (int, error) {
+	w.levels = append(w.levels, level)
+	return w.Write(p)
+}
+
+func Test_LevelWriter(t *testing.T) {
+	var main, errs bytes.Buffer
+	w := xlog.LevelWriterFunc(func(level xlog.LogLevel) io.Writer {
+		if level <= xlog.ERROR {
+			return &errs
+		}
+		return nil
+	}, &main)
+
+	xlog.SetFormatter(xlog.NewStringFormatter(w).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.ERROR, "k", 2)
+	logger.Log(xlog.CRITICAL, "down")
+	logger.Infof("k=%d", 3)
+
+	assert.Equal(t, "level=I pkg=xlog_test k=1\nlevel=I pkg=xlog_test \"k=3\"\n", main.String())
+	assert.Equal(t, "level=E pkg=xlog_test k=2\nlevel=C pkg=xlog_test \"down\"\n", errs.String())
+
+	_, _ = w.Write([]byte("raw\n"))
+	assert.Equal(t, "level=I pkg=xlog_test k=1\nlevel=I pkg=xlog_test \"k=3\"\nraw\n", main.String())
+}
+
+func Test_LevelWriterBatch(t *testing.T) {
+	w := &levelCountingWriter{}
+	xlog.SetFormatter(xlog.NewJSONFormatter(w).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	// the deferred entries with different levels are written separately
+	logger.Batch().
+		KV(xlog.INFO, "k", 1).
+		KV(xlog.INFO, "k", 2).
+		KV(xlog.WARNING, "k", 3).
+		KV(xlog.INFO, "k", 4).
+		Emit()
+
+	assert.Equal(t, []xlog.LogLevel{xlog.INFO, xlog.WARNING, xlog.INFO}, w.levels)
+	assert.Equal(t, `{"k":1,"level":"I","pkg":"xlog_test"}
+{"k":2,"level":"I","pkg":"xlog_test"}
+{"k":3,"level":"W","pkg":"xlog_test"}
+{"k":4,"level":"I","pkg":"xlog_test"}
+`, w.String())
+}
@@ -0,0 +1,95 @@
+package xlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LevelsResponse is the response of the levels handler
+type LevelsResponse struct {
+	// Levels are the current levels of the packages
+	Levels []RepoLogLevel `json:"levels"`
+}
+
+// LevelsRequest is the request to change the levels
+type LevelsRequest struct {
+	// Levels to set, use "*" as Package to set the level for the repo,
+	// and "*" as Repo to set the global level
+	Levels []RepoLogLevel `json:"levels"`
+}
+
+// Handler returns http.Handler to manage the log levels at runtime:
+// GET returns LevelsResponse with the current levels,
+// PUT applies LevelsRequest, and returns the updated levels.
+// The handler must be protected by the caller, as it changes the logging of the process.
+func Handler() http.Handler {
+	return http.HandlerFunc(serveLevels)
+}
+
+func serveLevels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req LevelsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		levels, err := ValidateRepoLevels(req.Levels)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		SetRepoLevels(levels)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	levels := GetRepoLevels()
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].Repo != levels[j].Repo {
+			return levels[i].Repo < levels[j].Repo
+		}
+		return levels[i].Package < levels[j].Package
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(LevelsResponse{Levels: levels})
+}
+
+// ValidateRepoLevels returns the levels in canonical format,
+// or error if the level, repo or package is unknown,
+// so the request can be applied entirely or not at all
+func ValidateRepoLevels(list []RepoLogLevel) ([]RepoLogLevel, error) {
+	levels := make([]RepoLogLevel, 0, len(list))
+	for _, ll := range list {
+		l, err := ParseLevel(strings.ToUpper(ll.Level))
+		if err != nil {
+			return nil, err
+		}
+		ll.Level = l.String()
+
+		if ll.Repo != "*" {
+			repo, err := GetRepoLogger(ll.Repo)
+			if err != nil {
+				return nil, errors.Errorf("unknown repo: %q", ll.Repo)
+			}
+			if ll.Package != "" && ll.Package != "*" {
+				logger.Lock()
+				_, ok := repo[ll.Package]
+				logger.Unlock()
+				if !ok {
+					return nil, errors.Errorf("unknown package: %q in repo %q", ll.Package, ll.Repo)
+				}
+			}
+		}
+		levels = append(levels, ll)
+	}
+	return levels, nil
+}
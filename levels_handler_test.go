@@ -0,0 +1,83 @@
+package xlog_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LevelsHandler(t *testing.T) {
+	const repo = "github.com/effective-security/xlog"
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	server := httptest.NewServer(xlog.Handler())
+	defer server.Close()
+
+	get := func() map[string]string {
+		res, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+		var resp xlog.LevelsResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		levels := map[string]string{}
+		for _, ll := range resp.Levels {
+			levels[ll.Repo+"/"+ll.Package] = ll.Level
+		}
+		return levels
+	}
+	put := func(body string) (int, string) {
+		req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return res.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	assert.Equal(t, "INFO", get()[repo+"/xlog_test"])
+
+	status, body := put(`{"levels":[{"repo":"` + repo + `","package":"xlog_test","level":"debug"}]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `{"repo":"`+repo+`","package":"xlog_test","level":"DEBUG"}`)
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	tcases := []struct {
+		body string
+		err  string
+	}{
+		{`{`, "invalid request: unexpected EOF"},
+		{`{"levels":[{"repo":"*","level":"LOUD"}]}`, "unable to parse log level: LOUD"},
+		{`{"levels":[{"repo":"unknown","level":"INFO"}]}`, `unknown repo: "unknown"`},
+		{`{"levels":[{"repo":"` + repo + `","package":"unknown","level":"INFO"}]}`, `unknown package: "unknown" in repo "` + repo + `"`},
+		// not applied partially
+		{`{"levels":[{"repo":"*","level":"ERROR"},{"repo":"unknown","level":"INFO"}]}`, `unknown repo: "unknown"`},
+	}
+	for _, tc := range tcases {
+		status, body = put(tc.body)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, tc.err, body)
+	}
+	assert.Equal(t, "DEBUG", get()[repo+"/xlog_test"])
+
+	status, _ = put(`{"levels":[{"repo":"*","level":"W"}]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "WARNING", get()[repo+"/xlog_test"])
+
+	res, err := http.Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "GET, PUT", res.Header.Get("Allow"))
+}
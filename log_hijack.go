@@ -38,6 +38,21 @@ type packageWriter struct {
 }
 
 func (p packageWriter) Write(b []byte) (int, error) {
-	p.pl.internalLog(kv, calldepth+2, INFO, "log", strings.TrimSpace(string(b)))
+	// high-volume std log output is dropped before any formatting,
+	// when the entries can not be emitted
+	if !p.enabled() {
+		return len(b), nil
+	}
+	p.pl.internalLog(nil, kv, calldepth+2, INFO, "log", strings.TrimSpace(string(b)))
 	return len(b), nil
 }
+
+// enabled returns false if INFO entries of the package are dropped,
+// because the level is disabled and no enablers can enable it,
+// or no formatter is set
+func (p packageWriter) enabled() bool {
+	if !p.pl.LevelAt(INFO) && logger.enablers.Load() == nil {
+		return false
+	}
+	return logger.sinkFor(p.pl.repo, p.pl.pkg) != nil
+}
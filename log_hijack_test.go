@@ -32,3 +32,24 @@ func Test_Hijack_Error(t *testing.T) {
 
 	assert.Empty(t, w.Bytes())
 }
+
+func Test_Hijack_Disabled(t *testing.T) {
+	w := bytes.NewBuffer([]byte{})
+	xlog.SetFormatter(xlog.NewStringFormatter(w))
+	xlog.SetGlobalLogLevel(xlog.WARNING)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		xlog.Stderr.Print("testing")
+	})
+	assert.Zero(t, allocs)
+	assert.Empty(t, w.Bytes())
+
+	// the enablers can enable the entries
+	xlog.AddEnabler(xlog.EnablerFunc(func(d *xlog.Decision, enabled bool) bool {
+		return enabled || d.Repo == "log"
+	}))
+	defer xlog.ResetEnablers()
+	xlog.Stderr.Print("enabled")
+	assert.Contains(t, w.String(), `log="enabled"`)
+}
@@ -15,8 +15,13 @@
 package xlog
 
 import (
+	"io"
+	"math"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -123,21 +128,344 @@ type RepoLogger map[string]*PackageLogger
 type OnErrorFn func(pkg string)
 
 type loggerStruct struct {
+	// Mutex protects the registry of packages and configuration updates,
+	// it is not acquired on the logging path
 	sync.Mutex
-	repoMap   map[string]RepoLogger
-	formatter Formatter
-	onError   OnErrorFn
+	repoMap map[string]RepoLogger
+
+	// sinks is immutable snapshot of formatters configuration,
+	// replaced on updates
+	sinks   atomic.Pointer[sinks]
+	onError atomic.Pointer[OnErrorFn]
+	hooks   atomic.Pointer[[]*registeredHook]
+	// errorHandlers are OnErrorFn callbacks scoped to repo or package
+	errorHandlers atomic.Pointer[[]*errorHandler]
+	// namedLevels are the levels of the named loggers subtrees
+	namedLevels atomic.Pointer[map[string]LogLevel]
+	// enablers decide whether the entry should be emitted
+	enablers atomic.Pointer[[]Enabler]
+	// fieldFuncs compute fields of the emitted entries
+	fieldFuncs atomic.Pointer[[]FieldFunc]
+	// globalFields are the static fields of the emitted entries
+	globalFields atomic.Pointer[[]any]
+	// fieldsProviders provide the dynamic global fields of the emitted entries
+	fieldsProviders atomic.Pointer[[]*fieldsProvider]
+	// errHelp maps error codes and fingerprints to documentation URLs
+	errHelp atomic.Pointer[map[string]string]
+	// redactor is used by formatters with redaction enabled
+	redactor atomic.Pointer[Redactor]
+	// secretKey is the key of SecretHash values
+	secretKey atomic.Pointer[[]byte]
+	// errorFormat specifies how the errors are logged
+	errorFormat atomic.Int32
+	// stackDepth is the maximum number of frames of the stack trace
+	stackDepth atomic.Int32
+
+	// metrics receives the counts of the entries
+	metrics atomic.Pointer[MetricsSink]
+	// deliveryErrors counts the errors reported by ReportError
+	deliveryErrors atomic.Uint64
+
+	// levelLimit specifies the maximum level to be logged,
+	// regardless of packages level, noLevelLimit if not limited
+	levelLimit atomic.Int32
+
+	// rateLock protects rate limiters
+	rateLock sync.Mutex
+	// rateLimits specifies rate limiters per repo, package and level
+	rateLimits  map[rateKey]*rateLimiter
+	rateSummary bool
+	rateCount   atomic.Int32
+	// rateTimer logs the pending summaries
+	rateTimer *time.Timer
+}
+
+const noLevelLimit = math.MaxInt32
+
+// sink is a formatter with its own lock,
+// serializing writes to the formatter
+type sink struct {
+	sync.Mutex
+	Formatter
+	// retired is set under the lock, when the formatter is swapped out,
+	// so writers holding a stale snapshot retry with the current one
+	retired bool
+}
+
+// sinks specifies formatters configuration
+type sinks struct {
+	formatter *sink
+	// repo specifies formatters per repo
+	repo map[string]*sink
+	// pkg specifies formatters per package
+	pkg map[pkgKey]*sink
+}
+
+type pkgKey struct {
+	repo string
+	pkg  string
+}
+
+// clone returns a copy of the configuration to be updated
+func (s *sinks) clone() *sinks {
+	c := &sinks{
+		repo: make(map[string]*sink),
+		pkg:  make(map[pkgKey]*sink),
+	}
+	if s == nil {
+		return c
+	}
+	c.formatter = s.formatter
+	for k, v := range s.repo {
+		c.repo[k] = v
+	}
+	for k, v := range s.pkg {
+		c.pkg[k] = v
+	}
+	return c
+}
+
+// sinkFor returns the sink for the formatter,
+// reusing the existing sink, so the same formatter has one lock
+func (s *sinks) sinkFor(f Formatter) *sink {
+	if reflect.TypeOf(f).Comparable() {
+		if s.formatter != nil && s.formatter.Formatter == f {
+			return s.formatter
+		}
+		for _, v := range s.repo {
+			if v.Formatter == f {
+				return v
+			}
+		}
+		for _, v := range s.pkg {
+			if v.Formatter == f {
+				return v
+			}
+		}
+	}
+	return &sink{Formatter: f}
+}
+
+// updateSinks applies the update to a copy of the sinks configuration
+func (l *loggerStruct) updateSinks(update func(s *sinks)) {
+	l.Lock()
+	defer l.Unlock()
+	s := l.sinks.Load().clone()
+	update(s)
+	l.sinks.Store(s)
+}
+
+// sinkFor returns the sink for the package, or nil
+func (l *loggerStruct) sinkFor(repo, pkg string) *sink {
+	s := l.sinks.Load()
+	if s == nil {
+		return nil
+	}
+	if f, ok := s.pkg[pkgKey{repo: repo, pkg: pkg}]; ok {
+		return f
+	}
+	if f, ok := s.repo[repo]; ok {
+		return f
+	}
+	return s.formatter
+}
+
+// lockedSinkFor returns the locked sink for the package, or nil.
+// The caller must unlock the returned sink.
+func (l *loggerStruct) lockedSinkFor(repo, pkg string) *sink {
+	for {
+		f := l.sinkFor(repo, pkg)
+		if f == nil {
+			return nil
+		}
+		f.Lock()
+		if !f.retired {
+			return f
+		}
+		f.Unlock()
+	}
+}
+
+// uses returns true if the sink is configured
+func (s *sinks) uses(f *sink) bool {
+	if s.formatter == f {
+		return true
+	}
+	for _, v := range s.repo {
+		if v == f {
+			return true
+		}
+	}
+	for _, v := range s.pkg {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// flushSinks flushes all configured formatters
+func (l *loggerStruct) flushSinks() {
+	s := l.sinks.Load()
+	if s == nil {
+		return
+	}
+	flushed := make(map[*sink]bool)
+	flush := func(f *sink) {
+		if f == nil || flushed[f] {
+			return
+		}
+		flushed[f] = true
+		f.Lock()
+		defer f.Unlock()
+		f.Flush()
+	}
+	flush(s.formatter)
+	for _, f := range s.repo {
+		flush(f)
+	}
+	for _, f := range s.pkg {
+		flush(f)
+	}
+}
+
+// enabled returns true if the level is enabled for the package level
+func (l *loggerStruct) enabled(pkgLevel, level LogLevel) bool {
+	if level == CRITICAL {
+		return true
+	}
+	return l.withinLimit(level) && pkgLevel >= level
+}
+
+// withinLimit returns false if the level is above the level limit
+func (l *loggerStruct) withinLimit(level LogLevel) bool {
+	return l.levelLimit.Load() >= int32(level)
+}
+
+// setLevelLimit limits the maximum level to be logged for all packages
+func setLevelLimit(level LogLevel) {
+	logger.levelLimit.Store(int32(level))
+}
+
+// resetLevelLimit removes the level limit
+func resetLevelLimit() {
+	logger.levelLimit.Store(noLevelLimit)
+}
+
+// atomicLevel is the log level of a package,
+// shared by the package logger and its children
+type atomicLevel struct {
+	v atomic.Int32
+}
+
+func newAtomicLevel(l LogLevel) *atomicLevel {
+	a := new(atomicLevel)
+	a.Store(l)
+	return a
+}
+
+// Load returns the level
+func (a *atomicLevel) Load() LogLevel {
+	return LogLevel(a.v.Load())
+}
+
+// Store sets the level
+func (a *atomicLevel) Store(l LogLevel) {
+	a.v.Store(int32(l))
 }
 
 // logger is the global logger
-var logger = new(loggerStruct)
+var logger = func() *loggerStruct {
+	l := new(loggerStruct)
+	l.levelLimit.Store(noLevelLimit)
+	return l
+}()
 
 // OnError allows to specify a callback for ERROR levels.
 // This is useful to reports metrics on ERROR in a package
 func OnError(fn OnErrorFn) {
+	if fn == nil {
+		logger.onError.Store(nil)
+		return
+	}
+	logger.onError.Store(&fn)
+}
+
+// ReportError invokes the callback specified by OnError,
+// sinks use it to report delivery errors
+func ReportError(pkg string) {
+	logger.deliveryErrors.Add(1)
+	if fn := logger.onError.Load(); fn != nil {
+		(*fn)(pkg)
+	}
+}
+
+// errorHandler is OnErrorFn scoped to repo or package
+type errorHandler struct {
+	repo string
+	pkg  string
+	fn   OnErrorFn
+}
+
+// OnRepoError adds the callback for ERROR levels in the packages of the repo,
+// so a library can observe its own errors,
+// and returns the function to remove the callback
+func OnRepoError(repo string, fn OnErrorFn) (remove func()) {
+	return OnPackageError(repo, "*", fn)
+}
+
+// OnPackageError adds the callback for ERROR levels in the package of the repo,
+// use "*" as pkg for all packages in the repo.
+// It returns the function to remove the callback.
+func OnPackageError(repo, pkg string, fn OnErrorFn) (remove func()) {
+	if pkg == "*" {
+		pkg = ""
+	}
+	h := &errorHandler{repo: repo, pkg: pkg, fn: fn}
+
 	logger.Lock()
 	defer logger.Unlock()
-	logger.onError = fn
+	var list []*errorHandler
+	if handlers := logger.errorHandlers.Load(); handlers != nil {
+		list = append(list, *handlers...)
+	}
+	list = append(list, h)
+	logger.errorHandlers.Store(&list)
+
+	return func() {
+		logger.Lock()
+		defer logger.Unlock()
+		handlers := logger.errorHandlers.Load()
+		if handlers == nil {
+			return
+		}
+		var list []*errorHandler
+		for _, v := range *handlers {
+			if v != h {
+				list = append(list, v)
+			}
+		}
+		if len(list) == 0 {
+			logger.errorHandlers.Store(nil)
+			return
+		}
+		logger.errorHandlers.Store(&list)
+	}
+}
+
+// reportError invokes the OnError callback,
+// and the callbacks scoped to the package or its repo
+func (l *loggerStruct) reportError(repo, pkg string) {
+	if fn := l.onError.Load(); fn != nil {
+		(*fn)(pkg)
+	}
+	if handlers := l.errorHandlers.Load(); handlers != nil {
+		for _, h := range *handlers {
+			if h.repo == repo && (h.pkg == "" || h.pkg == pkg) {
+				h.fn(pkg)
+			}
+		}
+	}
 }
 
 // SetGlobalLogLevel sets the log level for all packages in all repositories
@@ -177,9 +505,22 @@ func (r RepoLogger) SetRepoLogLevel(l LogLevel) {
 	r.setRepoLogLevelInternal(l)
 }
 
+// SetFormatter sets the formatter for all packages in the repository,
+// overriding the global formatter.
+// Pass nil to reset to the global formatter.
+func (r RepoLogger) SetFormatter(f Formatter) {
+	repos := map[string]bool{}
+	for _, p := range r {
+		repos[p.repo] = true
+	}
+	for repo := range repos {
+		SetRepoFormatter(repo, f)
+	}
+}
+
 func (r RepoLogger) setRepoLogLevelInternal(l LogLevel) {
 	for _, v := range r {
-		v.level = l
+		v.level.Store(l)
 	}
 }
 
@@ -217,22 +558,112 @@ func (r RepoLogger) SetLogLevel(m map[string]LogLevel) {
 		if !ok {
 			continue
 		}
-		l.level = v
+		l.level.Store(v)
 	}
 }
 
 // SetFormatter sets the formatting function for all logs.
+// The entries retained by SetBootstrapBuffer are replayed to the formatter.
 func SetFormatter(f Formatter) {
-	logger.Lock()
-	defer logger.Unlock()
-	logger.formatter = f
+	var old, cur *sink
+	logger.updateSinks(func(s *sinks) {
+		old = s.formatter
+		if f == nil {
+			s.formatter = nil
+			return
+		}
+		s.formatter = s.sinkFor(f)
+		cur = s.formatter
+	})
+	if old != nil && old != cur {
+		replayBootstrap(old, cur)
+	}
+}
+
+// SwapFormatter replaces the formatter for all logs,
+// and flushes the previous formatter after the in-flight entries are written,
+// so no entries are lost or interleaved during reconfiguration.
+// The previous formatter is closed if it implements io.Closer,
+// and is not used by repo or package formatters.
+func SwapFormatter(f Formatter) error {
+	var old, cur *sink
+	inUse := false
+	logger.updateSinks(func(s *sinks) {
+		old = s.formatter
+		if f == nil {
+			s.formatter = nil
+		} else {
+			s.formatter = s.sinkFor(f)
+		}
+		cur = s.formatter
+		inUse = s.uses(old)
+	})
+	if old == nil {
+		return nil
+	}
+	if _, ok := old.Formatter.(*bootstrapFormatter); ok {
+		replayBootstrap(old, cur)
+		return nil
+	}
+
+	// entries written by loggers with the old snapshot
+	// are completed before the lock is acquired
+	old.Lock()
+	defer old.Unlock()
+
+	old.Flush()
+	if inUse {
+		return nil
+	}
+	old.retired = true
+	if c, ok := old.Formatter.(io.Closer); ok {
+		return errors.WithMessage(c.Close(), "failed to close formatter")
+	}
+	return nil
+}
+
+// SetRepoFormatter sets the formatter for all packages in the repository,
+// overriding the global formatter.
+// Pass nil to reset to the global formatter.
+func SetRepoFormatter(repo string, f Formatter) {
+	logger.updateSinks(func(s *sinks) {
+		if f == nil {
+			delete(s.repo, repo)
+			return
+		}
+		s.repo[repo] = s.sinkFor(f)
+	})
+}
+
+// SetPackageFormatter sets the formatter for a package in the repository,
+// overriding the repo and global formatters.
+// Pass nil to reset to the repo or global formatter.
+func SetPackageFormatter(repo, pkg string, f Formatter) {
+	if pkg == "*" {
+		SetRepoFormatter(repo, f)
+		return
+	}
+
+	key := pkgKey{repo: repo, pkg: pkg}
+	logger.updateSinks(func(s *sinks) {
+		if f == nil {
+			delete(s.pkg, key)
+			return
+		}
+		s.pkg[key] = s.sinkFor(f)
+	})
 }
 
 // GetFormatter returns current formatter
 func GetFormatter() Formatter {
-	logger.Lock()
-	defer logger.Unlock()
-	return logger.formatter
+	s := logger.sinks.Load()
+	if s == nil || s.formatter == nil {
+		return nil
+	}
+	if _, ok := s.formatter.Formatter.(*bootstrapFormatter); ok {
+		return nil
+	}
+	return s.formatter.Formatter
 }
 
 // NewPackageLogger creates a package logger object.
@@ -247,12 +678,14 @@ func NewPackageLogger(repo string, pkg string) (p *PackageLogger) {
 	if !rok {
 		logger.repoMap[repo] = make(RepoLogger)
 		r = logger.repoMap[repo]
+		registerRepoPath(repo)
 	}
 	p, pok := r[pkg]
 	if !pok {
 		r[pkg] = &PackageLogger{
+			repo:  repo,
 			pkg:   pkg,
-			level: INFO,
+			level: newAtomicLevel(INFO),
 		}
 		p = r[pkg]
 	}
@@ -287,7 +720,7 @@ func SetPackageLogLevel(repo, pkg string, l LogLevel) {
 		defer logger.Unlock()
 
 		if p, ok := pkgLogger[pkg]; ok {
-			p.level = l
+			p.level.Store(l)
 		}
 	}
 }
@@ -333,7 +766,7 @@ func GetRepoLevels() []RepoLogLevel {
 			list = append(list, RepoLogLevel{
 				Repo:    repo,
 				Package: pkg,
-				Level:   rl.level.String(),
+				Level:   rl.level.Load().String(),
 			})
 		}
 	}
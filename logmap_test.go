@@ -138,3 +138,16 @@ func Test_GetRepoLevels(t *testing.T) {
 
 	xlog.SetRepoLevels(list)
 }
+
+func Test_ReportError(t *testing.T) {
+	xlog.ReportError("none")
+
+	var reported []string
+	xlog.OnError(func(pkg string) {
+		reported = append(reported, pkg)
+	})
+	defer xlog.OnError(nil)
+
+	xlog.ReportError("sink")
+	assert.Equal(t, []string{"sink"}, reported)
+}
@@ -20,40 +20,90 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
 )
 
+// ErrorPkg is the package reported to xlog.OnError callback,
+// when the spilled writes can not be read
+const ErrorPkg = "logrotate"
+
 // ChannelWriter provides an io.Writer that defers the write to a background
 // go routine. You might for example use this for a log.Logger destination
 type ChannelWriter struct {
-	write    chan []byte
+	lock     sync.Mutex
+	notFull  *sync.Cond
+	queue    [][]byte
+	depth    int
+	overflow xlog.OverflowPolicy
+	// spill is the temp file for the writes, when the buffer is full
+	spill *spillBuffer
+
+	wake     chan struct{}
+	interval chan time.Duration
+	flush    chan chan struct{}
 	stop     chan bool
 	stopped  chan bool
+	exited   chan struct{}
 	running  uint32
 	buffPool sync.Pool
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	flushes atomic.Uint64
+	spilled atomic.Uint64
+	onDrop  atomic.Pointer[func(d []byte)]
+}
+
+// ChannelWriterStats provides the counters of ChannelWriter
+type ChannelWriterStats struct {
+	// Written is the number of writes to the destination
+	Written uint64 `json:"written"`
+	// Dropped is the number of dropped writes
+	Dropped uint64 `json:"dropped"`
+	// Queued is the number of writes in the buffer
+	Queued int `json:"queued"`
+	// Flushes is the number of flushes of the destination
+	Flushes uint64 `json:"flushes"`
+	// Spilled is the number of writes spilled to the temp file
+	Spilled uint64 `json:"spilled"`
+	// SpillSize is the size of the spilled writes not yet replayed
+	SpillSize int64 `json:"spill_size"`
 }
 
 // NewChannelWriter provides an instance of io.Writer that
-// forwards all write over a channel to a background go routine
+// forwards all write over a buffer to a background go routine
 // that does the actual write, this can stop disk I/O cluttering
 // up app processing. [at the potential risk of loosing some
 // writes during a crash]
 //
 // dest is the io.Writer that we're wrapping
-// bufferDepth controls the size of the channel buffer (if this buffer fills, it'll start to block the writers)
+// bufferDepth controls the number of buffered writes, when the buffer is full
+// the writers are blocked, unless the overflow policy is changed by SetOverflow.
 // flushInterval if the writer is a bufio.Writer (or any other writer with a Flush() error method), then we'll flush at this interval when there are no writes.
 // you can pass zero for this if you don't want this behavour
 func NewChannelWriter(dest io.Writer, bufferDepth int, flushInterval time.Duration) *ChannelWriter {
-	cw := ChannelWriter{
-		write:   make(chan []byte, bufferDepth),
-		stop:    make(chan bool),
-		stopped: make(chan bool),
-		running: 1,
+	if bufferDepth < 1 {
+		bufferDepth = 1
 	}
+	cw := &ChannelWriter{
+		depth:    bufferDepth,
+		wake:     make(chan struct{}, 1),
+		interval: make(chan time.Duration, 1),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan bool),
+		stopped:  make(chan bool),
+		exited:   make(chan struct{}),
+		running:  1,
+	}
+	cw.notFull = sync.NewCond(&cw.lock)
 	cw.buffPool.New = func() any {
 		return make([]byte, 0, 256)
 	}
-	go cw.listen(dest, flushInterval)
-	return &cw
+	done := xlog.TrackGoroutine("logrotate.ChannelWriter")
+	go cw.listen(dest, flushInterval, done)
+	return cw
 }
 
 // IsStopped returns true if this ChannelWriter has been stopped
@@ -61,11 +111,17 @@ func (cw *ChannelWriter) IsStopped() bool {
 	return atomic.LoadUint32(&cw.running) == 0
 }
 
+// Started returns true if the background go routine is running
+func (cw *ChannelWriter) Started() bool {
+	return !cw.IsStopped()
+}
+
 // Stop tells the background writer to stop processing [if its running]
 // Once stopped you can not restart it, it is expected that you throw
 // this away once stopped.
-// Stop will drain the current contents of the write channel before stopping
-// Stop() will block until the channel is drained and the output flushed.
+// Stop will drain the current contents of the buffer before stopping
+// Stop() will block until the buffer is drained and the output flushed.
+// The writes after Stop are dropped.
 func (cw *ChannelWriter) Stop() {
 	if atomic.CompareAndSwapUint32(&cw.running, 1, 0) {
 		cw.stop <- true
@@ -73,58 +129,275 @@ func (cw *ChannelWriter) Stop() {
 	}
 }
 
+// Flush blocks until the buffered and the spilled writes are written,
+// and the destination is flushed
+func (cw *ChannelWriter) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case cw.flush <- flushed:
+		<-flushed
+	case <-cw.exited:
+	}
+}
+
+// Stats returns the counters of the writer
+func (cw *ChannelWriter) Stats() ChannelWriterStats {
+	cw.lock.Lock()
+	queued := len(cw.queue)
+	var spillSize int64
+	if cw.spill != nil {
+		spillSize = cw.spill.size()
+	}
+	cw.lock.Unlock()
+	return ChannelWriterStats{
+		Written:   cw.written.Load(),
+		Dropped:   cw.dropped.Load(),
+		Queued:    queued,
+		Flushes:   cw.flushes.Load(),
+		Spilled:   cw.spilled.Load(),
+		SpillSize: spillSize,
+	}
+}
+
+// SetSpill enables the spill of the writes to a temp file in dir,
+// or in the default directory for temporary files if dir is empty,
+// when the buffer is full. The spilled writes are replayed to the destination
+// in order, when the buffer is drained, so no writes are lost during
+// the downstream slowness, such as network sink outage.
+// When the file reaches maxSize bytes, the overflow policy applies.
+// The file is removed on Stop.
+func (cw *ChannelWriter) SetSpill(dir string, maxSize int64) error {
+	if maxSize <= 0 {
+		return errors.New("spill size must be positive")
+	}
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	if cw.spill != nil {
+		return errors.New("spill is already enabled")
+	}
+	spill, err := newSpillBuffer(dir, maxSize)
+	if err != nil {
+		return err
+	}
+	cw.spill = spill
+	return nil
+}
+
+// SetOverflow sets the behavior when the buffer is full:
+// xlog.OverflowBlock blocks the writer until there is space in the buffer, by default,
+// xlog.OverflowDropNewest drops the data being written,
+// and xlog.OverflowDropOldest drops the oldest write in the buffer
+func (cw *ChannelWriter) SetOverflow(policy xlog.OverflowPolicy) {
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	cw.overflow = policy
+	// the blocked writers apply the new policy
+	cw.notFull.Broadcast()
+}
+
+// SetBufferDepth sets the number of buffered writes,
+// the writes already buffered above the new depth are retained
+func (cw *ChannelWriter) SetBufferDepth(bufferDepth int) {
+	if bufferDepth < 1 {
+		bufferDepth = 1
+	}
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	cw.depth = bufferDepth
+	cw.notFull.Broadcast()
+}
+
+// SetFlushInterval sets the interval to flush the destination,
+// zero disables the periodic flush
+func (cw *ChannelWriter) SetFlushInterval(flushInterval time.Duration) {
+	// the pending interval is replaced
+	select {
+	case <-cw.interval:
+	default:
+	}
+	cw.interval <- flushInterval
+}
+
+// OnDrop sets the callback invoked with the dropped data,
+// the writes are dropped after the writer is stopped,
+// or when the buffer is full and the overflow policy drops the writes.
+// The data is nil for the spilled writes that can not be read.
+// The data must not be retained after the callback returns.
+func (cw *ChannelWriter) OnDrop(fn func(d []byte)) {
+	if fn == nil {
+		cw.onDrop.Store(nil)
+		return
+	}
+	cw.onDrop.Store(&fn)
+}
+
 // Write implements the io.Writer interface
 func (cw *ChannelWriter) Write(d []byte) (int, error) {
 	// the documented sematics of Write are that we can't hold onto the supplied
 	// bytes past the end of the function, so we need to create a copy to Put
-	// on the channel.
+	// on the queue.
 	buff := cw.buffPool.Get().([]byte)
 	buff = append(buff[:0], d...)
-	cw.write <- buff
+
+	var dropped []byte
+	cw.lock.Lock()
+	if cw.full() && !cw.IsStopped() && cw.spill != nil && cw.spill.push(buff) {
+		cw.spilled.Add(1)
+		cw.lock.Unlock()
+		cw.buffPool.Put(buff)
+		cw.notify()
+		return len(d), nil
+	}
+	for cw.full() && cw.overflow == xlog.OverflowBlock && !cw.IsStopped() {
+		// the background goroutine drains the buffer before stopping,
+		// so there will be space
+		cw.notFull.Wait()
+	}
+	switch {
+	case cw.IsStopped():
+		// nobody reads the buffer
+		dropped = buff
+	case !cw.full():
+		cw.queue = append(cw.queue, buff)
+	case cw.overflow == xlog.OverflowDropOldest && len(cw.queue) > 0 && (cw.spill == nil || !cw.spill.pending()):
+		// the spilled writes are newer than the buffer,
+		// so the oldest write is dropped only if nothing is spilled
+		dropped = cw.queue[0]
+		cw.queue[0] = nil
+		cw.queue = append(cw.queue[1:], buff)
+	default:
+		dropped = buff
+	}
+	cw.lock.Unlock()
+
+	if dropped != nil {
+		cw.drop(dropped)
+		cw.buffPool.Put(dropped)
+	}
+	cw.notify()
 	return len(d), nil
 }
 
+// full returns true if the write can not be added to the buffer:
+// the buffer is full, or the spilled writes must be replayed first.
+// The caller must hold the lock.
+func (cw *ChannelWriter) full() bool {
+	return len(cw.queue) >= cw.depth || (cw.spill != nil && cw.spill.pending())
+}
+
+// notify wakes up the background goroutine
+func (cw *ChannelWriter) notify() {
+	select {
+	case cw.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (cw *ChannelWriter) drop(d []byte) {
+	cw.dropped.Add(1)
+	if fn := cw.onDrop.Load(); fn != nil {
+		(*fn)(d)
+	}
+}
+
 type flushable interface {
 	Flush() error
 }
 
-// listen is our background go-routine, it reads from the channel and does
+// listen is our background go-routine, it reads from the buffer and does
 // the writes. It also flushes on a regular basis if configured to do so.
-func (cw *ChannelWriter) listen(dest io.Writer, flushInterval time.Duration) {
+func (cw *ChannelWriter) listen(dest io.Writer, flushInterval time.Duration, done func()) {
 	defer func() {
+		done()
+		close(cw.exited)
 		cw.stopped <- true
 	}()
-	var flushChan <-chan time.Time
 	flusher, canFlush := dest.(flushable)
-	if canFlush && flushInterval > 0 {
-		ft := time.NewTicker(flushInterval)
-		flushChan = ft.C
-		defer ft.Stop()
-	} else {
-		flushChan = make(chan time.Time)
+	flush := func() {
+		if canFlush {
+			flusher.Flush()
+			cw.flushes.Add(1)
+		}
+	}
+
+	var ticker *time.Ticker
+	var flushChan <-chan time.Time
+	resetTicker := func(d time.Duration) {
+		if ticker != nil {
+			ticker.Stop()
+			ticker = nil
+			flushChan = nil
+		}
+		if canFlush && d > 0 {
+			ticker = time.NewTicker(d)
+			flushChan = ticker.C
+		}
 	}
+	resetTicker(flushInterval)
+	defer resetTicker(0)
+
 	for {
 		select {
 		case <-flushChan:
-			if canFlush {
-				flusher.Flush()
-			}
-		case b := <-cw.write:
-			_, _ = dest.Write(b)
-			cw.buffPool.Put(b)
+			flush()
+		case d := <-cw.interval:
+			resetTicker(d)
+		case <-cw.wake:
+			cw.drain(dest)
+		case flushed := <-cw.flush:
+			cw.drain(dest)
+			flush()
+			close(flushed)
 		case <-cw.stop:
-			// drain what's left of the Write channel
-			for {
-				select {
-				case b := <-cw.write:
-					_, _ = dest.Write(b)
-				default:
-					if canFlush {
-						flusher.Flush()
-					}
-					return
+			// drain what's left of the buffer
+			cw.drain(dest)
+			flush()
+			cw.lock.Lock()
+			if cw.spill != nil {
+				_ = cw.spill.close()
+				cw.spill = nil
+			}
+			cw.lock.Unlock()
+			return
+		}
+	}
+}
+
+// drain writes the buffered data to the destination,
+// and then replays the spilled writes
+func (cw *ChannelWriter) drain(dest io.Writer) {
+	for {
+		cw.lock.Lock()
+		var b []byte
+		switch {
+		case len(cw.queue) > 0:
+			b = cw.queue[0]
+			cw.queue[0] = nil
+			cw.queue = cw.queue[1:]
+		case cw.spill != nil && cw.spill.pending():
+			var err error
+			b, err = cw.spill.pop(cw.buffPool.Get().([]byte))
+			if err != nil {
+				// the spilled writes can not be read, and are dropped
+				lost := cw.spill.reset()
+				cw.notFull.Broadcast()
+				cw.lock.Unlock()
+				xlog.ReportError(ErrorPkg)
+				for i := 0; i < lost; i++ {
+					cw.drop(nil)
 				}
+				continue
 			}
+		default:
+			cw.queue = nil
+			cw.lock.Unlock()
+			return
 		}
+		cw.notFull.Broadcast()
+		cw.lock.Unlock()
+
+		_, _ = dest.Write(b)
+		cw.written.Add(1)
+		cw.buffPool.Put(b)
 	}
 }
@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/effective-security/xlog"
 )
 
 type testWriter struct {
@@ -112,3 +115,275 @@ func TestChannelWriter_Writes(t *testing.T) {
 		}
 	}
 }
+
+func TestChannelWriter_Goroutines(t *testing.T) {
+	cw := NewChannelWriter(&testWriter{}, 10, 0)
+	if !cw.Started() {
+		t.Fatalf("ChannelWriter should be started")
+	}
+	if xlog.RunningGoroutines()["logrotate.ChannelWriter"] == 0 {
+		t.Fatalf("ChannelWriter goroutine should be tracked")
+	}
+	cw.Stop()
+	if cw.Started() {
+		t.Fatalf("ChannelWriter should be stopped")
+	}
+	if err := xlog.CheckGoroutines(); err != nil {
+		t.Fatalf("unexpected running goroutines: %v", err)
+	}
+}
+
+type blockedWriter struct {
+	testWriter
+	release chan struct{}
+}
+
+func (b *blockedWriter) Write(d []byte) (int, error) {
+	<-b.release
+	return b.testWriter.Write(d)
+}
+
+func TestChannelWriter_Stats(t *testing.T) {
+	dest := &blockedWriter{release: make(chan struct{})}
+	cw := NewChannelWriter(dest, 2, 0)
+	cw.SetOverflow(xlog.OverflowDropNewest)
+
+	var dropped []string
+	var lock sync.Mutex
+	cw.OnDrop(func(d []byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		dropped = append(dropped, string(d))
+	})
+
+	// the first write is taken by the blocked writer, two are queued
+	for i := 0; i < 3; i++ {
+		_, _ = cw.Write([]byte(fmt.Sprintf("w%d", i)))
+		if i == 0 {
+			waitUntil := time.Now().Add(time.Second)
+			for cw.Stats().Queued > 0 {
+				if time.Now().After(waitUntil) {
+					t.Fatalf("Gave up waiting for the write to be taken")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	_, _ = cw.Write([]byte("w3"))
+
+	stats := cw.Stats()
+	if stats.Dropped != 1 || stats.Queued != 2 || stats.Written != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	close(dest.release)
+	cw.Stop()
+	_, _ = cw.Write([]byte("w4"))
+
+	stats = cw.Stats()
+	exp := ChannelWriterStats{Written: 3, Dropped: 2}
+	if stats != exp {
+		t.Errorf("Expecting stats: %+v, got %+v", exp, stats)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if fmt.Sprint(dropped) != "[w3 w4]" {
+		t.Errorf("Unexpected dropped writes: %v", dropped)
+	}
+}
+
+func TestChannelWriter_StatsFlushes(t *testing.T) {
+	dest := &testFlushWriter{}
+	cw := NewChannelWriter(dest, 10, 0)
+	_, _ = cw.Write([]byte("w"))
+	cw.Stop()
+
+	stats := cw.Stats()
+	if stats.Written != 1 || stats.Flushes != 1 || stats.Flushes != uint64(dest.NumFlushes()) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestChannelWriter_Overflow(t *testing.T) {
+	tcases := []struct {
+		policy   xlog.OverflowPolicy
+		expected string
+		dropped  string
+	}{
+		{xlog.OverflowDropNewest, "[w0 w1 w2]", "[w3 w4]"},
+		{xlog.OverflowDropOldest, "[w0 w3 w4]", "[w1 w2]"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			dest := &blockedWriter{release: make(chan struct{})}
+			cw := NewChannelWriter(dest, 2, 0)
+			cw.SetOverflow(tc.policy)
+			var dropped []string
+			cw.OnDrop(func(d []byte) {
+				dropped = append(dropped, string(d))
+			})
+
+			_, _ = cw.Write([]byte("w0"))
+			waitUntil := time.Now().Add(time.Second)
+			for cw.Stats().Queued > 0 {
+				if time.Now().After(waitUntil) {
+					t.Fatalf("Gave up waiting for the write to be taken")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			for i := 1; i < 5; i++ {
+				_, _ = cw.Write([]byte(fmt.Sprintf("w%d", i)))
+			}
+			close(dest.release)
+			cw.Stop()
+
+			var written []string
+			for _, w := range dest.writes {
+				written = append(written, string(w))
+			}
+			if fmt.Sprint(written) != tc.expected {
+				t.Errorf("Expecting writes %s, got %v", tc.expected, written)
+			}
+			if fmt.Sprint(dropped) != tc.dropped {
+				t.Errorf("Expecting dropped %s, got %v", tc.dropped, dropped)
+			}
+		})
+	}
+}
+
+func TestChannelWriter_Tunable(t *testing.T) {
+	dest := &blockedWriter{testWriter: testWriter{}, release: make(chan struct{})}
+	cw := NewChannelWriter(dest, 1, 0)
+
+	_, _ = cw.Write([]byte("w0"))
+	_, _ = cw.Write([]byte("w1"))
+
+	// the buffer is full, the writer is blocked until the depth is increased
+	written := make(chan struct{})
+	go func() {
+		_, _ = cw.Write([]byte("w2"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatalf("Write should block when the buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cw.SetBufferDepth(10)
+	<-written
+	close(dest.release)
+	cw.Stop()
+	if dest.NumWrites() != 3 {
+		t.Errorf("Expecting 3 writes, got %d", dest.NumWrites())
+	}
+
+	fdest := &testFlushWriter{}
+	cw = NewChannelWriter(fdest, 10, 0)
+	defer cw.Stop()
+	cw.SetFlushInterval(time.Millisecond)
+	waitUntil := time.Now().Add(time.Second)
+	for fdest.NumFlushes() == 0 {
+		if time.Now().After(waitUntil) {
+			t.Fatalf("Gave up waiting to be flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChannelWriter_Spill(t *testing.T) {
+	dir := t.TempDir()
+	dest := &blockedWriter{release: make(chan struct{})}
+	cw := NewChannelWriter(dest, 1, 0)
+	cw.SetOverflow(xlog.OverflowDropNewest)
+	if err := cw.SetSpill(dir, 0); err == nil {
+		t.Fatalf("Expecting error for zero spill size")
+	}
+	if err := cw.SetSpill(dir, 40); err != nil {
+		t.Fatalf("Unable to enable spill: %v", err)
+	}
+	if err := cw.SetSpill(dir, 40); err == nil {
+		t.Fatalf("Expecting error when spill is already enabled")
+	}
+
+	// 4 writes are spilled, 6 bytes each with the prefix, and the last one is dropped
+	for i := 0; i < 10; i++ {
+		_, _ = cw.Write([]byte(fmt.Sprintf("w%d", i)))
+	}
+	stats := cw.Stats()
+	if stats.Spilled == 0 || stats.SpillSize != int64(stats.Spilled)*6 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "xlog-spill-*"))
+	if len(files) != 1 {
+		t.Fatalf("Expecting spill file, got %v", files)
+	}
+
+	close(dest.release)
+	cw.Stop()
+
+	stats = cw.Stats()
+	if stats.Written+stats.Dropped != 10 || stats.SpillSize != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	// the writes are replayed in order
+	for i := 1; i < len(dest.writes); i++ {
+		if string(dest.writes[i-1]) >= string(dest.writes[i]) {
+			t.Errorf("Unexpected order of writes: %s", dest.writes)
+			break
+		}
+	}
+	files, _ = filepath.Glob(filepath.Join(dir, "xlog-spill-*"))
+	if len(files) != 0 {
+		t.Errorf("Spill file should be removed, got %v", files)
+	}
+}
+
+func TestChannelWriter_SpillReplay(t *testing.T) {
+	dest := &blockedWriter{release: make(chan struct{})}
+	cw := NewChannelWriter(dest, 2, 0)
+	if err := cw.SetSpill("", 1024*1024); err != nil {
+		t.Fatalf("Unable to enable spill: %v", err)
+	}
+	defer cw.Stop()
+
+	for i := 0; i < 100; i++ {
+		_, _ = cw.Write([]byte(fmt.Sprintf("w%03d", i)))
+	}
+	close(dest.release)
+
+	waitUntil := time.Now().Add(time.Second)
+	for dest.NumWrites() < 100 {
+		if time.Now().After(waitUntil) {
+			t.Fatalf("Gave up waiting for the spilled writes, got %d", dest.NumWrites())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stats := cw.Stats()
+	if stats.Dropped != 0 || stats.Spilled == 0 || stats.SpillSize != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	dest.lock.Lock()
+	defer dest.lock.Unlock()
+	for i, w := range dest.writes {
+		if string(w) != fmt.Sprintf("w%03d", i) {
+			t.Fatalf("Write %d: unexpected %s", i, w)
+		}
+	}
+
+	// the spill is reset, and the writes are buffered in memory
+	_, _ = cw.Write([]byte("next"))
+}
+
+func TestChannelWriter_Flush(t *testing.T) {
+	dest := &testFlushWriter{}
+	cw := NewChannelWriter(dest, 10, 0)
+	_, _ = cw.Write([]byte("w0"))
+	_, _ = cw.Write([]byte("w1"))
+	cw.Flush()
+	if dest.NumWrites() != 2 || dest.NumFlushes() != 1 {
+		t.Errorf("Expecting 2 writes and 1 flush, got %d and %d", dest.NumWrites(), dest.NumFlushes())
+	}
+	cw.Stop()
+	// does not block after stop
+	cw.Flush()
+}
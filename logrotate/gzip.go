@@ -0,0 +1,258 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// GzipConfig specifies configuration for GzipWriter
+type GzipConfig struct {
+	// Filename is the file to write, such as /var/log/app.log.gz
+	Filename string
+	// MaxSize is the maximum compressed size in megabytes of the file before it is rotated,
+	// zero disables the rotation
+	MaxSize int
+	// MaxBackups is the maximum number of the rotated files to retain,
+	// zero retains all the files
+	MaxBackups int
+	// FlushInterval specifies the interval of the flush points,
+	// so the readers can decompress the data written so far,
+	// one second by default
+	FlushInterval time.Duration
+	// Level is the gzip compression level, gzip.DefaultCompression by default
+	Level int
+}
+
+// backupTimeFormat is the time format of the rotated file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// GzipWriter compresses the stream to gzip file on the fly,
+// with the flush points at FlushInterval.
+// When the file reaches MaxSize, the gzip member is finished
+// and the file is renamed to name-<time>.log.gz, so each rotated file is a valid gzip file.
+// The existing file is appended with a new gzip member.
+type GzipWriter struct {
+	cfg GzipConfig
+
+	lock   sync.Mutex
+	file   *os.File
+	gz     *gzip.Writer
+	size   int64
+	closed bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewGzipWriter returns GzipWriter, creating the file and its folder if needed
+func NewGzipWriter(cfg GzipConfig) (*GzipWriter, error) {
+	if cfg.Filename == "" {
+		return nil, errors.New("filename is required")
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(io.Discard, cfg.Level); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Filename), 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	w := &GzipWriter{
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	done := xlog.TrackGoroutine("logrotate.GzipWriter")
+	go w.flushLoop(done)
+	return w, nil
+}
+
+// open opens the file and starts a new gzip member,
+// the caller must hold the lock
+func (w *GzipWriter) open() error {
+	f, err := os.OpenFile(w.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.WithStack(err)
+	}
+	w.file = f
+	w.size = fi.Size()
+	w.gz, _ = gzip.NewWriterLevel(&countingWriter{w: f, n: &w.size}, w.cfg.Level)
+	return nil
+}
+
+// Write compresses p to the file, rotating the file if it reached MaxSize
+func (w *GzipWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, errors.New("writer is closed")
+	}
+	if w.cfg.MaxSize > 0 && w.size >= int64(w.cfg.MaxSize)*1024*1024 {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return w.gz.Write(p)
+}
+
+// Flush writes the flush point, so the data written so far can be decompressed
+func (w *GzipWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	return errors.WithStack(w.gz.Flush())
+}
+
+// Rotate finishes the gzip member, renames the file with the current time,
+// and opens a new file
+func (w *GzipWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return errors.New("writer is closed")
+	}
+	return w.rotate()
+}
+
+func (w *GzipWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.cfg.Filename, w.backupName(xlog.TimeNowFn())); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.removeBackups()
+}
+
+// closeFile finishes the gzip member and closes the file
+func (w *GzipWriter) closeFile() error {
+	err := w.gz.Close()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return errors.WithStack(err)
+}
+
+// backupName returns the name of the rotated file,
+// in name-<time>.log.gz format
+func (w *GzipWriter) backupName(t time.Time) string {
+	prefix, ext := w.backupParts()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backupParts returns the prefix and the extension of the rotated file names
+func (w *GzipWriter) backupParts() (string, string) {
+	name := w.cfg.Filename
+	ext := ""
+	if strings.HasSuffix(name, ".gz") {
+		name = strings.TrimSuffix(name, ".gz")
+		ext = ".gz"
+	}
+	ext = filepath.Ext(name) + ext
+	return strings.TrimSuffix(w.cfg.Filename, ext) + "-", ext
+}
+
+// removeBackups removes the oldest rotated files above MaxBackups
+func (w *GzipWriter) removeBackups() error {
+	if w.cfg.MaxBackups <= 0 {
+		return nil
+	}
+	prefix, ext := w.backupParts()
+	list, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var backups []string
+	for _, name := range list {
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= w.cfg.MaxBackups {
+		return nil
+	}
+	// the time format is sortable
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-w.cfg.MaxBackups] {
+		if err := os.Remove(name); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Close stops the flush points, finishes the gzip member and closes the file
+func (w *GzipWriter) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return errors.New("already closed")
+	}
+	w.closed = true
+	err := w.closeFile()
+	w.lock.Unlock()
+
+	close(w.stop)
+	<-w.stopped
+	return err
+}
+
+func (w *GzipWriter) flushLoop(done func()) {
+	defer func() {
+		done()
+		close(w.stopped)
+	}()
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = w.Flush()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// countingWriter counts the bytes written to the file
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,131 @@
+package logrotate_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/logrotate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readGzip(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestGzipWriter(t *testing.T) {
+	_, err := logrotate.NewGzipWriter(logrotate.GzipConfig{})
+	assert.EqualError(t, err, "filename is required")
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "logs", "app.log.gz")
+	_, err = logrotate.NewGzipWriter(logrotate.GzipConfig{Filename: name, Level: 100})
+	assert.Error(t, err)
+
+	w, err := logrotate.NewGzipWriter(logrotate.GzipConfig{Filename: name, FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("line1\n"))
+	require.NoError(t, err)
+
+	// the flush point allows to read the data written so far
+	assert.Eventually(t, func() bool {
+		f, err := os.Open(name)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			return false
+		}
+		b, _ := io.ReadAll(r)
+		return string(b) == "line1\n"
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, w.Close())
+	assert.EqualError(t, w.Close(), "already closed")
+	_, err = w.Write([]byte("closed\n"))
+	assert.EqualError(t, err, "writer is closed")
+	assert.NoError(t, w.Flush())
+	assert.EqualError(t, w.Rotate(), "writer is closed")
+
+	// the existing file is appended with a new member
+	w, err = logrotate.NewGzipWriter(logrotate.GzipConfig{Filename: name})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("line2\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "line1\nline2\n", readGzip(t, name))
+}
+
+func TestGzipWriter_Rotate(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fn := xlog.TimeNowFn
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = fn
+	}()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log.gz")
+	w, err := logrotate.NewGzipWriter(logrotate.GzipConfig{Filename: name, MaxBackups: 2})
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, line := range []string{"line1\n", "line2\n", "line3\n"} {
+		_, err = w.Write([]byte(line))
+		require.NoError(t, err)
+		require.NoError(t, w.Rotate())
+		now = now.Add(time.Second)
+	}
+	_, err = w.Write([]byte("line4\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	list, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "app-2024-01-02T03-04-06.000.log.gz"),
+		filepath.Join(dir, "app-2024-01-02T03-04-07.000.log.gz"),
+		name,
+	}, list)
+	assert.Equal(t, "line2\n", readGzip(t, list[0]))
+	assert.Equal(t, "line3\n", readGzip(t, list[1]))
+}
+
+func TestGzipWriter_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log.gz")
+	w, err := logrotate.NewGzipWriter(logrotate.GzipConfig{Filename: name, MaxSize: 1})
+	require.NoError(t, err)
+
+	data := make([]byte, 1024*1024+1)
+	_, _ = rand.Read(data)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("next\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	list, err := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.True(t, bytes.Equal(data, []byte(readGzip(t, list[0]))))
+	assert.Equal(t, "next\n", readGzip(t, name))
+}
@@ -16,9 +16,11 @@ package logrotate
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/effective-security/xlog"
@@ -26,67 +28,359 @@ import (
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
+// Rotator is the log rotator returned by Initialize
+type Rotator interface {
+	io.Closer
+	// Rotate forces the rotation: the buffered entries are written,
+	// and the current file is renamed with the current time, and a new file is opened
+	Rotate() error
+}
+
 type logrotator struct {
+	lock         sync.Mutex
 	oldFormatter xlog.Formatter
-	logger       io.Writer
-	channel      *ChannelWriter
+	outputs      []*output
 	closed       bool
+	unregister   func()
+}
+
+// output is the rotated file, with the extra sink and the channel writer if configured
+type output struct {
+	file    *fileWriter
+	logger  io.Writer
+	channel *ChannelWriter
+}
+
+// Config specifies configuration for the log rotator
+type Config struct {
+	// Folder is the folder of the log files, created if needed
+	Folder string
+	// BaseFilename is the name of the log file without the .log extension
+	BaseFilename string
+	// MaxAge is the maximum number of days to retain the rotated files,
+	// zero retains the files regardless of their age
+	MaxAge int
+	// MaxSize is the maximum size in megabytes of the file before it is rotated,
+	// 100 megabytes by default
+	MaxSize int
+	// MaxTotalSize is the maximum combined size in megabytes of the current and the rotated files,
+	// the oldest rotated files are removed first. Zero disables the limit.
+	MaxTotalSize int
+	// ErrorLog specifies the rotation of <BaseFilename>.error.log file,
+	// that receives ERROR and CRITICAL entries, while the other entries
+	// are written to <BaseFilename>.log. If not specified, all entries are written to <BaseFilename>.log.
+	ErrorLog *FileRotation
+	// Buffered specifies to write the entries in a background goroutine
+	Buffered bool
+	// ExtraSink is the additional writer of the entries, such as os.Stderr
+	ExtraSink io.Writer
+	// DirMode is the permissions of the created folder, 0755 by default
+	DirMode os.FileMode
+	// FileMode is the permissions of the log files, such as 0600 for sensitive logs.
+	// If not specified, the new files are created with 0600,
+	// and the permissions of the existing files are preserved.
+	FileMode os.FileMode
+	// Owner is the user name or ID of the folder and the log files,
+	// such as the service user. Supported on Unix only.
+	Owner string
+	// Group is the group name or ID of the folder and the log files.
+	// Supported on Unix only.
+	Group string
+}
+
+// FileRotation specifies the rotation of a log file
+type FileRotation struct {
+	// MaxAge is the maximum number of days to retain the rotated files,
+	// zero retains the files regardless of their age
+	MaxAge int
+	// MaxSize is the maximum size in megabytes of the file before it is rotated,
+	// 100 megabytes by default
+	MaxSize int
+	// MaxTotalSize is the maximum combined size in megabytes of the current and the rotated files,
+	// the oldest rotated files are removed first. Zero disables the limit.
+	MaxTotalSize int
+}
+
+// fileWriter is the buffered writer of the log file,
+// serializing the writes with the rotation
+type fileWriter struct {
+	lock    sync.Mutex
+	buf     *bufio.Writer
+	file    *lumberjack.Logger
+	limiter *sizeLimiter
+	perm    permissions
+}
+
+// permissions is the mode and ownership of the log files,
+// the rotated files preserve them
+type permissions struct {
+	mode os.FileMode
+	uid  int
+	gid  int
+}
+
+// apply creates the file if needed, and sets its mode and ownership
+func (p permissions) apply(name string) error {
+	if p.mode == 0 && p.uid == -1 && p.gid == -1 {
+		return nil
+	}
+	mode := p.mode
+	if mode == 0 {
+		// lumberjack default
+		mode = 0600
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_ = f.Close()
+	if p.mode != 0 {
+		// the mode of the existing file, or masked by umask
+		if err := os.Chmod(name, p.mode); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return chown(name, p.uid, p.gid)
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(p)
+}
+
+// Flush writes the buffered data to the file
+func (w *fileWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return errors.WithStack(w.buf.Flush())
+}
+
+// Rotate writes the buffered data, and rotates the file
+func (w *fileWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.buf.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.file.Rotate(); err != nil {
+		return errors.WithStack(err)
+	}
+	// lumberjack preserves the ownership of the rotated file on Linux only
+	if err := w.perm.apply(w.file.Filename); err != nil {
+		return err
+	}
+	if w.limiter != nil {
+		return w.limiter.rotated()
+	}
+	return nil
 }
 
 // Initialize creates a lumberjack log rotator and redirects logs output to it.
 // To ensure that any queued/buffered but unwritten log entries are flushed to disk
-// call Stop() on the returned stopper before exiting the process.
-// Once stopped, you can't resume the logger, you need to create a new one.
-func Initialize(logFolder, baseFilename string, maxAge, maxSize int, buffered bool, extraSink io.Writer) (io.Closer, error) {
-	err := os.MkdirAll(logFolder, 0755)
+// call Close() on the returned rotator before exiting the process.
+// Once closed, you can't resume the logger, you need to create a new one.
+// Rotate() can be called to force the rotation, such as on SIGHUP or by an admin endpoint.
+func Initialize(logFolder, baseFilename string, maxAge, maxSize int, buffered bool, extraSink io.Writer) (Rotator, error) {
+	return InitializeWithConfig(Config{
+		Folder:       logFolder,
+		BaseFilename: baseFilename,
+		MaxAge:       maxAge,
+		MaxSize:      maxSize,
+		Buffered:     buffered,
+		ExtraSink:    extraSink,
+	})
+}
+
+// InitializeWithConfig creates a lumberjack log rotator and redirects logs output to it,
+// see Initialize
+func InitializeWithConfig(cfg Config) (Rotator, error) {
+	uid, gid, err := lookupOwner(cfg.Owner, cfg.Group)
+	if err != nil {
+		return nil, err
+	}
+	perm := permissions{
+		mode: cfg.FileMode,
+		uid:  uid,
+		gid:  gid,
+	}
+
+	dirMode := cfg.DirMode
+	if dirMode == 0 {
+		dirMode = 0755
+	}
+	err = os.MkdirAll(cfg.Folder, dirMode)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if cfg.DirMode != 0 {
+		// the mode of the existing folder, or masked by umask
+		if err = os.Chmod(cfg.Folder, cfg.DirMode); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err = chown(cfg.Folder, uid, gid); err != nil {
+		return nil, err
+	}
 
-	fileWriter := lumberjack.Logger{
-		Filename: filepath.Join(logFolder, baseFilename+".log"),
-		MaxAge:   maxAge,
-		MaxSize:  maxSize,
+	rotations := []FileRotation{{
+		MaxAge:       cfg.MaxAge,
+		MaxSize:      cfg.MaxSize,
+		MaxTotalSize: cfg.MaxTotalSize,
+	}}
+	names := []string{cfg.BaseFilename + ".log"}
+	if cfg.ErrorLog != nil {
+		rotations = append(rotations, *cfg.ErrorLog)
+		names = append(names, cfg.BaseFilename+".error.log")
 	}
 
 	l := &logrotator{
-		logger:       bufio.NewWriterSize(&fileWriter, 8192),
 		oldFormatter: xlog.GetFormatter(),
 	}
-
-	if extraSink != nil {
-		l.logger = io.MultiWriter(l.logger, extraSink)
-	}
-
-	if buffered {
-		l.channel = NewChannelWriter(l.logger, 256, time.Second)
+	for i, r := range rotations {
+		file, err := newFileWriter(filepath.Join(cfg.Folder, names[i]), r, perm)
+		if err != nil {
+			return nil, err
+		}
+		o := &output{
+			file:   file,
+			logger: file,
+		}
+		if cfg.ExtraSink != nil {
+			o.logger = io.MultiWriter(o.logger, cfg.ExtraSink)
+		}
+		if cfg.Buffered {
+			o.channel = NewChannelWriter(o.logger, 256, time.Second)
+		}
+		l.outputs = append(l.outputs, o)
 	}
 
 	xlog.SetFormatter(xlog.NewDefaultFormatter(l.destination()))
+	// the queued entries are written on Shutdown, Fatal and Panic
+	l.unregister = xlog.RegisterShutdown(xlog.ShutdownSink{
+		Name:     "logrotate:" + cfg.BaseFilename,
+		Priority: xlog.PriorityFile,
+		Flush:    func(context.Context) error { return l.flush() },
+	})
 
 	return l, nil
 }
 
+// newFileWriter returns the writer of the rotated file
+func newFileWriter(filename string, r FileRotation, perm permissions) (*fileWriter, error) {
+	if r.MaxSize <= 0 {
+		// lumberjack default
+		r.MaxSize = 100
+	}
+	if r.MaxTotalSize > 0 && r.MaxTotalSize < r.MaxSize {
+		return nil, errors.Errorf("max total size %dMB is less than max size %dMB", r.MaxTotalSize, r.MaxSize)
+	}
+	// lumberjack preserves the mode of the existing file
+	if err := perm.apply(filename); err != nil {
+		return nil, err
+	}
+
+	lj := &lumberjack.Logger{
+		Filename: filename,
+		MaxAge:   r.MaxAge,
+		MaxSize:  r.MaxSize,
+	}
+	fw := &fileWriter{
+		file: lj,
+		perm: perm,
+	}
+	if r.MaxTotalSize > 0 {
+		fw.limiter = newSizeLimiter(lj, lj.Filename, r.MaxSize, r.MaxTotalSize)
+		fw.buf = bufio.NewWriterSize(fw.limiter, 8192)
+	} else {
+		fw.buf = bufio.NewWriterSize(lj, 8192)
+	}
+	return fw, nil
+}
+
+func (o *output) destination() io.Writer {
+	if o.channel != nil {
+		return o.channel
+	}
+	return o.logger
+}
+
+// destination returns the writer of the formatter,
+// that writes ERROR and CRITICAL entries to the error log, if configured
 func (c *logrotator) destination() io.Writer {
-	if c.channel != nil {
-		return c.channel
+	main := c.outputs[0].destination()
+	if len(c.outputs) == 1 {
+		return main
+	}
+	errorLog := c.outputs[1].destination()
+	return xlog.LevelWriterFunc(func(level xlog.LogLevel) io.Writer {
+		if level <= xlog.ERROR {
+			return errorLog
+		}
+		return main
+	}, main)
+}
+
+// Rotate writes the queued entries, and rotates the files
+func (c *logrotator) Rotate() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errors.New("already closed")
+	}
+	var err error
+	for _, o := range c.outputs {
+		if o.channel != nil {
+			o.channel.Flush()
+		}
+		if rerr := o.file.Rotate(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// flush writes the queued and buffered entries to the files
+func (c *logrotator) flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return nil
 	}
-	return c.logger
+	var err error
+	for _, o := range c.outputs {
+		if o.channel != nil {
+			o.channel.Flush()
+		}
+		if ferr := o.file.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
 }
 
 // Close will ensure that queued/buffered but unwritten log entries are flushed to disk
 func (c *logrotator) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if c.closed {
 		return errors.New("already closed")
 	}
 	c.closed = true
+	c.unregister()
 
 	// restore output
 	xlog.SetFormatter(c.oldFormatter)
 
-	if c.channel != nil {
-		c.channel.Stop()
-		c.channel = nil
+	var err error
+	for _, o := range c.outputs {
+		if o.channel != nil {
+			o.channel.Stop()
+			o.channel = nil
+		}
+		if ferr := o.file.Flush(); err == nil {
+			err = ferr
+		}
 	}
-	return nil
+	return err
 }
@@ -5,6 +5,9 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/effective-security/xlog"
@@ -41,3 +44,177 @@ func Test_Rotate(t *testing.T) {
 	writer.Flush()
 	assert.NotEmpty(t, b.Bytes())
 }
+
+func Test_ForceRotate(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logRotate, err := logrotate.Initialize(tmpDir, "forced", 1, 1, true, nil)
+	require.NoError(t, err)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "logrotate")
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.Info("before rotation")
+	require.NoError(t, logRotate.Rotate())
+	logger.Info("after rotation")
+	require.NoError(t, logRotate.Close())
+
+	assert.EqualError(t, logRotate.Rotate(), "already closed")
+	assert.EqualError(t, logRotate.Close(), "already closed")
+
+	backups, err := filepath.Glob(filepath.Join(tmpDir, "forced-*.log"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	b, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "before rotation")
+	assert.NotContains(t, string(b), "after rotation")
+
+	b, err = os.ReadFile(filepath.Join(tmpDir, "forced.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "after rotation")
+}
+
+func Test_MaxTotalSize(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, err := logrotate.InitializeWithConfig(logrotate.Config{
+		Folder:       tmpDir,
+		BaseFilename: "capped",
+		MaxSize:      2,
+		MaxTotalSize: 1,
+	})
+	assert.EqualError(t, err, "max total size 1MB is less than max size 2MB")
+
+	logRotate, err := logrotate.InitializeWithConfig(logrotate.Config{
+		Folder:       tmpDir,
+		BaseFilename: "capped",
+		MaxSize:      1,
+		MaxTotalSize: 3,
+	})
+	require.NoError(t, err)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "logrotate")
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	line := strings.Repeat("x", 1000)
+	for i := 0; i < 8000; i++ {
+		logger.KV(xlog.INFO, "i", i, "line", line)
+		if i%2000 == 0 {
+			require.NoError(t, logRotate.Rotate())
+		}
+	}
+	require.NoError(t, logRotate.Close())
+
+	files, err := filepath.Glob(filepath.Join(tmpDir, "capped*.log"))
+	require.NoError(t, err)
+	assert.Greater(t, len(files), 2)
+
+	var total int64
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		require.NoError(t, err)
+		total += fi.Size()
+	}
+	assert.LessOrEqual(t, total, int64(3*1024*1024))
+}
+
+func Test_ErrorLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logRotate, err := logrotate.InitializeWithConfig(logrotate.Config{
+		Folder:       tmpDir,
+		BaseFilename: "split",
+		MaxSize:      1,
+		ErrorLog: &logrotate.FileRotation{
+			MaxSize: 2,
+		},
+		Buffered: true,
+	})
+	require.NoError(t, err)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "logrotate")
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.Info("info entry")
+	logger.KV(xlog.ERROR, "reason", "error entry")
+	logger.Warning("warning entry")
+	require.NoError(t, logRotate.Close())
+
+	b, err := os.ReadFile(filepath.Join(tmpDir, "split.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "info entry")
+	assert.Contains(t, string(b), "warning entry")
+	assert.NotContains(t, string(b), "error entry")
+
+	b, err = os.ReadFile(filepath.Join(tmpDir, "split.error.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "error entry")
+	assert.NotContains(t, string(b), "info entry")
+	assert.NotContains(t, string(b), "warning entry")
+}
+
+func Test_Permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	tmpDir := filepath.Join(t.TempDir(), "logs")
+
+	_, err := logrotate.InitializeWithConfig(logrotate.Config{
+		Folder:       tmpDir,
+		BaseFilename: "perm",
+		Owner:        "xlog-no-such-user",
+	})
+	require.Error(t, err)
+
+	logRotate, err := logrotate.InitializeWithConfig(logrotate.Config{
+		Folder:       tmpDir,
+		BaseFilename: "perm",
+		DirMode:      0750,
+		FileMode:     0640,
+		Owner:        strconv.Itoa(os.Getuid()),
+		Group:        strconv.Itoa(os.Getgid()),
+	})
+	require.NoError(t, err)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "logrotate")
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.Info("before rotation")
+	require.NoError(t, logRotate.Rotate())
+	logger.Info("after rotation")
+	require.NoError(t, logRotate.Close())
+
+	fi, err := os.Stat(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+
+	files, err := filepath.Glob(filepath.Join(tmpDir, "perm*.log"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), fi.Mode().Perm(), name)
+	}
+}
+
+func Test_FatalFlush(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logRotate, err := logrotate.Initialize(tmpDir, "fatal", 1, 1, true, nil)
+	require.NoError(t, err)
+	defer logRotate.Close()
+
+	oldExit := xlog.ExitFunc
+	xlog.ExitFunc = func(int) {
+		b, err := os.ReadFile(filepath.Join(tmpDir, "fatal.log"))
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "before exit")
+	}
+	defer func() { xlog.ExitFunc = oldExit }()
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "logrotate")
+	logger.Fatal("before exit")
+}
@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package logrotate
+
+import (
+	"github.com/pkg/errors"
+)
+
+func lookupOwner(owner, group string) (int, int, error) {
+	if owner != "" || group != "" {
+		return -1, -1, errors.New("file ownership is not supported on this platform")
+	}
+	return -1, -1, nil
+}
+
+func chown(_ string, _, _ int) error {
+	return nil
+}
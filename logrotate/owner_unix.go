@@ -0,0 +1,47 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package logrotate
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// lookupOwner returns uid and gid of the user and group names or IDs,
+// -1 is returned for the empty name, to keep the current value
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return -1, -1, errors.WithMessagef(err, "failed to lookup user %q", owner)
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, errors.WithMessagef(err, "failed to lookup group %q", group)
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+	return uid, gid, nil
+}
+
+func chown(name string, uid, gid int) error {
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return errors.WithStack(os.Chown(name, uid, gid))
+}
@@ -0,0 +1,106 @@
+package logrotate
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// spillHeaderSize is the size of the record length prefix
+const spillHeaderSize = 4
+
+// spillBuffer is a temp file with the length-prefixed records,
+// written at the end and read from the start.
+// The file is truncated when all the records are read.
+type spillBuffer struct {
+	file *os.File
+	max  int64
+	// wpos is the end of the written records
+	wpos int64
+	// rpos is the start of the next record to read
+	rpos int64
+	// records is the number of records to read
+	records int
+	// header is the buffer of the record length prefix
+	header [spillHeaderSize]byte
+}
+
+func newSpillBuffer(dir string, maxSize int64) (*spillBuffer, error) {
+	f, err := os.CreateTemp(dir, "xlog-spill-*")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &spillBuffer{
+		file: f,
+		max:  maxSize,
+	}, nil
+}
+
+// pending returns true if the buffer has records to read
+func (s *spillBuffer) pending() bool {
+	return s.rpos < s.wpos
+}
+
+// size returns the size of the records to read
+func (s *spillBuffer) size() int64 {
+	return s.wpos - s.rpos
+}
+
+// push appends the record, returns false if the file is full or not writable
+func (s *spillBuffer) push(b []byte) bool {
+	n := int64(spillHeaderSize + len(b))
+	if s.wpos+n > s.max {
+		return false
+	}
+	binary.BigEndian.PutUint32(s.header[:], uint32(len(b)))
+	if _, err := s.file.WriteAt(s.header[:], s.wpos); err != nil {
+		return false
+	}
+	if _, err := s.file.WriteAt(b, s.wpos+spillHeaderSize); err != nil {
+		return false
+	}
+	s.wpos += n
+	s.records++
+	return true
+}
+
+// pop reads the next record to buf
+func (s *spillBuffer) pop(buf []byte) ([]byte, error) {
+	if _, err := s.file.ReadAt(s.header[:], s.rpos); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n := int(binary.BigEndian.Uint32(s.header[:]))
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := s.file.ReadAt(buf, s.rpos+spillHeaderSize); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.rpos += int64(spillHeaderSize + n)
+	s.records--
+	if s.rpos == s.wpos {
+		_ = s.reset()
+	}
+	return buf, nil
+}
+
+// reset discards the records, and returns the number of discarded records
+func (s *spillBuffer) reset() int {
+	discarded := s.records
+	s.rpos = 0
+	s.wpos = 0
+	s.records = 0
+	_ = s.file.Truncate(0)
+	return discarded
+}
+
+// close closes and removes the file
+func (s *spillBuffer) close() error {
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return errors.WithStack(err)
+}
@@ -0,0 +1,157 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/xlog"
+)
+
+func TestSpillBuffer_Full(t *testing.T) {
+	// 3 records fit, 6 bytes each with the prefix
+	s, err := newSpillBuffer(t.TempDir(), 20)
+	if err != nil {
+		t.Fatalf("Unable to create spill: %v", err)
+	}
+	defer s.close()
+
+	for i := 0; i < 3; i++ {
+		if !s.push([]byte(fmt.Sprintf("w%d", i))) {
+			t.Fatalf("Expecting push %d to succeed", i)
+		}
+	}
+	if s.push([]byte("w3")) {
+		t.Fatalf("Expecting push to fail when the file is full")
+	}
+	if s.size() != 18 || s.records != 3 {
+		t.Fatalf("Unexpected size %d and records %d", s.size(), s.records)
+	}
+
+	// the read space is not reused until all the records are read
+	b, err := s.pop(nil)
+	if err != nil || string(b) != "w0" {
+		t.Fatalf("Unexpected record %q: %v", b, err)
+	}
+	if s.push([]byte("w3")) {
+		t.Fatalf("Expecting push to fail before the records are read")
+	}
+	for i := 1; i < 3; i++ {
+		b, err = s.pop(b)
+		if err != nil || string(b) != fmt.Sprintf("w%d", i) {
+			t.Fatalf("Unexpected record %q: %v", b, err)
+		}
+	}
+
+	// the file is truncated when all the records are read
+	if s.pending() || s.records != 0 {
+		t.Fatalf("Expecting no pending records")
+	}
+	fi, err := s.file.Stat()
+	if err != nil || fi.Size() != 0 {
+		t.Fatalf("Expecting the file to be truncated: %v", err)
+	}
+	if !s.push([]byte("w3")) {
+		t.Fatalf("Expecting push to succeed after the records are read")
+	}
+}
+
+func TestSpillBuffer_Order(t *testing.T) {
+	s, err := newSpillBuffer(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatalf("Unable to create spill: %v", err)
+	}
+	defer s.close()
+
+	// the records of different size are read in the order of writes,
+	// while the writes continue
+	next := 0
+	var buf []byte
+	for i := 0; i < 100; i++ {
+		if !s.push([]byte(fmt.Sprintf("%0*d", i%7+1, i))) {
+			t.Fatalf("Unable to push %d", i)
+		}
+		if i%3 != 0 {
+			continue
+		}
+		buf, err = s.pop(buf)
+		if err != nil {
+			t.Fatalf("Unable to pop %d: %v", next, err)
+		}
+		if exp := fmt.Sprintf("%0*d", next%7+1, next); string(buf) != exp {
+			t.Fatalf("Expecting %q, got %q", exp, buf)
+		}
+		next++
+	}
+	for ; s.pending(); next++ {
+		buf, err = s.pop(buf)
+		if err != nil {
+			t.Fatalf("Unable to pop %d: %v", next, err)
+		}
+		if exp := fmt.Sprintf("%0*d", next%7+1, next); string(buf) != exp {
+			t.Fatalf("Expecting %q, got %q", exp, buf)
+		}
+	}
+	if next != 100 {
+		t.Fatalf("Expecting 100 records, got %d", next)
+	}
+}
+
+func TestChannelWriter_SpillReadError(t *testing.T) {
+	var reported []string
+	var lock sync.Mutex
+	xlog.OnError(func(pkg string) {
+		lock.Lock()
+		defer lock.Unlock()
+		reported = append(reported, pkg)
+	})
+	defer xlog.OnError(nil)
+
+	dest := &blockedWriter{release: make(chan struct{})}
+	cw := NewChannelWriter(dest, 1, 0)
+	if err := cw.SetSpill(t.TempDir(), 1024); err != nil {
+		t.Fatalf("Unable to enable spill: %v", err)
+	}
+	var dropped int
+	cw.OnDrop(func(d []byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		if d != nil {
+			t.Errorf("Expecting nil data for the lost spilled write, got %q", d)
+		}
+		dropped++
+	})
+
+	for i := 0; i < 10; i++ {
+		_, _ = cw.Write([]byte(fmt.Sprintf("w%d", i)))
+	}
+	stats := cw.Stats()
+	if stats.Spilled == 0 {
+		t.Fatalf("Expecting spilled writes: %+v", stats)
+	}
+
+	// the spilled writes can not be read
+	cw.lock.Lock()
+	name := cw.spill.file.Name()
+	cw.lock.Unlock()
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatalf("Unable to truncate spill: %v", err)
+	}
+
+	close(dest.release)
+	cw.Stop()
+
+	stats = cw.Stats()
+	if stats.Dropped != stats.Spilled || stats.Written+stats.Dropped != 10 || stats.SpillSize != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if uint64(dropped) != stats.Spilled {
+		t.Errorf("Expecting %d dropped writes, got %d", stats.Spilled, dropped)
+	}
+	if len(reported) != 1 || reported[0] != ErrorPkg {
+		t.Errorf("Expecting the error reported for %s, got %v", ErrorPkg, reported)
+	}
+}
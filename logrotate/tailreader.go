@@ -0,0 +1,193 @@
+package logrotate
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// TailReader follows a log file across rotations, like `tail -F`,
+// and emits complete lines
+type TailReader struct {
+	filename     string
+	pollInterval time.Duration
+
+	lines   chan string
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	reader  *bufio.Reader
+	partial []byte
+}
+
+// NewTailReader returns a reader, that follows the file.
+// If fromEnd is true, the existing content of the file is skipped.
+// pollInterval specifies how often the file is checked for new content
+// and rotation, 250ms by default.
+// The caller must call Close to stop the background goroutine.
+func NewTailReader(filename string, fromEnd bool, pollInterval time.Duration) (*TailReader, error) {
+	if pollInterval <= 0 {
+		pollInterval = 250 * time.Millisecond
+	}
+	t := &TailReader{
+		filename:     filename,
+		pollInterval: pollInterval,
+		lines:        make(chan string, 256),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	err := t.open()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if t.file != nil && fromEnd {
+		t.offset, err = t.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			t.file.Close()
+			return nil, err
+		}
+	}
+
+	done := xlog.TrackGoroutine("logrotate.TailReader")
+	go t.run(done)
+	return t, nil
+}
+
+// Lines returns the channel of lines, without the trailing new line.
+// The channel is closed when the reader is closed.
+func (t *TailReader) Lines() <-chan string {
+	return t.lines
+}
+
+// Close stops the reader
+func (t *TailReader) Close() error {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	<-t.stopped
+	return nil
+}
+
+func (t *TailReader) open() error {
+	f, err := os.Open(t.filename)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.file = f
+	t.info = info
+	t.offset = 0
+	t.reader = bufio.NewReader(f)
+	return nil
+}
+
+func (t *TailReader) closeFile() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+		t.reader = nil
+	}
+}
+
+func (t *TailReader) run(done func()) {
+	defer func() {
+		t.closeFile()
+		close(t.lines)
+		done()
+		close(t.stopped)
+	}()
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if !t.poll() {
+			return
+		}
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads new lines, and reopens the file if it was rotated.
+// It returns false if the reader is stopped.
+func (t *TailReader) poll() bool {
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			return true
+		}
+	}
+
+	if !t.readLines() {
+		return false
+	}
+
+	info, err := os.Stat(t.filename)
+	if err != nil {
+		// the file is moved, and new one is not created yet
+		return true
+	}
+	if !os.SameFile(info, t.info) {
+		// rotated: the remaining content of the old file is read above
+		t.closeFile()
+		t.partial = nil
+		if err := t.open(); err != nil {
+			return true
+		}
+		return t.readLines()
+	}
+	if info.Size() < t.offset {
+		// truncated
+		if _, err := t.file.Seek(0, io.SeekStart); err == nil {
+			t.offset = 0
+			t.partial = nil
+			t.reader.Reset(t.file)
+			return t.readLines()
+		}
+	}
+	return true
+}
+
+// readLines emits complete lines, and returns false if the reader is stopped
+func (t *TailReader) readLines() bool {
+	for {
+		b, err := t.reader.ReadBytes('\n')
+		t.offset += int64(len(b))
+		if err != nil {
+			// incomplete line, wait for the rest
+			t.partial = append(t.partial, b...)
+			return true
+		}
+
+		line := b[:len(b)-1]
+		if len(t.partial) > 0 {
+			line = append(t.partial, line...)
+			t.partial = nil
+		}
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+
+		select {
+		case t.lines <- string(line):
+		case <-t.stop:
+			return false
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, tr *TailReader, count int) []string {
+	var lines []string
+	timeout := time.After(5 * time.Second)
+	for len(lines) < count {
+		select {
+		case l := <-tr.Lines():
+			lines = append(lines, l)
+		case <-timeout:
+			t.Fatalf("timeout waiting for lines, got: %v", lines)
+		}
+	}
+	return lines
+}
+
+func appendFile(t *testing.T, name, s string) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestTailReader(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+
+	appendFile(t, name, "old\n")
+
+	tr, err := NewTailReader(name, true, time.Millisecond)
+	require.NoError(t, err)
+
+	appendFile(t, name, "line1\nline")
+	appendFile(t, name, "2\r\n")
+	assert.Equal(t, []string{"line1", "line2"}, readLines(t, tr, 2))
+
+	// rotate
+	appendFile(t, name, "before rotation\n")
+	require.NoError(t, os.Rename(name, name+".1"))
+	appendFile(t, name, "after rotation\n")
+	assert.Equal(t, []string{"before rotation", "after rotation"}, readLines(t, tr, 2))
+
+	// truncate
+	require.NoError(t, os.Truncate(name, 0))
+	time.Sleep(10 * time.Millisecond)
+	appendFile(t, name, "new\n")
+	assert.Equal(t, []string{"new"}, readLines(t, tr, 1))
+
+	require.NoError(t, tr.Close())
+	require.NoError(t, tr.Close())
+	_, ok := <-tr.Lines()
+	assert.False(t, ok)
+	assert.NoError(t, xlog.CheckGoroutines())
+}
+
+func TestTailReader_NotExists(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+
+	tr, err := NewTailReader(name, false, time.Millisecond)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	appendFile(t, name, "first\n")
+	assert.Equal(t, []string{"first"}, readLines(t, tr, 1))
+}
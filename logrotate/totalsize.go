@@ -0,0 +1,107 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lumberjackTimeFormat is the time format of the files rotated by lumberjack
+const lumberjackTimeFormat = "2006-01-02T15-04-05.000"
+
+const megabyte = 1024 * 1024
+
+// sizeLimiter is the writer to lumberjack, that detects the rotations,
+// and removes the oldest rotated files above the total size
+type sizeLimiter struct {
+	w        io.Writer
+	filename string
+	// maxSize is the size of the file before it is rotated
+	maxSize int64
+	// maxBackups is the total size of the rotated files
+	maxBackups int64
+	// size is the size of the current file
+	size int64
+}
+
+func newSizeLimiter(w io.Writer, filename string, maxSize, maxTotalSize int) *sizeLimiter {
+	l := &sizeLimiter{
+		w:          w,
+		filename:   filename,
+		maxSize:    int64(maxSize) * megabyte,
+		maxBackups: int64(maxTotalSize-maxSize) * megabyte,
+	}
+	if fi, err := os.Stat(filename); err == nil {
+		l.size = fi.Size()
+	}
+	return l
+}
+
+func (l *sizeLimiter) Write(p []byte) (int, error) {
+	// lumberjack rotates the file before the write, that exceeds the size
+	rotated := l.size+int64(len(p)) > l.maxSize
+	n, err := l.w.Write(p)
+	if rotated {
+		l.size = int64(n)
+		_ = l.removeBackups()
+	} else {
+		l.size += int64(n)
+	}
+	return n, err
+}
+
+// rotated is called after the forced rotation
+func (l *sizeLimiter) rotated() error {
+	l.size = 0
+	return l.removeBackups()
+}
+
+// removeBackups removes the oldest rotated files above the total size
+func (l *sizeLimiter) removeBackups() error {
+	ext := filepath.Ext(l.filename)
+	prefix := strings.TrimSuffix(l.filename, ext) + "-"
+	list, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	type backup struct {
+		name string
+		size int64
+	}
+	var backups []backup
+	var total int64
+	for _, name := range list {
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(strings.TrimSuffix(ts, ".gz"), ext)
+		if _, err := time.Parse(lumberjackTimeFormat, ts); err != nil {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			// removed by lumberjack
+			continue
+		}
+		backups = append(backups, backup{name: name, size: fi.Size()})
+		total += fi.Size()
+	}
+	// the time format is sortable
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].name < backups[j].name
+	})
+	for _, b := range backups {
+		if total <= l.maxBackups {
+			break
+		}
+		if err := os.Remove(b.name); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+		total -= b.size
+	}
+	return nil
+}
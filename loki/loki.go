@@ -0,0 +1,391 @@
+// Package loki provides formatter that pushes entries to Grafana Loki,
+// grouped into streams by labels, with batching and retries.
+package loki
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// PushPath is the path of Loki push API
+const PushPath = "/loki/api/v1/push"
+
+// Config specifies configuration for the Loki exporter
+type Config struct {
+	// Endpoint specifies Loki URL,
+	// PushPath is appended if the URL has no path
+	Endpoint string
+	// TenantID specifies X-Scope-OrgID header for multi-tenant Loki
+	TenantID string
+	// Authorization specifies the value of Authorization header
+	Authorization string
+	// Headers specifies extra headers
+	Headers map[string]string
+	// HTTPClient specifies the client, http.DefaultClient by default
+	HTTPClient *http.Client
+	// Labels specifies static labels of all streams, such as "service"
+	Labels map[string]string
+	// LabelKeys specifies the key-value entries used as stream labels,
+	// in addition to "pkg" and "level".
+	// Use low cardinality keys only, the values are removed from the line.
+	LabelKeys []string
+	// BatchSize specifies the maximum number of entries in a push, 1000 by default
+	BatchSize int
+	// BatchBytes specifies the maximum size of lines in a push, 1MB by default
+	BatchBytes int
+	// FlushInterval specifies the interval to push batched entries,
+	// 1 second by default
+	FlushInterval time.Duration
+	// MaxRetries specifies the number of retries of failed pushes, 3 by default
+	MaxRetries int
+	// Backoff specifies the initial delay between retries,
+	// doubled on each retry, 200ms by default
+	Backoff time.Duration
+}
+
+// Exporter is the formatter, that groups entries into streams by labels,
+// and pushes them with snappy-compressed protobuf encoding.
+// The batch is pushed when it is full, on the flush interval,
+// or on Flush and Shutdown calls.
+type Exporter struct {
+	cfg       Config
+	labelKeys map[string]bool
+
+	lock       sync.Mutex
+	withCaller bool
+	printEmpty bool
+	streams    map[string]*Stream
+	order      []string
+	count      int
+	size       int
+
+	// sendLock serializes pushes, so the entries of a stream are in order
+	sendLock sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New returns Exporter
+func New(cfg Config) (*Exporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if i := strings.Index(endpoint, "://"); i < 0 || !strings.Contains(endpoint[i+3:], "/") {
+		endpoint += PushPath
+	}
+	cfg.Endpoint = endpoint
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = 1024 * 1024
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+
+	e := &Exporter{
+		cfg:        cfg,
+		labelKeys:  map[string]bool{},
+		withCaller: true,
+		streams:    map[string]*Stream{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	for _, k := range cfg.LabelKeys {
+		e.labelKeys[k] = true
+	}
+	go e.run()
+	return e, nil
+}
+
+// Options allows to configure formatter behavior
+func (e *Exporter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			e.withCaller = true
+		case xlog.FormatNoCaller:
+			e.withCaller = false
+		case xlog.FormatPrintEmpty:
+			e.printEmpty = true
+		}
+	}
+	return e
+}
+
+// FormatKV adds the entry to the stream,
+// the entries are key/value pairs, emitted in logfmt
+func (e *Exporter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	e.format(pkg, l, depth+1, entries, "")
+}
+
+// Format adds the entry to the stream
+func (e *Exporter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	e.format(pkg, l, depth+1, nil, strings.TrimSpace(fmt.Sprint(entries...)))
+}
+
+// Flush pushes the batched entries,
+// the errors are reported to stderr
+func (e *Exporter) Flush() {
+	if err := e.ForceFlush(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "xlog: loki: %v\n", err)
+	}
+}
+
+// ForceFlush pushes the batched entries
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	e.lock.Lock()
+	batch := e.take()
+	e.lock.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	return e.push(ctx, batch)
+}
+
+// Shutdown stops the background flush, and pushes the batched entries
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+	})
+	return e.ForceFlush(ctx)
+}
+
+func (e *Exporter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	now := xlog.TimeNowFn()
+
+	e.lock.Lock()
+	labels := map[string]string{}
+	for k, v := range e.cfg.Labels {
+		labels[k] = v
+	}
+	if pkg != "" {
+		labels["pkg"] = pkg
+	}
+	labels["level"] = strings.ToLower(l.String())
+
+	var line strings.Builder
+	if e.withCaller {
+		caller, _, _ := xlog.Caller(depth + 1)
+		line.WriteString("func=")
+		line.WriteString(caller)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			e.lock.Unlock()
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if e.labelKeys[k] {
+			if v != nil {
+				labels[k] = strings.Trim(xlog.EscapedString(v), `"`)
+			}
+			continue
+		}
+		if v == nil && !e.printEmpty {
+			continue
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(k)
+		line.WriteByte('=')
+		line.WriteString(xlog.EscapedString(v))
+	}
+	if msg != "" {
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(msg)
+	}
+
+	key := formatLabels(labels)
+	s := e.streams[key]
+	if s == nil {
+		s = &Stream{Labels: key}
+		e.streams[key] = s
+		e.order = append(e.order, key)
+	}
+	s.Entries = append(s.Entries, Entry{Timestamp: now, Line: line.String()})
+	e.count++
+	e.size += line.Len()
+
+	var batch *PushRequest
+	if e.count >= e.cfg.BatchSize || e.size >= e.cfg.BatchBytes {
+		batch = e.take()
+	}
+	e.lock.Unlock()
+
+	xlog.ObserveEntrySize(pkg, line.Len())
+	if batch != nil {
+		if err := e.push(context.Background(), batch); err != nil {
+			fmt.Fprintf(os.Stderr, "xlog: loki: %v\n", err)
+		}
+	}
+}
+
+// take returns the batched streams, the caller must hold the lock
+func (e *Exporter) take() *PushRequest {
+	if e.count == 0 {
+		return nil
+	}
+	req := &PushRequest{Streams: make([]Stream, 0, len(e.order))}
+	for _, key := range e.order {
+		req.Streams = append(req.Streams, *e.streams[key])
+	}
+	e.streams = map[string]*Stream{}
+	e.order = nil
+	e.count = 0
+	e.size = 0
+	return req
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// push the batch, retrying with backoff
+// on network, throttling and server errors
+func (e *Exporter) push(ctx context.Context, req *PushRequest) error {
+	count := 0
+	for _, s := range req.Streams {
+		count += len(s.Entries)
+	}
+	body := snappy.Encode(nil, req.Marshal())
+
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+
+	delay := e.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = e.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= e.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "failed to push %d entries", count)
+		}
+		delay *= 2
+	}
+	return errors.WithMessagef(err, "failed to push %d entries", count)
+}
+
+// post sends the request, and returns true if it can be retried
+func (e *Exporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if e.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", e.cfg.TenantID)
+	}
+	if e.cfg.Authorization != "" {
+		req.Header.Set("Authorization", e.cfg.Authorization)
+	}
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, errors.Errorf("unexpected status: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// formatLabels returns the labels in Prometheus format,
+// sorted by name
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(labelName(k))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelName replaces the characters not allowed in label names
+func labelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && i > 0 {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
@@ -0,0 +1,157 @@
+package loki
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Exporter(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() { xlog.TimeNowFn = time.Now }()
+
+	var lock sync.Mutex
+	var bodies [][]byte
+	statuses := []int{http.StatusTooManyRequests, http.StatusNoContent}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		assert.Equal(t, PushPath, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "tenant1", r.Header.Get("X-Scope-OrgID"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		body, _ := io.ReadAll(r.Body)
+		decoded, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		bodies = append(bodies, decoded)
+
+		status := http.StatusNoContent
+		if len(statuses) > 0 {
+			status = statuses[0]
+			statuses = statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	_, err := New(Config{})
+	assert.EqualError(t, err, "endpoint is required")
+
+	e, err := New(Config{
+		Endpoint:      srv.URL,
+		TenantID:      "tenant1",
+		Authorization: "Bearer token",
+		Labels:        map[string]string{"service": "api"},
+		LabelKeys:     []string{"tenant.id"},
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	require.NoError(t, err)
+	e.Options(xlog.FormatNoCaller)
+
+	e.FormatKV("api", xlog.INFO, 1, "tenant.id", "t1", "k", 1, "empty", nil)
+	e.Format("api", xlog.ERROR, 1, "failed")
+	e.FormatKV("api", xlog.INFO, 1, "tenant.id", "t1", "k", "v")
+	require.NoError(t, e.ForceFlush(context.Background()))
+
+	expected := &PushRequest{
+		Streams: []Stream{
+			{
+				Labels: `{level="info", pkg="api", service="api", tenant_id="t1"}`,
+				Entries: []Entry{
+					{Timestamp: now, Line: "k=1"},
+					{Timestamp: now, Line: `k="v"`},
+				},
+			},
+			{
+				Labels: `{level="error", pkg="api", service="api"}`,
+				Entries: []Entry{
+					{Timestamp: now, Line: "failed"},
+				},
+			},
+		},
+	}
+
+	lock.Lock()
+	require.Len(t, bodies, 2)
+	assert.Equal(t, expected.Marshal(), bodies[0])
+	assert.Equal(t, bodies[0], bodies[1])
+	bodies = nil
+	lock.Unlock()
+
+	e.Options(xlog.FormatWithCaller)
+	e.Format("api", xlog.WARNING, 1, "with caller")
+	require.NoError(t, e.Shutdown(context.Background()))
+
+	expected = &PushRequest{
+		Streams: []Stream{
+			{
+				Labels: `{level="warning", pkg="api", service="api"}`,
+				Entries: []Entry{
+					{Timestamp: now, Line: "func=Test_Exporter with caller"},
+				},
+			},
+		},
+	}
+	lock.Lock()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, expected.Marshal(), bodies[0])
+	lock.Unlock()
+
+	// nothing to push
+	require.NoError(t, e.ForceFlush(context.Background()))
+}
+
+func Test_ExporterBatch(t *testing.T) {
+	var lock sync.Mutex
+	var count int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		count++
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("entry out of order"))
+	}))
+	defer srv.Close()
+
+	e, err := New(Config{
+		Endpoint:      srv.URL + "/custom/push",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer e.Shutdown(context.Background())
+
+	e.FormatKV("api", xlog.INFO, 1, "k", 1)
+	assert.Equal(t, 0, count)
+	e.FormatKV("api", xlog.INFO, 1, "k", 2)
+
+	lock.Lock()
+	assert.Equal(t, 1, count)
+	lock.Unlock()
+
+	e.FormatKV("api", xlog.INFO, 1, "k", 3)
+	err = e.ForceFlush(context.Background())
+	assert.EqualError(t, err, "failed to push 1 entries: unexpected status: 400 Bad Request: entry out of order")
+
+	assert.Panics(t, func() {
+		e.FormatKV("api", xlog.INFO, 1, 1, 2)
+	})
+}
+
+func Test_FormatLabels(t *testing.T) {
+	assert.Equal(t, `{}`, formatLabels(nil))
+	assert.Equal(t, `{_a="1", b_c="x\"y"}`, formatLabels(map[string]string{"b-c": `x"y`, "1a": "1"}))
+}
@@ -0,0 +1,81 @@
+package loki
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// PushRequest is the request of Loki push API
+type PushRequest struct {
+	Streams []Stream
+}
+
+// Stream is the list of entries with the same labels
+type Stream struct {
+	// Labels in Prometheus format, such as {level="info", pkg="api"}
+	Labels  string
+	Entries []Entry
+}
+
+// Entry is the log line
+type Entry struct {
+	Timestamp time.Time
+	Line      string
+}
+
+// protobuf wire types
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Marshal returns the protobuf encoding of logproto.PushRequest
+func (r *PushRequest) Marshal() []byte {
+	var b []byte
+	for i := range r.Streams {
+		b = appendBytesField(b, 1, r.Streams[i].marshal())
+	}
+	return b
+}
+
+// marshal returns the protobuf encoding of logproto.StreamAdapter
+func (s *Stream) marshal() []byte {
+	var b []byte
+	b = appendBytesField(b, 1, []byte(s.Labels))
+	for i := range s.Entries {
+		b = appendBytesField(b, 2, s.Entries[i].marshal())
+	}
+	return b
+}
+
+// marshal returns the protobuf encoding of logproto.EntryAdapter
+func (e *Entry) marshal() []byte {
+	// google.protobuf.Timestamp
+	var ts []byte
+	if sec := e.Timestamp.Unix(); sec != 0 {
+		ts = appendVarintField(ts, 1, uint64(sec))
+	}
+	if nanos := e.Timestamp.Nanosecond(); nanos != 0 {
+		ts = appendVarintField(ts, 2, uint64(nanos))
+	}
+
+	var b []byte
+	b = appendBytesField(b, 1, ts)
+	b = appendBytesField(b, 2, []byte(e.Line))
+	return b
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,32 @@
+package loki
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PushRequestMarshal(t *testing.T) {
+	req := &PushRequest{
+		Streams: []Stream{
+			{
+				Labels: `{a="b"}`,
+				Entries: []Entry{
+					{Timestamp: time.Unix(1, 2), Line: "hi"},
+				},
+			},
+		},
+	}
+
+	// PushRequest{streams: [{labels: `{a="b"}`, entries: [{timestamp: {seconds: 1, nanos: 2}, line: "hi"}]}]}
+	expected := "0a15" + // streams, 21 bytes
+		"0a07" + hex.EncodeToString([]byte(`{a="b"}`)) + // labels
+		"120a" + // entries, 10 bytes
+		"0a04" + "0801" + "1002" + // timestamp
+		"1202" + hex.EncodeToString([]byte("hi")) // line
+	assert.Equal(t, expected, hex.EncodeToString(req.Marshal()))
+
+	assert.Empty(t, (&PushRequest{}).Marshal())
+}
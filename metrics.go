@@ -0,0 +1,39 @@
+package xlog
+
+// MetricsSink receives the counts of the log entries per package and level,
+// such as xlogprom.Collector exposing them as Prometheus counters.
+// The methods are called on the logging path, and must not block.
+type MetricsSink interface {
+	// EntryLogged is called for each entry written to the formatter
+	EntryLogged(pkg string, level LogLevel)
+	// EntryDropped is called for each entry enabled by the level,
+	// but dropped by the rate limits, the hooks, or reported by ReportDropped
+	EntryDropped(pkg string, level LogLevel)
+}
+
+// SetMetricsSink sets the sink of the entries counts, nil removes it
+func SetMetricsSink(m MetricsSink) {
+	if m == nil {
+		logger.metrics.Store(nil)
+		return
+	}
+	logger.metrics.Store(&m)
+}
+
+// ReportDropped reports the entry dropped by a sink to MetricsSink,
+// sinks use it to report the entries dropped on overflow
+func ReportDropped(pkg string, level LogLevel) {
+	logger.entryDropped(pkg, level)
+}
+
+func (l *loggerStruct) entryLogged(pkg string, level LogLevel) {
+	if m := l.metrics.Load(); m != nil {
+		(*m).EntryLogged(pkg, level)
+	}
+}
+
+func (l *loggerStruct) entryDropped(pkg string, level LogLevel) {
+	if m := l.metrics.Load(); m != nil {
+		(*m).EntryDropped(pkg, level)
+	}
+}
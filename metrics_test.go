@@ -0,0 +1,79 @@
+package xlog_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingSink counts the entries per level
+type countingSink struct {
+	lock    sync.Mutex
+	logged  map[xlog.LogLevel]int
+	dropped map[xlog.LogLevel]int
+}
+
+func newCountingSink() *countingSink {
+	return &countingSink{
+		logged:  make(map[xlog.LogLevel]int),
+		dropped: make(map[xlog.LogLevel]int),
+	}
+}
+
+func (s *countingSink) EntryLogged(pkg string, level xlog.LogLevel) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if pkg == "xlog_test" {
+		s.logged[level]++
+	}
+}
+
+func (s *countingSink) EntryDropped(pkg string, level xlog.LogLevel) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if pkg == "xlog_test" {
+		s.dropped[level]++
+	}
+}
+
+func Test_MetricsSink(t *testing.T) {
+	m := newCountingSink()
+	xlog.SetMetricsSink(m)
+	defer xlog.SetMetricsSink(nil)
+	defer xlog.WithRateLimit("github.com/effective-security/xlog", "xlog_test", xlog.WARNING, 0)
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.Infof("k=%d", 2)
+	logger.Error("failed")
+	// disabled by the level
+	logger.KV(xlog.DEBUG, "k", 3)
+
+	// dropped by the rate limit
+	xlog.WithRateLimit("github.com/effective-security/xlog", "xlog_test", xlog.WARNING, 1)
+	logger.KV(xlog.WARNING, "k", 4)
+	logger.KV(xlog.WARNING, "k", 5)
+
+	// dropped by the hook
+	remove := xlog.AddRepoHook("github.com/effective-security/xlog", xlog.HookFunc(func(e *xlog.Entry) error {
+		return xlog.ErrDropEntry
+	}))
+	logger.KV(xlog.NOTICE, "k", 6)
+	logger.Noticef("k=%d", 7)
+	remove()
+
+	xlog.ReportDropped("xlog_test", xlog.TRACE)
+
+	assert.Equal(t, map[xlog.LogLevel]int{xlog.INFO: 2, xlog.ERROR: 1, xlog.WARNING: 1}, m.logged)
+	assert.Equal(t, map[xlog.LogLevel]int{xlog.WARNING: 1, xlog.NOTICE: 2, xlog.TRACE: 1}, m.dropped)
+
+	xlog.SetMetricsSink(nil)
+	logger.KV(xlog.INFO, "k", 8)
+	assert.Equal(t, 2, m.logged[xlog.INFO])
+}
@@ -0,0 +1,62 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FormatWithMonotonic(t *testing.T) {
+	defer func(fn func() time.Duration) {
+		xlog.MonotonicNowFn = fn
+	}(xlog.MonotonicNowFn)
+	xlog.MonotonicNowFn = func() time.Duration {
+		return 12*time.Second + 345*time.Millisecond
+	}
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	tcases := []struct {
+		f   func(w *bytes.Buffer) xlog.Formatter
+		exp string
+	}{
+		{
+			f:   func(w *bytes.Buffer) xlog.Formatter { return xlog.NewStringFormatter(w) },
+			exp: "mono=12345000000 level=I pkg=xlog_test k=1\n",
+		},
+		{
+			f:   func(w *bytes.Buffer) xlog.Formatter { return xlog.NewPrettyFormatter(w) },
+			exp: "[12.345000000] I | pkg=xlog_test, k=1\n",
+		},
+		{
+			f:   func(w *bytes.Buffer) xlog.Formatter { return xlog.NewJSONFormatter(w) },
+			exp: `{"k":1,"level":"I","mono":12345000000,"pkg":"xlog_test"}` + "\n",
+		},
+	}
+	for _, tc := range tcases {
+		var b bytes.Buffer
+		f := tc.f(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithMonotonic)
+		xlog.SetFormatter(f)
+		logger.KV(xlog.INFO, "k", 1)
+		assert.Equal(t, tc.exp, b.String())
+	}
+
+	e, err := xlog.ParseEntry([]byte(`{"k":1,"level":"I","mono":12345000000,"pkg":"xlog_test","time":"2024-01-02T03:04:05Z"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 12*time.Second+345*time.Millisecond, e.Monotonic)
+	assert.Equal(t, []any{"k", int64(1)}, e.Fields)
+
+	o, err := xlog.ParseFormatterOption("WithMonotonic")
+	require.NoError(t, err)
+	assert.Equal(t, xlog.FormatWithMonotonic, o)
+}
+
+func Test_MonotonicNow(t *testing.T) {
+	m1 := xlog.MonotonicNowFn()
+	m2 := xlog.MonotonicNowFn()
+	assert.Positive(t, m1)
+	assert.GreaterOrEqual(t, m2, m1)
+}
@@ -0,0 +1,55 @@
+package xlog
+
+// MultiFormatter forwards log entries to multiple formatters
+type MultiFormatter struct {
+	formatters []Formatter
+}
+
+// NewMultiFormatter returns an instance of Formatter,
+// that forwards log entries to all provided formatters.
+// Each formatter can be configured with its own options and writer,
+// for example colored console output and JSON to a file.
+func NewMultiFormatter(formatters ...Formatter) Formatter {
+	return &MultiFormatter{
+		formatters: formatters,
+	}
+}
+
+// Options allows to configure formatter behavior,
+// the options are applied to all formatters
+func (m *MultiFormatter) Options(ops ...FormatterOption) Formatter {
+	for _, f := range m.formatters {
+		f.Options(ops...)
+	}
+	return m
+}
+
+// Settings applies the settings to the formatters
+func (m *MultiFormatter) Settings(settings FormatterSettings) Formatter {
+	for _, f := range m.formatters {
+		ApplySettings(f, settings)
+	}
+	return m
+}
+
+// Format log entry string to the stream
+func (m *MultiFormatter) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	for _, f := range m.formatters {
+		f.Format(pkg, l, depth+1, entries...)
+	}
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (m *MultiFormatter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	for _, f := range m.formatters {
+		f.FormatKV(pkg, l, depth+1, entries...)
+	}
+}
+
+// Flush the logs
+func (m *MultiFormatter) Flush() {
+	for _, f := range m.formatters {
+		f.Flush()
+	}
+}
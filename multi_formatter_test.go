@@ -0,0 +1,37 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MultiFormatter(t *testing.T) {
+	var console, file bytes.Buffer
+
+	f := xlog.NewMultiFormatter(
+		xlog.NewPrettyFormatter(&console).Options(xlog.FormatWithColor),
+		xlog.NewJSONFormatter(&file).Options(xlog.FormatSkipTime),
+	)
+	xlog.SetFormatter(f)
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.Info("msg")
+	f.Flush()
+
+	assert.Equal(t, "2021-04-01 00:00:00.000000 \x1b[0;96mI | pkg=xlog_test, func=Test_MultiFormatter, k=1\x1b[0m\n"+
+		"2021-04-01 00:00:00.000000 \x1b[0;96mI | pkg=xlog_test, func=Test_MultiFormatter, \"msg\"\x1b[0m\n", console.String())
+	assert.Equal(t, `{"func":"Test_MultiFormatter","k":1,"level":"I","pkg":"xlog_test"}`+"\n"+
+		`{"func":"Test_MultiFormatter","level":"I","msg":"msg","pkg":"xlog_test"}`+"\n", file.String())
+
+	console.Reset()
+	file.Reset()
+
+	f.Options(xlog.FormatNoCaller)
+	logger.KV(xlog.INFO, "k", 2)
+	assert.Equal(t, "2021-04-01 00:00:00.000000 \x1b[0;96mI | pkg=xlog_test, k=2\x1b[0m\n", console.String())
+	assert.Equal(t, `{"k":2,"level":"I","pkg":"xlog_test"}`+"\n", file.String())
+}
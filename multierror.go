@@ -0,0 +1,41 @@
+package xlog
+
+import "fmt"
+
+// Errors returns the errors of a multi-error,
+// created by errors.Join or by multierror packages,
+// or nil if err is not a multi-error
+func Errors(err error) []error {
+	switch typ := err.(type) {
+	case interface{ Unwrap() []error }:
+		return typ.Unwrap()
+	case interface{ WrappedErrors() []error }:
+		// github.com/hashicorp/go-multierror
+		return typ.WrappedErrors()
+	case interface{ Errors() []error }:
+		// go.uber.org/multierr
+		return typ.Errors()
+	}
+	return nil
+}
+
+// ErrorValue returns the value of the error to be logged:
+// the list of values for multi-errors,
+// or the string with details for other errors,
+// or with the message only if set by SetErrorFormat
+func ErrorValue(err error) any {
+	list := Errors(err)
+	if len(list) == 0 {
+		if GetErrorFormat() == ErrorFormatMessage {
+			return err.Error()
+		}
+		return fmt.Sprintf("%+v", err)
+	}
+	values := make([]any, 0, len(list))
+	for _, e := range list {
+		if e != nil {
+			values = append(values, ErrorValue(e))
+		}
+	}
+	return values
+}
@@ -0,0 +1,52 @@
+package xlog_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type multiErr struct {
+	errs []error
+}
+
+func (e *multiErr) Error() string {
+	return fmt.Sprintf("%d errors occurred", len(e.errs))
+}
+
+func (e *multiErr) WrappedErrors() []error {
+	return e.errs
+}
+
+func Test_MultiErrors(t *testing.T) {
+	e1 := fmt.Errorf("first")
+	e2 := fmt.Errorf("second")
+	joined := errors.Join(e1, e2)
+
+	assert.Equal(t, []error{e1, e2}, xlog.Errors(joined))
+	assert.Nil(t, xlog.Errors(e1))
+	assert.Equal(t, "first", xlog.ErrorValue(e1))
+
+	nested := &multiErr{errs: []error{joined, fmt.Errorf("third")}}
+	assert.Equal(t, []any{[]any{"first", "second"}, "third"}, xlog.ErrorValue(nested))
+
+	assert.Equal(t, `["first","second"]`, xlog.EscapedString(joined))
+	assert.Equal(t, `[["first","second"],"third"]`, xlog.EscapedString(nested))
+	assert.Equal(t, `"first"`, xlog.EscapedString(e1))
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.ERROR, "err", joined)
+	assert.Contains(t, b.String(), `{"err":["first","second"],`)
+
+	b.Reset()
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	logger.KV(xlog.ERROR, "err", nested)
+	assert.Equal(t, `level=E pkg=xlog_test err=[["first","second"],"third"]`+"\n", b.String())
+}
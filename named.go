@@ -0,0 +1,128 @@
+package xlog
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// KeyLogger is the key of the logger name set by WithName
+const KeyLogger = "logger"
+
+// nameLevel caches the level resolved for the logger name
+// with the snapshot of the named levels
+type nameLevel struct {
+	levels *map[string]LogLevel
+	level  LogLevel
+	ok     bool
+}
+
+// nameLevelCache is shared by the loggers with the same name
+type nameLevelCache struct {
+	atomic.Pointer[nameLevel]
+}
+
+// WithName returns a child logger, named as the parent name joined with the name by ".",
+// such as "svc.http.auth". The name is emitted as "logger" field.
+// The child inherits the level of the package,
+// unless the level is set for its named subtree by SetNamedLogLevel.
+func (p *PackageLogger) WithName(name string) ExtendedKeyValueLogger {
+	if name == "" {
+		return p
+	}
+	c := p.clone()
+	if c.name != "" {
+		c.name = c.name + "." + name
+	} else {
+		c.name = name
+	}
+	c.names = new(nameLevelCache)
+	if len(c.values) >= 2 && c.values[0] == KeyLogger {
+		c.values[1] = c.name
+	} else {
+		c.values = append([]any{KeyLogger, c.name}, c.values...)
+	}
+	return c
+}
+
+// levelFor returns the level set by WithLevel,
+// the level of the named subtree of the logger, or the package level
+func (p *PackageLogger) levelFor() LogLevel {
+	if p.name != "" && !p.ownLevel {
+		if l, ok := p.names.resolve(p.name); ok {
+			return l
+		}
+	}
+	return p.level.Load()
+}
+
+// resolve returns the level of the closest named subtree
+func (c *nameLevelCache) resolve(name string) (LogLevel, bool) {
+	levels := logger.namedLevels.Load()
+	if levels == nil {
+		return 0, false
+	}
+	if cached := c.Load(); cached != nil && cached.levels == levels {
+		return cached.level, cached.ok
+	}
+
+	res := &nameLevel{levels: levels}
+	for n := name; ; {
+		if l, ok := (*levels)[n]; ok {
+			res.level, res.ok = l, true
+			break
+		}
+		idx := strings.LastIndexByte(n, '.')
+		if idx < 0 {
+			break
+		}
+		n = n[:idx]
+	}
+	c.Store(res)
+	return res.level, res.ok
+}
+
+// SetNamedLogLevel sets the level of the loggers with the name,
+// created by WithName, and of their children,
+// unless a child subtree has own level
+func SetNamedLogLevel(name string, l LogLevel) {
+	logger.updateNamedLevels(func(levels map[string]LogLevel) {
+		levels[name] = l
+	})
+}
+
+// ResetNamedLogLevel removes the level of the named subtree,
+// so the loggers inherit the level of the parent subtree or the package
+func ResetNamedLogLevel(name string) {
+	logger.updateNamedLevels(func(levels map[string]LogLevel) {
+		delete(levels, name)
+	})
+}
+
+// GetNamedLogLevels returns the levels of the named subtrees
+func GetNamedLogLevels() map[string]LogLevel {
+	res := map[string]LogLevel{}
+	if levels := logger.namedLevels.Load(); levels != nil {
+		for k, v := range *levels {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (l *loggerStruct) updateNamedLevels(update func(levels map[string]LogLevel)) {
+	l.Lock()
+	defer l.Unlock()
+
+	levels := map[string]LogLevel{}
+	if current := l.namedLevels.Load(); current != nil {
+		for k, v := range *current {
+			levels[k] = v
+		}
+	}
+	update(levels)
+	if len(levels) == 0 {
+		l.namedLevels.Store(nil)
+		return
+	}
+	l.namedLevels.Store(&levels)
+}
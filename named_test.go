@@ -0,0 +1,102 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithName(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetNamedLogLevel("svc")
+	defer xlog.ResetNamedLogLevel("svc.http.auth")
+
+	svc := logger.WithValues("k", 1).(xlog.ExtendedKeyValueLogger).WithName("svc")
+	http := svc.WithName("http")
+	auth := http.WithPrefix("p").WithName("auth")
+	db := svc.WithName("db")
+
+	svc.KV(xlog.INFO, "v", 1)
+	auth.KV(xlog.INFO, "v", 2)
+	assert.Equal(t,
+		"level=I pkg=xlog_test logger=\"svc\" k=1 v=1\n"+
+			"level=I pkg=xlog_test logger=\"svc.http.auth\" k=1 p.v=2\n",
+		b.String())
+	assert.Equal(t, logger, logger.WithName(""))
+
+	// inherited from the package
+	b.Reset()
+	auth.KV(xlog.DEBUG, "v", 3)
+	assert.Empty(t, b.String())
+
+	// subtree override
+	xlog.SetNamedLogLevel("svc.http.auth", xlog.DEBUG)
+	xlog.SetNamedLogLevel("svc", xlog.WARNING)
+	assert.Equal(t, map[string]xlog.LogLevel{"svc": xlog.WARNING, "svc.http.auth": xlog.DEBUG}, xlog.GetNamedLogLevels())
+	assert.Equal(t, map[string]string{"svc": "WARNING", "svc.http.auth": "DEBUG"}, xlog.EffectiveConfig().NamedLevels)
+
+	auth.KV(xlog.DEBUG, "v", 4)
+	http.KV(xlog.INFO, "v", 5)
+	db.KV(xlog.INFO, "v", 6)
+	db.KV(xlog.WARNING, "v", 7)
+	logger.KV(xlog.INFO, "v", 8)
+	assert.Equal(t,
+		"level=D pkg=xlog_test logger=\"svc.http.auth\" k=1 p.v=4\n"+
+			"level=W pkg=xlog_test logger=\"svc.db\" k=1 v=7\n"+
+			"level=I pkg=xlog_test v=8\n",
+		b.String())
+
+	// reset
+	b.Reset()
+	xlog.ResetNamedLogLevel("svc")
+	http.KV(xlog.INFO, "v", 9)
+	auth.KV(xlog.DEBUG, "v", 10)
+	assert.Equal(t,
+		"level=I pkg=xlog_test logger=\"svc.http\" k=1 v=9\n"+
+			"level=D pkg=xlog_test logger=\"svc.http.auth\" k=1 p.v=10\n",
+		b.String())
+
+	xlog.ResetNamedLogLevel("svc.http.auth")
+	assert.Empty(t, xlog.GetNamedLogLevels())
+	b.Reset()
+	auth.KV(xlog.DEBUG, "v", 11)
+	assert.Empty(t, b.String())
+
+	assert.NotNil(t, xlog.NewNilLogger().(xlog.ExtendedKeyValueLogger).WithName("svc"))
+}
+
+func Test_WithLevel(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetNamedLogLevel("conn")
+
+	conn := logger.WithName("conn")
+	verbose := conn.WithValues("id", 7).(xlog.ExtendedKeyValueLogger).WithLevel(xlog.DEBUG)
+	quiet := logger.WithLevel(xlog.ERROR)
+
+	conn.KV(xlog.DEBUG, "v", 1)
+	verbose.KV(xlog.DEBUG, "v", 2)
+	quiet.KV(xlog.WARNING, "v", 3)
+	assert.Equal(t, "level=D pkg=xlog_test logger=\"conn\" id=7 v=2\n", b.String())
+
+	// independent of the package and named levels
+	b.Reset()
+	xlog.SetGlobalLogLevel(xlog.ERROR)
+	xlog.SetNamedLogLevel("conn", xlog.ERROR)
+	verbose.KV(xlog.DEBUG, "v", 4)
+	verbose.WithName("read").KV(xlog.DEBUG, "v", 5)
+	xlog.SetGlobalLogLevel(xlog.DEBUG)
+	quiet.KV(xlog.INFO, "v", 6)
+	assert.Equal(t,
+		"level=D pkg=xlog_test logger=\"conn\" id=7 v=4\n"+
+			"level=D pkg=xlog_test logger=\"conn.read\" id=7 v=5\n",
+		b.String())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	assert.NotNil(t, xlog.NewNilLogger().(xlog.ExtendedKeyValueLogger).WithLevel(xlog.DEBUG))
+}
@@ -96,3 +96,26 @@ func (l *NilLogger) Tracef(format string, args ...any) {}
 func (l *NilLogger) WithValues(keysAndValues ...any) KeyValueLogger {
 	return l
 }
+
+// WithTags adds tags to a logger.
+func (l *NilLogger) WithTags(tags ...string) ExtendedKeyValueLogger {
+	return l
+}
+
+// WithPrefix returns a logger that emits subsequent keys with the prefix.
+func (l *NilLogger) WithPrefix(prefix string) ExtendedKeyValueLogger {
+	return l
+}
+
+// WithName returns a child logger with the name.
+func (l *NilLogger) WithName(name string) ExtendedKeyValueLogger {
+	return l
+}
+
+// WithLevel returns a child logger with the level.
+func (l *NilLogger) WithLevel(level LogLevel) ExtendedKeyValueLogger {
+	return l
+}
+
+// Deprecated does nothing
+func (l *NilLogger) Deprecated(feature string, entries ...any) {}
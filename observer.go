@@ -0,0 +1,192 @@
+package xlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ObservedLogs is the concurrency-safe collection of the entries
+// recorded by the observer formatter, see NewObserver
+type ObservedLogs struct {
+	lock    sync.RWMutex
+	entries []Entry
+}
+
+// observer is the formatter, that records the entries to ObservedLogs
+type observer struct {
+	level LogLevel
+	logs  *ObservedLogs
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+}
+
+// NewObserver returns the formatter, that records the entries
+// with the level or more severe to ObservedLogs in memory,
+// so the unit tests verify the logged level, package, message and fields.
+// The "msg" value of key/value entries is recorded as the message.
+func NewObserver(level LogLevel) (Formatter, *ObservedLogs) {
+	logs := &ObservedLogs{}
+	return &observer{level: level, logs: logs}, logs
+}
+
+// Options allows to configure formatter behavior
+func (o *observer) Options(ops ...FormatterOption) Formatter {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case FormatWithCaller, FormatWithLocation:
+			o.withCaller = true
+		case FormatNoCaller:
+			o.withCaller = false
+		case FormatSkipTime:
+			o.skipTime = true
+		}
+	}
+	return o
+}
+
+// FormatKV records the entry
+func (o *observer) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	if l > o.level {
+		return
+	}
+	e := o.entry(pkg, l, depth+1)
+	for i := 0; i < len(entries); i += 2 {
+		var v any
+		if i+1 < len(entries) {
+			v = entries[i+1]
+		}
+		if k, ok := entries[i].(string); ok && k == "msg" && e.Message == "" {
+			e.Message = fmt.Sprint(v)
+			continue
+		}
+		e.Fields = append(e.Fields, entries[i], v)
+	}
+	o.logs.add(e)
+}
+
+// Format records the entry
+func (o *observer) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	if l > o.level {
+		return
+	}
+	e := o.entry(pkg, l, depth+1)
+	e.Message = strings.TrimSpace(fmt.Sprint(entries...))
+	o.logs.add(e)
+}
+
+// Flush does nothing
+func (o *observer) Flush() {}
+
+func (o *observer) entry(pkg string, l LogLevel, depth int) Entry {
+	o.lock.Lock()
+	withCaller, skipTime := o.withCaller, o.skipTime
+	o.lock.Unlock()
+
+	e := Entry{
+		Level: l,
+		Pkg:   pkg,
+	}
+	if !skipTime {
+		e.Time = TimeNowFn()
+	}
+	if withCaller {
+		caller, file, line := Caller(depth + 1)
+		e.Caller = caller
+		e.Source = fmt.Sprintf("%s:%d", file, line)
+	}
+	return e
+}
+
+func (o *ObservedLogs) add(e Entry) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.entries = append(o.entries, e)
+}
+
+// Len returns the number of the observed entries
+func (o *ObservedLogs) Len() int {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return len(o.entries)
+}
+
+// All returns a copy of the observed entries
+func (o *ObservedLogs) All() []Entry {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return append([]Entry{}, o.entries...)
+}
+
+// TakeAll returns the observed entries, and removes them
+func (o *ObservedLogs) TakeAll() []Entry {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	entries := o.entries
+	o.entries = nil
+	return entries
+}
+
+// Filter returns a copy of the observed entries, that match the filter
+func (o *ObservedLogs) Filter(match func(e *Entry) bool) *ObservedLogs {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	filtered := &ObservedLogs{}
+	for i := range o.entries {
+		if match(&o.entries[i]) {
+			filtered.entries = append(filtered.entries, o.entries[i])
+		}
+	}
+	return filtered
+}
+
+// FilterLevel returns the observed entries with the level
+func (o *ObservedLogs) FilterLevel(level LogLevel) *ObservedLogs {
+	return o.Filter(func(e *Entry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterPkg returns the observed entries of the package
+func (o *ObservedLogs) FilterPkg(pkg string) *ObservedLogs {
+	return o.Filter(func(e *Entry) bool {
+		return e.Pkg == pkg
+	})
+}
+
+// FilterMessage returns the observed entries with the message
+func (o *ObservedLogs) FilterMessage(msg string) *ObservedLogs {
+	return o.Filter(func(e *Entry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterMessageContains returns the observed entries, that contain the message
+func (o *ObservedLogs) FilterMessageContains(msg string) *ObservedLogs {
+	return o.Filter(func(e *Entry) bool {
+		return strings.Contains(e.Message, msg)
+	})
+}
+
+// FilterField returns the observed entries with the field.
+// The values are compared by their string representation,
+// so int and int64 values are equal.
+func (o *ObservedLogs) FilterField(key string, value any) *ObservedLogs {
+	want := fmt.Sprint(value)
+	return o.Filter(func(e *Entry) bool {
+		v, ok := e.Field(key)
+		return ok && fmt.Sprint(v) == want
+	})
+}
+
+// FilterFieldKey returns the observed entries with the field key, regardless of the value
+func (o *ObservedLogs) FilterFieldKey(key string) *ObservedLogs {
+	return o.Filter(func(e *Entry) bool {
+		_, ok := e.Field(key)
+		return ok
+	})
+}
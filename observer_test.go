@@ -0,0 +1,67 @@
+package xlog_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Observer(t *testing.T) {
+	f, logs := xlog.NewObserver(xlog.INFO)
+	xlog.SetPackageFormatter("github.com/effective-security/xlog", "observer_test", f)
+	defer xlog.SetPackageFormatter("github.com/effective-security/xlog", "observer_test", nil)
+	xlog.SetGlobalLogLevel(xlog.DEBUG)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "observer_test")
+	logger.Infof("started %d workers", 3)
+	logger.KV(xlog.WARNING, "msg", "slow request", "duration", "2s", "status", 200)
+	logger.KV(xlog.ERROR, "msg", "request failed", "status", 500)
+	logger.Debug("below the observer level")
+
+	require.Equal(t, 3, logs.Len())
+
+	all := logs.All()
+	assert.Equal(t, xlog.INFO, all[0].Level)
+	assert.Equal(t, "observer_test", all[0].Pkg)
+	assert.Equal(t, "started 3 workers", all[0].Message)
+	assert.False(t, all[0].Time.IsZero())
+
+	assert.Equal(t, 1, logs.FilterLevel(xlog.ERROR).Len())
+	assert.Equal(t, 2, logs.FilterFieldKey("status").Len())
+	assert.Equal(t, 1, logs.FilterPkg("observer_test").FilterField("status", int64(500)).Len())
+	assert.Equal(t, 0, logs.FilterField("status", 404).Len())
+	assert.Equal(t, 2, logs.FilterMessageContains("request").Len())
+	assert.Equal(t, 1, logs.FilterMessage("slow request").Len())
+	assert.Equal(t, 0, logs.FilterMessage("slow").Len())
+
+	warn := logs.FilterLevel(xlog.WARNING).All()
+	require.Len(t, warn, 1)
+	assert.Equal(t, []any{"duration", "2s", "status", 200}, warn[0].Fields)
+
+	// filters return a copy
+	assert.Len(t, logs.FilterLevel(xlog.INFO).TakeAll(), 1)
+	assert.Equal(t, 3, logs.Len())
+
+	assert.Len(t, logs.TakeAll(), 3)
+	assert.Equal(t, 0, logs.Len())
+	assert.Empty(t, logs.TakeAll())
+}
+
+func Test_ObserverOptions(t *testing.T) {
+	f, logs := xlog.NewObserver(xlog.DEBUG)
+	f.Options(xlog.FormatWithCaller, xlog.FormatSkipTime)
+	xlog.SetPackageFormatter("github.com/effective-security/xlog", "observer_test", f)
+	defer xlog.SetPackageFormatter("github.com/effective-security/xlog", "observer_test", nil)
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "observer_test")
+	logger.Info("with caller")
+
+	all := logs.All()
+	require.Len(t, all, 1)
+	assert.True(t, all[0].Time.IsZero())
+	assert.Contains(t, all[0].Caller, "Test_ObserverOptions")
+	assert.Contains(t, all[0].Source, "observer_test.go:")
+}
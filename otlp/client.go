@@ -0,0 +1,103 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Client exports the log records.
+// NewHTTPClient provides OTLP/HTTP transport,
+// OTLP/gRPC transport is provided by github.com/effective-security/xlog/otlp/otlpgrpc module.
+type Client interface {
+	Export(ctx context.Context, req *ExportLogsServiceRequest) error
+}
+
+// RetryableError is returned by Client,
+// if the export failed with a transient error and can be retried
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable returns true if the error can be retried
+func IsRetryable(err error) bool {
+	var r *RetryableError
+	return errors.As(err, &r)
+}
+
+// HTTPConfig specifies configuration for OTLP/HTTP client
+type HTTPConfig struct {
+	// Endpoint specifies the collector URL,
+	// "/v1/logs" is appended if the URL has no path
+	Endpoint string
+	// Headers specifies extra headers, such as authorization
+	Headers map[string]string
+	// HTTPClient specifies the client, http.DefaultClient by default
+	HTTPClient *http.Client
+}
+
+type httpClient struct {
+	cfg HTTPConfig
+}
+
+// NewHTTPClient returns OTLP/HTTP client with JSON encoding
+func NewHTTPClient(cfg HTTPConfig) Client {
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if i := strings.Index(endpoint, "://"); i < 0 || !strings.Contains(endpoint[i+3:], "/") {
+		endpoint += "/v1/logs"
+	}
+	cfg.Endpoint = endpoint
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &httpClient{cfg: cfg}
+}
+
+// Export sends the request,
+// throttling and unavailability responses are retryable as specified by OTLP
+func (c *httpClient) Export(ctx context.Context, req *ExportLogsServiceRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range c.cfg.Headers {
+		r.Header.Set(k, v)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(r)
+	if err != nil {
+		return &RetryableError{Err: errors.WithStack(err)}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = errors.Errorf("unexpected status: %s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &RetryableError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HTTPClient(t *testing.T) {
+	status := http.StatusOK
+	var got ExportLogsServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := NewHTTPClient(HTTPConfig{
+		Endpoint: srv.URL,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	assert.Equal(t, srv.URL+"/v1/logs", c.(*httpClient).cfg.Endpoint)
+
+	req := &ExportLogsServiceRequest{
+		ResourceLogs: []ResourceLogs{{ScopeLogs: []ScopeLogs{{
+			Scope:      Scope{Name: "test"},
+			LogRecords: []LogRecord{{SeverityNumber: SeverityInfo, Body: value("msg")}},
+		}}}},
+	}
+	require.NoError(t, c.Export(context.Background(), req))
+	assert.Equal(t, "msg", *got.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.StringValue)
+
+	status = http.StatusServiceUnavailable
+	err := c.Export(context.Background(), req)
+	assert.EqualError(t, err, "unexpected status: 503 Service Unavailable")
+	assert.True(t, IsRetryable(err))
+
+	status = http.StatusBadRequest
+	err = c.Export(context.Background(), req)
+	assert.EqualError(t, err, "unexpected status: 400 Bad Request")
+	assert.False(t, IsRetryable(err))
+
+	c = NewHTTPClient(HTTPConfig{Endpoint: "http://127.0.0.1:1/custom/logs"})
+	assert.Equal(t, "http://127.0.0.1:1/custom/logs", c.(*httpClient).cfg.Endpoint)
+	err = c.Export(context.Background(), req)
+	assert.True(t, IsRetryable(err))
+}
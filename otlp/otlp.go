@@ -0,0 +1,311 @@
+// Package otlp provides formatter that exports entries
+// as OpenTelemetry log records over OTLP, with batching and retries.
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// DefaultScopeName is the default name of the instrumentation scope
+const DefaultScopeName = "github.com/effective-security/xlog"
+
+// Config specifies configuration for the OTLP exporter
+type Config struct {
+	// Client exports the batches, see NewHTTPClient
+	Client Client
+	// Resource specifies the resource attributes, such as "service.name"
+	Resource map[string]any
+	// ScopeName specifies the instrumentation scope, DefaultScopeName by default
+	ScopeName string
+	// BatchSize specifies the maximum number of records in a batch, 512 by default
+	BatchSize int
+	// FlushInterval specifies the interval to export batched records,
+	// 5 seconds by default
+	FlushInterval time.Duration
+	// MaxRetries specifies the number of retries of failed exports, 3 by default
+	MaxRetries int
+	// Backoff specifies the initial delay between retries,
+	// doubled on each retry, 200ms by default
+	Backoff time.Duration
+}
+
+// SeverityNumber is the severity of OpenTelemetry log record
+type SeverityNumber int
+
+// Severity numbers of OpenTelemetry log data model
+const (
+	SeverityTrace SeverityNumber = 1
+	SeverityDebug SeverityNumber = 5
+	SeverityInfo  SeverityNumber = 9
+	SeverityInfo2 SeverityNumber = 10
+	SeverityWarn  SeverityNumber = 13
+	SeverityError SeverityNumber = 17
+	SeverityFatal SeverityNumber = 21
+)
+
+var levelsToSeverity = map[xlog.LogLevel]SeverityNumber{
+	xlog.CRITICAL: SeverityFatal,
+	xlog.ERROR:    SeverityError,
+	xlog.WARNING:  SeverityWarn,
+	xlog.NOTICE:   SeverityInfo2,
+	xlog.INFO:     SeverityInfo,
+	xlog.TRACE:    SeverityTrace,
+	xlog.DEBUG:    SeverityDebug,
+}
+
+// SeverityFor returns OpenTelemetry severity number for the log level
+func SeverityFor(l xlog.LogLevel) SeverityNumber {
+	if s, ok := levelsToSeverity[l]; ok {
+		return s
+	}
+	return SeverityInfo
+}
+
+// Exporter is the formatter, that converts entries into log records,
+// and exports them in batches.
+// The batch is exported when it is full, on the flush interval,
+// or on Flush and Shutdown calls.
+type Exporter struct {
+	cfg      Config
+	resource Resource
+	scope    Scope
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	records    []LogRecord
+
+	// sendLock serializes exports
+	sendLock sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New returns Exporter
+func New(cfg Config) *Exporter {
+	if cfg.ScopeName == "" {
+		cfg.ScopeName = DefaultScopeName
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+
+	e := &Exporter{
+		cfg:        cfg,
+		resource:   Resource{Attributes: attributes(cfg.Resource)},
+		scope:      Scope{Name: cfg.ScopeName},
+		withCaller: true,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Options allows to configure formatter behavior
+func (e *Exporter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			e.withCaller = true
+		case xlog.FormatNoCaller:
+			e.withCaller = false
+		case xlog.FormatSkipTime:
+			e.skipTime = true
+		case xlog.FormatPrintEmpty:
+			e.printEmpty = true
+		}
+	}
+	return e
+}
+
+// FormatKV converts the entry to log record,
+// the entries are key/value pairs, emitted as attributes
+func (e *Exporter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	e.format(pkg, l, depth+1, entries, "")
+}
+
+// Format converts the entry to log record
+func (e *Exporter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	e.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush exports the batched records,
+// the errors are reported to stderr
+func (e *Exporter) Flush() {
+	if err := e.ForceFlush(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "xlog: otlp: %v\n", err)
+	}
+}
+
+// ForceFlush exports the batched records
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	e.lock.Lock()
+	batch := e.take()
+	e.lock.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return e.export(ctx, batch)
+}
+
+// Shutdown stops the background flush, and exports the batched records
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+	})
+	return e.ForceFlush(ctx)
+}
+
+func (e *Exporter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	now := xlog.TimeNowFn()
+
+	e.lock.Lock()
+	r := LogRecord{
+		ObservedTimeUnixNano: strconv.FormatInt(now.UnixNano(), 10),
+		SeverityNumber:       SeverityFor(l),
+		SeverityText:         l.String(),
+	}
+	if !e.skipTime {
+		r.TimeUnixNano = r.ObservedTimeUnixNano
+	}
+	if pkg != "" {
+		r.Attributes = append(r.Attributes, KeyValue{Key: "code.namespace", Value: value(pkg)})
+	}
+	if e.withCaller {
+		caller, file, line := xlog.Caller(depth + 1)
+		r.Attributes = append(r.Attributes,
+			KeyValue{Key: "code.function", Value: value(caller)},
+			KeyValue{Key: "code.filepath", Value: value(file)},
+			KeyValue{Key: "code.lineno", Value: value(line)},
+		)
+	}
+
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			e.lock.Unlock()
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		switch {
+		case k == "msg" && msg == "":
+			msg = fmt.Sprint(v)
+			continue
+		case k == xlog.KeyTraceID:
+			r.TraceID = fmt.Sprint(v)
+			continue
+		case k == xlog.KeySpanID:
+			r.SpanID = fmt.Sprint(v)
+			continue
+		case v == nil && !e.printEmpty:
+			continue
+		}
+		r.Attributes = append(r.Attributes, KeyValue{Key: k, Value: value(v)})
+	}
+	r.Body = value(strings.TrimSpace(msg))
+
+	var batch []LogRecord
+	e.records = append(e.records, r)
+	if len(e.records) >= e.cfg.BatchSize {
+		batch = e.take()
+	}
+	e.lock.Unlock()
+
+	if len(batch) > 0 {
+		if err := e.export(context.Background(), batch); err != nil {
+			fmt.Fprintf(os.Stderr, "xlog: otlp: %v\n", err)
+		}
+	}
+}
+
+// take returns the batched records, the caller must hold the lock
+func (e *Exporter) take() []LogRecord {
+	batch := e.records
+	e.records = nil
+	return batch
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// export the records, retrying with backoff on retryable errors
+func (e *Exporter) export(ctx context.Context, records []LogRecord) error {
+	req := &ExportLogsServiceRequest{
+		ResourceLogs: []ResourceLogs{
+			{
+				Resource: e.resource,
+				ScopeLogs: []ScopeLogs{
+					{
+						Scope:      e.scope,
+						LogRecords: records,
+					},
+				},
+			},
+		},
+	}
+
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+
+	delay := e.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = e.cfg.Client.Export(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) || attempt >= e.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "failed to export %d log records", len(records))
+		}
+		delay *= 2
+	}
+	return errors.WithMessagef(err, "failed to export %d log records", len(records))
+}
@@ -0,0 +1,135 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	lock sync.Mutex
+	reqs []*ExportLogsServiceRequest
+	errs []error
+}
+
+func (c *mockClient) Export(_ context.Context, req *ExportLogsServiceRequest) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.reqs = append(c.reqs, req)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (c *mockClient) Requests() []*ExportLogsServiceRequest {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*ExportLogsServiceRequest{}, c.reqs...)
+}
+
+func Test_Exporter(t *testing.T) {
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	client := &mockClient{}
+	e := New(Config{
+		Client:        client,
+		Resource:      map[string]any{"service.name": "svc", "service.instance": 1},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	e.Options(xlog.FormatNoCaller)
+
+	e.FormatKV("pkg", xlog.WARNING, 1,
+		"msg", "request",
+		xlog.KeyTraceID, "0102030405060708090a0b0c0d0e0f10",
+		xlog.KeySpanID, "0102030405060708",
+		"count", 2, "ratio", 0.5, "ok", true, "err", errors.New("failed"),
+		xlog.KeyTags, xlog.Tags{"t1"}, "nil", nil)
+	assert.Empty(t, client.Requests())
+
+	e.Format("", xlog.ERROR, 1, "failed ", "request")
+	reqs := client.Requests()
+	require.Len(t, reqs, 1)
+
+	js, err := json.Marshal(reqs[0])
+	require.NoError(t, err)
+	assert.Equal(t, `{"resourceLogs":[{"resource":{"attributes":[`+
+		`{"key":"service.instance","value":{"intValue":"1"}},`+
+		`{"key":"service.name","value":{"stringValue":"svc"}}]},`+
+		`"scopeLogs":[{"scope":{"name":"github.com/effective-security/xlog"},"logRecords":[`+
+		`{"timeUnixNano":"1617235200000000000","observedTimeUnixNano":"1617235200000000000","severityNumber":13,"severityText":"WARNING",`+
+		`"body":{"stringValue":"request"},"attributes":[`+
+		`{"key":"code.namespace","value":{"stringValue":"pkg"}},`+
+		`{"key":"count","value":{"intValue":"2"}},`+
+		`{"key":"ratio","value":{"doubleValue":0.5}},`+
+		`{"key":"ok","value":{"boolValue":true}},`+
+		`{"key":"err","value":{"stringValue":"failed"}},`+
+		`{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"t1"}]}}}],`+
+		`"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0102030405060708"},`+
+		`{"timeUnixNano":"1617235200000000000","observedTimeUnixNano":"1617235200000000000","severityNumber":17,"severityText":"ERROR",`+
+		`"body":{"stringValue":"failed request"}}]}]}]}`, string(js))
+
+	// retryable errors are retried
+	client.errs = []error{&RetryableError{Err: errors.New("unavailable")}}
+	e.Options(xlog.FormatWithCaller, xlog.FormatSkipTime)
+	e.Format("", xlog.INFO, 1, "msg")
+	require.NoError(t, e.ForceFlush(context.Background()))
+	reqs = client.Requests()
+	require.Len(t, reqs, 3)
+	r := reqs[2].ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Empty(t, r.TimeUnixNano)
+	assert.Equal(t, "code.function", r.Attributes[0].Key)
+	assert.Equal(t, "Test_Exporter", *r.Attributes[0].Value.StringValue)
+
+	// other errors are not retried
+	client.errs = []error{errors.New("bad request")}
+	e.Format("", xlog.INFO, 1, "msg")
+	err = e.ForceFlush(context.Background())
+	assert.EqualError(t, err, "failed to export 1 log records: bad request")
+	assert.Len(t, client.Requests(), 4)
+
+	require.NoError(t, e.Shutdown(context.Background()))
+	require.NoError(t, e.Shutdown(context.Background()))
+
+	assert.Panics(t, func() {
+		e.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+	e.Flush()
+}
+
+func Test_ExporterInterval(t *testing.T) {
+	client := &mockClient{}
+	e := New(Config{
+		Client:        client,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer e.Shutdown(context.Background())
+
+	e.Format("", xlog.INFO, 1, "msg")
+	assert.Eventually(t, func() bool {
+		return len(client.Requests()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_SeverityFor(t *testing.T) {
+	assert.Equal(t, SeverityFatal, SeverityFor(xlog.CRITICAL))
+	assert.Equal(t, SeverityInfo2, SeverityFor(xlog.NOTICE))
+	assert.Equal(t, SeverityTrace, SeverityFor(xlog.TRACE))
+	assert.Equal(t, SeverityDebug, SeverityFor(xlog.DEBUG))
+}
@@ -0,0 +1,174 @@
+// Package otlpgrpc provides OTLP/gRPC transport for otlp.Exporter,
+// that sends the log records with the collector LogsService client.
+//
+// The package is a separate module, so the core module does not depend on gRPC.
+package otlpgrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/effective-security/xlog/otlp"
+	"github.com/pkg/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Config specifies configuration for OTLP/gRPC client
+type Config struct {
+	// Conn specifies the connection to the collector
+	Conn grpc.ClientConnInterface
+	// Headers specifies extra metadata, such as authorization
+	Headers map[string]string
+}
+
+// Client exports the log records with OTLP/gRPC
+type Client struct {
+	client collogspb.LogsServiceClient
+	md     metadata.MD
+}
+
+var _ otlp.Client = (*Client)(nil)
+
+// New returns OTLP/gRPC client
+//
+//	conn, err := grpc.NewClient("collector:4317", grpc.WithTransportCredentials(creds))
+//	...
+//	e := otlp.New(otlp.Config{Client: otlpgrpc.New(otlpgrpc.Config{Conn: conn})})
+func New(cfg Config) *Client {
+	return &Client{
+		client: collogspb.NewLogsServiceClient(cfg.Conn),
+		md:     metadata.New(cfg.Headers),
+	}
+}
+
+// Export sends the request,
+// the status codes are retryable as specified by OTLP
+func (c *Client) Export(ctx context.Context, req *otlp.ExportLogsServiceRequest) error {
+	if len(c.md) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, c.md)
+	}
+	resp, err := c.client.Export(ctx, ToRequest(req))
+	if err != nil {
+		if retryable(status.Code(err)) {
+			return &otlp.RetryableError{Err: errors.WithStack(err)}
+		}
+		return errors.WithStack(err)
+	}
+	if ps := resp.GetPartialSuccess(); ps.GetRejectedLogRecords() > 0 {
+		return errors.Errorf("rejected %d log records: %s", ps.GetRejectedLogRecords(), ps.GetErrorMessage())
+	}
+	return nil
+}
+
+// retryable returns true for the transient codes
+func retryable(code codes.Code) bool {
+	switch code {
+	case codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable,
+		codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// ToRequest returns the protobuf request of the request
+func ToRequest(req *otlp.ExportLogsServiceRequest) *collogspb.ExportLogsServiceRequest {
+	pr := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: make([]*logspb.ResourceLogs, 0, len(req.ResourceLogs)),
+	}
+	for _, rl := range req.ResourceLogs {
+		prl := &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{
+				Attributes: toAttributes(rl.Resource.Attributes),
+			},
+			ScopeLogs: make([]*logspb.ScopeLogs, 0, len(rl.ScopeLogs)),
+		}
+		for _, sl := range rl.ScopeLogs {
+			psl := &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{
+					Name:    sl.Scope.Name,
+					Version: sl.Scope.Version,
+				},
+				LogRecords: make([]*logspb.LogRecord, 0, len(sl.LogRecords)),
+			}
+			for i := range sl.LogRecords {
+				psl.LogRecords = append(psl.LogRecords, toLogRecord(&sl.LogRecords[i]))
+			}
+			prl.ScopeLogs = append(prl.ScopeLogs, psl)
+		}
+		pr.ResourceLogs = append(pr.ResourceLogs, prl)
+	}
+	return pr
+}
+
+// toLogRecord returns the protobuf record,
+// the IDs that are not valid hex are skipped
+func toLogRecord(r *otlp.LogRecord) *logspb.LogRecord {
+	pr := &logspb.LogRecord{
+		TimeUnixNano:         parseUint(r.TimeUnixNano),
+		ObservedTimeUnixNano: parseUint(r.ObservedTimeUnixNano),
+		SeverityNumber:       logspb.SeverityNumber(r.SeverityNumber),
+		SeverityText:         r.SeverityText,
+		Body:                 toValue(r.Body),
+		Attributes:           toAttributes(r.Attributes),
+	}
+	if id, err := hex.DecodeString(r.TraceID); err == nil && len(id) == 16 {
+		pr.TraceId = id
+	}
+	if id, err := hex.DecodeString(r.SpanID); err == nil && len(id) == 8 {
+		pr.SpanId = id
+	}
+	return pr
+}
+
+func toAttributes(list []otlp.KeyValue) []*commonpb.KeyValue {
+	if len(list) == 0 {
+		return nil
+	}
+	attrs := make([]*commonpb.KeyValue, len(list))
+	for i, kv := range list {
+		attrs[i] = &commonpb.KeyValue{Key: kv.Key, Value: toValue(kv.Value)}
+	}
+	return attrs
+}
+
+func toValue(v otlp.AnyValue) *commonpb.AnyValue {
+	switch {
+	case v.StringValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: *v.StringValue}}
+	case v.BoolValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: *v.BoolValue}}
+	case v.IntValue != nil:
+		n, err := strconv.ParseInt(*v.IntValue, 10, 64)
+		if err != nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: *v.IntValue}}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: n}}
+	case v.DoubleValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: *v.DoubleValue}}
+	case v.ArrayValue != nil:
+		values := make([]*commonpb.AnyValue, len(v.ArrayValue.Values))
+		for i, av := range v.ArrayValue.Values {
+			values[i] = toValue(av)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	}
+	return &commonpb.AnyValue{}
+}
+
+func parseUint(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
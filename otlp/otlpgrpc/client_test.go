@@ -0,0 +1,199 @@
+package otlpgrpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/otlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// logsServer is the fake collector, that keeps the requests
+type logsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	lock     sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	auth     []string
+	err      error
+	rejected int64
+}
+
+func (s *logsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.auth = append(s.auth, md.Get("authorization")...)
+	s.requests = append(s.requests, req)
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if s.rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: s.rejected,
+			ErrorMessage:       "too old",
+		}
+	}
+	return resp, nil
+}
+
+// setError sets the error of the next exports, or the number of rejected records
+func (s *logsServer) setError(err error, rejected int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+	s.rejected = rejected
+}
+
+func newConn(t *testing.T, srv *logsServer) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestExporter(t *testing.T) {
+	defer func(fn func() time.Time) { xlog.TimeNowFn = fn }(xlog.TimeNowFn)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+
+	srv := new(logsServer)
+	e := otlp.New(otlp.Config{
+		Client: New(Config{
+			Conn:    newConn(t, srv),
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		}),
+		Resource:   map[string]any{"service.name": "api"},
+		MaxRetries: -1,
+	})
+	defer e.Shutdown(context.Background())
+	e.Options(xlog.FormatNoCaller)
+
+	e.FormatKV("pkg", xlog.WARNING, 1,
+		"msg", "hello",
+		xlog.KeyTraceID, "0102030405060708090a0b0c0d0e0f10",
+		xlog.KeySpanID, "0102030405060708",
+		"n", 1,
+		"big", uint64(1<<63),
+		"ok", true,
+		"f", 1.5,
+		"tags", []string{"a", "b"})
+	require.NoError(t, e.ForceFlush(context.Background()))
+
+	srv.lock.Lock()
+	require.Len(t, srv.requests, 1)
+	req := srv.requests[0]
+	assert.Equal(t, []string{"Bearer token"}, srv.auth)
+	srv.lock.Unlock()
+
+	require.Len(t, req.GetResourceLogs(), 1)
+	rl := req.GetResourceLogs()[0]
+	require.Len(t, rl.GetResource().GetAttributes(), 1)
+	assert.Equal(t, "service.name", rl.GetResource().GetAttributes()[0].GetKey())
+	assert.Equal(t, "api", rl.GetResource().GetAttributes()[0].GetValue().GetStringValue())
+
+	require.Len(t, rl.GetScopeLogs(), 1)
+	sl := rl.GetScopeLogs()[0]
+	assert.Equal(t, otlp.DefaultScopeName, sl.GetScope().GetName())
+	require.Len(t, sl.GetLogRecords(), 1)
+
+	r := sl.GetLogRecords()[0]
+	assert.Equal(t, uint64(now.UnixNano()), r.GetTimeUnixNano())
+	assert.Equal(t, uint64(now.UnixNano()), r.GetObservedTimeUnixNano())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, r.GetSeverityNumber())
+	assert.Equal(t, "WARNING", r.GetSeverityText())
+	assert.Equal(t, "hello", r.GetBody().GetStringValue())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, r.GetTraceId())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, r.GetSpanId())
+
+	attrs := map[string]any{}
+	for _, kv := range r.GetAttributes() {
+		attrs[kv.GetKey()] = attrValue(kv.GetValue())
+	}
+	assert.Equal(t, map[string]any{
+		"code.namespace": "pkg",
+		"n":              int64(1),
+		"big":            "9223372036854775808",
+		"ok":             true,
+		"f":              1.5,
+		"tags":           []any{"a", "b"},
+	}, attrs)
+}
+
+// attrValue returns the Go value of the attribute
+func attrValue(v *commonpb.AnyValue) any {
+	switch typ := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return typ.StringValue
+	case *commonpb.AnyValue_IntValue:
+		return typ.IntValue
+	case *commonpb.AnyValue_BoolValue:
+		return typ.BoolValue
+	case *commonpb.AnyValue_DoubleValue:
+		return typ.DoubleValue
+	case *commonpb.AnyValue_ArrayValue:
+		var list []any
+		for _, av := range typ.ArrayValue.GetValues() {
+			list = append(list, attrValue(av))
+		}
+		return list
+	}
+	return nil
+}
+
+func TestExportErrors(t *testing.T) {
+	srv := new(logsServer)
+	c := New(Config{Conn: newConn(t, srv)})
+	req := &otlp.ExportLogsServiceRequest{
+		ResourceLogs: []otlp.ResourceLogs{{ScopeLogs: []otlp.ScopeLogs{{
+			LogRecords: []otlp.LogRecord{{SeverityNumber: otlp.SeverityInfo, TraceID: "invalid"}},
+		}}}},
+	}
+	require.NoError(t, c.Export(context.Background(), req))
+	srv.lock.Lock()
+	assert.Empty(t, srv.auth)
+	assert.Empty(t, srv.requests[0].GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetTraceId())
+	srv.lock.Unlock()
+
+	srv.setError(status.Error(codes.Unavailable, "collector is down"), 0)
+	err := c.Export(context.Background(), req)
+	assert.True(t, otlp.IsRetryable(err))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	srv.setError(status.Error(codes.InvalidArgument, "bad request"), 0)
+	err = c.Export(context.Background(), req)
+	assert.False(t, otlp.IsRetryable(err))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	srv.setError(nil, 1)
+	err = c.Export(context.Background(), req)
+	assert.EqualError(t, err, "rejected 1 log records: too old")
+	assert.False(t, otlp.IsRetryable(err))
+}
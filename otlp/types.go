@@ -0,0 +1,132 @@
+package otlp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// ExportLogsServiceRequest is the OTLP logs export request,
+// with JSON encoding as specified by OTLP/HTTP
+type ExportLogsServiceRequest struct {
+	ResourceLogs []ResourceLogs `json:"resourceLogs"`
+}
+
+// ResourceLogs is a collection of logs from a resource
+type ResourceLogs struct {
+	Resource  Resource    `json:"resource"`
+	ScopeLogs []ScopeLogs `json:"scopeLogs"`
+}
+
+// Resource is the entity producing logs
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeLogs is a collection of logs from an instrumentation scope
+type ScopeLogs struct {
+	Scope      Scope       `json:"scope"`
+	LogRecords []LogRecord `json:"logRecords"`
+}
+
+// Scope is the instrumentation scope
+type Scope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// LogRecord is OpenTelemetry log record
+type LogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       SeverityNumber `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 AnyValue       `json:"body"`
+	Attributes           []KeyValue     `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+// KeyValue is the attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is the value of the attribute or the body,
+// only one of the fields is set
+type AnyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue `json:"arrayValue,omitempty"`
+}
+
+// ArrayValue is the list of values
+type ArrayValue struct {
+	Values []AnyValue `json:"values"`
+}
+
+// value returns AnyValue for the entry value
+func value(v any) AnyValue {
+	switch typ := v.(type) {
+	case string:
+		return AnyValue{StringValue: &typ}
+	case bool:
+		return AnyValue{BoolValue: &typ}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		s := fmt.Sprint(typ)
+		return AnyValue{IntValue: &s}
+	case uint, uint64:
+		s := fmt.Sprint(typ)
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return AnyValue{StringValue: &s}
+		}
+		return AnyValue{IntValue: &s}
+	case float32:
+		f := float64(typ)
+		return AnyValue{DoubleValue: &f}
+	case float64:
+		return AnyValue{DoubleValue: &typ}
+	case error:
+		s := typ.Error()
+		return AnyValue{StringValue: &s}
+	case time.Time:
+		s := typ.UTC().Format(time.RFC3339Nano)
+		return AnyValue{StringValue: &s}
+	case time.Duration:
+		s := typ.String()
+		return AnyValue{StringValue: &s}
+	case xlog.Tags:
+		return stringsValue(typ)
+	case []string:
+		return stringsValue(typ)
+	}
+	s := strings.Trim(xlog.EscapedString(v), `"`)
+	return AnyValue{StringValue: &s}
+}
+
+func stringsValue(list []string) AnyValue {
+	values := make([]AnyValue, len(list))
+	for i, s := range list {
+		values[i] = value(s)
+	}
+	return AnyValue{ArrayValue: &ArrayValue{Values: values}}
+}
+
+// attributes returns sorted attributes of the map
+func attributes(m map[string]any) []KeyValue {
+	list := make([]KeyValue, 0, len(m))
+	for k, v := range m {
+		list = append(list, KeyValue{Key: k, Value: value(v)})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
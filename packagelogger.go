@@ -20,14 +20,23 @@ import (
 	"os"
 )
 
-// ExitFunc can be overriten
+// ExitFunc can be overriten,
+// Fatal flushes the registered writers before calling it
 var ExitFunc = os.Exit
 
 // PackageLogger is logger implementation for packages
 type PackageLogger struct {
+	repo   string
 	pkg    string
-	level  LogLevel
+	level  *atomicLevel
 	values []any
+	tags   Tags
+	prefix string
+	name   string
+	names  *nameLevelCache
+	// ownLevel is true if the level is set by WithLevel,
+	// independent of the package level
+	ownLevel bool
 }
 
 const calldepth = 2
@@ -42,62 +51,221 @@ const (
 // WithValues adds some key-value pairs of context to a logger.
 // See Info for documentation on how key/value pairs work.
 func (p *PackageLogger) WithValues(keysAndValues ...any) KeyValueLogger {
-	return &PackageLogger{
-		pkg:    p.pkg,
-		level:  p.level,
-		values: append(p.values, keysAndValues...),
+	c := p.clone()
+	c.values = appendKV(c.values, p.prefix, keysAndValues)
+	return c
+}
+
+// WithPrefix returns a logger that emits subsequent keys with the prefix,
+// in "prefix.key" format. Nested prefixes are joined with ".".
+func (p *PackageLogger) WithPrefix(prefix string) ExtendedKeyValueLogger {
+	c := p.clone()
+	if c.prefix != "" && prefix != "" {
+		c.prefix = c.prefix + "." + prefix
+	} else if prefix != "" {
+		c.prefix = prefix
+	}
+	return c
+}
+
+// withPrefix returns key-value pairs with the keys prefixed.
+// Without the prefix the list is returned as is, with the typed fields
+// expanded by emit only for the formatters that do not write them
+func (p *PackageLogger) withPrefix(kvList []any) []any {
+	if p.prefix == "" {
+		return kvList
 	}
+	return appendKV(make([]any, 0, len(kvList)*2), p.prefix, kvList)
 }
 
-func (p *PackageLogger) internalLog(t entriesType, depth int, inLevel LogLevel, entries ...any) {
-	logger.Lock()
-	defer logger.Unlock()
+// WithLevel returns a child logger with own minimum level,
+// independent of the package and named levels,
+// to change the verbosity of a single component instance
+func (p *PackageLogger) WithLevel(l LogLevel) ExtendedKeyValueLogger {
+	c := p.clone()
+	c.level = newAtomicLevel(l)
+	c.ownLevel = true
+	return c
+}
+
+// WithTags adds tags to a logger.
+// Tags are emitted as labels by the sinks that support it,
+// or as "tags" array by other formatters.
+func (p *PackageLogger) WithTags(tags ...string) ExtendedKeyValueLogger {
+	c := p.clone()
+	c.tags = p.tags.With(tags...)
+	return c
+}
 
-	if inLevel == ERROR && logger.onError != nil {
-		logger.onError(p.pkg)
+// clone returns a copy of the logger to be extended by With methods
+func (p *PackageLogger) clone() *PackageLogger {
+	return &PackageLogger{
+		repo:     p.repo,
+		pkg:      p.pkg,
+		level:    p.level,
+		values:   append([]any{}, p.values...),
+		tags:     p.tags,
+		prefix:   p.prefix,
+		name:     p.name,
+		names:    p.names,
+		ownLevel: p.ownLevel,
 	}
+}
 
-	if inLevel != CRITICAL && p.level < inLevel {
+// contextValues returns logger values and computed fields, including tags
+func (p *PackageLogger) contextValues(ctx context.Context, inLevel LogLevel) []any {
+	computed := logger.computeFields(ctx, inLevel, p.pkg)
+	if len(p.tags) == 0 && len(computed) == 0 {
+		return resolveLazy(p.values)
+	}
+	values := append(resolveLazy(p.values)[:len(p.values):len(p.values)], computed...)
+	if len(p.tags) > 0 {
+		values = append(values, KeyTags, p.tags)
+	}
+	return values
+}
+
+func (p *PackageLogger) internalLog(ctx context.Context, t entriesType, depth int, inLevel LogLevel, entries ...any) {
+	if ctx != nil {
+		if inLevel >= TRACE && p.retainTail(ctx, t, inLevel, entries) {
+			return
+		}
+		if inLevel <= ERROR {
+			if b := TailFromContext(ctx); b != nil {
+				b.flush(depth + 1)
+			}
+		}
+	}
+	f := p.formatter(ctx, depth+1, inLevel)
+	if f == nil {
 		return
 	}
-	if len(p.values) > 0 {
-		entries = append(p.values, entries...)
+	defer f.Unlock()
+
+	p.emit(ctx, f, t, depth+1, inLevel, entries...)
+}
+
+// emit formats the entry with the locked sink,
+// after the hooks, context values and computed fields are applied
+func (p *PackageLogger) emit(ctx context.Context, f *sink, t entriesType, depth int, inLevel LogLevel, entries ...any) {
+	entries = resolveLazy(entries)
+	if t == kv && (logger.hooks.Load() != nil || !writesFields(f.Formatter)) {
+		entries = expandFields(entries)
 	}
-	if logger.formatter != nil {
+	if logger.hooks.Load() != nil {
+		e := p.entry(ctx, inLevel)
+		msg := ""
 		if t == plain {
-			logger.formatter.Format(p.pkg, inLevel, depth+1, entries...)
+			msg = fmt.Sprint(entries...)
+			e.Message = msg
 		} else {
-			logger.formatter.FormatKV(p.pkg, inLevel, depth+1, entries...)
+			e.Fields = append(e.Fields, entries...)
+		}
+		if !logger.fireHooks(p.repo, e, depth+1) {
+			logger.entryDropped(p.pkg, inLevel)
+			return
+		}
+		inLevel = e.Level
+		switch {
+		case t == kv:
+			entries = e.values()
+		case e.Message != msg:
+			entries = append(e.values(), e.Message)
+		default:
+			entries = append(e.values(), entries...)
 		}
+	} else if values := p.contextValues(ctx, inLevel); len(values) > 0 {
+		entries = append(values, entries...)
+	}
+	if help := logger.errorHelpFor(inLevel, entries); help != "" {
+		if t == plain {
+			entries = append(flatten(false, nil, KeyErrHelp, help), entries...)
+		} else {
+			entries = append(entries[:len(entries):len(entries)], KeyErrHelp, help)
+		}
+	}
+	logger.entryLogged(p.pkg, inLevel)
+	if t == plain {
+		f.Format(p.pkg, inLevel, depth+1, entries...)
+	} else {
+		f.FormatKV(p.pkg, inLevel, depth+1, entries...)
 	}
 }
 
 func (p *PackageLogger) internalLogf(depth int, inLevel LogLevel, format string, args ...any) {
-	logger.Lock()
-	defer logger.Unlock()
+	f := p.formatter(nil, depth+1, inLevel)
+	if f == nil {
+		return
+	}
+	defer f.Unlock()
+
+	args = resolveLazy(args)
+	msg := fmt.Sprintf(format, args...)
+	var values []any
+	if logger.hooks.Load() != nil {
+		e := p.entry(nil, inLevel)
+		e.Message = msg
+		if !logger.fireHooks(p.repo, e, depth+1) {
+			logger.entryDropped(p.pkg, inLevel)
+			return
+		}
+		inLevel = e.Level
+		msg = e.Message
+		values = e.values()
+	} else {
+		values = p.contextValues(nil, inLevel)
+	}
+	if help := logger.errorHelpFor(inLevel, args); help != "" {
+		values = append(values[:len(values):len(values)], KeyErrHelp, help)
+	}
 
-	if inLevel == ERROR && logger.onError != nil {
-		logger.onError(p.pkg)
+	entries := []any{msg}
+	if len(values) > 0 {
+		entries = append(flatten(false, nil, values...), entries)
 	}
 
-	if inLevel != CRITICAL && p.level < inLevel {
-		return
+	logger.entryLogged(p.pkg, inLevel)
+	f.Format(p.pkg, inLevel, depth+1, entries...)
+}
+
+// entry returns the Entry for hooks, with the logger values and computed fields
+func (p *PackageLogger) entry(ctx context.Context, inLevel LogLevel) *Entry {
+	return &Entry{
+		Time:   TimeNowFn(),
+		Level:  inLevel,
+		Pkg:    p.pkg,
+		Fields: append(append([]any{}, resolveLazy(p.values)...), logger.computeFields(ctx, inLevel, p.pkg)...),
+		Tags:   append(Tags{}, p.tags...),
+	}
+}
+
+// formatter returns the locked sink to log the entry,
+// or nil if the entry must be dropped.
+// The caller must unlock the returned sink.
+func (p *PackageLogger) formatter(ctx context.Context, depth int, inLevel LogLevel) *sink {
+	if inLevel == ERROR {
+		logger.reportError(p.repo, p.pkg)
 	}
-	if logger.formatter != nil {
-		entries := []any{fmt.Sprintf(format, args...)}
-		if len(p.values) > 0 {
-			entries = append(flatten(false, p.values...), entries)
-		}
 
-		logger.formatter.Format(p.pkg, inLevel, depth+1, entries...)
+	if !logger.decide(ctx, p.repo, p.pkg, p.levelFor(), inLevel) {
+		return nil
 	}
+	f := logger.lockedSinkFor(p.repo, p.pkg)
+	if f == nil {
+		return nil
+	}
+	if !logger.allowRate(f.Formatter, p.repo, p.pkg, inLevel, depth+1) {
+		f.Unlock()
+		logger.entryDropped(p.pkg, inLevel)
+		return nil
+	}
+	return f
 }
 
-// LevelAt returns the current log level
+// LevelAt returns the current log level,
+// the enablers are not evaluated
 func (p *PackageLogger) LevelAt(l LogLevel) bool {
-	logger.Lock()
-	defer logger.Unlock()
-	return p.level >= l
+	return logger.enabled(p.levelFor(), l)
 }
 
 // Logf a formatted string at any level between ERROR and TRACE
@@ -107,7 +275,7 @@ func (p *PackageLogger) Logf(l LogLevel, format string, args ...any) {
 
 // Log a message at any level between ERROR and TRACE
 func (p *PackageLogger) Log(l LogLevel, args ...any) {
-	p.internalLog(plain, calldepth, l, args...)
+	p.internalLog(nil, plain, calldepth, l, args...)
 }
 
 // Panic and fatal
@@ -115,27 +283,31 @@ func (p *PackageLogger) Log(l LogLevel, args ...any) {
 // Panicf is implementation for stdlib compatibility
 func (p *PackageLogger) Panicf(format string, args ...any) {
 	s := fmt.Sprintf(format, args...)
-	p.internalLog(plain, calldepth, CRITICAL, s)
+	p.internalLog(nil, plain, calldepth, CRITICAL, s)
+	flushOnExit()
 	panic(s)
 }
 
 // Panic is implementation for stdlib compatibility
 func (p *PackageLogger) Panic(args ...any) {
 	s := fmt.Sprint(args...)
-	p.internalLog(plain, calldepth, CRITICAL, s)
+	p.internalLog(nil, plain, calldepth, CRITICAL, s)
+	flushOnExit()
 	panic(s)
 }
 
 // Fatalf is implementation for stdlib compatibility
 func (p *PackageLogger) Fatalf(format string, args ...any) {
 	p.internalLogf(calldepth, CRITICAL, format, args...)
+	flushOnExit()
 	ExitFunc(1)
 }
 
 // Fatal is implementation for stdlib compatibility
 func (p *PackageLogger) Fatal(args ...any) {
 	s := fmt.Sprint(args...)
-	p.internalLog(plain, calldepth, CRITICAL, s)
+	p.internalLog(nil, plain, calldepth, CRITICAL, s)
+	flushOnExit()
 	ExitFunc(1)
 }
 
@@ -148,7 +320,7 @@ func (p *PackageLogger) Errorf(format string, args ...any) {
 
 // Error is implementation for stdlib compatibility
 func (p *PackageLogger) Error(entries ...any) {
-	p.internalLog(plain, calldepth, ERROR, entries...)
+	p.internalLog(nil, plain, calldepth, ERROR, entries...)
 }
 
 // Warning Functions
@@ -160,7 +332,7 @@ func (p *PackageLogger) Warningf(format string, args ...any) {
 
 // Warning is implementation for stdlib compatibility
 func (p *PackageLogger) Warning(entries ...any) {
-	p.internalLog(plain, calldepth, WARNING, entries...)
+	p.internalLog(nil, plain, calldepth, WARNING, entries...)
 }
 
 // Notice Functions
@@ -172,7 +344,7 @@ func (p *PackageLogger) Noticef(format string, args ...any) {
 
 // Notice is implementation for stdlib compatibility
 func (p *PackageLogger) Notice(entries ...any) {
-	p.internalLog(plain, calldepth, NOTICE, entries...)
+	p.internalLog(nil, plain, calldepth, NOTICE, entries...)
 }
 
 // Info Functions
@@ -184,23 +356,28 @@ func (p *PackageLogger) Infof(format string, args ...any) {
 
 // Info is implementation for stdlib compatibility
 func (p *PackageLogger) Info(entries ...any) {
-	p.internalLog(plain, calldepth, INFO, entries...)
+	p.internalLog(nil, plain, calldepth, INFO, entries...)
 }
 
 // KV prints key=value pairs
 func (p *PackageLogger) KV(l LogLevel, entries ...any) {
-	p.internalLog(kv, calldepth, l, entries...)
+	p.internalLog(nil, kv, calldepth, l, p.withPrefix(entries)...)
 }
 
 // ContextKV logs entries in "key1=value1, ..., keyN=valueN" format,
 // and add log entries from ctx as well.
 // ContextWithKV method can be used to add extra values to context
 func (p *PackageLogger) ContextKV(ctx context.Context, l LogLevel, entries ...any) {
+	entries = p.withPrefix(entries)
 	extra := ContextEntries(ctx)
-	if len(extra) > 0 {
-		entries = append(extra, entries...)
+	trace := TraceEntries(ctx)
+	if len(extra) > 0 || len(trace) > 0 {
+		all := make([]any, 0, len(extra)+len(trace)+len(entries))
+		all = append(all, extra...)
+		all = append(all, trace...)
+		entries = append(all, entries...)
 	}
-	p.internalLog(kv, calldepth, l, entries...)
+	p.internalLog(ctx, kv, calldepth, l, entries...)
 }
 
 // Debug Functions
@@ -212,7 +389,7 @@ func (p *PackageLogger) Debugf(format string, args ...any) {
 
 // Debug is implementation for stdlib compatibility
 func (p *PackageLogger) Debug(entries ...any) {
-	p.internalLog(plain, calldepth, DEBUG, entries...)
+	p.internalLog(nil, plain, calldepth, DEBUG, entries...)
 }
 
 // Trace Functions
@@ -224,12 +401,20 @@ func (p *PackageLogger) Tracef(format string, args ...any) {
 
 // Trace is implementation for stdlib compatibility
 func (p *PackageLogger) Trace(entries ...any) {
-	p.internalLog(plain, calldepth, TRACE, entries...)
+	p.internalLog(nil, plain, calldepth, TRACE, entries...)
+}
+
+// SetFormatter sets the formatter for the package,
+// overriding the repo and global formatters.
+// Pass nil to reset to the repo or global formatter.
+func (p *PackageLogger) SetFormatter(f Formatter) {
+	SetPackageFormatter(p.repo, p.pkg, f)
 }
 
 // Flush the logs
 func (p *PackageLogger) Flush() {
-	logger.Lock()
-	defer logger.Unlock()
-	logger.formatter.Flush()
+	if f := logger.lockedSinkFor(p.repo, p.pkg); f != nil {
+		defer f.Unlock()
+		f.Flush()
+	}
 }
@@ -0,0 +1,95 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatPkgOptions(t *testing.T) {
+	assert.Equal(t, "SkipPkg", xlog.FormatSkipPkg.String())
+
+	tcases := []struct {
+		name     string
+		opt      xlog.FormatterOption
+		settings xlog.FormatterSettings
+		str      string
+		pretty   string
+		json     string
+	}{
+		{
+			name:   "skip",
+			opt:    xlog.FormatSkipPkg,
+			str:    "level=I k=1\n",
+			pretty: "I | k=1\n",
+			json:   `{"k":1,"level":"I"}` + "\n",
+		},
+		{
+			name:     "rename",
+			settings: xlog.FormatterSettings{PkgKey: "component"},
+			str:      "level=I component=xlog_test k=1\n",
+			pretty:   "I | component=xlog_test, k=1\n",
+			json:     `{"component":"xlog_test","k":1,"level":"I"}` + "\n",
+		},
+		{
+			name:   "full path",
+			opt:    xlog.FormatWithPkgFullPath,
+			str:    "level=I pkg=github.com/effective-security/xlog_test k=1\n",
+			pretty: "I | pkg=github.com/effective-security/xlog_test, k=1\n",
+			json:   `{"k":1,"level":"I","pkg":"github.com/effective-security/xlog_test"}` + "\n",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var str, pretty, js bytes.Buffer
+			xlog.SetFormatter(xlog.ApplySettings(xlog.NewMultiFormatter(
+				xlog.NewStringFormatter(&str),
+				xlog.NewPrettyFormatter(&pretty),
+				xlog.NewJSONFormatter(&js),
+			).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, tc.opt), tc.settings))
+			xlog.SetGlobalLogLevel(xlog.INFO)
+
+			logger.KV(xlog.INFO, "k", 1)
+			assert.Equal(t, tc.str, str.String())
+			assert.Equal(t, tc.pretty, pretty.String())
+			assert.Equal(t, tc.json, js.String())
+		})
+	}
+}
+
+func Test_FormatPkgPath(t *testing.T) {
+	assert.Equal(t, "WithPkgPath", xlog.FormatWithPkgPath.String())
+	assert.Equal(t, "WithPkgFullPath", xlog.FormatWithPkgFullPath.String())
+	for _, o := range []xlog.FormatterOption{xlog.FormatWithPkgPath, xlog.FormatWithPkgFullPath} {
+		parsed, err := xlog.ParseFormatterOption(o.String())
+		assert.NoError(t, err)
+		assert.Equal(t, o, parsed)
+	}
+
+	assert.Equal(t, "github.com/effective-security/xlog_test", xlog.CallerPkgPath(1))
+
+	_ = xlog.NewPackageLogger("example.com/pkgpath", "pkgpath_test")
+	assert.Equal(t, "internal/server/http", xlog.RelativePkgPath("example.com/pkgpath/internal/server/http"))
+	assert.Equal(t, "pkgpath", xlog.RelativePkgPath("example.com/pkgpath"))
+	assert.Equal(t, "example.com/pkgpathx/http", xlog.RelativePkgPath("example.com/pkgpathx/http"))
+
+	// the test package is not in the xlog repo, relative to the parent
+	_ = xlog.NewPackageLogger("github.com/effective-security", "pkgpath_test")
+
+	var str, pretty, js bytes.Buffer
+	xlog.SetFormatter(xlog.ApplySettings(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&str),
+		xlog.NewPrettyFormatter(&pretty),
+		xlog.NewJSONFormatter(&js),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithPkgPath), xlog.FormatterSettings{PkgKey: "module"}))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.Info("msg")
+	assert.Equal(t, "level=I module=xlog_test k=1\nlevel=I module=xlog_test \"msg\"\n", str.String())
+	assert.Equal(t, "I | module=xlog_test, k=1\nI | module=xlog_test, \"msg\"\n", pretty.String())
+	assert.Equal(t, `{"k":1,"level":"I","module":"xlog_test"}`+"\n"+`{"level":"I","module":"xlog_test","msg":"msg"}`+"\n", js.String())
+}
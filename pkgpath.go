@@ -0,0 +1,88 @@
+package xlog
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// pkgPathFormat specifies how the pkg field is printed
+type pkgPathFormat int
+
+const (
+	// pkgNameOnly prints the package name, as registered by NewPackageLogger
+	pkgNameOnly pkgPathFormat = iota
+	// pkgRelativePath prints the package path relative to the repo
+	pkgRelativePath
+	// pkgFullPath prints the full import path of the package
+	pkgFullPath
+)
+
+// repoPaths is the snapshot of the registered repos,
+// sorted by the length in descending order, so the longest repo matches first
+var repoPaths atomic.Pointer[[]string]
+
+// registerRepoPath adds the repo to the snapshot of the registered repos,
+// must be called under the logger lock
+func registerRepoPath(repo string) {
+	var list []string
+	if cur := repoPaths.Load(); cur != nil {
+		list = append(list, *cur...)
+	}
+	list = append(list, repo)
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i]) > len(list[j])
+	})
+	repoPaths.Store(&list)
+}
+
+// pkgName returns the value of the pkg field,
+// the package path is resolved from the caller at the depth
+func (c *config) pkgName(pkg string, depth int) string {
+	if c.pkgPath == pkgNameOnly || pkg == "" {
+		return pkg
+	}
+	p := CallerPkgPath(depth + 1)
+	if p == "" {
+		return pkg
+	}
+	if c.pkgPath == pkgRelativePath {
+		return RelativePkgPath(p)
+	}
+	return p
+}
+
+// RelativePkgPath returns the import path relative to the longest registered repo,
+// the last element of the repo for the root package of the repo,
+// or the path as is, if it is not in any of the registered repos
+func RelativePkgPath(pkgPath string) string {
+	repos := repoPaths.Load()
+	if repos == nil {
+		return pkgPath
+	}
+	for _, repo := range *repos {
+		if pkgPath == repo {
+			return repo[strings.LastIndexByte(repo, '/')+1:]
+		}
+		if rel, ok := strings.CutPrefix(pkgPath, repo); ok && rel[0] == '/' {
+			return rel[1:]
+		}
+	}
+	return pkgPath
+}
+
+// funcPkgPath returns the import path of the package from the function name,
+// as returned by runtime.Frame.Function
+func funcPkgPath(function string) string {
+	slash := strings.LastIndexByte(function, '/') + 1
+	dot := strings.IndexByte(function[slash:], '.')
+	if dot < 0 {
+		return ""
+	}
+	p := function[:slash+dot]
+	// the dots in the last element of the path are escaped in the symbol name
+	if strings.Contains(p, "%2e") {
+		p = strings.ReplaceAll(p, "%2e", ".")
+	}
+	return p
+}
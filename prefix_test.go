@@ -0,0 +1,32 @@
+package xlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithPrefix(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewPrettyFormatter(writer).Options(xlog.FormatNoCaller))
+
+	dl := logger.WithValues("svc", "api").(xlog.ExtendedKeyValueLogger).WithPrefix("db").WithValues("conn", 1).(xlog.ExtendedKeyValueLogger)
+	dl.KV(xlog.INFO, "query", "select", "rows", 2)
+	assert.Equal(t, "2021-04-01 00:00:00.000000 I | pkg=xlog_test, svc=\"api\", db.conn=1, db.query=\"select\", db.rows=2\n", b.String())
+	b.Reset()
+
+	ctx := xlog.ContextWithKV(context.Background(), "req", 1)
+	dl.WithPrefix("tx").WithPrefix("").ContextKV(ctx, xlog.INFO, "id", 3)
+	assert.Equal(t, "2021-04-01 00:00:00.000000 I | pkg=xlog_test, svc=\"api\", db.conn=1, req=1, db.tx.id=3\n", b.String())
+	b.Reset()
+
+	xlog.NewNilLogger().(xlog.ExtendedKeyValueLogger).WithPrefix("db").KV(xlog.INFO, "k2", 2)
+	assert.Empty(t, b.String())
+}
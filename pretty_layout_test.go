@@ -0,0 +1,39 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrettyLayout(t *testing.T) {
+	var b bytes.Buffer
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{
+		Delimiter: " - ",
+		Separator: "; ",
+		Segments:  []xlog.PrettySegment{xlog.SegmentFunc, xlog.SegmentSrc, xlog.SegmentPkg},
+	}).Options(xlog.FormatSkipTime, xlog.FormatWithLocation))
+
+	logger.KV(xlog.INFO, "k1", 1, "k2", "v2")
+	assert.Equal(t, "I - func=Test_PrettyLayout; src=pretty_layout_test.go:21; pkg=xlog_test; k1=1; k2=\"v2\"\n", b.String())
+	b.Reset()
+
+	// the segments not in the list are not printed
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{
+		Segments: []xlog.PrettySegment{xlog.SegmentPkg},
+	}).Options(xlog.FormatSkipTime, xlog.FormatWithLocation))
+
+	logger.Info("msg")
+	assert.Equal(t, "I | pkg=xlog_test, \"msg\"\n", b.String())
+	b.Reset()
+
+	// default layout
+	xlog.SetFormatter(xlog.NewPrettyFormatterWithLayout(&b, xlog.PrettyLayout{}).Options(xlog.FormatSkipTime, xlog.FormatWithLocation))
+
+	logger.Info("msg")
+	assert.Equal(t, "I | pkg=xlog_test, src=pretty_layout_test.go:37, func=Test_PrettyLayout, \"msg\"\n", b.String())
+}
@@ -0,0 +1,180 @@
+package xlog
+
+import (
+	"sort"
+	"time"
+)
+
+// rateSummaryInterval is the interval to log the pending summary,
+// when no entry is logged in the next window
+const rateSummaryInterval = time.Second
+
+type rateKey struct {
+	repo  string
+	pkg   string
+	level LogLevel
+}
+
+type rateLimiter struct {
+	max        int
+	window     time.Time
+	count      int
+	suppressed uint64
+	dropped    uint64
+}
+
+// RateLimitStats provides the number of dropped entries per package and level
+type RateLimitStats struct {
+	Repo         string   `json:"repo"`
+	Pkg          string   `json:"pkg"`
+	Level        LogLevel `json:"level"`
+	MaxPerSecond int      `json:"max_per_second"`
+	Dropped      uint64   `json:"dropped"`
+}
+
+// WithRateLimit limits the number of entries per second
+// logged by the package of the repo at the level.
+// If maxPerSecond is zero or negative, the limit is removed.
+func WithRateLimit(repo, pkg string, level LogLevel, maxPerSecond int) {
+	logger.rateLock.Lock()
+	defer logger.rateLock.Unlock()
+	defer func() {
+		logger.rateCount.Store(int32(len(logger.rateLimits)))
+	}()
+
+	key := rateKey{repo: repo, pkg: pkg, level: level}
+	if maxPerSecond <= 0 {
+		delete(logger.rateLimits, key)
+		return
+	}
+	if logger.rateLimits == nil {
+		logger.rateLimits = make(map[rateKey]*rateLimiter)
+	}
+	if rl, ok := logger.rateLimits[key]; ok {
+		rl.max = maxPerSecond
+		return
+	}
+	logger.rateLimits[key] = &rateLimiter{max: maxPerSecond}
+}
+
+// SetRateLimitSummary enables to log a summary line
+// with the number of entries dropped by rate limits in the previous second.
+// The summary is logged by the next entry of the package,
+// or after a second if the package stops logging, and on Shutdown.
+func SetRateLimitSummary(enabled bool) {
+	logger.rateLock.Lock()
+	defer logger.rateLock.Unlock()
+	logger.rateSummary = enabled
+}
+
+// GetRateLimits returns configured rate limits with the number of dropped entries
+func GetRateLimits() []RateLimitStats {
+	logger.rateLock.Lock()
+	defer logger.rateLock.Unlock()
+
+	list := make([]RateLimitStats, 0, len(logger.rateLimits))
+	for k, rl := range logger.rateLimits {
+		list = append(list, RateLimitStats{
+			Repo:         k.repo,
+			Pkg:          k.pkg,
+			Level:        k.level,
+			MaxPerSecond: rl.max,
+			Dropped:      rl.dropped,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Repo != list[j].Repo {
+			return list[i].Repo < list[j].Repo
+		}
+		if list[i].Pkg != list[j].Pkg {
+			return list[i].Pkg < list[j].Pkg
+		}
+		return list[i].Level < list[j].Level
+	})
+	return list
+}
+
+// allowRate returns true if the entry is allowed by the rate limit,
+// the caller must hold the lock of the formatter
+func (l *loggerStruct) allowRate(f Formatter, repo, pkg string, level LogLevel, depth int) bool {
+	if l.rateCount.Load() == 0 {
+		return true
+	}
+
+	l.rateLock.Lock()
+	defer l.rateLock.Unlock()
+
+	rl, ok := l.rateLimits[rateKey{repo: repo, pkg: pkg, level: level}]
+	if !ok {
+		return true
+	}
+
+	window := TimeNowFn().Truncate(time.Second)
+	if !window.Equal(rl.window) {
+		if l.rateSummary && rl.suppressed > 0 {
+			writeRateSummary(f, pkg, level, depth+1, rl.suppressed, rl.max)
+		}
+		rl.window = window
+		rl.count = 0
+		rl.suppressed = 0
+	}
+
+	rl.count++
+	if rl.count > rl.max {
+		rl.suppressed++
+		rl.dropped++
+		if l.rateSummary && l.rateTimer == nil {
+			// the summary is logged by the timer,
+			// if the package stops logging
+			l.rateTimer = time.AfterFunc(rateSummaryInterval, l.flushRateSummaries)
+		}
+		return false
+	}
+	return true
+}
+
+// flushRateSummaries logs the summaries of the entries suppressed
+// since the last summary
+func (l *loggerStruct) flushRateSummaries() {
+	type pending struct {
+		rateKey
+		suppressed uint64
+		max        int
+	}
+
+	// the summaries are collected under the rate lock,
+	// and logged under the lock of the formatter,
+	// in the same order of locks as allowRate
+	l.rateLock.Lock()
+	if l.rateTimer != nil {
+		l.rateTimer.Stop()
+		l.rateTimer = nil
+	}
+	var list []pending
+	if l.rateSummary {
+		for k, rl := range l.rateLimits {
+			if rl.suppressed > 0 {
+				list = append(list, pending{rateKey: k, suppressed: rl.suppressed, max: rl.max})
+				rl.suppressed = 0
+			}
+		}
+	}
+	l.rateLock.Unlock()
+
+	for _, p := range list {
+		f := l.lockedSinkFor(p.repo, p.pkg)
+		if f == nil {
+			continue
+		}
+		writeRateSummary(f.Formatter, p.pkg, p.level, 1, p.suppressed, p.max)
+		f.Unlock()
+	}
+}
+
+func writeRateSummary(f Formatter, pkg string, level LogLevel, depth int, dropped uint64, maxPerSecond int) {
+	f.FormatKV(pkg, level, depth+1,
+		"reason", "rate_limited",
+		"dropped", dropped,
+		"max_per_second", maxPerSecond,
+	)
+}
@@ -0,0 +1,110 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRepo = "github.com/effective-security/xlog"
+
+func Test_RateLimit(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = func() time.Time {
+			return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+		}
+		xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 0)
+		xlog.SetRateLimitSummary(false)
+	}()
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 1)
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 2)
+	xlog.SetRateLimitSummary(true)
+
+	for i := 0; i < 5; i++ {
+		logger.KV(xlog.ERROR, "i", i)
+		logger.Errorf("error %d", i)
+	}
+	logger.KV(xlog.INFO, "i", 1)
+
+	assert.Equal(t, "level=E pkg=xlog_test i=0\n"+
+		"level=E pkg=xlog_test \"error 0\"\n"+
+		"level=I pkg=xlog_test i=1\n", b.String())
+	b.Reset()
+
+	assert.Equal(t, []xlog.RateLimitStats{
+		{Repo: testRepo, Pkg: "xlog_test", Level: xlog.ERROR, MaxPerSecond: 2, Dropped: 8},
+	}, xlog.GetRateLimits())
+
+	now = now.Add(time.Second)
+	logger.KV(xlog.ERROR, "i", 6)
+	assert.Equal(t, "level=E pkg=xlog_test reason=\"rate_limited\" dropped=8 max_per_second=2\n"+
+		"level=E pkg=xlog_test i=6\n", b.String())
+	b.Reset()
+
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 0)
+	assert.Empty(t, xlog.GetRateLimits())
+	for i := 0; i < 3; i++ {
+		logger.KV(xlog.ERROR, "i", i)
+	}
+	assert.Equal(t, "level=E pkg=xlog_test i=0\nlevel=E pkg=xlog_test i=1\nlevel=E pkg=xlog_test i=2\n", b.String())
+}
+
+func Test_RateLimitRepo(t *testing.T) {
+	defer xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 0)
+
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	// the package with the same name in other repo is not limited
+	other := xlog.NewPackageLogger("example.com/other", "xlog_test")
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 1)
+	for i := 0; i < 2; i++ {
+		logger.KV(xlog.ERROR, "i", i)
+		other.KV(xlog.ERROR, "other", i)
+	}
+	assert.Equal(t, "level=E pkg=xlog_test i=0\n"+
+		"level=E pkg=xlog_test other=0\n"+
+		"level=E pkg=xlog_test other=1\n", b.String())
+}
+
+func Test_RateLimitSummaryFlush(t *testing.T) {
+	defer func() {
+		xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 0)
+		xlog.SetRateLimitSummary(false)
+	}()
+
+	b := &lockedBuffer{}
+	xlog.SetFormatter(xlog.NewStringFormatter(b).Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.WithRateLimit(testRepo, "xlog_test", xlog.ERROR, 1)
+	xlog.SetRateLimitSummary(true)
+
+	// the pending summary is logged on Shutdown
+	for i := 0; i < 3; i++ {
+		logger.KV(xlog.ERROR, "i", i)
+	}
+	require.NoError(t, xlog.Shutdown(context.Background()))
+	summary := "level=E pkg=xlog_test i=0\n" +
+		"level=E pkg=xlog_test reason=\"rate_limited\" dropped=2 max_per_second=1\n"
+	assert.Equal(t, summary, b.String())
+
+	// the pending summary is logged by the timer, when the package stops logging
+	logger.KV(xlog.ERROR, "i", 3)
+	assert.Equal(t, summary, b.String())
+	assert.Eventually(t, func() bool {
+		return b.String() == summary+"level=E pkg=xlog_test reason=\"rate_limited\" dropped=1 max_per_second=1\n"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,320 @@
+package xlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReaderFilter specifies which entries are returned by Reader
+type ReaderFilter struct {
+	// From specifies the minimum time of entries, if not zero
+	From time.Time
+	// To specifies the maximum time of entries, if not zero
+	To time.Time
+	// Level specifies the least severe level of entries,
+	// for example WARNING returns WARNING, ERROR and CRITICAL entries.
+	// If empty, all levels are returned.
+	Level string
+	// Packages specifies the list of packages, if not empty
+	Packages []string
+	// Keys specifies the fields that entries must have,
+	// if the value is not empty, the field must have the value
+	Keys map[string]string
+}
+
+// Reader parses log entries produced by JSONFormatter,
+// or by stackdriver formatter
+type Reader struct {
+	scanner *bufio.Scanner
+	filter  *ReaderFilter
+	level   LogLevel
+	skipped int
+}
+
+// NewReader returns a reader of JSON log entries.
+// Lines that are not valid JSON entries are skipped.
+func NewReader(r io.Reader, filter *ReaderFilter) (*Reader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	rd := &Reader{
+		scanner: scanner,
+		filter:  filter,
+		level:   DEBUG,
+	}
+	if filter != nil && filter.Level != "" {
+		l, err := ParseLevel(strings.ToUpper(filter.Level))
+		if err != nil {
+			return nil, err
+		}
+		rd.level = l
+	}
+	return rd, nil
+}
+
+// Next returns the next entry matching the filter,
+// or io.EOF when there are no more entries
+func (r *Reader) Next() (*Entry, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e, err := ParseEntry(line)
+		if err != nil {
+			r.skipped++
+			continue
+		}
+		if r.match(e) {
+			return e, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return nil, io.EOF
+}
+
+// ReadAll returns all remaining entries matching the filter
+func (r *Reader) ReadAll() ([]*Entry, error) {
+	var list []*Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return list, err
+		}
+		list = append(list, e)
+	}
+}
+
+// Skipped returns the number of skipped lines, that are not valid entries
+func (r *Reader) Skipped() int {
+	return r.skipped
+}
+
+func (r *Reader) match(e *Entry) bool {
+	if e.Level > r.level {
+		return false
+	}
+	return r.filter == nil || r.filter.matchFields(e)
+}
+
+// Match returns true if the entry matches the filter
+func (f *ReaderFilter) Match(e *Entry) bool {
+	if f.Level != "" {
+		l, err := ParseLevel(strings.ToUpper(f.Level))
+		if err == nil && e.Level > l {
+			return false
+		}
+	}
+	return f.matchFields(e)
+}
+
+// matchFields matches the entry by the filter, except the level
+func (f *ReaderFilter) matchFields(e *Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	if len(f.Packages) > 0 {
+		found := false
+		for _, pkg := range f.Packages {
+			if pkg == e.Pkg {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range f.Keys {
+		val, ok := e.Field(k)
+		if !ok {
+			return false
+		}
+		if v != "" && fmt.Sprint(val) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseEntry parses a log entry produced by JSONFormatter,
+// or by stackdriver formatter
+func ParseEntry(line []byte) (*Entry, error) {
+	m := map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if _, ok := m["severity"]; ok {
+		return parseStackdriverEntry(m)
+	}
+
+	e := &Entry{
+		Pkg:     popString(m, "pkg"),
+		Caller:  popString(m, "func"),
+		Source:  popString(m, "src"),
+		Message: popString(m, "msg"),
+	}
+	if lvl := popString(m, "level"); lvl != "" {
+		l, err := ParseLevel(lvl)
+		if err != nil {
+			return nil, err
+		}
+		e.Level = l
+	} else {
+		e.Level = INFO
+	}
+	if ts := popString(m, "time"); ts != "" {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		e.Time = t
+	}
+	if mono, ok := m[KeyMonotonic].(json.Number); ok {
+		delete(m, KeyMonotonic)
+		if ns, err := mono.Int64(); err == nil {
+			e.Monotonic = time.Duration(ns)
+		}
+	}
+	if tags, ok := m[KeyTags].([]any); ok {
+		delete(m, KeyTags)
+		for _, tag := range tags {
+			e.Tags = append(e.Tags, fmt.Sprint(tag))
+		}
+	}
+	e.Fields = sortedFields(m)
+	return e, nil
+}
+
+func parseStackdriverEntry(m map[string]any) (*Entry, error) {
+	e := &Entry{
+		Pkg: popString(m, "component"),
+	}
+
+	switch severity := popString(m, "severity"); severity {
+	case "ALERT", "EMERGENCY":
+		e.Level = CRITICAL
+	case "DEFAULT", "":
+		e.Level = INFO
+	default:
+		l, err := ParseLevel(severity)
+		if err != nil {
+			return nil, err
+		}
+		e.Level = l
+	}
+	if ts := popString(m, "timestamp"); ts != "" {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		e.Time = t
+	}
+	if loc, ok := m["sourceLocation"].(map[string]any); ok {
+		e.Caller = popString(loc, "function")
+		if file := popString(loc, "file"); file != "" {
+			e.Source = file
+			if line, ok := loc["line"].(json.Number); ok {
+				e.Source += ":" + line.String()
+			}
+		}
+	}
+
+	fields := map[string]any{}
+	switch payload := m["message"].(type) {
+	case map[string]any:
+		e.Message = popString(payload, "msg")
+		fields = payload
+	case string:
+		e.Message = payload
+	}
+	if v := popString(m, "logging.googleapis.com/trace"); v != "" {
+		// projects/PROJECT_ID/traces/TRACE_ID format
+		if _, id, ok := strings.Cut(v, "/traces/"); ok {
+			v = id
+		}
+		fields[KeyTraceID] = v
+	}
+	if v := popString(m, "logging.googleapis.com/spanId"); v != "" {
+		fields[KeySpanID] = v
+	}
+	if v, ok := m["logging.googleapis.com/trace_sampled"].(bool); ok && v {
+		fields[KeyTraceSampled] = true
+	}
+	if labels, ok := m["logging.googleapis.com/labels"].(map[string]any); ok {
+		for k, v := range labels {
+			if v == "true" {
+				e.Tags = append(e.Tags, k)
+			} else {
+				e.Tags = append(e.Tags, k+"="+fmt.Sprint(v))
+			}
+		}
+		sort.Strings(e.Tags)
+	}
+	e.Fields = sortedFields(fields)
+	return e, nil
+}
+
+// popString removes the key from the map, and returns its value as string
+func popString(m map[string]any, key string) string {
+	v, ok := m[key]
+	if !ok {
+		return ""
+	}
+	delete(m, key)
+	switch typ := v.(type) {
+	case string:
+		return typ
+	case json.Number:
+		return typ.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedFields returns key-value pairs sorted by key,
+// numbers are converted to int64 or float64
+func sortedFields(m map[string]any) []any {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]any, 0, len(m)*2)
+	for _, k := range keys {
+		v := m[k]
+		if n, ok := v.(json.Number); ok {
+			if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+				v = i
+			} else if f, err := n.Float64(); err == nil {
+				v = f
+			}
+		}
+		list = append(list, k, v)
+	}
+	return list
+}
@@ -0,0 +1,96 @@
+package xlog_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/stackdriver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Reader(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k1", 1, "k2", "v2")
+	logger.WithTags("audit").KV(xlog.WARNING, "k1", 2, "f", 1.5)
+	logger.Error("failed")
+	b.WriteString("not a json\n\n")
+
+	xlog.SetFormatter(stackdriver.NewFormatter(&b, "sd"))
+	logger.WithTags("env=prod").KV(xlog.ERROR, "k1", 3)
+
+	r, err := xlog.NewReader(strings.NewReader(b.String()), nil)
+	require.NoError(t, err)
+	list, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	assert.Equal(t, 1, r.Skipped())
+
+	ts := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, &xlog.Entry{
+		Time:   ts,
+		Level:  xlog.INFO,
+		Pkg:    "xlog_test",
+		Caller: "Test_Reader",
+		Fields: []any{"k1", int64(1), "k2", "v2"},
+	}, list[0])
+	assert.Equal(t, xlog.Tags{"audit"}, list[1].Tags)
+	v, ok := list[1].Field("f")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, v)
+	assert.Equal(t, "failed", list[2].Message)
+	assert.Equal(t, xlog.ERROR, list[2].Level)
+	assert.Contains(t, list[2].Source, "reader_test.go:")
+
+	assert.Equal(t, xlog.ERROR, list[3].Level)
+	assert.Equal(t, ts, list[3].Time)
+	assert.Equal(t, "Test_Reader", list[3].Caller)
+	assert.Contains(t, list[3].Source, "reader_test.go:")
+	assert.Equal(t, []any{"k1", int64(3)}, list[3].Fields)
+	assert.Equal(t, xlog.Tags{"env=prod"}, list[3].Tags)
+
+	r, err = xlog.NewReader(strings.NewReader(b.String()), &xlog.ReaderFilter{
+		Level: "warning",
+		Keys:  map[string]string{"k1": ""},
+	})
+	require.NoError(t, err)
+	list, err = r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	r, err = xlog.NewReader(strings.NewReader(b.String()), &xlog.ReaderFilter{
+		From:     ts.Add(-time.Hour),
+		To:       ts.Add(time.Hour),
+		Packages: []string{"xlog_test"},
+		Keys:     map[string]string{"k1": "2"},
+	})
+	require.NoError(t, err)
+	e, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, xlog.WARNING, e.Level)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	r, err = xlog.NewReader(strings.NewReader(b.String()), &xlog.ReaderFilter{
+		From:     ts.Add(time.Hour),
+		Packages: []string{"other"},
+	})
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = xlog.NewReader(strings.NewReader(""), &xlog.ReaderFilter{Level: "invalid"})
+	assert.EqualError(t, err, "unable to parse log level: INVALID")
+
+	_, err = xlog.ParseEntry([]byte(`{"level":"X"}`))
+	assert.EqualError(t, err, "unable to parse log level: X")
+	_, err = xlog.ParseEntry([]byte(`{"time":"invalid"}`))
+	assert.Error(t, err)
+}
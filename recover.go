@@ -0,0 +1,28 @@
+package xlog
+
+// KeyPanic is the key of the panic value, logged by RecoverAndLog
+const KeyPanic = "panic"
+
+// RecoverAndLog recovers the panic, and logs the panic value with the stack trace
+// at CRITICAL level. It must be called directly by defer:
+//
+//	defer xlog.RecoverAndLog(logger, func() {
+//		w.WriteHeader(http.StatusInternalServerError)
+//	})
+//
+// onPanic is called after the panic is logged,
+// if onPanic is nil, the panic is re-raised.
+func RecoverAndLog(logger KeyValueLogger, onPanic func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logger.KV(CRITICAL, KeyPanic, r, KeyStack, StackTrace(2))
+	if onPanic == nil {
+		if f, ok := logger.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		panic(r)
+	}
+	onPanic()
+}
@@ -0,0 +1,47 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func panicWith(v any) {
+	panic(v)
+}
+
+func Test_RecoverAndLog(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	called := false
+	func() {
+		defer xlog.RecoverAndLog(logger, func() { called = true })
+		panicWith("boom")
+	}()
+	assert.True(t, called)
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "level=C pkg=xlog_test panic=\"boom\" stack=\""), out)
+	assert.Contains(t, out, "xlog_test.panicWith")
+
+	// no panic
+	b.Reset()
+	called = false
+	func() {
+		defer xlog.RecoverAndLog(logger, func() { called = true })
+	}()
+	assert.False(t, called)
+	assert.Empty(t, b.String())
+
+	// re-panic
+	b.Reset()
+	assert.PanicsWithValue(t, "again", func() {
+		defer xlog.RecoverAndLog(logger.WithValues("k", 1), nil)
+		panicWith("again")
+	})
+	assert.True(t, strings.HasPrefix(b.String(), "level=C pkg=xlog_test k=1 panic=\"again\" stack=\""), b.String())
+}
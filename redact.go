@@ -0,0 +1,151 @@
+package xlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// Redacted is the replacement of redacted values
+const Redacted = "[REDACTED]"
+
+// RedactMode specifies how the redacted values are replaced
+type RedactMode int
+
+const (
+	// RedactReplace replaces the values with "[REDACTED]"
+	RedactReplace RedactMode = iota
+	// RedactHash replaces the values with "hmac:" prefixed HMAC-SHA256 of the value,
+	// that allows to correlate the values without revealing them.
+	// The mode requires RedactConfig.HashKey.
+	RedactHash
+)
+
+// hashPrefix is the prefix of the hashed values
+const hashPrefix = "hmac:"
+
+// RedactConfig specifies configuration for Redactor
+type RedactConfig struct {
+	// Keys specifies case-insensitive regular expressions of the keys,
+	// which values must be redacted, for example "password", "token", "ssn"
+	Keys []string
+	// Values specifies regular expressions of the values to be redacted,
+	// the matched parts of string values are replaced
+	Values []string
+	// Mode specifies how the values are replaced
+	Mode RedactMode
+	// HashKey specifies the secret key of RedactHash mode,
+	// so the values of low entropy, such as phone numbers,
+	// can not be recovered by brute force. Required for RedactHash mode.
+	HashKey []byte
+}
+
+// Redactor scrubs sensitive values from key-value entries.
+// The formatters apply the redactor set by SetRedactor,
+// if configured with FormatWithRedaction option.
+type Redactor struct {
+	keys   []*regexp.Regexp
+	values []*regexp.Regexp
+	mode   RedactMode
+	key    []byte
+}
+
+// NewRedactor returns Redactor
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	if cfg.Mode == RedactHash && len(cfg.HashKey) == 0 {
+		return nil, errors.New("hash key is required for RedactHash mode")
+	}
+	r := &Redactor{
+		mode: cfg.Mode,
+		key:  append([]byte(nil), cfg.HashKey...),
+	}
+	for _, k := range cfg.Keys {
+		re, err := regexp.Compile("(?i)" + k)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid key pattern: %s", k)
+		}
+		r.keys = append(r.keys, re)
+	}
+	for _, v := range cfg.Values {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid value pattern: %s", v)
+		}
+		r.values = append(r.values, re)
+	}
+	return r, nil
+}
+
+// SetRedactor sets the redactor to be used by formatters,
+// configured with FormatWithRedaction option.
+// Pass nil to disable redaction.
+func SetRedactor(r *Redactor) {
+	logger.redactor.Store(r)
+}
+
+// GetRedactor returns the current redactor, or nil
+func GetRedactor() *Redactor {
+	return logger.redactor.Load()
+}
+
+// Redact returns the value to be logged for the key
+func (r *Redactor) Redact(key string, value any) any {
+	if r == nil || value == nil {
+		return value
+	}
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return r.replace(fmt.Sprint(value))
+		}
+	}
+	if len(r.values) == 0 {
+		return value
+	}
+
+	var s string
+	switch typ := value.(type) {
+	case string:
+		s = typ
+	case error:
+		s = fmt.Sprintf("%+v", typ)
+	case fmt.Stringer:
+		s = typ.String()
+	default:
+		return value
+	}
+
+	redacted := s
+	for _, re := range r.values {
+		redacted = re.ReplaceAllStringFunc(redacted, r.replace)
+	}
+	if redacted == s {
+		return value
+	}
+	return redacted
+}
+
+func (r *Redactor) replace(s string) string {
+	if r.mode == RedactHash {
+		return hashValue(r.key, s)
+	}
+	return Redacted
+}
+
+// hashValue returns "hmac:" prefixed HMAC-SHA256 of the value
+func hashValue(key []byte, s string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// redactor returns the redactor, if configured for the formatter
+func (c *config) redactor() *Redactor {
+	if !c.redact {
+		return nil
+	}
+	return GetRedactor()
+}
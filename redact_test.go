@@ -0,0 +1,85 @@
+package xlog_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Redactor(t *testing.T) {
+	_, err := xlog.NewRedactor(xlog.RedactConfig{Keys: []string{"("}})
+	assert.EqualError(t, err, "invalid key pattern: (: error parsing regexp: missing closing ): `(?i)(`")
+	_, err = xlog.NewRedactor(xlog.RedactConfig{Values: []string{"["}})
+	assert.EqualError(t, err, "invalid value pattern: [: error parsing regexp: missing closing ]: `[`")
+
+	r, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys:   []string{"password", "^token$", "ssn"},
+		Values: []string{`\d{3}-\d{2}-\d{4}`},
+	})
+	require.NoError(t, err)
+
+	var nr *xlog.Redactor
+	assert.Equal(t, "secret", nr.Redact("password", "secret"))
+
+	assert.Equal(t, xlog.Redacted, r.Redact("Password", "secret"))
+	assert.Equal(t, xlog.Redacted, r.Redact("db_password", 123))
+	assert.Equal(t, xlog.Redacted, r.Redact("TOKEN", "t"))
+	assert.Equal(t, "t", r.Redact("access_token", "t"))
+	assert.Nil(t, r.Redact("password", nil))
+	assert.Equal(t, 123, r.Redact("k", 123))
+	assert.Equal(t, "ssn is [REDACTED]", r.Redact("k", "ssn is 123-45-6789"))
+	assert.Equal(t, "failed for [REDACTED]", r.Redact("err", fmt.Errorf("failed for 123-45-6789")))
+
+	_, err = xlog.NewRedactor(xlog.RedactConfig{
+		Keys: []string{"email"},
+		Mode: xlog.RedactHash,
+	})
+	assert.EqualError(t, err, "hash key is required for RedactHash mode")
+
+	h, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys:    []string{"email"},
+		Mode:    xlog.RedactHash,
+		HashKey: []byte("key1"),
+	})
+	require.NoError(t, err)
+	hv := h.Redact("email", "user@example.com")
+	assert.Equal(t, "hmac:c8526b5cc1c0f50c", hv)
+	assert.Equal(t, hv, h.Redact("email", "user@example.com"))
+	assert.NotEqual(t, hv, h.Redact("email", "admin@example.com"))
+
+	// the hash depends on the key
+	h2, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys:    []string{"email"},
+		Mode:    xlog.RedactHash,
+		HashKey: []byte("key2"),
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, hv, h2.Redact("email", "user@example.com"))
+}
+
+func Test_FormatWithRedaction(t *testing.T) {
+	r, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys: []string{"password"},
+	})
+	require.NoError(t, err)
+	xlog.SetRedactor(r)
+	defer xlog.SetRedactor(nil)
+	assert.Equal(t, r, xlog.GetRedactor())
+
+	var redacted, plain, js bytes.Buffer
+	xlog.SetFormatter(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&redacted).Options(xlog.FormatWithRedaction),
+		xlog.NewStringFormatter(&plain),
+		xlog.NewJSONFormatter(&js).Options(xlog.FormatWithRedaction),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "user", "u1", "password", "secret")
+	assert.Equal(t, "level=I pkg=xlog_test user=\"u1\" password=\"[REDACTED]\"\n", redacted.String())
+	assert.Equal(t, "level=I pkg=xlog_test user=\"u1\" password=\"secret\"\n", plain.String())
+	assert.Equal(t, `{"level":"I","password":"[REDACTED]","pkg":"xlog_test","user":"u1"}`+"\n", js.String())
+}
@@ -0,0 +1,117 @@
+package xlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedactionViolation describes the value, that leaked to the log
+// despite the redaction policy. The leaked value is not reported.
+type RedactionViolation struct {
+	// Line is the line number in the log, starting from 1
+	Line int
+	// Pkg is the package of the entry, if the line is a JSON entry
+	Pkg string
+	// Key is the key of the leaked field, "msg" for the message,
+	// or empty if the line is not a JSON entry
+	Key string
+	// Rule is the key or value pattern of the policy
+	Rule string
+}
+
+// String returns the description of the violation
+func (v RedactionViolation) String() string {
+	return fmt.Sprintf("line %d: pkg=%q key=%q rule=%q", v.Line, v.Pkg, v.Key, v.Rule)
+}
+
+// Verify scans the log produced by the formatters, and returns the values
+// that must have been redacted by the policies of the redactor:
+// the values of the fields with the keys matching the key patterns,
+// that are not replaced, and the values matching the value patterns.
+// The JSON entries, as parsed by ParseEntry, are verified by the fields,
+// and other lines are verified by the value patterns only.
+func (r *Redactor) Verify(rd io.Reader) ([]RedactionViolation, error) {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var list []RedactionViolation
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		e, err := ParseEntry(b)
+		if err != nil {
+			if rule := r.valueRule(string(b)); rule != "" {
+				list = append(list, RedactionViolation{Line: line, Rule: rule})
+			}
+			continue
+		}
+		list = append(list, r.verifyEntry(line, e)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return list, errors.WithStack(err)
+	}
+	return list, nil
+}
+
+// VerifyFile scans the log file, see Verify
+func (r *Redactor) VerifyFile(name string) ([]RedactionViolation, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	return r.Verify(f)
+}
+
+func (r *Redactor) verifyEntry(line int, e *Entry) []RedactionViolation {
+	var list []RedactionViolation
+	if e.Message != "" {
+		if rule := r.valueRule(e.Message); rule != "" {
+			list = append(list, RedactionViolation{Line: line, Pkg: e.Pkg, Key: "msg", Rule: rule})
+		}
+	}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		k, _ := e.Fields[i].(string)
+		v := fmt.Sprint(e.Fields[i+1])
+		rule := r.keyRule(k, v)
+		if rule == "" {
+			rule = r.valueRule(v)
+		}
+		if rule != "" {
+			list = append(list, RedactionViolation{Line: line, Pkg: e.Pkg, Key: k, Rule: rule})
+		}
+	}
+	return list
+}
+
+// keyRule returns the key pattern, if the key must be redacted,
+// and the value is not replaced
+func (r *Redactor) keyRule(key, value string) string {
+	if value == "" || value == Redacted || strings.HasPrefix(value, hashPrefix) {
+		return ""
+	}
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return strings.TrimPrefix(re.String(), "(?i)")
+		}
+	}
+	return ""
+}
+
+// valueRule returns the value pattern, if the value has the part to be redacted
+func (r *Redactor) valueRule(value string) string {
+	for _, re := range r.values {
+		if re.MatchString(value) {
+			return re.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package xlog_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedactorVerify(t *testing.T) {
+	r, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys:   []string{"password", "token"},
+		Values: []string{`\d{3}-\d{2}-\d{4}`},
+	})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetRedactor(r)
+	defer xlog.SetRedactor(nil)
+
+	// redacted output has no violations
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatWithRedaction))
+	logger.KV(xlog.INFO, "password", "secret", "ssn", "123-45-6789", "k", 1)
+	list, err := r.Verify(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// the output of the formatter without redaction
+	b.Reset()
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller))
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "Password", "secret", "ssn", "123-45-6789", "token", "")
+	logger.Info("ssn: 123-45-6789")
+	b.WriteString("\nplain line ssn=123-45-6789\n")
+	b.WriteString("plain line\n")
+
+	list, err = r.Verify(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, []xlog.RedactionViolation{
+		{Line: 2, Pkg: "xlog_test", Key: "Password", Rule: "password"},
+		{Line: 2, Pkg: "xlog_test", Key: "ssn", Rule: `\d{3}-\d{2}-\d{4}`},
+		{Line: 3, Pkg: "xlog_test", Key: "msg", Rule: `\d{3}-\d{2}-\d{4}`},
+		{Line: 5, Rule: `\d{3}-\d{2}-\d{4}`},
+	}, list)
+	assert.Equal(t, `line 2: pkg="xlog_test" key="Password" rule="password"`, list[0].String())
+	for _, v := range list {
+		assert.False(t, strings.Contains(v.String(), "secret"))
+	}
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(name, b.Bytes(), 0644))
+	list2, err := r.VerifyFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, list, list2)
+
+	_, err = r.VerifyFile(filepath.Join(t.TempDir(), "missing.log"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package xlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SamplerConfig specifies configuration for Sampler
+type SamplerConfig struct {
+	// Tick specifies the sampling interval, 1 second by default
+	Tick time.Duration
+	// First specifies the number of identical entries
+	// to be logged per Tick
+	First int
+	// Thereafter specifies that every Thereafter entry is logged
+	// after First entries were logged in the same Tick.
+	// If zero, all the entries after First are dropped.
+	Thereafter int
+	// Summary specifies to log the number of suppressed entries
+	// at the end of each Tick
+	Summary bool
+	// Propagate specifies to include the number of entries dropped
+	// since the previous logged entry with the same identity,
+	// as "sampled_dropped" value of the next logged entry
+	Propagate bool
+}
+
+// KeySampledDropped is the key for the number of sampled away entries
+const KeySampledDropped = "sampled_dropped"
+
+// Sampler is a formatter wrapper, that limits the number of identical entries
+// logged per tick. Entries are identical if they have the same package,
+// level and message. For key-value entries, the message is
+// the list of keys and the value of "msg" key.
+type Sampler struct {
+	inner Formatter
+	cfg   SamplerConfig
+
+	lock      sync.Mutex
+	windowEnd time.Time
+	counters  map[samplerKey]*samplerCounter
+	dropped   uint64
+	// propagated specifies the number of dropped entries
+	// to be reported with the next logged entry
+	propagated map[samplerKey]int
+}
+
+type samplerKey struct {
+	pkg   string
+	level LogLevel
+	msg   string
+}
+
+type samplerCounter struct {
+	count      int
+	suppressed int
+}
+
+// NewSampler returns a formatter, that samples entries
+// before forwarding them to the inner formatter
+func NewSampler(inner Formatter, cfg SamplerConfig) *Sampler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	return &Sampler{
+		inner:      inner,
+		cfg:        cfg,
+		counters:   make(map[samplerKey]*samplerCounter),
+		propagated: make(map[samplerKey]int),
+	}
+}
+
+// Options allows to configure formatter behavior
+func (s *Sampler) Options(ops ...FormatterOption) Formatter {
+	s.inner.Options(ops...)
+	return s
+}
+
+// Settings applies the settings to the inner formatter
+func (s *Sampler) Settings(settings FormatterSettings) Formatter {
+	ApplySettings(s.inner, settings)
+	return s
+}
+
+// Format log entry string to the stream
+func (s *Sampler) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	if ok, dropped := s.sample(pkg, l, depth+1, fmt.Sprint(entries...)); ok {
+		if dropped > 0 {
+			entries = append(entries, fmt.Sprintf("%s=%d", KeySampledDropped, dropped))
+		}
+		s.inner.Format(pkg, l, depth+1, entries...)
+	}
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (s *Sampler) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	if ok, dropped := s.sample(pkg, l, depth+1, kvFingerprint(entries)); ok {
+		if dropped > 0 {
+			entries = append(entries, KeySampledDropped, dropped)
+		}
+		s.inner.FormatKV(pkg, l, depth+1, entries...)
+	}
+}
+
+// Flush logs the summary of suppressed entries, if configured,
+// and flushes the inner formatter
+func (s *Sampler) Flush() {
+	s.lock.Lock()
+	s.summary(2)
+	s.lock.Unlock()
+	s.inner.Flush()
+}
+
+// Dropped returns the total number of dropped entries
+func (s *Sampler) Dropped() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dropped
+}
+
+// sample returns true if the entry has to be logged,
+// and the number of dropped entries to propagate
+func (s *Sampler) sample(pkg string, l LogLevel, depth int, msg string) (bool, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := TimeNowFn()
+	if !now.Before(s.windowEnd) {
+		s.summary(depth + 1)
+		s.windowEnd = now.Add(s.cfg.Tick)
+	}
+
+	key := samplerKey{pkg: pkg, level: l, msg: msg}
+	c := s.counters[key]
+	if c == nil {
+		c = new(samplerCounter)
+		s.counters[key] = c
+	}
+	c.count++
+	if c.count <= s.cfg.First ||
+		(s.cfg.Thereafter > 0 && (c.count-s.cfg.First)%s.cfg.Thereafter == 0) {
+		dropped := s.propagated[key]
+		delete(s.propagated, key)
+		return true, dropped
+	}
+	c.suppressed++
+	s.dropped++
+	if s.cfg.Propagate {
+		s.propagated[key]++
+	}
+	return false, 0
+}
+
+// summary logs the number of suppressed entries, and resets the counters,
+// the caller must hold the lock
+func (s *Sampler) summary(depth int) {
+	for key, c := range s.counters {
+		if s.cfg.Summary && c.suppressed > 0 {
+			s.inner.FormatKV(key.pkg, key.level, depth+1,
+				"suppressed", c.suppressed,
+				"sampled", key.msg,
+			)
+		}
+	}
+	s.counters = make(map[samplerKey]*samplerCounter)
+}
+
+// kvFingerprint returns the identity of key-value entries
+func kvFingerprint(entries []any) string {
+	var sb strings.Builder
+	for i := 0; i < len(entries); i += 2 {
+		k, ok := entries[i].(string)
+		if !ok {
+			continue
+		}
+		if sb.Len() > 0 {
+			_ = sb.WriteByte(',')
+		}
+		_, _ = sb.WriteString(k)
+		if k == "msg" && i+1 < len(entries) {
+			_ = sb.WriteByte('=')
+			_, _ = sb.WriteString(fmt.Sprint(entries[i+1]))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,100 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sampler(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = func() time.Time {
+			return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}()
+
+	var b bytes.Buffer
+	s := xlog.NewSampler(xlog.NewStringFormatter(&b), xlog.SamplerConfig{
+		First:      2,
+		Thereafter: 3,
+		Summary:    true,
+	})
+	xlog.SetFormatter(s.Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	for i := 0; i < 6; i++ {
+		logger.KV(xlog.INFO, "msg", "retry", "attempt", i)
+	}
+	logger.KV(xlog.INFO, "msg", "other", "attempt", 1)
+	logger.Info("plain")
+	logger.Info("plain")
+	logger.Info("plain")
+
+	assert.Equal(t, "level=I pkg=xlog_test msg=\"retry\" attempt=0\n"+
+		"level=I pkg=xlog_test msg=\"retry\" attempt=1\n"+
+		"level=I pkg=xlog_test msg=\"retry\" attempt=4\n"+
+		"level=I pkg=xlog_test msg=\"other\" attempt=1\n"+
+		"level=I pkg=xlog_test \"plain\"\n"+
+		"level=I pkg=xlog_test \"plain\"\n", b.String())
+	assert.Equal(t, uint64(4), s.Dropped())
+	b.Reset()
+
+	// next tick resets the counters and logs the summary
+	now = now.Add(time.Second)
+	logger.KV(xlog.INFO, "msg", "retry", "attempt", 6)
+	assert.Contains(t, b.String(), "level=I pkg=xlog_test suppressed=3 sampled=\"msg=retry,attempt\"\n")
+	assert.Contains(t, b.String(), "level=I pkg=xlog_test suppressed=1 sampled=\"plain\"\n")
+	assert.Contains(t, b.String(), "level=I pkg=xlog_test msg=\"retry\" attempt=6\n")
+	b.Reset()
+
+	logger.Info("plain")
+	logger.Info("plain")
+	logger.Info("plain")
+	s.Flush()
+	assert.Equal(t, "level=I pkg=xlog_test \"plain\"\n"+
+		"level=I pkg=xlog_test \"plain\"\n"+
+		"level=I pkg=xlog_test suppressed=1 sampled=\"plain\"\n", b.String())
+}
+
+func Test_SamplerPropagate(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = func() time.Time {
+			return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}()
+
+	var b bytes.Buffer
+	s := xlog.NewSampler(xlog.NewStringFormatter(&b), xlog.SamplerConfig{
+		First:      1,
+		Thereafter: 3,
+		Propagate:  true,
+	})
+	xlog.SetFormatter(s.Options(xlog.FormatSkipTime, xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	for i := 0; i < 5; i++ {
+		logger.KV(xlog.INFO, "msg", "retry", "attempt", i)
+	}
+	logger.Info("plain")
+	logger.Info("plain")
+
+	// the dropped count is carried over to the next tick
+	now = now.Add(time.Second)
+	logger.KV(xlog.INFO, "msg", "retry", "attempt", 5)
+	logger.Info("plain")
+
+	assert.Equal(t, "level=I pkg=xlog_test msg=\"retry\" attempt=0\n"+
+		"level=I pkg=xlog_test msg=\"retry\" attempt=3 sampled_dropped=2\n"+
+		"level=I pkg=xlog_test \"plain\"\n"+
+		"level=I pkg=xlog_test msg=\"retry\" attempt=5 sampled_dropped=1\n"+
+		"level=I pkg=xlog_test \"plain\" \"sampled_dropped=1\"\n",
+		b.String())
+	assert.Equal(t, uint64(4), s.Dropped())
+}
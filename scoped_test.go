@@ -0,0 +1,66 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScopedHooksAndErrors(t *testing.T) {
+	const repo = "github.com/effective-security/xlog"
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.ResetHooks()
+
+	other := xlog.NewPackageLogger("github.com/effective-security/other", "scoped")
+	pkg2 := xlog.NewPackageLogger(repo, "scoped2")
+
+	var repoHooks, pkgHooks []string
+	removeRepoHook := xlog.AddRepoHook(repo, xlog.HookFunc(func(e *xlog.Entry) error {
+		repoHooks = append(repoHooks, e.Pkg)
+		return nil
+	}))
+	removePkgHook := xlog.AddPackageHook(repo, "scoped2", xlog.HookFunc(func(e *xlog.Entry) error {
+		pkgHooks = append(pkgHooks, e.Pkg)
+		e.Fields = append(e.Fields, "hooked", true)
+		return nil
+	}))
+
+	var repoErrors, pkgErrors []string
+	removeRepoErr := xlog.OnRepoError(repo, func(pkg string) {
+		repoErrors = append(repoErrors, pkg)
+	})
+	removePkgErr := xlog.OnPackageError(repo, "xlog_test", func(pkg string) {
+		pkgErrors = append(pkgErrors, pkg)
+	})
+
+	logger.KV(xlog.ERROR, "k", 1)
+	pkg2.KV(xlog.ERROR, "k", 2)
+	other.KV(xlog.ERROR, "k", 3)
+
+	assert.Equal(t, []string{"xlog_test", "scoped2"}, repoHooks)
+	assert.Equal(t, []string{"scoped2"}, pkgHooks)
+	assert.Equal(t, []string{"xlog_test", "scoped2"}, repoErrors)
+	assert.Equal(t, []string{"xlog_test"}, pkgErrors)
+	assert.Contains(t, b.String(), "level=E pkg=scoped2 k=2 hooked=true\n")
+	assert.Contains(t, b.String(), "level=E pkg=scoped k=3\n")
+
+	removeRepoHook()
+	removePkgHook()
+	removeRepoErr()
+	removePkgErr()
+	// removed twice
+	removeRepoHook()
+	removeRepoErr()
+
+	logger.KV(xlog.ERROR, "k", 4)
+	pkg2.KV(xlog.ERROR, "k", 5)
+	assert.Len(t, repoHooks, 2)
+	assert.Len(t, pkgHooks, 1)
+	assert.Len(t, repoErrors, 2)
+	assert.Len(t, pkgErrors, 1)
+	assert.Empty(t, xlog.EffectiveConfig().Hooks)
+}
@@ -0,0 +1,65 @@
+package xlog
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SecretMask is the output of the secret values
+const SecretMask = "*****"
+
+// SecretValue wraps a value, that must not be logged.
+// The value is always printed masked, regardless of the formatter.
+type SecretValue struct {
+	value any
+	hash  bool
+}
+
+// Secret returns the value wrapper, that is printed as "*****"
+func Secret(v any) SecretValue {
+	return SecretValue{value: v}
+}
+
+// SecretHash returns the value wrapper, that is printed as "hmac:" prefixed HMAC-SHA256,
+// that allows to correlate the values without revealing them.
+// The value is printed as "*****", if the key is not set by SetSecretHashKey.
+func SecretHash(v any) SecretValue {
+	return SecretValue{value: v, hash: true}
+}
+
+// SetSecretHashKey sets the secret key of SecretHash values,
+// so the values of low entropy can not be recovered by brute force.
+// Pass nil to print the values masked.
+func SetSecretHashKey(key []byte) {
+	if len(key) == 0 {
+		logger.secretKey.Store(nil)
+		return
+	}
+	key = append([]byte(nil), key...)
+	logger.secretKey.Store(&key)
+}
+
+// Value returns the wrapped value
+func (s SecretValue) Value() any {
+	return s.value
+}
+
+// String returns the masked value
+func (s SecretValue) String() string {
+	if s.hash {
+		if key := logger.secretKey.Load(); key != nil {
+			return hashValue(*key, fmt.Sprint(s.value))
+		}
+	}
+	return SecretMask
+}
+
+// Format implements fmt.Formatter, to mask the value for all verbs
+func (s SecretValue) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(s.String()))
+}
+
+// MarshalJSON returns the masked value
+func (s SecretValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
@@ -0,0 +1,50 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Secret(t *testing.T) {
+	s := xlog.Secret("key123")
+	assert.Equal(t, "key123", s.Value())
+	assert.Equal(t, xlog.SecretMask, s.String())
+	assert.Equal(t, `"*****"`, xlog.EscapedString(s))
+	assert.Equal(t, "*****", fmt.Sprintf("%v", s))
+	assert.Equal(t, "*****", fmt.Sprintf("%+v", s))
+	assert.Equal(t, "*****", fmt.Sprintf("%#v", s))
+	assert.Equal(t, "*****", fmt.Sprintf("%s", s))
+
+	js, err := json.Marshal(map[string]any{"apikey": s})
+	require.NoError(t, err)
+	assert.Equal(t, `{"apikey":"*****"}`, string(js))
+
+	// masked without the key
+	h := xlog.SecretHash("user@example.com")
+	assert.Equal(t, xlog.SecretMask, h.String())
+
+	xlog.SetSecretHashKey([]byte("key1"))
+	defer xlog.SetSecretHashKey(nil)
+	assert.Equal(t, "hmac:c8526b5cc1c0f50c", h.String())
+	assert.Equal(t, `"hmac:c8526b5cc1c0f50c"`, xlog.EscapedString(h))
+
+	var str, js2 bytes.Buffer
+	xlog.SetFormatter(xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(&str),
+		xlog.NewJSONFormatter(&js2),
+	).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "apikey", xlog.Secret("key123"))
+	logger.Info("apikey:", xlog.Secret("key123"))
+	assert.Equal(t, "level=I pkg=xlog_test apikey=\"*****\"\n"+
+		"level=I pkg=xlog_test \"apikey:\" \"*****\"\n", str.String())
+	assert.Equal(t, `{"apikey":"*****","level":"I","pkg":"xlog_test"}`+"\n"+
+		`{"level":"I","msg":"apikey:*****","pkg":"xlog_test"}`+"\n", js2.String())
+}
@@ -0,0 +1,252 @@
+package xlog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SelfTester is implemented by formatters and writers,
+// that can verify the delivery of the entries, such as network sinks.
+// SelfTest calls it after the probe entry is written and flushed.
+type SelfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// SelfTestResult is the result of the probe of a sink
+type SelfTestResult struct {
+	// Sink is the name of the sink: "formatter" for the global formatter,
+	// "repo:<repo>" or "package:<repo>:<pkg>" for the formatters set per repo or package,
+	// with "/<index>" suffix for the formatters of MultiFormatter
+	Sink string `json:"sink"`
+	// Type is the type of the formatter
+	Type string `json:"type"`
+	// Duration is the time to write and flush the probe
+	Duration time.Duration `json:"duration"`
+	// Error is the reason the probe was not accepted, or empty
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTestReport is the result of SelfTest
+type SelfTestReport struct {
+	// Probe is the ID of the probe entries
+	Probe string `json:"probe"`
+	// Sinks specifies the results per sink
+	Sinks []SelfTestResult `json:"sinks"`
+}
+
+// Failed returns the results of the sinks, that did not accept the probe
+func (r *SelfTestReport) Failed() []SelfTestResult {
+	var list []SelfTestResult
+	for _, res := range r.Sinks {
+		if res.Error != "" {
+			list = append(list, res)
+		}
+	}
+	return list
+}
+
+// KeyProbe is the key of the probe ID in the entries written by SelfTest
+const KeyProbe = "probe"
+
+// SelfTest writes a probe entry through every configured formatter,
+// regardless of the log levels, and verifies it was accepted:
+// the writes of the formatter did not fail, the async writers did not drop it,
+// the writers with Flush(ctx) error method delivered it,
+// no delivery error was reported by ReportError,
+// and the formatters and writers implementing SelfTester verified it.
+// Each probe is limited by the context deadline.
+//
+// The report is returned with an error if any sink failed,
+// so services can fail fast when logging is misconfigured:
+//
+//	if _, err := xlog.SelfTest(ctx); err != nil {
+//		fmt.Fprintf(os.Stderr, "logging is misconfigured: %v\n", err)
+//		os.Exit(1)
+//	}
+func SelfTest(ctx context.Context) (*SelfTestReport, error) {
+	report := &SelfTestReport{
+		Probe: newProbeID(),
+	}
+	for _, s := range logger.selfTestSinks() {
+		report.Sinks = append(report.Sinks, s.probe(ctx, report.Probe)...)
+	}
+	if len(report.Sinks) == 0 {
+		return report, errors.New("self-test: no formatter configured")
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		list := make([]string, len(failed))
+		for i, res := range failed {
+			list[i] = res.Sink + ": " + res.Error
+		}
+		return report, errors.Errorf("self-test: %s", strings.Join(list, "; "))
+	}
+	return report, nil
+}
+
+// namedSink is the sink with the name for the report
+type namedSink struct {
+	name string
+	*sink
+}
+
+// selfTestSinks returns the distinct configured sinks,
+// the global formatter first, then per repo and per package, sorted by name
+func (l *loggerStruct) selfTestSinks() []namedSink {
+	s := l.sinks.Load()
+	if s == nil {
+		return nil
+	}
+	seen := make(map[*sink]bool)
+	var list []namedSink
+	add := func(name string, f *sink) {
+		if f == nil || seen[f] {
+			return
+		}
+		seen[f] = true
+		list = append(list, namedSink{name: name, sink: f})
+	}
+	add("formatter", s.formatter)
+
+	repos := make([]string, 0, len(s.repo))
+	for repo := range s.repo {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		add("repo:"+repo, s.repo[repo])
+	}
+
+	pkgs := make([]pkgKey, 0, len(s.pkg))
+	for key := range s.pkg {
+		pkgs = append(pkgs, key)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].repo == pkgs[j].repo {
+			return pkgs[i].pkg < pkgs[j].pkg
+		}
+		return pkgs[i].repo < pkgs[j].repo
+	})
+	for _, key := range pkgs {
+		add("package:"+key.repo+":"+key.pkg, s.pkg[key])
+	}
+	return list
+}
+
+// probe writes the probe entry to the sink, waiting up to the context deadline.
+// If the sink is blocked, the probe goroutine remains blocked until it is released.
+func (s namedSink) probe(ctx context.Context, id string) []SelfTestResult {
+	started := time.Now()
+	done := make(chan []SelfTestResult, 1)
+	go func() {
+		s.Lock()
+		defer s.Unlock()
+		done <- probeFormatter(ctx, s.name, s.Formatter, id)
+	}()
+
+	select {
+	case list := <-done:
+		return list
+	case <-ctx.Done():
+		return []SelfTestResult{{
+			Sink:     s.name,
+			Type:     fmt.Sprintf("%T", s.Formatter),
+			Duration: time.Since(started),
+			Error:    "probe not accepted: " + ctx.Err().Error(),
+		}}
+	}
+}
+
+// formatterWriter is implemented by the formatters writing to the buffered writer
+type formatterWriter interface {
+	writer() (*bufio.Writer, *sizeWriter)
+}
+
+// contextFlusher is implemented by the writers delivering the entries asynchronously
+type contextFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// probeFormatter writes the probe entry to the formatter,
+// the formatters of MultiFormatter are probed separately
+func probeFormatter(ctx context.Context, name string, f Formatter, id string) []SelfTestResult {
+	if m, ok := f.(*MultiFormatter); ok {
+		var list []SelfTestResult
+		for i, mf := range m.formatters {
+			list = append(list, probeFormatter(ctx, name+"/"+strconv.Itoa(i), mf, id)...)
+		}
+		return list
+	}
+
+	started := time.Now()
+	res := SelfTestResult{
+		Sink: name,
+		Type: fmt.Sprintf("%T", f),
+	}
+	if err := probe(ctx, f, id); err != nil {
+		res.Error = err.Error()
+	}
+	res.Duration = time.Since(started)
+	return []SelfTestResult{res}
+}
+
+func probe(ctx context.Context, f Formatter, id string) error {
+	var dest any
+	if fw, ok := f.(formatterWriter); ok {
+		_, sw := fw.writer()
+		dest = sw.w
+	}
+	var dropped uint64
+	if aw, ok := dest.(*AsyncWriter); ok {
+		dropped = aw.Dropped()
+	}
+	reported := logger.deliveryErrors.Load()
+
+	f.FormatKV("xlog", NOTICE, calldepth, "msg", "self-test probe", KeyProbe, id)
+	f.Flush()
+
+	if fw, ok := f.(formatterWriter); ok {
+		// bufio.Writer keeps the first write error
+		bw, _ := fw.writer()
+		if err := bw.Flush(); err != nil {
+			return errors.WithMessage(err, "probe not written")
+		}
+	}
+	if w, ok := dest.(interface{ Flush() }); ok {
+		// wait for the async writers to write the probe
+		w.Flush()
+	}
+	if aw, ok := dest.(*AsyncWriter); ok && aw.Dropped() > dropped {
+		return errors.New("probe dropped by AsyncWriter")
+	}
+	if cf, ok := dest.(contextFlusher); ok {
+		if err := cf.Flush(ctx); err != nil {
+			return errors.WithMessage(err, "probe not delivered")
+		}
+	}
+	if logger.deliveryErrors.Load() > reported {
+		return errors.New("delivery error reported")
+	}
+	for _, v := range []any{f, dest} {
+		if st, ok := v.(SelfTester); ok {
+			if err := st.SelfTest(ctx); err != nil {
+				return errors.WithMessage(err, "self-test failed")
+			}
+		}
+	}
+	return nil
+}
+
+func newProbeID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
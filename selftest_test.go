@@ -0,0 +1,133 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter fails all writes
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+// deliveryWriter fails the delivery on flush
+type deliveryWriter struct {
+	bytes.Buffer
+	err error
+}
+
+func (w *deliveryWriter) Flush(context.Context) error {
+	return w.err
+}
+
+// testerFormatter verifies the delivery with SelfTest
+type testerFormatter struct {
+	xlog.Formatter
+	err error
+}
+
+func (f *testerFormatter) SelfTest(context.Context) error {
+	return f.err
+}
+
+func results(r *xlog.SelfTestReport) map[string]xlog.SelfTestResult {
+	m := make(map[string]xlog.SelfTestResult)
+	for _, res := range r.Sinks {
+		m[res.Sink] = res
+	}
+	return m
+}
+
+func Test_SelfTest(t *testing.T) {
+	const repo = "github.com/effective-security/selftest"
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	defer xlog.SetRepoFormatter(repo, nil)
+
+	var b, bm bytes.Buffer
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b))
+	xlog.SetGlobalLogLevel(xlog.ERROR)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	report, err := xlog.SelfTest(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Probe, 16)
+	res := results(report)["formatter"]
+	assert.Equal(t, "*xlog.JSONFormatter", res.Type)
+	assert.Empty(t, res.Error)
+	// the probe is written regardless of the level
+	assert.Contains(t, b.String(), `"msg":"self-test probe","pkg":"xlog","probe":"`+report.Probe+`"`)
+
+	aw := xlog.NewAsyncWriter(&bm, xlog.AsyncConfig{})
+	defer aw.Close()
+	xlog.SetRepoFormatter(repo, xlog.NewMultiFormatter(
+		xlog.NewStringFormatter(aw),
+		xlog.NewJSONFormatter(failingWriter{}),
+		xlog.NewJSONFormatter(&deliveryWriter{err: fmt.Errorf("connection refused")}),
+		&testerFormatter{Formatter: xlog.NewNilFormatter(), err: fmt.Errorf("not ready")},
+	))
+
+	report, err = xlog.SelfTest(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "self-test: "+
+		"repo:"+repo+"/1: probe not written: disk full; "+
+		"repo:"+repo+"/2: probe not delivered: connection refused; "+
+		"repo:"+repo+"/3: self-test failed: not ready", err.Error())
+	assert.Len(t, report.Failed(), 3)
+
+	m := results(report)
+	assert.Empty(t, m["formatter"].Error)
+	assert.Empty(t, m["repo:"+repo+"/0"].Error)
+	assert.Equal(t, "*xlog.StringFormatter", m["repo:"+repo+"/0"].Type)
+	assert.Contains(t, bm.String(), "self-test probe")
+	assert.Equal(t, "*xlog_test.testerFormatter", m["repo:"+repo+"/3"].Type)
+}
+
+func Test_SelfTestDeliveryError(t *testing.T) {
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	xlog.SetFormatter(&testerFormatter{Formatter: xlog.NewNilFormatter()})
+	_, err := xlog.SelfTest(context.Background())
+	require.NoError(t, err)
+
+	// the sink reports the delivery error on flush
+	xlog.SetFormatter(&reportingFormatter{Formatter: xlog.NewNilFormatter()})
+	_, err = xlog.SelfTest(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "self-test: formatter: delivery error reported", err.Error())
+}
+
+// reportingFormatter reports the delivery error on flush
+type reportingFormatter struct {
+	xlog.Formatter
+}
+
+func (f *reportingFormatter) Flush() {
+	xlog.ReportError("selftest")
+}
+
+func Test_SelfTestTimeout(t *testing.T) {
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	w := newBlockingWriter()
+	defer close(w.release)
+	xlog.SetFormatter(xlog.NewJSONFormatter(w))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := xlog.SelfTest(ctx)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "self-test: formatter: probe not accepted: context deadline exceeded"))
+	// the sinks probed after the deadline are not accepted
+	require.Len(t, report.Sinks, len(report.Failed()))
+	assert.Equal(t, "probe not accepted: context deadline exceeded", results(report)["formatter"].Error)
+}
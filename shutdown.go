@@ -0,0 +1,137 @@
+package xlog
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Shutdown priorities of the sinks, lower priority is flushed first
+const (
+	PriorityAudit   = 0
+	PriorityFile    = 10
+	PriorityRemote  = 20
+	PriorityConsole = 30
+)
+
+// ShutdownSink specifies a sink to be flushed on Shutdown
+type ShutdownSink struct {
+	// Name of the sink, used in errors
+	Name string
+	// Priority specifies the order of flush, lower priority is flushed first,
+	// sinks with the same priority are flushed in order of registration
+	Priority int
+	// Timeout specifies the maximum time to flush the sink,
+	// if zero, the sink can use the remaining time of Shutdown context
+	Timeout time.Duration
+	// Flush persists the buffered entries of the sink
+	Flush func(ctx context.Context) error
+}
+
+// FlushTimeout is the maximum time to flush the registered sinks
+// on Panic and Fatal, before the process exits
+var FlushTimeout = 5 * time.Second
+
+type shutdownSink struct {
+	ShutdownSink
+	id uint64
+}
+
+var shutdownSinks struct {
+	sync.Mutex
+	next uint64
+	list []shutdownSink
+}
+
+// RegisterShutdown registers the sink to be flushed on Shutdown,
+// and by Panic and Fatal before exit, so the buffered entries are not lost.
+// The returned function unregisters the sink.
+func RegisterShutdown(s ShutdownSink) (unregister func()) {
+	shutdownSinks.Lock()
+	defer shutdownSinks.Unlock()
+	shutdownSinks.next++
+	id := shutdownSinks.next
+	shutdownSinks.list = append(shutdownSinks.list, shutdownSink{ShutdownSink: s, id: id})
+
+	return func() {
+		shutdownSinks.Lock()
+		defer shutdownSinks.Unlock()
+		for i, s := range shutdownSinks.list {
+			if s.id == id {
+				shutdownSinks.list = append(shutdownSinks.list[:i:i], shutdownSinks.list[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// CloserFlush returns the flush function for ShutdownSink, that closes c
+func CloserFlush(c io.Closer) func(ctx context.Context) error {
+	return func(context.Context) error {
+		return c.Close()
+	}
+}
+
+// Shutdown flushes the registered sinks in priority order,
+// each sink is limited by its timeout and the context deadline,
+// so the most critical sinks are persisted even if the drain window is short.
+// The sinks are unregistered, and the errors of all sinks are returned.
+func Shutdown(ctx context.Context) error {
+	shutdownSinks.Lock()
+	list := shutdownSinks.list
+	shutdownSinks.list = nil
+	shutdownSinks.Unlock()
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority < list[j].Priority
+	})
+
+	logger.flushRateSummaries()
+	logger.flushSinks()
+
+	var errs []string
+	for _, s := range list {
+		if err := flushSink(ctx, s.ShutdownSink); err != nil {
+			errs = append(errs, s.Name+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("failed to flush sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func flushSink(ctx context.Context, s ShutdownSink) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Flush(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flushOnExit calls Shutdown on Panic and Fatal, limited by FlushTimeout
+func flushOnExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), FlushTimeout)
+	defer cancel()
+	_ = Shutdown(ctx)
+}
@@ -0,0 +1,144 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closer struct {
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_Shutdown(t *testing.T) {
+	var order []string
+	flush := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	c := &closer{}
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "console", Priority: xlog.PriorityConsole, Flush: flush("console")})
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "remote", Priority: xlog.PriorityRemote, Flush: flush("remote")})
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "audit", Priority: xlog.PriorityAudit, Flush: flush("audit")})
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "file", Priority: xlog.PriorityFile, Flush: xlog.CloserFlush(c)})
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "remote2", Priority: xlog.PriorityRemote, Flush: flush("remote2")})
+
+	require.NoError(t, xlog.Shutdown(context.Background()))
+	assert.Equal(t, []string{"audit", "remote", "remote2", "console"}, order)
+	assert.True(t, c.closed)
+
+	// sinks are unregistered
+	order = nil
+	require.NoError(t, xlog.Shutdown(context.Background()))
+	assert.Empty(t, order)
+}
+
+func Test_ShutdownTimeout(t *testing.T) {
+	var order []string
+	xlog.RegisterShutdown(xlog.ShutdownSink{
+		Name:    "slow",
+		Timeout: 10 * time.Millisecond,
+		Flush: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+	xlog.RegisterShutdown(xlog.ShutdownSink{
+		Name: "failed",
+		Flush: func(context.Context) error {
+			return errors.New("connection refused")
+		},
+	})
+	xlog.RegisterShutdown(xlog.ShutdownSink{
+		Name:     "console",
+		Priority: xlog.PriorityConsole,
+		Flush: func(context.Context) error {
+			order = append(order, "console")
+			return nil
+		},
+	})
+
+	err := xlog.Shutdown(context.Background())
+	assert.EqualError(t, err, "failed to flush sinks: slow: context deadline exceeded; failed: connection refused")
+	assert.Equal(t, []string{"console"}, order)
+
+	// the remaining sinks are not flushed after the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "file", Flush: xlog.CloserFlush(io.NopCloser(nil))})
+	err = xlog.Shutdown(ctx)
+	assert.EqualError(t, err, "failed to flush sinks: file: context canceled")
+}
+
+func Test_ShutdownUnregister(t *testing.T) {
+	var order []string
+	flush := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	xlog.RegisterShutdown(xlog.ShutdownSink{Name: "first", Flush: flush("first")})
+	unregister := xlog.RegisterShutdown(xlog.ShutdownSink{Name: "second", Flush: flush("second")})
+	unregister()
+	unregister()
+
+	require.NoError(t, xlog.Shutdown(context.Background()))
+	assert.Equal(t, []string{"first"}, order)
+}
+
+func Test_FatalShutdown(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	var flushed []string
+	sink := xlog.ShutdownSink{
+		Name: "buffered",
+		Flush: func(context.Context) error {
+			flushed = append(flushed, b.String())
+			return nil
+		},
+	}
+	defer xlog.RegisterShutdown(sink)()
+
+	exitCode := -1
+	oldExit := xlog.ExitFunc
+	xlog.ExitFunc = func(code int) { exitCode = code }
+	defer func() { xlog.ExitFunc = oldExit }()
+
+	logger := xlog.NewPackageLogger("github.com/effective-security/xlog", "shutdown_test")
+
+	// the sinks are drained by Shutdown, before exit
+	logger.Fatal("fatal")
+	assert.Equal(t, 1, exitCode)
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "level=C pkg=shutdown_test \"fatal\"\n", flushed[0])
+
+	xlog.RegisterShutdown(sink)
+	assert.Panics(t, func() {
+		logger.Panicf("panic %d", 1)
+	})
+	require.Len(t, flushed, 2)
+	assert.Contains(t, flushed[1], "\"panic 1\"")
+
+	// the sinks are unregistered
+	require.NoError(t, xlog.Shutdown(context.Background()))
+	assert.Len(t, flushed, 2)
+}
@@ -0,0 +1,46 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RepoFormatter(t *testing.T) {
+	const repo = "github.com/effective-security/xlog/sinks"
+	pkgA := xlog.NewPackageLogger(repo, "a")
+	pkgB := xlog.NewPackageLogger(repo, "b")
+	defer func() {
+		xlog.SetRepoFormatter(repo, nil)
+	}()
+
+	var global, repoOut, pkgOut bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&global).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	xlog.MustRepoLogger(repo).SetFormatter(xlog.NewStringFormatter(&repoOut).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	pkgB.SetFormatter(xlog.NewJSONFormatter(&pkgOut).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	pkgA.KV(xlog.INFO, "k", 1)
+	pkgB.WithValues("v", 2).KV(xlog.INFO, "k", 2)
+	logger.KV(xlog.INFO, "k", 3)
+
+	assert.Equal(t, "level=I pkg=a k=1\n", repoOut.String())
+	assert.Equal(t, `{"k":2,"level":"I","pkg":"b","v":2}`+"\n", pkgOut.String())
+	assert.Equal(t, "level=I pkg=xlog_test k=3\n", global.String())
+
+	global.Reset()
+	repoOut.Reset()
+	pkgOut.Reset()
+
+	xlog.SetPackageFormatter(repo, "b", nil)
+	pkgB.KV(xlog.INFO, "k", 2)
+	assert.Equal(t, "level=I pkg=b k=2\n", repoOut.String())
+
+	xlog.SetPackageFormatter(repo, "*", nil)
+	pkgA.KV(xlog.INFO, "k", 1)
+	pkgB.Flush()
+	assert.Equal(t, "level=I pkg=a k=1\n", global.String())
+	assert.Empty(t, pkgOut.String())
+}
@@ -0,0 +1,58 @@
+package xlog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// KeyStack is the key of the stack trace,
+// logged with FormatWithStackTrace option
+const KeyStack = "stack"
+
+// defaultStackDepth is the default number of frames of the stack trace
+const defaultStackDepth = 32
+
+// SetStackTraceDepth sets the maximum number of frames of the stack trace,
+// logged with FormatWithStackTrace option, 32 by default
+func SetStackTraceDepth(depth int) {
+	if depth <= 0 {
+		depth = defaultStackDepth
+	}
+	logger.stackDepth.Store(int32(depth))
+}
+
+// StackTrace returns the stack trace of the current goroutine,
+// starting from the caller at depth, as accepted by Caller,
+// so depth 1 is the caller of StackTrace.
+// Each frame is printed as the function name,
+// and the file:line location on the next line indented by tab.
+func StackTrace(depth int) string {
+	max := int(logger.stackDepth.Load())
+	if max <= 0 {
+		max = defaultStackDepth
+	}
+	pcs := make([]uintptr, max)
+	n := runtime.Callers(depth+1, pcs)
+	if n == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
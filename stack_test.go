@@ -0,0 +1,55 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StackTrace(t *testing.T) {
+	defer xlog.SetStackTraceDepth(0)
+
+	st := xlog.StackTrace(1)
+	lines := strings.Split(st, "\n")
+	require.GreaterOrEqual(t, len(lines), 2)
+	assert.Equal(t, "github.com/effective-security/xlog_test.Test_StackTrace", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "\t"))
+	assert.Contains(t, lines[1], "stack_test.go:")
+
+	xlog.SetStackTraceDepth(1)
+	assert.Len(t, strings.Split(xlog.StackTrace(1), "\n"), 2)
+}
+
+func Test_FormatWithStackTrace(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithStackTrace))
+
+	logger.KV(xlog.WARNING, "k", 1)
+	assert.Equal(t, "level=W pkg=xlog_test k=1\n", b.String())
+
+	b.Reset()
+	logger.KV(xlog.ERROR, "k", 1)
+	assert.True(t, strings.HasPrefix(b.String(), "level=E pkg=xlog_test k=1 stack=\"github.com/effective-security/xlog_test.Test_FormatWithStackTrace\\n\\t"), b.String())
+
+	b.Reset()
+	logger.Error("failed")
+	assert.True(t, strings.HasPrefix(b.String(), "level=E pkg=xlog_test \"failed\" stack=\"github.com/effective-security/xlog_test.Test_FormatWithStackTrace\\n\\t"), b.String())
+
+	b.Reset()
+	xlog.SetFormatter(xlog.NewPrettyFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithStackTrace))
+	logger.KV(xlog.ERROR, "k", 1)
+	assert.True(t, strings.HasPrefix(b.String(), "E | pkg=xlog_test, k=1, stack=\"github.com/effective-security/xlog_test.Test_FormatWithStackTrace\\n\\t"), b.String())
+
+	b.Reset()
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithStackTrace))
+	logger.KV(xlog.ERROR, "k", 1)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.True(t, strings.HasPrefix(m[xlog.KeyStack].(string), "github.com/effective-security/xlog_test.Test_FormatWithStackTrace\n\t"))
+}
@@ -0,0 +1,91 @@
+package stackdriver
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// Client writes the entries to Cloud Logging API.
+// The client of github.com/effective-security/xlog/stackdriver/cloudlogging module
+// writes with the official cloud.google.com/go/logging client,
+// so the API calls are batched and the monitored resource is detected on GCE, GKE and Cloud Run.
+type Client interface {
+	// Log buffers the entry to be written
+	Log(e Entry)
+	// Flush writes the buffered entries
+	Flush() error
+}
+
+// Entry is the entry of Cloud Logging API
+type Entry struct {
+	// Timestamp is zero with FormatSkipTime option,
+	// so the time is set on ingestion
+	Timestamp time.Time
+	// Severity is the name of the severity, such as INFO or ERROR
+	Severity string
+	// Payload is JSON object of jsonPayload,
+	// with the message, component and Error Reporting fields
+	Payload        json.RawMessage
+	Labels         map[string]string
+	Trace          string
+	SpanID         string
+	TraceSampled   bool
+	Operation      *Operation
+	HTTPRequest    *HTTPRequest
+	SourceLocation *SourceLocation
+}
+
+// SourceLocation is the source code location of the entry
+type SourceLocation struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// NewFormatterWithClient returns an instance of StackdriverFormatter,
+// that writes the entries with the Cloud Logging API client, instead of JSON lines.
+// The log name is specified by the client.
+func NewFormatterWithClient(client Client, settings Settings) xlog.Formatter {
+	f := NewFormatterWithSettings(nil, "", settings).(*formatter)
+	f.client = client
+	return f
+}
+
+// export returns the API entry,
+// the fields of the entry, not supported by the API, are in the payload
+func (ee *entry) export(now time.Time) (Entry, error) {
+	e := Entry{
+		Timestamp:    now,
+		Severity:     string(ee.Severity),
+		Labels:       ee.Labels,
+		Trace:        ee.Trace,
+		SpanID:       ee.SpanID,
+		TraceSampled: ee.TraceSampled,
+		Operation:    ee.Operation,
+		HTTPRequest:  ee.HTTPRequest,
+	}
+	if ee.Source != nil {
+		e.SourceLocation = &SourceLocation{
+			File:     ee.Source.FilePath,
+			Line:     ee.Source.LineNumber,
+			Function: ee.Source.Function,
+		}
+	}
+
+	payload := entry{
+		Component:      ee.Component,
+		JSONPayload:    ee.JSONPayload,
+		Type:           ee.Type,
+		ServiceContext: ee.ServiceContext,
+		StackTrace:     ee.StackTrace,
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return e, errors.WithStack(err)
+	}
+	e.Payload = b
+	return e, nil
+}
@@ -0,0 +1,69 @@
+package stackdriver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	entries []Entry
+	flushed int
+	err     error
+}
+
+func (c *mockClient) Log(e Entry) {
+	c.entries = append(c.entries, e)
+}
+
+func (c *mockClient) Flush() error {
+	c.flushed++
+	return c.err
+}
+
+func Test_FormatterWithClient(t *testing.T) {
+	prevNow := xlog.TimeNowFn
+	defer func() { xlog.TimeNowFn = prevNow }()
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+
+	client := &mockClient{}
+	f := NewFormatterWithClient(client, Settings{ProjectID: "p1", LabelKeys: []string{"tenant"}})
+	xlog.SetFormatter(f)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	ctx := ContextWithTraceHeader(context.Background(), "0102030405060708090a0b0c0d0e0f10/1;o=1")
+	logger.ContextKV(ctx, xlog.INFO, "tenant", "t1", "k", 1)
+	logger.Error("failed")
+	// the entries are not written until flushed
+	assert.Equal(t, 0, client.flushed)
+
+	require.Len(t, client.entries, 2)
+	e := client.entries[0]
+	assert.Equal(t, now, e.Timestamp)
+	assert.Equal(t, "INFO", e.Severity)
+	assert.Equal(t, `{"component":"stackdriver","message":{"k":1}}`, string(e.Payload))
+	assert.Equal(t, map[string]string{"tenant": "t1"}, e.Labels)
+	assert.Equal(t, "projects/p1/traces/0102030405060708090a0b0c0d0e0f10", e.Trace)
+	assert.Equal(t, "0000000000000001", e.SpanID)
+	assert.True(t, e.TraceSampled)
+	assert.Equal(t, &SourceLocation{Function: "Test_FormatterWithClient"}, e.SourceLocation)
+
+	e = client.entries[1]
+	assert.Equal(t, "ERROR", e.Severity)
+	assert.Equal(t, `{"component":"stackdriver","message":{"msg":"failed"}}`, string(e.Payload))
+	assert.Equal(t, "client_test.go", e.SourceLocation.File)
+	assert.NotZero(t, e.SourceLocation.Line)
+
+	f.Flush()
+	assert.Equal(t, 1, client.flushed)
+	client.err = errors.New("unavailable")
+	f.Flush()
+	assert.Equal(t, 2, client.flushed)
+}
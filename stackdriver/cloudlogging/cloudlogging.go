@@ -0,0 +1,103 @@
+// Package cloudlogging adapts cloud.google.com/go/logging client to stackdriver.Client,
+// so the entries of stackdriver formatter are written with Cloud Logging API.
+//
+// The package is a separate module, so the core module does not depend on the Cloud SDK.
+package cloudlogging
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/effective-security/xlog/stackdriver"
+)
+
+// Client writes the entries with logging.Logger,
+// that batches the API calls in the background
+type Client struct {
+	logger *logging.Logger
+}
+
+var _ stackdriver.Client = (*Client)(nil)
+
+// New returns the Client, that writes to the logger
+//
+//	client, err := logging.NewClient(ctx, projectID)
+//	...
+//	f := stackdriver.NewFormatterWithClient(cloudlogging.New(client.Logger("api")), settings)
+func New(logger *logging.Logger) *Client {
+	return &Client{logger: logger}
+}
+
+// Log buffers the entry to be written
+func (c *Client) Log(e stackdriver.Entry) {
+	c.logger.Log(ToEntry(e))
+}
+
+// Flush writes the buffered entries
+func (c *Client) Flush() error {
+	return c.logger.Flush()
+}
+
+// ToEntry returns logging.Entry of the entry
+func ToEntry(e stackdriver.Entry) logging.Entry {
+	le := logging.Entry{
+		Timestamp:    e.Timestamp,
+		Severity:     logging.ParseSeverity(e.Severity),
+		Labels:       e.Labels,
+		Trace:        e.Trace,
+		SpanID:       e.SpanID,
+		TraceSampled: e.TraceSampled,
+		HTTPRequest:  toHTTPRequest(e.HTTPRequest),
+	}
+	if len(e.Payload) > 0 {
+		le.Payload = e.Payload
+	}
+	if op := e.Operation; op != nil {
+		le.Operation = &loggingpb.LogEntryOperation{
+			Id:       op.ID,
+			Producer: op.Producer,
+			First:    op.First,
+			Last:     op.Last,
+		}
+	}
+	if sl := e.SourceLocation; sl != nil {
+		le.SourceLocation = &loggingpb.LogEntrySourceLocation{
+			File:     sl.File,
+			Line:     int64(sl.Line),
+			Function: sl.Function,
+		}
+	}
+	return le
+}
+
+// toHTTPRequest returns the request of logging client,
+// the values that can not be parsed are skipped
+func toHTTPRequest(r *stackdriver.HTTPRequest) *logging.HTTPRequest {
+	if r == nil {
+		return nil
+	}
+	u, err := url.Parse(r.RequestURL)
+	if err != nil {
+		u = &url.URL{Opaque: r.RequestURL}
+	}
+	hr := &logging.HTTPRequest{
+		Request: &http.Request{
+			Method: r.RequestMethod,
+			URL:    u,
+			Header: http.Header{},
+		},
+		Status:   r.Status,
+		RemoteIP: r.RemoteIP,
+	}
+	if n, err := strconv.ParseInt(r.ResponseSize, 10, 64); err == nil {
+		hr.ResponseSize = n
+	}
+	if d, err := time.ParseDuration(r.Latency); err == nil {
+		hr.Latency = d
+	}
+	return hr
+}
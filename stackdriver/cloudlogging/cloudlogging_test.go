@@ -0,0 +1,177 @@
+package cloudlogging
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/stackdriver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "cloudlogging")
+
+// loggingServer is the fake Cloud Logging API, that keeps the written entries
+type loggingServer struct {
+	loggingpb.UnimplementedLoggingServiceV2Server
+
+	lock    sync.Mutex
+	entries []*loggingpb.LogEntry
+}
+
+func (s *loggingServer) WriteLogEntries(_ context.Context, req *loggingpb.WriteLogEntriesRequest) (*loggingpb.WriteLogEntriesResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, e := range req.GetEntries() {
+		if e.LogName == "" {
+			e.LogName = req.GetLogName()
+		}
+		s.entries = append(s.entries, e)
+	}
+	return &loggingpb.WriteLogEntriesResponse{}, nil
+}
+
+// logEntries returns the entries written to the log,
+// the client writes its own diagnostic entries to other log
+func (s *loggingServer) logEntries(logName string) []*loggingpb.LogEntry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var list []*loggingpb.LogEntry
+	for _, e := range s.entries {
+		if e.GetLogName() == logName {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// newLogger returns the logger of the client connected to the fake server
+func newLogger(t *testing.T, srv *loggingServer) *logging.Logger {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	loggingpb.RegisterLoggingServiceV2Server(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	client, err := logging.NewClient(context.Background(), "projects/p1", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	return client.Logger("api", logging.CommonResource(&monitoredres.MonitoredResource{Type: "global"}))
+}
+
+func TestClient(t *testing.T) {
+	defer func(fn func() time.Time) { xlog.TimeNowFn = fn }(xlog.TimeNowFn)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+
+	srv := new(loggingServer)
+	f := stackdriver.NewFormatterWithClient(New(newLogger(t, srv)), stackdriver.Settings{
+		ProjectID:   "p1",
+		LabelKeys:   []string{"tenant"},
+		HTTPRequest: true,
+	})
+	xlog.SetFormatter(f)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	ctx := stackdriver.ContextWithTraceHeader(context.Background(), "0102030405060708090a0b0c0d0e0f10/1;o=1")
+	logger.ContextKV(ctx, xlog.WARNING,
+		"tenant", "t1",
+		stackdriver.KeyOperation, stackdriver.Operation{ID: "op1", Producer: "jobs", First: true},
+		"method", "GET",
+		"path", "/v1/status?x=1",
+		"status", 404,
+		"bytes", 12,
+		"remote_addr", "10.0.0.1",
+		xlog.KeyDuration, 1500*time.Millisecond,
+		"k", 1)
+	f.Flush()
+
+	entries := srv.logEntries("projects/p1/logs/api")
+	require.Len(t, entries, 1)
+	e := entries[0]
+
+	assert.Equal(t, now, e.GetTimestamp().AsTime())
+	assert.Equal(t, "WARNING", e.GetSeverity().String())
+	assert.Equal(t, map[string]string{"tenant": "t1"}, e.GetLabels())
+	assert.Equal(t, "projects/p1/traces/0102030405060708090a0b0c0d0e0f10", e.GetTrace())
+	assert.Equal(t, "0000000000000001", e.GetSpanId())
+	assert.True(t, e.GetTraceSampled())
+
+	op := e.GetOperation()
+	require.NotNil(t, op)
+	assert.Equal(t, "op1", op.GetId())
+	assert.Equal(t, "jobs", op.GetProducer())
+	assert.True(t, op.GetFirst())
+	assert.False(t, op.GetLast())
+
+	hr := e.GetHttpRequest()
+	require.NotNil(t, hr)
+	assert.Equal(t, "GET", hr.GetRequestMethod())
+	assert.Equal(t, "/v1/status?x=1", hr.GetRequestUrl())
+	assert.Equal(t, int32(404), hr.GetStatus())
+	assert.Equal(t, int64(12), hr.GetResponseSize())
+	assert.Equal(t, "10.0.0.1", hr.GetRemoteIp())
+	assert.Equal(t, 1500*time.Millisecond, hr.GetLatency().AsDuration())
+
+	sl := e.GetSourceLocation()
+	require.NotNil(t, sl)
+	assert.Equal(t, "TestClient", sl.GetFunction())
+
+	payload := e.GetJsonPayload()
+	require.NotNil(t, payload)
+	exp, err := structpb.NewStruct(map[string]any{
+		"component": "cloudlogging",
+		"message":   map[string]any{"k": 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, exp.AsMap(), payload.AsMap())
+}
+
+func TestToEntry(t *testing.T) {
+	e := ToEntry(stackdriver.Entry{
+		Severity: "ERROR",
+		SourceLocation: &stackdriver.SourceLocation{
+			File:     "main.go",
+			Line:     10,
+			Function: "main",
+		},
+		HTTPRequest: &stackdriver.HTTPRequest{
+			RequestURL:   "%zz",
+			ResponseSize: "n/a",
+			Latency:      "slow",
+		},
+	})
+	assert.Equal(t, logging.Error, e.Severity)
+	assert.Nil(t, e.Payload)
+	assert.Nil(t, e.Operation)
+	assert.Equal(t, "main.go", e.SourceLocation.GetFile())
+	assert.Equal(t, int64(10), e.SourceLocation.GetLine())
+	assert.Equal(t, "main", e.SourceLocation.GetFunction())
+	// the values that can not be parsed are skipped
+	assert.Equal(t, "%zz", e.HTTPRequest.Request.URL.String())
+	assert.Zero(t, e.HTTPRequest.ResponseSize)
+	assert.Zero(t, e.HTTPRequest.Latency)
+
+	assert.Nil(t, ToEntry(stackdriver.Entry{}).HTTPRequest)
+}
@@ -0,0 +1,68 @@
+package stackdriver
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/effective-security/xlog"
+)
+
+// reportedErrorEventType is the type of the entries, reported to Error Reporting
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// maxStackFrames is the maximum number of frames of the reported stack trace
+const maxStackFrames = 64
+
+// errorMessage returns the first line of the reported error:
+// the message, the err value, or the severity if the entry has neither
+func (o *kventries) errorMessage(s severity) string {
+	var errValue any
+	for i := 0; i+1 < len(o.entries); i += 2 {
+		switch o.entries[i] {
+		case "msg":
+			if msg, ok := o.entries[i+1].(string); ok && msg != "" {
+				return msg
+			}
+		case xlog.KeyError:
+			if errValue == nil {
+				errValue = o.redactor.Redact(xlog.KeyError, o.entries[i+1])
+			}
+		}
+	}
+	if errValue != nil {
+		return fmt.Sprint(errValue)
+	}
+	return string(s)
+}
+
+// goroutineStack returns the stack trace of the current goroutine
+// in the format of Go panics, recognized by Error Reporting,
+// starting from the caller at depth, so depth 1 is the caller of goroutineStack
+func goroutineStack(depth int) string {
+	pcs := make([]uintptr, maxStackFrames)
+	n := runtime.Callers(depth+1, pcs)
+
+	var b strings.Builder
+	b.WriteString("goroutine ")
+	b.WriteString(strconv.FormatUint(xlog.GoroutineID(), 10))
+	b.WriteString(" [running]:\n")
+	if n == 0 {
+		return b.String()
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		b.WriteString(frame.Function)
+		b.WriteString("(...)\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package stackdriver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ErrorReporting(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatterWithSettings(&b, "sd", Settings{Service: "api", Version: "v1.2"}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	stackPrefix := "\n\ngoroutine " + strconv.FormatUint(xlog.GoroutineID(), 10) + " [running]:\n" +
+		"github.com/effective-security/xlog/stackdriver.Test_ErrorReporting.func"
+
+	tcases := []struct {
+		name    string
+		log     func()
+		message string
+	}{
+		{
+			name:    "msg",
+			log:     func() { logger.Error("request failed") },
+			message: "request failed",
+		},
+		{
+			name:    "err",
+			log:     func() { logger.KV(xlog.ERROR, "err", errors.New("connection refused"), "k", 1) },
+			message: "connection refused",
+		},
+		{
+			name:    "fields",
+			log:     func() { logger.KV(xlog.CRITICAL, "k", 1) },
+			message: "CRITICAL",
+		},
+	}
+	for _, tc := range tcases {
+		b.Reset()
+		tc.log()
+		t.Run(tc.name, func(t *testing.T) {
+
+			var m map[string]any
+			require.NoError(t, json.Unmarshal(b.Bytes(), &m))
+			assert.Equal(t, reportedErrorEventType, m["@type"])
+			assert.Equal(t, map[string]any{"service": "api", "version": "v1.2"}, m["serviceContext"])
+			stack, _ := m["stack_trace"].(string)
+			assert.True(t, strings.HasPrefix(stack, tc.message+stackPrefix), stack)
+		})
+	}
+
+	// not reported below ERROR
+	b.Reset()
+	logger.KV(xlog.WARNING, "err", errors.New("retry"))
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"err":"retry"},"severity":"WARNING","sourceLocation":{"function":"Test_ErrorReporting"}}`+"\n", b.String())
+}
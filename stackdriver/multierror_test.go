@@ -0,0 +1,23 @@
+package stackdriver
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MultiErrors(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	err := errors.Join(errors.New("first"), errors.New("second"))
+	logger.KV(xlog.ERROR, "err", err)
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"err":["first","second"]},"severity":"ERROR","sourceLocation":{"function":"Test_MultiErrors"}}`+"\n", b.String())
+
+	assert.Equal(t, `["first","second"]`, String(err))
+	assert.Equal(t, `"first"`, String(errors.New("first")))
+}
@@ -0,0 +1,42 @@
+package stackdriver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatPkgOptions(t *testing.T) {
+	var b bytes.Buffer
+	f := NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatSkipPkg)
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v")
+	assert.Equal(t, `{"logName":"sd","message":{"k":"v"},"severity":"INFO","sourceLocation":{"function":"Test_FormatPkgOptions"}}`+"\n", b.String())
+
+	b.Reset()
+	f = xlog.ApplySettings(NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{PkgKey: "module"})
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v")
+	f.Format("pkg", xlog.INFO, 1, "msg")
+	assert.Equal(t, `{"logName":"sd","message":{"module":"pkg","k":"v"},"severity":"INFO","sourceLocation":{"function":"Test_FormatPkgOptions"}}`+"\n"+
+		`{"logName":"sd","message":{"module":"pkg","msg":"msg"},"severity":"INFO","sourceLocation":{"function":"Test_FormatPkgOptions"}}`+"\n", b.String())
+
+	b.Reset()
+	f = NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithPkgFullPath)
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v")
+	assert.Equal(t, `{"logName":"sd","component":"github.com/effective-security/xlog/stackdriver","message":{"k":"v"},"severity":"INFO","sourceLocation":{"function":"Test_FormatPkgOptions"}}`+"\n", b.String())
+
+	b.Reset()
+	f = xlog.ApplySettings(NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithPkgPath), xlog.FormatterSettings{PkgKey: "module"})
+	f.FormatKV("pkg", xlog.INFO, 1, "k", "v")
+	assert.Equal(t, `{"logName":"sd","message":{"module":"stackdriver","k":"v"},"severity":"INFO","sourceLocation":{"function":"Test_FormatPkgOptions"}}`+"\n", b.String())
+}
+
+func Test_FormatMaxMessageLen(t *testing.T) {
+	var b bytes.Buffer
+	f := xlog.ApplySettings(NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{MaxMessageLen: 5})
+	f.Format("pkg", xlog.INFO, 1, "hello world")
+	f.Format("pkg", xlog.INFO, 1, "hello")
+	assert.Equal(t, `{"logName":"sd","component":"pkg","message":{"msg":"hello...","truncated":true},"severity":"INFO","sourceLocation":{"function":"Test_FormatMaxMessageLen"}}`+"\n"+
+		`{"logName":"sd","component":"pkg","message":{"msg":"hello"},"severity":"INFO","sourceLocation":{"function":"Test_FormatMaxMessageLen"}}`+"\n", b.String())
+}
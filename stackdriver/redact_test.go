@@ -0,0 +1,24 @@
+package stackdriver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FormatWithRedaction(t *testing.T) {
+	r, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys: []string{"token"},
+	})
+	require.NoError(t, err)
+	xlog.SetRedactor(r)
+	defer xlog.SetRedactor(nil)
+
+	var b bytes.Buffer
+	f := NewFormatter(&b, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatWithRedaction)
+	f.FormatKV("pkg", xlog.INFO, 1, "token", "t1", "k", "v")
+	assert.Equal(t, `{"logName":"sd","component":"pkg","message":{"token":"[REDACTED]","k":"v"},"severity":"INFO","sourceLocation":{"function":"Test_FormatWithRedaction"}}`+"\n", b.String())
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"runtime"
 	"strings"
@@ -53,15 +54,41 @@ var levelsToSeverity = map[xlog.LogLevel]severity{
 // formatter provides logs format for StackDriver
 type formatter struct {
 	config
-	w       *bufio.Writer
-	logName string
+	w        *bufio.Writer
+	client   Client
+	logName  string
+	settings Settings
+}
+
+// Settings specifies the integration of the formatter with Google Cloud services
+type Settings struct {
+	// ProjectID specifies to log the trace in projects/PROJECT_ID/traces/TRACE_ID format,
+	// so Cloud Logging links the entries to Cloud Trace
+	ProjectID string
+	// Service and Version are reported in the serviceContext of ERROR and CRITICAL entries,
+	// the non-empty Service enables Error Reporting of the entries
+	Service string
+	Version string
+	// LabelKeys specifies the keys of the fields logged as labels,
+	// in addition to the tags
+	LabelKeys []string
+	// HTTPRequest specifies to log the request fields of xloghttp middleware,
+	// method, path, status, bytes, duration and remote_addr, as httpRequest
+	HTTPRequest bool
 }
 
 // NewFormatter returns an instance of StackdriverFormatter
 func NewFormatter(w io.Writer, logName string) xlog.Formatter {
+	return NewFormatterWithSettings(w, logName, Settings{})
+}
+
+// NewFormatterWithSettings returns an instance of StackdriverFormatter
+// with Google Cloud integration settings
+func NewFormatterWithSettings(w io.Writer, logName string, settings Settings) xlog.Formatter {
 	return &formatter{
-		w:       bufio.NewWriter(w),
-		logName: logName,
+		w:        bufio.NewWriter(w),
+		logName:  logName,
+		settings: settings,
 		config: config{
 			withCaller: true,
 			skipTime:   false,
@@ -75,11 +102,21 @@ func (c *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
 	return c
 }
 
+// Settings applies the non-zero settings
+func (c *formatter) Settings(settings xlog.FormatterSettings) xlog.Formatter {
+	c.config.settings(settings)
+	return c
+}
+
 // FormatKV log entry string to the stream,
 // the entries are key/value pairs
 func (c *formatter) FormatKV(pkg string, level xlog.LogLevel, depth int, entries ...any) {
+	if c.errorChain {
+		entries = xlog.ExpandErrorChains(entries)
+	}
 	obj := &kventries{
 		printEmpty: c.printEmpty,
+		redactor:   c.redactor(),
 		entries:    entries,
 	}
 	c.format(pkg, level, depth+1, obj)
@@ -104,16 +141,36 @@ func (c *formatter) format(pkg string, l xlog.LogLevel, depth int, obj *kventrie
 
 	if len(entries) > 0 {
 		str := fmt.Sprint(entries...)
-		if len(str) > 1024 {
-			str = str[:1024] + "..."
+		n := c.maxMessageLen
+		if n == 0 {
+			n = xlog.DefaultMaxLen
+		}
+		truncated := n > 0 && len(str) > n && !xlog.IsCompressed(str)
+		if truncated {
+			str = xlog.TruncateString(str, n)
 		}
 		obj.entries = append(obj.entries, "msg", str)
+		if truncated {
+			obj.entries = append(obj.entries, xlog.KeyTruncated, true)
+		}
+	}
+
+	component := c.pkgName(pkg, depth+1)
+	if c.skipPkg {
+		component = ""
+	} else if c.pkgKey != "" && pkg != "" {
+		obj.entries = append([]any{c.pkgKey, component}, obj.entries...)
+		component = ""
+	}
+
+	if c.stackTrace && l <= xlog.ERROR {
+		obj.entries = append(obj.entries, xlog.KeyStack, xlog.StackTrace(depth+1))
 	}
 
 	fn, file, line := callerName(depth + 1)
 	ee := entry{
 		LogName:     c.logName,
-		Component:   pkg,
+		Component:   component,
 		Severity:    severity,
 		JSONPayload: obj,
 		Source: &reportLocation{
@@ -121,8 +178,23 @@ func (c *formatter) format(pkg string, l xlog.LogLevel, depth int, obj *kventrie
 		},
 	}
 
+	obj.extractFields(&ee, &c.settings)
+	if ee.Trace != "" && c.settings.ProjectID != "" {
+		ee.Trace = "projects/" + c.settings.ProjectID + "/traces/" + ee.Trace
+	}
+	if c.settings.Service != "" && l <= xlog.ERROR {
+		ee.Type = reportedErrorEventType
+		ee.ServiceContext = &serviceContext{
+			Service: c.settings.Service,
+			Version: c.settings.Version,
+		}
+		ee.StackTrace = obj.errorMessage(severity) + "\n\n" + goroutineStack(depth+1)
+	}
+
+	var now time.Time
 	if !c.config.skipTime {
-		ee.Time = xlog.TimeNowFn().UTC().Format(time.RFC3339)
+		now = xlog.TimeNowFn().UTC()
+		ee.Time = now.Format(time.RFC3339)
 	}
 
 	if c.config.withCaller {
@@ -132,27 +204,59 @@ func (c *formatter) format(pkg string, l xlog.LogLevel, depth int, obj *kventrie
 		}
 	}
 
+	if c.client != nil {
+		// the client batches the entries, and is flushed by Flush
+		if e, err := ee.export(now); err == nil {
+			c.client.Log(e)
+			xlog.ObserveEntrySize(pkg, len(e.Payload))
+		}
+		return
+	}
+
 	b, err := json.Marshal(ee)
 	if err == nil {
 		_, _ = c.w.Write(b)
 		_ = c.w.WriteByte('\n')
+		xlog.ObserveEntrySize(pkg, len(b)+1)
 	}
 
 	c.Flush()
 }
 
-// Flush the logs
+// Flush the logs,
+// the errors of the client are reported to stderr
 func (c *formatter) Flush() {
+	if c.client != nil {
+		if err := c.client.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "xlog: stackdriver: %v\n", err)
+		}
+		return
+	}
 	c.w.Flush()
 }
 
 type entry struct {
-	LogName     string          `json:"logName,omitempty"`
-	Component   string          `json:"component,omitempty"`
-	Time        string          `json:"timestamp,omitempty"`
-	JSONPayload any             `json:"message,omitempty"`
-	Severity    severity        `json:"severity,omitempty"`
-	Source      *reportLocation `json:"sourceLocation,omitempty"`
+	LogName      string            `json:"logName,omitempty"`
+	Component    string            `json:"component,omitempty"`
+	Time         string            `json:"timestamp,omitempty"`
+	JSONPayload  any               `json:"message,omitempty"`
+	Severity     severity          `json:"severity,omitempty"`
+	Source       *reportLocation   `json:"sourceLocation,omitempty"`
+	Trace        string            `json:"logging.googleapis.com/trace,omitempty"`
+	SpanID       string            `json:"logging.googleapis.com/spanId,omitempty"`
+	TraceSampled bool              `json:"logging.googleapis.com/trace_sampled,omitempty"`
+	Labels       map[string]string `json:"logging.googleapis.com/labels,omitempty"`
+	Operation    *Operation        `json:"logging.googleapis.com/operation,omitempty"`
+	HTTPRequest  *HTTPRequest      `json:"httpRequest,omitempty"`
+	// Type, ServiceContext and StackTrace are reported to Error Reporting
+	Type           string          `json:"@type,omitempty"`
+	ServiceContext *serviceContext `json:"serviceContext,omitempty"`
+	StackTrace     string          `json:"stack_trace,omitempty"`
+}
+
+type serviceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
 }
 
 type reportLocation struct {
@@ -167,7 +271,7 @@ func String(value any) string {
 		// if error does not support json.Marshaler,
 		// the print the full details
 		if _, ok := value.(json.Marshaler); !ok {
-			value = fmt.Sprintf("%+v", err)
+			value = xlog.ErrorValue(err)
 		}
 	}
 	buffer := &bytes.Buffer{}
@@ -200,6 +304,27 @@ type config struct {
 	skipTime   bool
 	debug      bool
 	printEmpty bool
+	redact     bool
+	skipPkg    bool
+	errorChain bool
+	stackTrace bool
+	// pkgKey specifies the key of the package name in the payload,
+	// instead of the component field
+	pkgKey string
+	// pkgPath is FormatWithPkgPath or FormatWithPkgFullPath option,
+	// to log the package path instead of the name
+	pkgPath xlog.FormatterOption
+	// maxMessageLen is the truncation length of the message,
+	// zero is xlog.DefaultMaxLen, and negative disables the truncation
+	maxMessageLen int
+}
+
+// redactor returns the redactor, if configured for the formatter
+func (c *config) redactor() *xlog.Redactor {
+	if !c.redact {
+		return nil
+	}
+	return xlog.GetRedactor()
 }
 
 // Options allows to configure formatter behavior
@@ -216,10 +341,46 @@ func (c *config) options(ops []xlog.FormatterOption) {
 			c.debug = true
 		case xlog.FormatPrintEmpty:
 			c.printEmpty = true
+		case xlog.FormatWithRedaction:
+			c.redact = true
+		case xlog.FormatSkipPkg:
+			c.skipPkg = true
+		case xlog.FormatWithErrorChain:
+			c.errorChain = true
+		case xlog.FormatWithStackTrace:
+			c.stackTrace = true
+		case xlog.FormatWithPkgPath, xlog.FormatWithPkgFullPath:
+			c.pkgPath = op
 		}
 	}
 }
 
+// settings applies the non-zero settings, MaxValueLen is not supported
+func (c *config) settings(s xlog.FormatterSettings) {
+	if s.PkgKey != "" {
+		c.pkgKey = s.PkgKey
+	}
+	if s.MaxMessageLen != 0 {
+		c.maxMessageLen = s.MaxMessageLen
+	}
+}
+
+// pkgName returns the package name or path,
+// the path is resolved from the caller at the depth
+func (c *config) pkgName(pkg string, depth int) string {
+	if c.pkgPath == 0 || pkg == "" {
+		return pkg
+	}
+	p := xlog.CallerPkgPath(depth + 1)
+	if p == "" {
+		return pkg
+	}
+	if c.pkgPath == xlog.FormatWithPkgPath {
+		return xlog.RelativePkgPath(p)
+	}
+	return p
+}
+
 func removePart(val, open, close string) string {
 	b, a, ok := strings.Cut(val, open)
 	if !ok {
@@ -235,6 +396,69 @@ func removePart(val, open, close string) string {
 type kventries struct {
 	entries    []any
 	printEmpty bool
+	redactor   *xlog.Redactor
+}
+
+// extractFields removes trace_id, span_id, trace_sampled, tags, operation,
+// and the label and request fields specified by the settings from the entries,
+// and sets them as the top level fields of the entry
+func (o *kventries) extractFields(ee *entry, s *Settings) {
+	size := len(o.entries)
+	list := make([]any, 0, size)
+	var req *HTTPRequest
+	if s.HTTPRequest && o.has(keyMethod) && o.has(keyStatus) {
+		req = &HTTPRequest{}
+	}
+	for i := 0; i < size; i += 2 {
+		var v any
+		if i+1 < size {
+			v = o.entries[i+1]
+		}
+		if k, ok := o.entries[i].(string); ok {
+			if req != nil && req.set(k, v) {
+				continue
+			}
+			if isLabelKey(s.LabelKeys, k) {
+				ee.setLabel(k, fmt.Sprint(v))
+				continue
+			}
+		}
+		switch o.entries[i] {
+		case xlog.KeyTraceID:
+			if s, ok := v.(string); ok {
+				ee.Trace = s
+				continue
+			}
+		case xlog.KeySpanID:
+			if s, ok := v.(string); ok {
+				ee.SpanID = s
+				continue
+			}
+		case xlog.KeyTraceSampled:
+			if b, ok := v.(bool); ok {
+				ee.TraceSampled = b
+				continue
+			}
+		case xlog.KeyTags:
+			if tags, ok := v.(xlog.Tags); ok {
+				for k, v := range tags.Labels() {
+					ee.setLabel(k, v)
+				}
+				continue
+			}
+		case KeyOperation:
+			if op := operationValue(v); op != nil {
+				ee.Operation = op
+				continue
+			}
+		}
+		list = append(list, o.entries[i])
+		if i+1 < size {
+			list = append(list, v)
+		}
+	}
+	o.entries = list
+	ee.HTTPRequest = req
 }
 
 func (o *kventries) MarshalJSON() (out []byte, err error) {
@@ -254,7 +478,7 @@ func (o *kventries) MarshalJSON() (out []byte, err error) {
 		}
 		var v any
 		if i+1 < size {
-			v = o.entries[i+1]
+			v = o.redactor.Redact(k, o.entries[i+1])
 		}
 		if v == nil && !o.printEmpty {
 			continue
@@ -129,3 +129,30 @@ type someSvc struct{}
 func (s *someSvc) log(msg string) {
 	logger.Info(msg)
 }
+
+func Test_FormatterErrorChain(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatter(writer, "sd").Options(xlog.FormatSkipTime, xlog.FormatNoCaller, xlog.FormatWithErrorChain))
+
+	err := fmt.Errorf("query failed: %w", errors.New("connection refused"))
+	logger.KV(xlog.WARNING, "err", err, "k", 1)
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"err.message":"query failed: connection refused","err.cause":"connection refused","k":1},"severity":"WARNING","sourceLocation":{"function":"Test_FormatterErrorChain"}}`+"\n", b.String())
+}
+
+func Test_FormatterStackTrace(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatter(writer, "sd").Options(xlog.FormatSkipTime, xlog.FormatNoCaller, xlog.FormatWithStackTrace))
+
+	logger.KV(xlog.WARNING, "k", 1)
+	assert.NotContains(t, b.String(), `"stack"`)
+
+	b.Reset()
+	logger.KV(xlog.ERROR, "k", 1)
+	assert.Contains(t, b.String(), `"stack":"github.com/effective-security/xlog/stackdriver.Test_FormatterStackTrace\n\t`)
+}
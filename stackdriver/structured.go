@@ -0,0 +1,114 @@
+package stackdriver
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// KeyOperation is the key of Operation value,
+// logged as logging.googleapis.com/operation field
+const KeyOperation = "operation"
+
+// the keys of xloghttp middleware fields, mapped to httpRequest
+const (
+	keyMethod     = "method"
+	keyPath       = "path"
+	keyStatus     = "status"
+	keyBytes      = "bytes"
+	keyRemoteAddr = "remote_addr"
+)
+
+// Operation groups the entries of a long-running operation in Cloud Logging
+type Operation struct {
+	// ID is the identifier of the operation, unique within the producer
+	ID string `json:"id,omitempty"`
+	// Producer is the name of the component, such as "github.com/org/repo/jobs"
+	Producer string `json:"producer,omitempty"`
+	// First is set for the first entry of the operation
+	First bool `json:"first,omitempty"`
+	// Last is set for the last entry of the operation
+	Last bool `json:"last,omitempty"`
+}
+
+// operationValue returns the operation, if the value is Operation
+func operationValue(v any) *Operation {
+	switch op := v.(type) {
+	case Operation:
+		return &op
+	case *Operation:
+		return op
+	}
+	return nil
+}
+
+// HTTPRequest is the request payload of Cloud Logging entry
+type HTTPRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	// ResponseSize is the decimal number of bytes
+	ResponseSize string `json:"responseSize,omitempty"`
+	RemoteIP     string `json:"remoteIp,omitempty"`
+	// Latency is the duration in seconds with "s" suffix, such as "1.5s"
+	Latency string `json:"latency,omitempty"`
+}
+
+// set sets the request field, and returns false if the key is not a request field
+func (r *HTTPRequest) set(k string, v any) bool {
+	switch k {
+	case keyMethod:
+		r.RequestMethod = fmt.Sprint(v)
+	case keyPath:
+		r.RequestURL = fmt.Sprint(v)
+	case keyStatus:
+		status, err := strconv.Atoi(fmt.Sprint(v))
+		if err != nil {
+			return false
+		}
+		r.Status = status
+	case keyBytes:
+		r.ResponseSize = fmt.Sprint(v)
+	case keyRemoteAddr:
+		r.RemoteIP = fmt.Sprint(v)
+	case xlog.KeyDuration:
+		d, ok := v.(time.Duration)
+		if !ok {
+			return false
+		}
+		// google.protobuf.Duration JSON format
+		r.Latency = strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+	default:
+		return false
+	}
+	return true
+}
+
+// has returns true if the entries have the key
+func (o *kventries) has(key string) bool {
+	for i := 0; i < len(o.entries); i += 2 {
+		if o.entries[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isLabelKey(keys []string, k string) bool {
+	for _, key := range keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// setLabel adds the label to the entry
+func (ee *entry) setLabel(k, v string) {
+	if ee.Labels == nil {
+		ee.Labels = make(map[string]string)
+	}
+	ee.Labels[k] = v
+}
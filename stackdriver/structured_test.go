@@ -0,0 +1,39 @@
+package stackdriver
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatterStructured(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatterWithSettings(&b, "sd", Settings{
+		LabelKeys:   []string{"tenant", "shard"},
+		HTTPRequest: true,
+	}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	logger.WithTags("env=prod").KV(xlog.INFO, "tenant", "t1", "shard", 7, "k", 1)
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterStructured"},"logging.googleapis.com/labels":{"env":"prod","shard":"7","tenant":"t1"}}`+"\n", b.String())
+
+	b.Reset()
+	logger.KV(xlog.INFO, KeyOperation, Operation{ID: "op1", Producer: "jobs", First: true}, "k", 1)
+	logger.KV(xlog.INFO, KeyOperation, &Operation{ID: "op1", Producer: "jobs", Last: true})
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterStructured"},"logging.googleapis.com/operation":{"id":"op1","producer":"jobs","first":true}}`+"\n"+
+		`{"logName":"sd","component":"stackdriver","message":{},"severity":"INFO","sourceLocation":{"function":"Test_FormatterStructured"},"logging.googleapis.com/operation":{"id":"op1","producer":"jobs","last":true}}`+"\n", b.String())
+
+	b.Reset()
+	logger.KV(xlog.INFO, "method", "GET", "path", "/v1/status", "status", 200, "bytes", 42,
+		xlog.KeyDuration, 1500*time.Millisecond, "remote_addr", "10.0.0.1:5000", "request_id", "r1")
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"request_id":"r1"},"severity":"INFO","sourceLocation":{"function":"Test_FormatterStructured"},"httpRequest":{"requestMethod":"GET","requestUrl":"/v1/status","status":200,"responseSize":"42","remoteIp":"10.0.0.1:5000","latency":"1.5s"}}`+"\n", b.String())
+
+	// not a request without the method
+	b.Reset()
+	logger.KV(xlog.INFO, "status", "ok")
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"status":"ok"},"severity":"INFO","sourceLocation":{"function":"Test_FormatterStructured"}}`+"\n", b.String())
+}
@@ -0,0 +1,22 @@
+package stackdriver
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatterLabels(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatter(writer, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	logger.WithTags("audit", "env=prod").KV(xlog.INFO, "k1", 1)
+	result := b.String()
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k1":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterLabels"},"logging.googleapis.com/labels":{"audit":"true","env":"prod"}}`+"\n", result)
+}
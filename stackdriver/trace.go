@@ -0,0 +1,57 @@
+package stackdriver
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHeader is the HTTP header of the trace context, set by Google Cloud load balancers
+const TraceHeader = "X-Cloud-Trace-Context"
+
+// ContextWithTraceHeader returns context with the remote span context,
+// parsed from X-Cloud-Trace-Context header in TRACE_ID/SPAN_ID;o=OPTIONS format,
+// so ContextKV logs the trace, span ID and sampled flag.
+// The context is returned as is, if the header is not valid,
+// or the context already has a valid span context.
+func ContextWithTraceHeader(ctx context.Context, header string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	sc, ok := ParseTraceHeader(header)
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// ParseTraceHeader returns the span context from X-Cloud-Trace-Context header value,
+// the span ID is decimal, and o=1 option specifies the sampled trace
+func ParseTraceHeader(header string) (trace.SpanContext, bool) {
+	ids, options, _ := strings.Cut(header, ";")
+	traceHex, spanDec, _ := strings.Cut(ids, "/")
+
+	traceID, err := trace.TraceIDFromHex(traceHex)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	span, err := strconv.ParseUint(spanDec, 10, 64)
+	if err != nil || span == 0 {
+		return trace.SpanContext{}, false
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], span)
+
+	cfg := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}
+	if options == "o=1" {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+	return trace.NewSpanContext(cfg), true
+}
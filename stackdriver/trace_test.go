@@ -0,0 +1,84 @@
+package stackdriver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_FormatterTrace(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatter(writer, "sd").Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.ContextKV(ctx, xlog.INFO, "k1", 1)
+	result := b.String()
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k1":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterTrace"},"logging.googleapis.com/trace":"0102030405060708090a0b0c0d0e0f10","logging.googleapis.com/spanId":"0102030405060708"}`+"\n", result)
+}
+
+func Test_FormatterTraceHeader(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(NewFormatterWithSettings(writer, "sd", Settings{ProjectID: "my-project"}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+
+	ctx := ContextWithTraceHeader(context.Background(), "0102030405060708090a0b0c0d0e0f10/72623859790382856;o=1")
+	logger.ContextKV(ctx, xlog.INFO, "k1", 1)
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k1":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterTraceHeader"},"logging.googleapis.com/trace":"projects/my-project/traces/0102030405060708090a0b0c0d0e0f10","logging.googleapis.com/spanId":"0102030405060708","logging.googleapis.com/trace_sampled":true}`+"\n", b.String())
+
+	e, err := xlog.ParseEntry(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		"k1", int64(1),
+		xlog.KeySpanID, "0102030405060708",
+		xlog.KeyTraceID, "0102030405060708090a0b0c0d0e0f10",
+		xlog.KeyTraceSampled, true,
+	}, e.Fields)
+
+	// not sampled
+	b.Reset()
+	ctx = ContextWithTraceHeader(context.Background(), "0102030405060708090a0b0c0d0e0f10/72623859790382856;o=0")
+	logger.ContextKV(ctx, xlog.INFO, "k1", 1)
+	assert.Equal(t, `{"logName":"sd","component":"stackdriver","message":{"k1":1},"severity":"INFO","sourceLocation":{"function":"Test_FormatterTraceHeader"},"logging.googleapis.com/trace":"projects/my-project/traces/0102030405060708090a0b0c0d0e0f10","logging.googleapis.com/spanId":"0102030405060708"}`+"\n", b.String())
+}
+
+func Test_ParseTraceHeader(t *testing.T) {
+	sc, ok := ParseTraceHeader("0102030405060708090a0b0c0d0e0f10/1")
+	require.True(t, ok)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", sc.TraceID().String())
+	assert.Equal(t, "0000000000000001", sc.SpanID().String())
+	assert.False(t, sc.IsSampled())
+	assert.True(t, sc.IsRemote())
+
+	for _, h := range []string{
+		"",
+		"0102030405060708090a0b0c0d0e0f10",
+		"0102030405060708090a0b0c0d0e0f10/0;o=1",
+		"0102030405060708090a0b0c0d0e0f10/abc",
+		"xyz/1",
+	} {
+		_, ok = ParseTraceHeader(h)
+		assert.False(t, ok, h)
+		assert.Equal(t, context.Background(), ContextWithTraceHeader(context.Background(), h))
+	}
+
+	// the existing span context is not replaced
+	ctx := ContextWithTraceHeader(context.Background(), "0102030405060708090a0b0c0d0e0f10/1")
+	assert.Equal(t, ctx, ContextWithTraceHeader(ctx, "0102030405060708090a0b0c0d0e0f11/2"))
+}
@@ -0,0 +1,258 @@
+package xlog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StreamConfig specifies configuration for Streamer
+type StreamConfig struct {
+	// History specifies the number of recent entries
+	// sent to new subscribers, 100 by default.
+	// Use negative value to disable the history.
+	History int
+	// ClientBuffer specifies the number of entries buffered per subscriber,
+	// 256 by default. Entries are dropped for slow subscribers.
+	ClientBuffer int
+}
+
+// Streamer is a formatter, that streams JSON entries to subscribers in real time.
+// It also implements http.Handler, serving entries as Server-Sent Events,
+// for quick debugging without SSH access to the host.
+//
+// Use it with MultiFormatter to keep the regular output:
+//
+//	streamer := xlog.NewStreamer(xlog.StreamConfig{})
+//	xlog.SetFormatter(xlog.NewMultiFormatter(formatter, streamer))
+//	mux.Handle("/debug/logs", streamer)
+type Streamer struct {
+	cfg StreamConfig
+
+	lock      sync.Mutex
+	buf       bytes.Buffer
+	formatter Formatter
+	subs      map[*streamSub]struct{}
+	history   []streamEntry
+	next      int
+}
+
+type streamEntry struct {
+	entry *Entry
+	line  []byte
+}
+
+type streamSub struct {
+	filter  *ReaderFilter
+	ch      chan []byte
+	dropped uint64
+}
+
+// NewStreamer returns an instance of Streamer
+func NewStreamer(cfg StreamConfig) *Streamer {
+	if cfg.History == 0 {
+		cfg.History = 100
+	}
+	if cfg.ClientBuffer <= 0 {
+		cfg.ClientBuffer = 256
+	}
+	s := &Streamer{
+		cfg:  cfg,
+		subs: make(map[*streamSub]struct{}),
+	}
+	s.formatter = NewJSONFormatter(&s.buf)
+	return s
+}
+
+// Options allows to configure formatter behavior
+func (s *Streamer) Options(ops ...FormatterOption) Formatter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.formatter.Options(ops...)
+	return s
+}
+
+// Settings applies the settings to the formatter
+func (s *Streamer) Settings(settings FormatterSettings) Formatter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ApplySettings(s.formatter, settings)
+	return s
+}
+
+// Format log entry string to the stream
+func (s *Streamer) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.active() {
+		return
+	}
+	s.formatter.Format(pkg, l, depth+1, entries...)
+	s.publish(&Entry{
+		Time:    TimeNowFn(),
+		Level:   l,
+		Pkg:     pkg,
+		Message: fmt.Sprint(entries...),
+	})
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (s *Streamer) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.active() {
+		return
+	}
+	s.formatter.FormatKV(pkg, l, depth+1, entries...)
+	s.publish(&Entry{
+		Time:   TimeNowFn(),
+		Level:  l,
+		Pkg:    pkg,
+		Fields: entries,
+	})
+}
+
+// Flush is no-op, the entries are sent immediately
+func (s *Streamer) Flush() {}
+
+// active returns true if there are subscribers or the history is enabled,
+// the caller must hold the lock
+func (s *Streamer) active() bool {
+	return len(s.subs) > 0 || s.cfg.History > 0
+}
+
+// publish sends the formatted entry to subscribers,
+// the caller must hold the lock
+func (s *Streamer) publish(e *Entry) {
+	line := bytes.TrimSpace(s.buf.Bytes())
+	se := streamEntry{
+		entry: e,
+		line:  append([]byte{}, line...),
+	}
+	s.buf.Reset()
+
+	if s.cfg.History > 0 {
+		if len(s.history) < s.cfg.History {
+			s.history = append(s.history, se)
+		} else {
+			s.history[s.next] = se
+			s.next = (s.next + 1) % s.cfg.History
+		}
+	}
+
+	for sub := range s.subs {
+		sub.send(se)
+	}
+}
+
+func (sub *streamSub) send(se streamEntry) {
+	if sub.filter != nil && !sub.filter.Match(se.entry) {
+		return
+	}
+	select {
+	case sub.ch <- se.line:
+	default:
+		sub.dropped++
+	}
+}
+
+// Subscribe returns the channel of JSON entries matching the filter,
+// starting with the recent entries from the history,
+// and the function to unsubscribe.
+func (s *Streamer) Subscribe(filter *ReaderFilter) (<-chan []byte, func()) {
+	sub := &streamSub{
+		filter: filter,
+		ch:     make(chan []byte, s.cfg.ClientBuffer),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	count := len(s.history)
+	for i := 0; i < count; i++ {
+		sub.send(s.history[(s.next+i)%count])
+	}
+	s.subs[sub] = struct{}{}
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			delete(s.subs, sub)
+			close(sub.ch)
+		})
+	}
+}
+
+// Subscribers returns the number of active subscribers
+func (s *Streamer) Subscribers() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.subs)
+}
+
+// ServeHTTP streams entries as Server-Sent Events.
+// The filter is specified by query parameters:
+// level, pkg (can be repeated), and key (can be repeated, in "key" or "key=value" format).
+func (s *Streamer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := streamFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch, unsubscribe := s.Subscribe(filter)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func streamFilter(r *http.Request) (*ReaderFilter, error) {
+	q := r.URL.Query()
+	filter := &ReaderFilter{
+		Level:    q.Get("level"),
+		Packages: q["pkg"],
+	}
+	if filter.Level != "" {
+		if _, err := ParseLevel(strings.ToUpper(filter.Level)); err != nil {
+			return nil, err
+		}
+	}
+	for _, kv := range q["key"] {
+		if filter.Keys == nil {
+			filter.Keys = make(map[string]string)
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		filter.Keys[k] = v
+	}
+	return filter, nil
+}
@@ -0,0 +1,88 @@
+package xlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Streamer(t *testing.T) {
+	var b bytes.Buffer
+	s := xlog.NewStreamer(xlog.StreamConfig{History: 2})
+	xlog.SetFormatter(xlog.NewMultiFormatter(xlog.NewStringFormatter(&b), s).Options(xlog.FormatNoCaller))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.Error("err")
+
+	ch, unsubscribe := s.Subscribe(nil)
+	assert.Equal(t, 1, s.Subscribers())
+	assert.Equal(t, `{"k":2,"level":"I","pkg":"xlog_test","time":"2021-04-01T00:00:00Z"}`, string(<-ch))
+	assert.Contains(t, string(<-ch), `"level":"E","msg":"err","pkg":"xlog_test"`)
+
+	ch2, unsubscribe2 := s.Subscribe(&xlog.ReaderFilter{
+		Level: "WARNING",
+	})
+	logger.KV(xlog.INFO, "k", 3)
+	logger.KV(xlog.WARNING, "k", 4)
+	assert.Equal(t, `{"k":3,"level":"I","pkg":"xlog_test","time":"2021-04-01T00:00:00Z"}`, string(<-ch))
+	assert.Equal(t, `{"k":4,"level":"W","pkg":"xlog_test","time":"2021-04-01T00:00:00Z"}`, string(<-ch))
+	assert.Contains(t, string(<-ch2), `"level":"E","msg":"err"`)
+	assert.Equal(t, `{"k":4,"level":"W","pkg":"xlog_test","time":"2021-04-01T00:00:00Z"}`, string(<-ch2))
+
+	unsubscribe()
+	unsubscribe()
+	unsubscribe2()
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.Equal(t, 0, s.Subscribers())
+	s.Flush()
+}
+
+func Test_StreamerHTTP(t *testing.T) {
+	s := xlog.NewStreamer(xlog.StreamConfig{History: -1})
+	xlog.SetFormatter(s.Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "?level=X")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?level=info&pkg=xlog_test&key=k=2&key=v", nil)
+	require.NoError(t, err)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool { return s.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	logger.KV(xlog.INFO, "k", 1, "v", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.KV(xlog.DEBUG, "k", 2, "v", 1)
+	logger.KV(xlog.INFO, "k", 2, "v", 2)
+
+	r := bufio.NewReader(res.Body)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, `data: {"k":2,"level":"I","pkg":"xlog_test","v":2}`+"\n", line)
+
+	cancel()
+	assert.Eventually(t, func() bool { return s.Subscribers() == 0 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,119 @@
+package xlog_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closingFormatter struct {
+	xlog.Formatter
+	buf          *bytes.Buffer
+	closed       bool
+	afterClose   atomic.Int32
+	closeErr     error
+	flushedCount int
+}
+
+func newClosingFormatter() *closingFormatter {
+	b := &bytes.Buffer{}
+	return &closingFormatter{
+		Formatter: xlog.NewStringFormatter(b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime),
+		buf:       b,
+	}
+}
+
+func (f *closingFormatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	if f.closed {
+		f.afterClose.Add(1)
+		return
+	}
+	f.Formatter.FormatKV(pkg, l, depth+1, entries...)
+}
+
+func (f *closingFormatter) Flush() {
+	f.flushedCount++
+	f.Formatter.Flush()
+}
+
+func (f *closingFormatter) Close() error {
+	f.closed = true
+	return f.closeErr
+}
+
+func Test_SwapFormatter(t *testing.T) {
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewStringFormatter(&bytes.Buffer{}))
+
+	formatters := []*closingFormatter{newClosingFormatter()}
+	require.NoError(t, xlog.SwapFormatter(formatters[0]))
+
+	const writers = 4
+	const count = 200
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				logger.KV(xlog.INFO, "k", j)
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		f := newClosingFormatter()
+		formatters = append(formatters, f)
+		require.NoError(t, xlog.SwapFormatter(f))
+	}
+	wg.Wait()
+
+	total := 0
+	for i, f := range formatters {
+		assert.Zero(t, f.afterClose.Load(), "formatter %d", i)
+		if i < len(formatters)-1 {
+			assert.True(t, f.closed, "formatter %d", i)
+			assert.Equal(t, 1, f.flushedCount, "formatter %d", i)
+		}
+		out := f.buf.String()
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if line == "" {
+				continue
+			}
+			assert.True(t, strings.HasPrefix(line, "level=I pkg=xlog_test k="), line)
+			total++
+		}
+	}
+	assert.Equal(t, writers*count, total)
+}
+
+func Test_SwapFormatterInUse(t *testing.T) {
+	defer xlog.SetFormatter(xlog.NewStringFormatter(&bytes.Buffer{}))
+
+	f := newClosingFormatter()
+	f.closeErr = assert.AnError
+	xlog.SetFormatter(f)
+	xlog.SetRepoFormatter("github.com/effective-security/swap", f)
+	defer xlog.SetRepoFormatter("github.com/effective-security/swap", nil)
+
+	// the formatter is still used by the repo
+	require.NoError(t, xlog.SwapFormatter(newClosingFormatter()))
+	assert.False(t, f.closed)
+	assert.Equal(t, 1, f.flushedCount)
+
+	xlog.SetRepoFormatter("github.com/effective-security/swap", nil)
+	xlog.SetFormatter(f)
+	err := xlog.SwapFormatter(nil)
+	assert.EqualError(t, err, "failed to close formatter: "+assert.AnError.Error())
+	assert.True(t, f.closed)
+	assert.Nil(t, xlog.GetFormatter())
+
+	require.NoError(t, xlog.SwapFormatter(nil))
+}
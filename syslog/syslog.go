@@ -0,0 +1,306 @@
+// Package syslog provides RFC 5424 formatter, and the writer
+// to send the messages over unix socket, UDP or TCP.
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+// Facility is the syslog facility
+type Facility int
+
+// Facilities defined by RFC 5424
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	AuthPriv
+	Ftp
+	_ // NTP
+	_ // log audit
+	_ // log alert
+	_ // clock daemon
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Severity is the syslog severity
+type Severity int
+
+// Severities defined by RFC 5424
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Informational
+	Debug
+)
+
+var levelsToSeverity = map[xlog.LogLevel]Severity{
+	xlog.CRITICAL: Critical,
+	xlog.ERROR:    Error,
+	xlog.WARNING:  Warning,
+	xlog.NOTICE:   Notice,
+	xlog.INFO:     Informational,
+	xlog.TRACE:    Debug,
+	xlog.DEBUG:    Debug,
+}
+
+// SeverityFor returns the syslog severity for the log level
+func SeverityFor(l xlog.LogLevel) Severity {
+	if s, ok := levelsToSeverity[l]; ok {
+		return s
+	}
+	return Informational
+}
+
+// DefaultSDID is the default ID of the structured data element,
+// 32473 is the private enterprise number reserved for documentation
+const DefaultSDID = "xlog@32473"
+
+// Config specifies configuration for the syslog formatter
+type Config struct {
+	// Facility of the messages, User by default
+	Facility Facility
+	// Hostname of the messages, os.Hostname by default
+	Hostname string
+	// AppName of the messages, the executable name by default
+	AppName string
+	// SDID specifies the ID of the structured data element
+	// with key-value entries, DefaultSDID by default
+	SDID string
+}
+
+const nilValue = "-"
+
+// formatter provides RFC 5424 logs format
+type formatter struct {
+	w        io.Writer
+	facility Facility
+	hostname string
+	appName  string
+	procID   string
+	sdID     string
+
+	lock       sync.Mutex
+	withCaller bool
+	skipTime   bool
+	printEmpty bool
+	buf        bytes.Buffer
+}
+
+// NewFormatter returns an instance of RFC 5424 formatter,
+// each entry is written with a single Write call
+func NewFormatter(w io.Writer, cfg Config) xlog.Formatter {
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = DefaultSDID
+	}
+	if cfg.Facility == Kern {
+		cfg.Facility = User
+	}
+	return &formatter{
+		w:          w,
+		facility:   cfg.Facility,
+		hostname:   header(cfg.Hostname, 255),
+		appName:    header(cfg.AppName, 48),
+		procID:     strconv.Itoa(os.Getpid()),
+		sdID:       sdName(cfg.SDID),
+		withCaller: true,
+	}
+}
+
+// Options allows to configure formatter behavior
+func (f *formatter) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, op := range ops {
+		switch op {
+		case xlog.FormatWithCaller:
+			f.withCaller = true
+		case xlog.FormatNoCaller:
+			f.withCaller = false
+		case xlog.FormatSkipTime:
+			f.skipTime = true
+		case xlog.FormatPrintEmpty:
+			f.printEmpty = true
+		}
+	}
+	return f
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs, emitted as structured data
+func (f *formatter) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, entries, "")
+}
+
+// Format log entry string to the stream
+func (f *formatter) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	f.format(pkg, l, depth+1, nil, fmt.Sprint(entries...))
+}
+
+// Flush is no-op, the entries are written immediately
+func (f *formatter) Flush() {}
+
+func (f *formatter) format(pkg string, l xlog.LogLevel, depth int, kv []any, msg string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	b := &f.buf
+	b.Reset()
+
+	// HEADER: <PRI>VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID
+	pri := int(f.facility)*8 + int(SeverityFor(l))
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(pri))
+	b.WriteString(">1 ")
+	if f.skipTime {
+		b.WriteString(nilValue)
+	} else {
+		b.WriteString(xlog.TimeNowFn().UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	}
+	b.WriteByte(' ')
+	b.WriteString(f.hostname)
+	b.WriteByte(' ')
+	b.WriteString(f.appName)
+	b.WriteByte(' ')
+	b.WriteString(f.procID)
+	b.WriteByte(' ')
+	b.WriteString(header(pkg, 32))
+	b.WriteByte(' ')
+
+	// STRUCTURED-DATA
+	b.WriteByte('[')
+	b.WriteString(f.sdID)
+	if f.withCaller {
+		caller, _, _ := xlog.Caller(depth + 1)
+		writeParam(b, "func", caller)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("key is not a string: %v", xlog.EscapedString(kv[i])))
+		}
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if k == "msg" && msg == "" {
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if v == nil && !f.printEmpty {
+			continue
+		}
+		val := value(v)
+		if val == "" && !f.printEmpty {
+			continue
+		}
+		writeParam(b, k, val)
+	}
+	b.WriteByte(']')
+
+	if msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(strings.TrimSpace(msg))
+	}
+
+	_, _ = f.w.Write(b.Bytes())
+	xlog.ObserveEntrySize(pkg, b.Len())
+}
+
+// value returns the string value of the parameter
+func value(v any) string {
+	switch typ := v.(type) {
+	case string:
+		return typ
+	case error:
+		return typ.Error()
+	case time.Time:
+		return typ.UTC().Format(time.RFC3339)
+	}
+	return strings.Trim(xlog.EscapedString(v), `"`)
+}
+
+// writeParam writes SD-PARAM, escaping the value as required by RFC 5424
+func writeParam(b *bytes.Buffer, name, val string) {
+	b.WriteByte(' ')
+	b.WriteString(sdName(name))
+	b.WriteString(`="`)
+	for _, r := range val {
+		switch r {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
+
+// sdName returns SD-NAME: up to 32 printable US-ASCII characters,
+// except '=', ' ', ']' and '"'
+func sdName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	if name == "" {
+		return nilValue
+	}
+	return name
+}
+
+// header returns the header field: printable US-ASCII characters,
+// limited by size
+func header(s string, size int) string {
+	h := strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(h) > size {
+		h = h[:size]
+	}
+	if h == "" {
+		return nilValue
+	}
+	return h
+}
@@ -0,0 +1,67 @@
+package syslog
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Formatter(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	var b bytes.Buffer
+	f := NewFormatter(&b, Config{
+		Facility: Local0,
+		Hostname: "host1",
+		AppName:  "my app",
+	}).Options(xlog.FormatNoCaller, xlog.FormatSkipTime)
+
+	f.FormatKV("pkg", xlog.WARNING, 1, "msg", "hello", "k1", 1, "k2", `a"b]c\d`, "nil", nil, "empty", "", "err", errors.New("failed"))
+	assert.Equal(t, `<132>1 - host1 my_app `+pid+` pkg [xlog@32473 k1="1" k2="a\"b\]c\\d" err="failed"] hello`, b.String())
+
+	b.Reset()
+	f.Format("pkg", xlog.ERROR, 1, "plain ", "message")
+	assert.Equal(t, `<131>1 - host1 my_app `+pid+` pkg [xlog@32473] plain message`, b.String())
+
+	assert.Panics(t, func() {
+		f.FormatKV("pkg", xlog.INFO, 1, 1, 2)
+	})
+
+	xlog.TimeNowFn = func() time.Time {
+		return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() {
+		xlog.TimeNowFn = time.Now
+	}()
+
+	b.Reset()
+	f = NewFormatter(&b, Config{Hostname: "host1", AppName: "app", SDID: "kv@1"})
+	f.FormatKV("", xlog.DEBUG, 1, "k", "v")
+	f.Flush()
+	assert.Equal(t, `<15>1 2021-04-01T00:00:00.000000Z host1 app `+pid+` - [kv@1 func="Test_Formatter" k="v"]`, b.String())
+}
+
+func Test_Severity(t *testing.T) {
+	assert.Equal(t, Critical, SeverityFor(xlog.CRITICAL))
+	assert.Equal(t, Error, SeverityFor(xlog.ERROR))
+	assert.Equal(t, Warning, SeverityFor(xlog.WARNING))
+	assert.Equal(t, Notice, SeverityFor(xlog.NOTICE))
+	assert.Equal(t, Informational, SeverityFor(xlog.INFO))
+	assert.Equal(t, Debug, SeverityFor(xlog.TRACE))
+	assert.Equal(t, Debug, SeverityFor(xlog.DEBUG))
+	assert.Equal(t, Informational, SeverityFor(xlog.LogLevel(100)))
+}
+
+func Test_Names(t *testing.T) {
+	assert.Equal(t, "-", sdName(""))
+	assert.Equal(t, "a_b_c_d", sdName(`a=b]c"d`))
+	assert.Len(t, sdName("0123456789012345678901234567890123456789"), 32)
+	assert.Equal(t, "-", header("", 10))
+	assert.Equal(t, "0123", header("0123456789", 4))
+}
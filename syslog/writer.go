@@ -0,0 +1,123 @@
+package syslog
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// localSockets are the paths of the local syslog socket
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Writer sends the messages to syslog server,
+// reconnecting on failures
+type Writer struct {
+	network string
+	raddr   string
+	timeout time.Duration
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// Dial returns the writer connected to syslog server.
+// The network is one of "unix", "unixgram", "udp" or "tcp".
+// If network is empty, the writer connects to the local syslog socket.
+// Messages over TCP are framed with octet counting, as specified by RFC 6587.
+func Dial(network, raddr string) (*Writer, error) {
+	w := &Writer{
+		network: network,
+		raddr:   raddr,
+		timeout: 5 * time.Second,
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect the writer, the caller must hold the lock
+func (w *Writer) connect() (err error) {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network == "" {
+		for _, network := range []string{"unixgram", "unix"} {
+			for _, path := range localSockets {
+				w.conn, err = net.DialTimeout(network, path, w.timeout)
+				if err == nil {
+					return nil
+				}
+			}
+		}
+		return errors.New("unix syslog delivery error")
+	}
+
+	w.conn, err = net.DialTimeout(w.network, w.raddr, w.timeout)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to connect to syslog: %s://%s", w.network, w.raddr)
+	}
+	return nil
+}
+
+// Write sends the message, reconnecting once on failure
+func (w *Writer) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, errors.New("syslog writer is closed")
+	}
+	if w.conn != nil {
+		if n, err := w.write(b); err == nil {
+			return n, nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	return w.write(b)
+}
+
+// write the message, the caller must hold the lock
+func (w *Writer) write(b []byte) (int, error) {
+	if w.network == "tcp" || w.network == "tcp4" || w.network == "tcp6" {
+		frame := make([]byte, 0, len(b)+8)
+		frame = strconv.AppendInt(frame, int64(len(b)), 10)
+		frame = append(frame, ' ')
+		frame = append(frame, b...)
+		if _, err := w.conn.Write(frame); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return len(b), nil
+	}
+	n, err := w.conn.Write(b)
+	if err != nil {
+		return n, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+// Close the connection
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,83 @@
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	w, err := Dial("udp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("<14>1 - - - - - - msg"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "<14>1 - - - - - - msg", string(buf[:n]))
+}
+
+func Test_WriterTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			line, _ := r.ReadString('g')
+			received <- line
+			_ = conn.Close()
+		}
+	}()
+
+	w, err := Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("<14>1 msg"))
+	require.NoError(t, err)
+	assert.Equal(t, "9 <14>1 msg", <-received)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("<14>1 msg"))
+	assert.EqualError(t, err, "syslog writer is closed")
+}
+
+func Test_WriterUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	defer pc.Close()
+
+	w, err := Dial("unixgram", path)
+	require.NoError(t, err)
+	defer w.Close()
+
+	f := NewFormatter(w, Config{Hostname: "h", AppName: "a"})
+	f.FormatKV("pkg", 3, 1, "msg", "hello")
+
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), "] hello")
+
+	_, err = Dial("tcp", "127.0.0.1:1")
+	assert.Error(t, err)
+}
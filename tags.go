@@ -0,0 +1,56 @@
+package xlog
+
+import (
+	"sort"
+	"strings"
+)
+
+// KeyTags is the key used to log entry tags
+const KeyTags = "tags"
+
+// Tags is a small set of low-cardinality labels attached to log entries,
+// separate from key-value fields.
+// Sinks that support labels (like Stackdriver) emit them as labels,
+// other formatters emit them as "tags" array.
+type Tags []string
+
+// With returns a new sorted set of tags, combined with the provided tags
+func (t Tags) With(tags ...string) Tags {
+	list := make(Tags, 0, len(t)+len(tags))
+	list = append(list, t...)
+	for _, tag := range tags {
+		if tag != "" && !list.Has(tag) {
+			list = append(list, tag)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Has returns true if the tag is in the set
+func (t Tags) Has(tag string) bool {
+	for _, v := range t {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels returns tags as labels map.
+// Tags in "key=value" format are mapped to the key and the value,
+// other tags are mapped to "true" value.
+func (t Tags) Labels() map[string]string {
+	if len(t) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(t))
+	for _, tag := range t {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok {
+			v = "true"
+		}
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,43 @@
+package xlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Tags(t *testing.T) {
+	tags := xlog.Tags{}.With("b", "a", "", "b")
+	assert.Equal(t, xlog.Tags{"a", "b"}, tags)
+	assert.True(t, tags.Has("a"))
+	assert.False(t, tags.Has("c"))
+
+	tags2 := tags.With("env=prod")
+	assert.Equal(t, xlog.Tags{"a", "b"}, tags)
+	assert.Equal(t, map[string]string{"a": "true", "b": "true", "env": "prod"}, tags2.Labels())
+	assert.Nil(t, xlog.Tags{}.Labels())
+}
+
+func Test_WithTags(t *testing.T) {
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewPrettyFormatter(writer).Options(xlog.FormatNoCaller))
+
+	tl := logger.WithTags("audit").WithValues("k1", 1).(xlog.ExtendedKeyValueLogger).WithTags("security", "audit")
+	tl.KV(xlog.INFO, "k2", 2)
+	assert.Equal(t, "2021-04-01 00:00:00.000000 I | pkg=xlog_test, k1=1, tags=[\"audit\",\"security\"], k2=2\n", b.String())
+	b.Reset()
+
+	xlog.SetFormatter(xlog.NewJSONFormatter(writer).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	tl.KV(xlog.INFO, "k2", 2)
+	assert.Equal(t, `{"k1":1,"k2":2,"level":"I","pkg":"xlog_test","tags":["audit","security"]}`+"\n", b.String())
+	b.Reset()
+
+	xlog.NewNilLogger().(xlog.ExtendedKeyValueLogger).WithTags("audit").KV(xlog.INFO, "k2", 2)
+	assert.Empty(t, b.String())
+}
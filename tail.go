@@ -0,0 +1,166 @@
+package xlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TailBuffer retains DEBUG and TRACE entries of a request, disabled by the level,
+// to emit them only if the request fails (tail-based logging).
+// The retained entries are emitted before an ERROR entry logged with the context,
+// or by Flush, and discarded by Discard.
+// TailBuffer is safe for concurrent use.
+type TailBuffer struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries []tailEntry
+	closed  bool
+}
+
+type tailEntry struct {
+	ctx     context.Context
+	p       *PackageLogger
+	t       entriesType
+	level   LogLevel
+	time    time.Time
+	entries []any
+}
+
+type tailContextKey struct{}
+
+// ContextWithTail returns the context with TailBuffer,
+// retaining up to size entries logged with ContextKV for ttl,
+// the oldest entries are dropped when the buffer is full.
+// Zero ttl retains the entries until the request ends.
+//
+//	ctx, tail := xlog.ContextWithTail(ctx, 100, time.Minute)
+//	err := handle(ctx)
+//	tail.End(err)
+//
+// The emitted entries have the logged_at field with the time they were logged.
+func ContextWithTail(ctx context.Context, size int, ttl time.Duration) (context.Context, *TailBuffer) {
+	b := &TailBuffer{
+		size: size,
+		ttl:  ttl,
+	}
+	return context.WithValue(ctx, tailContextKey{}, b), b
+}
+
+// TailFromContext returns TailBuffer of the context, or nil
+func TailFromContext(ctx context.Context) *TailBuffer {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(tailContextKey{}).(*TailBuffer)
+	return b
+}
+
+// Len returns the number of retained entries
+func (b *TailBuffer) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.expire(TimeNowFn())
+	return len(b.entries)
+}
+
+// Flush emits the retained entries
+func (b *TailBuffer) Flush() {
+	b.flush(calldepth)
+}
+
+// Discard drops the retained entries
+func (b *TailBuffer) Discard() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.entries = nil
+}
+
+// End emits the retained entries if err is not nil, or discards them otherwise.
+// The entries logged after End are not retained.
+func (b *TailBuffer) End(err error) {
+	if err != nil {
+		b.flush(calldepth)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.entries = nil
+	b.closed = true
+}
+
+// retain returns false if the entry can not be retained
+func (b *TailBuffer) retain(e tailEntry) bool {
+	if b.size <= 0 {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return false
+	}
+	e.time = TimeNowFn()
+	e.entries = append([]any(nil), e.entries...)
+	b.expire(e.time)
+	if len(b.entries) >= b.size {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+	}
+	b.entries = append(b.entries, e)
+	return true
+}
+
+// expire drops the entries older than ttl,
+// the caller must hold the lock
+func (b *TailBuffer) expire(now time.Time) {
+	if b.ttl <= 0 {
+		return
+	}
+	idx := 0
+	for idx < len(b.entries) && now.Sub(b.entries[idx].time) > b.ttl {
+		idx++
+	}
+	if idx > 0 {
+		b.entries = append(b.entries[:0], b.entries[idx:]...)
+	}
+}
+
+func (b *TailBuffer) flush(depth int) {
+	b.lock.Lock()
+	b.expire(TimeNowFn())
+	entries := b.entries
+	b.entries = nil
+	b.lock.Unlock()
+
+	for _, e := range entries {
+		e.p.emitTail(e, depth+1)
+	}
+}
+
+// emitTail emits the retained entry, regardless of the level
+func (p *PackageLogger) emitTail(e tailEntry, depth int) {
+	f := logger.lockedSinkFor(p.repo, p.pkg)
+	if f == nil {
+		return
+	}
+	defer f.Unlock()
+
+	loggedAt := e.time.UTC().Format(time.RFC3339Nano)
+	entries := e.entries
+	if e.t == kv {
+		entries = append(entries, KeyLoggedAt, loggedAt)
+	} else {
+		entries = append(flatten(false, nil, KeyLoggedAt, loggedAt), entries...)
+	}
+	p.emit(e.ctx, f, e.t, depth+1, e.level, entries...)
+}
+
+// retainTail returns true if the entry disabled by the level
+// is retained by TailBuffer of the context
+func (p *PackageLogger) retainTail(ctx context.Context, t entriesType, inLevel LogLevel, entries []any) bool {
+	b := TailFromContext(ctx)
+	if b == nil || logger.decide(ctx, p.repo, p.pkg, p.levelFor(), inLevel) {
+		return false
+	}
+	return b.retain(tailEntry{ctx: ctx, p: p, t: t, level: inLevel, entries: entries})
+}
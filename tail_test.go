@@ -0,0 +1,93 @@
+package xlog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TailBuffer(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fn := xlog.TimeNowFn
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = fn
+	}()
+
+	assert.Nil(t, xlog.TailFromContext(context.Background()))
+
+	ctx, tail := xlog.ContextWithTail(context.Background(), 2, time.Minute)
+	assert.Equal(t, tail, xlog.TailFromContext(ctx))
+
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 1)
+	logger.ContextKV(ctx, xlog.TRACE, "step", 2)
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 3)
+	logger.ContextKV(ctx, xlog.INFO, "msg", "enabled")
+	assert.Equal(t, 2, tail.Len())
+	assert.Equal(t, "level=I pkg=xlog_test msg=\"enabled\"\n", b.String())
+
+	b.Reset()
+	logger.ContextKV(ctx, xlog.ERROR, "err", "failed")
+	assert.Equal(t, 0, tail.Len())
+	assert.Equal(t, "level=T pkg=xlog_test step=2 logged_at=\"2024-01-02T03:04:05Z\"\n"+
+		"level=D pkg=xlog_test step=3 logged_at=\"2024-01-02T03:04:05Z\"\n"+
+		"level=E pkg=xlog_test err=\"failed\"\n", b.String())
+
+	// expired entries are dropped
+	b.Reset()
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 4)
+	now = now.Add(2 * time.Minute)
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 5)
+	assert.Equal(t, 1, tail.Len())
+	tail.End(fmt.Errorf("failed"))
+	assert.Equal(t, "level=D pkg=xlog_test step=5 logged_at=\"2024-01-02T03:06:05Z\"\n", b.String())
+
+	// the entries are not retained after End
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 6)
+	assert.Equal(t, 0, tail.Len())
+
+	// successful request discards the entries
+	b.Reset()
+	ctx, tail = xlog.ContextWithTail(context.Background(), 10, 0)
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 1)
+	assert.Equal(t, 1, tail.Len())
+	tail.End(nil)
+	assert.Equal(t, 0, tail.Len())
+	assert.Empty(t, b.String())
+
+	// Flush and Discard
+	ctx, tail = xlog.ContextWithTail(context.Background(), 10, 0)
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 1)
+	tail.Discard()
+	tail.Flush()
+	assert.Empty(t, b.String())
+
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 2)
+	tail.Flush()
+	assert.Equal(t, "level=D pkg=xlog_test step=2 logged_at=\"2024-01-02T03:06:05Z\"\n", b.String())
+}
+
+func Test_TailBufferCaller(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatWithCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	ctx, tail := xlog.ContextWithTail(context.Background(), 10, 0)
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 1)
+	tail.Flush()
+	logger.ContextKV(ctx, xlog.DEBUG, "step", 2)
+	logger.ContextKV(ctx, xlog.ERROR, "err", "failed")
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		assert.Contains(t, line, "func=Test_TailBufferCaller")
+	}
+}
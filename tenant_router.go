@@ -0,0 +1,226 @@
+package xlog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TenantRouterConfig specifies configuration for TenantRouter
+type TenantRouterConfig struct {
+	// Key specifies the entry key with the tenant ID
+	Key string
+	// New returns the formatter for the tenant,
+	// configured with the tenant's credentials and endpoint
+	New func(tenant string) (Formatter, error)
+	// Default specifies the formatter for entries without the tenant,
+	// or for tenants that failed. If nil, such entries are dropped.
+	Default Formatter
+	// MaxTenants limits the number of tenant formatters,
+	// entries of other tenants are sent to the default formatter.
+	// Zero means no limit.
+	MaxTenants int
+	// RetryInterval specifies the interval to retry a failed tenant,
+	// 1 minute by default
+	RetryInterval time.Duration
+}
+
+// TenantRouter dispatches log entries to formatters by tenant,
+// each tenant has its own formatter, so batching and failures
+// of one tenant do not affect others.
+type TenantRouter struct {
+	cfg TenantRouterConfig
+
+	lock     sync.Mutex
+	ops      []FormatterOption
+	settings FormatterSettings
+	tenants  map[string]Formatter
+	failed   map[string]*tenantFailure
+}
+
+type tenantFailure struct {
+	err     error
+	retryAt time.Time
+}
+
+// NewTenantRouter returns TenantRouter
+func NewTenantRouter(cfg TenantRouterConfig) *TenantRouter {
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Minute
+	}
+	return &TenantRouter{
+		cfg:     cfg,
+		tenants: make(map[string]Formatter),
+		failed:  make(map[string]*tenantFailure),
+	}
+}
+
+// Options allows to configure formatter behavior,
+// the options are applied to all existing and new formatters
+func (r *TenantRouter) Options(ops ...FormatterOption) Formatter {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.ops = append(r.ops, ops...)
+	for _, f := range r.tenants {
+		f.Options(ops...)
+	}
+	if r.cfg.Default != nil {
+		r.cfg.Default.Options(ops...)
+	}
+	return r
+}
+
+// Settings applies the settings to the tenant and default formatters
+func (r *TenantRouter) Settings(settings FormatterSettings) Formatter {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.settings = r.settings.merge(settings)
+	for _, f := range r.tenants {
+		ApplySettings(f, settings)
+	}
+	if r.cfg.Default != nil {
+		ApplySettings(r.cfg.Default, settings)
+	}
+	return r
+}
+
+// Format log entry string to the stream,
+// the entries without keys are sent to the default formatter
+func (r *TenantRouter) Format(pkg string, l LogLevel, depth int, entries ...any) {
+	if r.cfg.Default != nil {
+		r.cfg.Default.Format(pkg, l, depth+1, entries...)
+	}
+}
+
+// FormatKV log entry string to the stream,
+// the entries are key/value pairs
+func (r *TenantRouter) FormatKV(pkg string, l LogLevel, depth int, entries ...any) {
+	tenant := r.tenant(entries)
+	f := r.formatter(tenant)
+	if f == nil {
+		return
+	}
+	if f == r.cfg.Default {
+		f.FormatKV(pkg, l, depth+1, entries...)
+		return
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.fail(tenant, errors.Errorf("formatter panic: %v", rec))
+		}
+	}()
+	f.FormatKV(pkg, l, depth+1, entries...)
+}
+
+// Flush the logs of all tenants
+func (r *TenantRouter) Flush() {
+	r.lock.Lock()
+	list := make([]Formatter, 0, len(r.tenants))
+	for _, f := range r.tenants {
+		list = append(list, f)
+	}
+	r.lock.Unlock()
+
+	for _, f := range list {
+		f.Flush()
+	}
+	if r.cfg.Default != nil {
+		r.cfg.Default.Flush()
+	}
+}
+
+// Tenants returns the sorted list of tenants with active formatters
+func (r *TenantRouter) Tenants() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	list := make([]string, 0, len(r.tenants))
+	for t := range r.tenants {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Failed returns the errors of the failed tenants
+func (r *TenantRouter) Failed() map[string]error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	m := make(map[string]error, len(r.failed))
+	for t, f := range r.failed {
+		m[t] = f.err
+	}
+	return m
+}
+
+// tenant returns the tenant ID from the entries
+func (r *TenantRouter) tenant(entries []any) string {
+	for i := 0; i+1 < len(entries); i += 2 {
+		if k, ok := entries[i].(string); ok && k == r.cfg.Key {
+			return fmt.Sprint(entries[i+1])
+		}
+	}
+	return ""
+}
+
+// formatter returns the formatter for the tenant
+func (r *TenantRouter) formatter(tenant string) Formatter {
+	if tenant == "" {
+		return r.cfg.Default
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if f, ok := r.tenants[tenant]; ok {
+		return f
+	}
+	if r.cfg.MaxTenants > 0 && len(r.tenants) >= r.cfg.MaxTenants {
+		return r.cfg.Default
+	}
+	if failure, ok := r.failed[tenant]; ok {
+		if TimeNowFn().Before(failure.retryAt) {
+			return r.cfg.Default
+		}
+		delete(r.failed, tenant)
+	}
+
+	f, err := r.cfg.New(tenant)
+	if err != nil {
+		r.failLocked(tenant, errors.WithMessagef(err, "failed to create formatter for tenant %q", tenant))
+		return r.cfg.Default
+	}
+	if len(r.ops) > 0 {
+		f.Options(r.ops...)
+	}
+	if !r.settings.IsZero() {
+		ApplySettings(f, r.settings)
+	}
+	r.tenants[tenant] = f
+	return f
+}
+
+// fail removes the formatter of the tenant,
+// the entries are sent to the default formatter until the retry interval
+func (r *TenantRouter) fail(tenant string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.failLocked(tenant, err)
+}
+
+func (r *TenantRouter) failLocked(tenant string, err error) {
+	delete(r.tenants, tenant)
+	r.failed[tenant] = &tenantFailure{
+		err:     err,
+		retryAt: TimeNowFn().Add(r.cfg.RetryInterval),
+	}
+	fmt.Fprintf(os.Stderr, "xlog: tenant %q failed: %v\n", tenant, err)
+}
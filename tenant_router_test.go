@@ -0,0 +1,120 @@
+package xlog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicFormatter struct {
+	xlog.NilFormatter
+}
+
+func (f *panicFormatter) FormatKV(pkg string, level xlog.LogLevel, depth int, entries ...any) {
+	panic("endpoint failed")
+}
+
+func Test_TenantRouter(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	defer func() {
+		xlog.TimeNowFn = func() time.Time {
+			return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}()
+
+	var def bytes.Buffer
+	outs := map[string]*bytes.Buffer{}
+	r := xlog.NewTenantRouter(xlog.TenantRouterConfig{
+		Key: "tenant",
+		New: func(tenant string) (xlog.Formatter, error) {
+			switch tenant {
+			case "bad":
+				return nil, errors.New("no credentials")
+			case "panic":
+				return &panicFormatter{}, nil
+			}
+			b := new(bytes.Buffer)
+			outs[tenant] = b
+			return xlog.NewStringFormatter(b), nil
+		},
+		Default:    xlog.NewStringFormatter(&def),
+		MaxTenants: 3,
+	})
+	xlog.SetFormatter(r.Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "tenant", "t1", "k", 1)
+	logger.KV(xlog.INFO, "tenant", "t2", "k", 2)
+	logger.KV(xlog.INFO, "tenant", "t1", "k", 3)
+	logger.KV(xlog.INFO, "k", 4)
+	logger.Info("plain")
+	logger.KV(xlog.INFO, "tenant", "bad", "k", 5)
+	logger.KV(xlog.INFO, "tenant", "panic", "k", 6)
+	logger.KV(xlog.INFO, "tenant", "panic", "k", 7)
+	logger.KV(xlog.INFO, "tenant", "t3", "k", 8)
+	logger.KV(xlog.INFO, "tenant", "t4", "k", 9)
+	r.Flush()
+
+	assert.Equal(t, "level=I pkg=xlog_test tenant=\"t1\" k=1\n"+
+		"level=I pkg=xlog_test tenant=\"t1\" k=3\n", outs["t1"].String())
+	assert.Equal(t, "level=I pkg=xlog_test tenant=\"t2\" k=2\n", outs["t2"].String())
+	assert.Equal(t, "level=I pkg=xlog_test tenant=\"t3\" k=8\n", outs["t3"].String())
+	assert.Nil(t, outs["t4"])
+	assert.Equal(t, "level=I pkg=xlog_test k=4\n"+
+		"level=I pkg=xlog_test \"plain\"\n"+
+		"level=I pkg=xlog_test tenant=\"bad\" k=5\n"+
+		"level=I pkg=xlog_test tenant=\"panic\" k=7\n"+
+		"level=I pkg=xlog_test tenant=\"t4\" k=9\n", def.String())
+
+	assert.Equal(t, []string{"t1", "t2", "t3"}, r.Tenants())
+	failed := r.Failed()
+	assert.Len(t, failed, 2)
+	assert.EqualError(t, failed["bad"], "failed to create formatter for tenant \"bad\": no credentials")
+	assert.EqualError(t, failed["panic"], "formatter panic: endpoint failed")
+
+	// the failed tenant is retried after the interval
+	created := 0
+	r2 := xlog.NewTenantRouter(xlog.TenantRouterConfig{
+		Key: "tenant",
+		New: func(tenant string) (xlog.Formatter, error) {
+			created++
+			return &panicFormatter{}, nil
+		},
+	})
+	r2.FormatKV("pkg", xlog.INFO, 1, "tenant", "t1")
+	r2.FormatKV("pkg", xlog.INFO, 1, "tenant", "t1")
+	assert.Equal(t, 1, created)
+	assert.Len(t, r2.Failed(), 1)
+	assert.Empty(t, r2.Tenants())
+
+	now = now.Add(time.Minute)
+	r2.FormatKV("pkg", xlog.INFO, 1, "tenant", "t1")
+	assert.Equal(t, 2, created)
+	assert.Len(t, r2.Failed(), 1)
+}
+
+func Test_TenantRouterSettings(t *testing.T) {
+	var def, t1 bytes.Buffer
+	r := xlog.NewTenantRouter(xlog.TenantRouterConfig{
+		Key: "tenant",
+		New: func(tenant string) (xlog.Formatter, error) {
+			return xlog.NewStringFormatter(&t1), nil
+		},
+		Default: xlog.NewStringFormatter(&def),
+	})
+	f := xlog.ApplySettings(r.Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{PkgKey: "component"})
+	xlog.SetFormatter(f)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	// the settings are applied to the tenant formatters created later
+	logger.KV(xlog.INFO, "tenant", "t1", "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	assert.Equal(t, "level=I component=xlog_test tenant=\"t1\" k=1\n", t1.String())
+	assert.Equal(t, "level=I component=xlog_test k=2\n", def.String())
+}
@@ -0,0 +1,61 @@
+package xlog
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// TruncateString returns the string truncated to at most n bytes at the rune boundary,
+// with "..." suffix, or the string as is, if it is not longer than n bytes
+func TruncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+// truncatedValue is the summary of the truncated JSON object or array
+type truncatedValue struct {
+	Truncated bool `json:"truncated"`
+	Len       int  `json:"len"`
+}
+
+// truncateEscaped truncates the escaped value at b[start:] to maxLen bytes, so the value remains valid:
+// the quoted string is cut at the rune and escape sequence boundary, and the quote is closed,
+// the JSON object or array is replaced with {"truncated":true,"len":N} summary
+func truncateEscaped(b []byte, start, maxLen int) []byte {
+	val := b[start:]
+	switch val[0] {
+	case '{', '[':
+		b = append(b[:start], `{"truncated":true,"len":`...)
+		b = strconv.AppendInt(b, int64(len(val)), 10)
+		return append(b, '}')
+	case '"':
+		// the last byte is the closing quote
+		i := 1
+		for i < len(val)-1 {
+			step := 1
+			if val[i] == '\\' {
+				step = 2
+				if i+1 < len(val) && val[i+1] == 'u' {
+					step = 6
+				}
+			} else if val[i] >= utf8.RuneSelf {
+				_, step = utf8.DecodeRune(val[i:])
+			}
+			if i+step > maxLen {
+				break
+			}
+			i += step
+		}
+		return append(b[:start+i], `..."`...)
+	}
+	n := maxLen
+	for n > 0 && !utf8.RuneStart(val[n]) {
+		n--
+	}
+	return append(b[:start+n], "..."...)
+}
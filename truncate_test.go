@@ -0,0 +1,163 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatterSettings(t *testing.T) {
+	assert.True(t, xlog.FormatterSettings{}.IsZero())
+	assert.False(t, xlog.FormatterSettings{MaxMessageLen: -1}.IsZero())
+
+	var js bytes.Buffer
+	f := xlog.NewJSONFormatter(&js)
+	assert.Equal(t, f, xlog.ApplySettings(f, xlog.FormatterSettings{MaxValueLen: 2048, MaxMessageLen: 10}))
+	// the zero fields keep the current values
+	xlog.ApplySettings(f, xlog.FormatterSettings{PkgKey: "component", MaxMessageLen: -1})
+	xlog.SetFormatter(f)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	assert.Equal(t, &xlog.FormatterSettings{PkgKey: "component", MaxValueLen: 2048, MaxMessageLen: -1}, xlog.EffectiveConfig().Formatter.Settings)
+
+	xlog.SetFormatter(xlog.NewJSONFormatter(&js))
+	assert.Nil(t, xlog.EffectiveConfig().Formatter.Settings)
+
+	// the formatters without settings are returned as is
+	nf := xlog.NewNilFormatter()
+	assert.Equal(t, nf, xlog.ApplySettings(nf, xlog.FormatterSettings{PkgKey: "component"}))
+}
+
+func Test_FormatMaxLen(t *testing.T) {
+	value := strings.Repeat("a", 12)
+	tcases := []struct {
+		name     string
+		settings xlog.FormatterSettings
+		str      string
+		pretty   string
+		json     string
+	}{
+		{
+			name:     "value",
+			settings: xlog.FormatterSettings{MaxValueLen: 6},
+			str:      `level=I pkg=xlog_test k="aaaaa..." n=1 truncated=true` + "\n",
+			pretty:   `I | pkg=xlog_test, k="aaaaa...", n=1, truncated=true` + "\n",
+			json:     `{"k":"aaaaaa...","level":"I","n":1,"pkg":"xlog_test","truncated":true}` + "\n",
+		},
+		{
+			name:     "disabled",
+			settings: xlog.FormatterSettings{MaxValueLen: -1},
+			str:      `level=I pkg=xlog_test k="aaaaaaaaaaaa" n=1` + "\n",
+			pretty:   `I | pkg=xlog_test, k="aaaaaaaaaaaa", n=1` + "\n",
+			json:     `{"k":"aaaaaaaaaaaa","level":"I","n":1,"pkg":"xlog_test"}` + "\n",
+		},
+		{
+			name:   "default",
+			str:    `level=I pkg=xlog_test k="aaaaaaaaaaaa" n=1` + "\n",
+			pretty: `I | pkg=xlog_test, k="aaaaaaaaaaaa", n=1` + "\n",
+			json:   `{"k":"aaaaaaaaaaaa","level":"I","n":1,"pkg":"xlog_test"}` + "\n",
+		},
+	}
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var str, pretty, js bytes.Buffer
+			xlog.SetFormatter(xlog.ApplySettings(xlog.NewMultiFormatter(
+				xlog.NewStringFormatter(&str),
+				xlog.NewPrettyFormatter(&pretty),
+				xlog.NewJSONFormatter(&js),
+			).Options(xlog.FormatNoCaller, xlog.FormatSkipTime), tc.settings))
+
+			logger.KV(xlog.INFO, "k", value, "n", 1)
+			assert.Equal(t, tc.str, str.String())
+			assert.Equal(t, tc.pretty, pretty.String())
+			assert.Equal(t, tc.json, js.String())
+		})
+	}
+}
+
+func Test_FormatMaxMessageLen(t *testing.T) {
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	var js bytes.Buffer
+	xlog.SetFormatter(xlog.ApplySettings(xlog.NewJSONFormatter(&js).Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{MaxMessageLen: 5}))
+	logger.Info("hello world")
+	assert.Equal(t, `{"level":"I","msg":"hello...","pkg":"xlog_test","truncated":true}`+"\n", js.String())
+
+	js.Reset()
+	xlog.SetFormatter(xlog.ApplySettings(xlog.NewJSONFormatter(&js).Options(xlog.FormatNoCaller, xlog.FormatSkipTime), xlog.FormatterSettings{MaxMessageLen: -1}))
+	logger.Info(strings.Repeat("a", 2000))
+	assert.Contains(t, js.String(), strings.Repeat("a", 2000))
+	assert.NotContains(t, js.String(), xlog.KeyTruncated)
+}
+
+func Test_TruncateString(t *testing.T) {
+	assert.Equal(t, "hello", xlog.TruncateString("hello", 5))
+	assert.Equal(t, "hel...", xlog.TruncateString("hello", 3))
+	// "é" is 2 bytes, not split
+	assert.Equal(t, "é...", xlog.TruncateString("ééé", 3))
+	assert.Equal(t, "éé...", xlog.TruncateString("ééé", 4))
+}
+
+func Test_TruncateValid(t *testing.T) {
+	tcases := []struct {
+		name  string
+		value any
+		str   string
+		json  string
+	}{
+		{
+			name:  "runes",
+			value: "ééééé",
+			str:   `k="éé..."`,
+			json:  `"k":"ééé..."`,
+		},
+		{
+			name:  "escape",
+			value: "ab\ncd\nef",
+			str:   `k="ab\nc..."`,
+			json:  `"k":"ab\ncd\n..."`,
+		},
+		{
+			name:  "unicode escape",
+			value: "ab\x01cdef",
+			str:   `k="ab..."`,
+			json:  `"k":"ab\u0001cde..."`,
+		},
+		{
+			name:  "object",
+			value: map[string]int{"a": 1, "b": 2},
+			str:   `k={"truncated":true,"len":13}`,
+			json:  `"k":{"truncated":true,"len":13}`,
+		},
+		{
+			name:  "array",
+			value: []string{"abcdef", "ghi"},
+			str:   `k={"truncated":true,"len":16}`,
+			json:  `"k":{"truncated":true,"len":16}`,
+		},
+	}
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var str, js bytes.Buffer
+			xlog.SetFormatter(xlog.ApplySettings(xlog.NewMultiFormatter(
+				xlog.NewStringFormatter(&str),
+				xlog.NewJSONFormatter(&js),
+			).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatSkipLevel, xlog.FormatSkipPkg), xlog.FormatterSettings{MaxValueLen: 6}))
+
+			logger.KV(xlog.INFO, "k", tc.value)
+			assert.Equal(t, tc.str+" truncated=true\n", str.String())
+			assert.Equal(t, "{"+tc.json+`,"truncated":true}`+"\n", js.String())
+			assert.True(t, json.Valid(js.Bytes()))
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package xlog
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field is the typed key-value entry, created by String, Int, Err, Duration, Time and other constructors.
+// Fields can be mixed with key-value pairs in KV, ContextKV and WithValues:
+//
+//	logger.KV(xlog.INFO, xlog.String("user", name), xlog.Int("attempt", n), "status", status)
+//
+// The scalar values are stored without boxing, and written by the text formatters as is.
+type Field struct {
+	// Key of the entry
+	Key string
+
+	kind fieldKind
+	// num is the int64, uint64, float64 bits, bool or duration value
+	num uint64
+	str string
+	// any is the time, error or any value
+	any any
+}
+
+// fieldKind is the type of the value stored in Field
+type fieldKind uint8
+
+const (
+	anyKind fieldKind = iota
+	stringKind
+	intKind
+	int64Kind
+	uint64Kind
+	float64Kind
+	boolKind
+	durationKind
+)
+
+// String returns the string field
+func String(key, val string) Field {
+	return Field{Key: key, kind: stringKind, str: val}
+}
+
+// Int returns the int field
+func Int(key string, val int) Field {
+	return Field{Key: key, kind: intKind, num: uint64(val)}
+}
+
+// Int64 returns the int64 field
+func Int64(key string, val int64) Field {
+	return Field{Key: key, kind: int64Kind, num: uint64(val)}
+}
+
+// Uint64 returns the uint64 field
+func Uint64(key string, val uint64) Field {
+	return Field{Key: key, kind: uint64Kind, num: val}
+}
+
+// Float64 returns the float64 field
+func Float64(key string, val float64) Field {
+	return Field{Key: key, kind: float64Kind, num: math.Float64bits(val)}
+}
+
+// Bool returns the bool field
+func Bool(key string, val bool) Field {
+	f := Field{Key: key, kind: boolKind}
+	if val {
+		f.num = 1
+	}
+	return f
+}
+
+// Err returns the field with KeyError key,
+// the nil error is not logged
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: KeyError}
+	}
+	return Field{Key: KeyError, any: err}
+}
+
+// Duration returns the duration field
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: uint64(val)}
+}
+
+// Time returns the time field
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, any: val}
+}
+
+// Any returns the field with any value
+func Any(key string, val any) Field {
+	return Field{Key: key, any: val}
+}
+
+// Value returns the value of the field
+func (f Field) Value() any {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return int(f.num)
+	case int64Kind:
+		return int64(f.num)
+	case uint64Kind:
+		return f.num
+	case float64Kind:
+		return math.Float64frombits(f.num)
+	case boolKind:
+		return f.num != 0
+	case durationKind:
+		return time.Duration(f.num)
+	}
+	return f.any
+}
+
+// isEmpty returns true if the field has nil value
+func (f Field) isEmpty() bool {
+	return f.kind == anyKind && f.any == nil
+}
+
+// appendValue appends the value of the field as written by appendEscaped,
+// without boxing the scalar values
+func (f Field) appendValue(b []byte) []byte {
+	switch f.kind {
+	case stringKind:
+		return appendQuoted(b, strings.TrimSpace(f.str))
+	case intKind, int64Kind:
+		return strconv.AppendInt(b, int64(f.num), 10)
+	case uint64Kind:
+		return strconv.AppendUint(b, f.num, 10)
+	case boolKind:
+		return strconv.AppendBool(b, f.num != 0)
+	case durationKind:
+		return append(b, time.Duration(f.num).String()...)
+	}
+	return appendEscaped(b, f.Value())
+}
+
+// isCompressed returns true if the field has the compressed value
+func (f Field) isCompressed() bool {
+	if f.kind == stringKind {
+		return IsCompressed(f.str)
+	}
+	return f.kind == anyKind && isCompressedValue(f.any)
+}
+
+// hasFields returns true if the entries have typed fields
+func hasFields(kvList []any) bool {
+	for _, v := range kvList {
+		if _, ok := v.(Field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldsWriter is implemented by the formatters that write the typed fields,
+// so the fields are not expanded to key-value pairs for them
+type fieldsWriter interface {
+	writesFields() bool
+}
+
+// writesFields returns true if the formatter writes the typed fields
+func writesFields(f Formatter) bool {
+	w, ok := f.(fieldsWriter)
+	return ok && w.writesFields()
+}
+
+// expandFields returns the key-value pairs with the typed fields expanded,
+// or the list as is if it has no typed fields
+func expandFields(kvList []any) []any {
+	if !hasFields(kvList) {
+		return kvList
+	}
+	return appendKV(make([]any, 0, len(kvList)*2), "", kvList)
+}
+
+// kvAt returns the key and value of the entry at i, and the index of the next entry:
+// the typed field is a single entry, and the key-value pair is two entries
+func kvAt(kvList []any, i int) (k any, v any, next int) {
+	if f, ok := kvList[i].(Field); ok {
+		return f.Key, f.Value(), i + 1
+	}
+	if i+1 < len(kvList) {
+		return kvList[i], kvList[i+1], i + 2
+	}
+	return kvList[i], nil, i + 2
+}
+
+// appendKV appends the key-value pairs to the list,
+// with the typed fields expanded to the pairs, and the keys prefixed
+func appendKV(list []any, prefix string, kvList []any) []any {
+	for i := 0; i < len(kvList); {
+		var k, v any
+		k, v, i = kvAt(kvList, i)
+		if s, ok := k.(string); ok && prefix != "" {
+			k = prefix + "." + s
+		}
+		list = append(list, k, v)
+	}
+	return list
+}
+
+// quoteString returns the string in JSON format,
+// as encoded by json.Encoder with HTML escaping disabled
+func quoteString(s string) string {
+	var buf [64]byte
+	return string(appendQuoted(buf[:0], s))
+}
+
+// appendQuoted appends the string in JSON format,
+// as encoded by json.Encoder with HTML escaping disabled
+func appendQuoted(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, `\b`...)
+			case '\f':
+				b = append(b, `\f`...)
+			case '\n':
+				b = append(b, `\n`...)
+			case '\r':
+				b = append(b, `\r`...)
+			case '\t':
+				b = append(b, `\t`...)
+			default:
+				b = append(b, `\u00`...)
+				b = append(b, hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, `\u202`...)
+			b = append(b, hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,179 @@
+package xlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TypedFields(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.KV(xlog.INFO,
+		xlog.String("s", "v"),
+		xlog.Int("i", 1),
+		"pair", 2,
+		xlog.Int64("i64", -3),
+		xlog.Uint64("u64", 4),
+		xlog.Bool("b", true),
+		xlog.Duration("d", time.Second),
+		xlog.Time("t", ts),
+		xlog.Any("a", []int{5}),
+		xlog.Err(fmt.Errorf("failed")),
+		xlog.Err(nil),
+	)
+	assert.Equal(t, "level=I pkg=xlog_test s=\"v\" i=1 pair=2 i64=-3 u64=4 b=true d=1s t=2024-01-02T03:04:05Z a=[5] err=\"failed\"\n", b.String())
+
+	b.Reset()
+	logger.WithPrefix("p").WithValues(xlog.String("s", "v")).KV(xlog.INFO, xlog.Int("i", 1))
+	assert.Equal(t, "level=I pkg=xlog_test p.s=\"v\" p.i=1\n", b.String())
+
+	b.Reset()
+	xlog.SetFormatter(xlog.NewJSONFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	logger.KV(xlog.INFO, xlog.String("s", "v"), xlog.Int("i", 1))
+	assert.Equal(t, `{"i":1,"level":"I","pkg":"xlog_test","s":"v"}`+"\n", b.String())
+}
+
+// Test_TypedFieldsFormatters verifies that the formatters write the typed fields
+// as the same key-value pairs, expanded for the wrapped formatters
+func Test_TypedFieldsFormatters(t *testing.T) {
+	r, err := xlog.NewRedactor(xlog.RedactConfig{
+		Keys: []string{"password"},
+	})
+	require.NoError(t, err)
+	xlog.SetRedactor(r)
+	defer xlog.SetRedactor(nil)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	entries := []any{
+		xlog.Float64("f", 1.5),
+		xlog.String("empty", ""),
+		xlog.String("long", "abcdef"),
+		xlog.Any("lazy", xlog.Lazy(func() any { return 7 })),
+		xlog.String("password", "secret"),
+		xlog.Err(nil),
+		"pair", true,
+	}
+	tcases := []struct {
+		name string
+		f    func(w io.Writer) xlog.Formatter
+		opt  xlog.FormatterOption
+		exp  string
+	}{
+		{
+			name: "string",
+			f:    xlog.NewStringFormatter,
+			exp:  "level=I pkg=xlog_test f=1.5 long=\"abc...\" lazy=7 password=\"sec...\" pair=true truncated=true\n",
+		},
+		{
+			name: "redacted",
+			f:    xlog.NewStringFormatter,
+			opt:  xlog.FormatWithRedaction,
+			exp:  "level=I pkg=xlog_test f=1.5 long=\"abc...\" lazy=7 password=\"[RE...\" pair=true truncated=true\n",
+		},
+		{
+			name: "pretty",
+			f:    xlog.NewPrettyFormatter,
+			exp:  "I | pkg=xlog_test, f=1.5, long=\"abc...\", lazy=7, password=\"sec...\", pair=true, truncated=true\n",
+		},
+		{
+			name: "json",
+			f:    xlog.NewJSONFormatter,
+			exp:  `{"empty":"","err":null,"f":1.5,"lazy":7,"level":"I","long":"abcd...","pair":true,"password":"secr...","pkg":"xlog_test","truncated":true}` + "\n",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var direct, expanded bytes.Buffer
+			opts := []xlog.FormatterOption{xlog.FormatNoCaller, xlog.FormatSkipTime, tc.opt}
+			settings := xlog.FormatterSettings{MaxValueLen: 4}
+
+			// the formatter set directly writes the typed fields
+			xlog.SetFormatter(xlog.ApplySettings(tc.f(&direct).Options(opts...), settings))
+			logger.KV(xlog.INFO, entries...)
+			assert.Equal(t, tc.exp, direct.String())
+
+			// the typed fields are expanded for the wrapped formatter
+			xlog.SetFormatter(xlog.ApplySettings(xlog.NewMultiFormatter(tc.f(&expanded)).Options(opts...), settings))
+			logger.KV(xlog.INFO, entries...)
+			assert.Equal(t, direct.String(), expanded.String())
+		})
+	}
+}
+
+func Test_EscapedStringMatchesJSON(t *testing.T) {
+	values := []string{
+		"",
+		"plain",
+		`quote " and \ backslash`,
+		"control \x00\x01\x1f \b\f\n\r\t end",
+		"html <a href=\"x\">&</a>",
+		"unicode привет 世界     é",
+		"emoji 😀",
+	}
+	for _, v := range values {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		assert.Equal(t, strings.TrimSpace(buf.String()), xlog.EscapedString(v), "value: %q", v)
+	}
+
+	var s string
+	err := json.Unmarshal([]byte(xlog.EscapedString("invalid \xff\xfe utf8")), &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid \ufffd\ufffd utf8", s)
+}
+
+// BenchmarkLogTypedFields compares the typed fields with the same key-value pairs
+func BenchmarkLogTypedFields(b *testing.B) {
+	xlog.SetFormatter(xlog.NewStringFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	l := xlog.NewPackageLogger(benchRepo, "typed")
+
+	b.Run("kv", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.KV(xlog.INFO, "k1", 1000, "k2", "value")
+		}
+	})
+	b.Run("typed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.KV(xlog.INFO, xlog.Int("k1", 1000), xlog.String("k2", "value"))
+		}
+	})
+}
+
+func Test_EscapedStringNumbersMatchJSON(t *testing.T) {
+	values := []any{
+		nil,
+		int8(-8), int16(-16), int32(-32), int64(-64), -1,
+		uint8(8), uint16(16), uint32(32), uint64(64), uint(1),
+		0.0, -0.0, 1.5, -3.1415, 1e20, 1e21, 1e-6, 1e-7, 123456789.123, 5e-324,
+		float32(0), float32(1.1), float32(-2.5e-7), float32(3e21), float32(16777216),
+	}
+	for _, v := range values {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		_ = enc.Encode(v)
+		assert.Equal(t, strings.TrimSpace(buf.String()), xlog.EscapedString(v), "value: %T %v", v, v)
+	}
+
+	assert.Empty(t, xlog.EscapedString(math.NaN()))
+	assert.Empty(t, xlog.EscapedString(math.Inf(1)))
+}
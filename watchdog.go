@@ -0,0 +1,169 @@
+package xlog
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+var xlogger = NewPackageLogger("github.com/effective-security/xlog", "xlog")
+
+// MemoryWatchdogConfig specifies configuration for MemoryWatchdog
+type MemoryWatchdogConfig struct {
+	// Threshold specifies the memory size in bytes,
+	// above which the log level is limited
+	Threshold uint64
+	// RestoreThreshold specifies the memory size in bytes,
+	// below which the log level is restored.
+	// If not specified, 90% of Threshold is used.
+	RestoreThreshold uint64
+	// Level specifies the maximum log level under memory pressure.
+	// The zero value is ERROR.
+	Level LogLevel
+	// Interval specifies how often the memory is checked,
+	// 1 second by default.
+	Interval time.Duration
+	// MemoryFn returns the current memory usage in bytes,
+	// for example process RSS or the size of logs queue.
+	// If not specified, the memory mapped by the Go runtime is used.
+	MemoryFn func() uint64
+}
+
+// MemoryWatchdog limits the log level when the process is under memory pressure,
+// protecting services from logging-induced OOM.
+type MemoryWatchdog struct {
+	cfg MemoryWatchdogConfig
+
+	lock     sync.Mutex
+	degraded bool
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewMemoryWatchdog returns a new MemoryWatchdog,
+// call Start to run it in the background, or Check to evaluate it once.
+func NewMemoryWatchdog(cfg MemoryWatchdogConfig) *MemoryWatchdog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.RestoreThreshold == 0 || cfg.RestoreThreshold > cfg.Threshold {
+		cfg.RestoreThreshold = cfg.Threshold / 10 * 9
+	}
+	if cfg.MemoryFn == nil {
+		cfg.MemoryFn = runtimeMemory
+	}
+	return &MemoryWatchdog{
+		cfg: cfg,
+	}
+}
+
+// Start runs the watchdog in the background
+func (w *MemoryWatchdog) Start() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.stopped = make(chan struct{})
+	go w.run(w.stop, w.stopped)
+}
+
+// Stop stops the watchdog, and restores the log level
+func (w *MemoryWatchdog) Stop() {
+	w.lock.Lock()
+	stop, stopped := w.stop, w.stopped
+	w.stop = nil
+	w.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.degraded {
+		w.degraded = false
+		resetLevelLimit()
+	}
+}
+
+// Degraded returns true if the log level is currently limited
+func (w *MemoryWatchdog) Degraded() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.degraded
+}
+
+// Check evaluates the memory usage, and limits or restores the log level.
+// It returns true if the log level is limited.
+func (w *MemoryWatchdog) Check() bool {
+	mem := w.cfg.MemoryFn()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if !w.degraded && mem > w.cfg.Threshold {
+		w.degraded = true
+		// the notice is logged before the limit is applied,
+		// as the limit may be below WARNING
+		xlogger.KV(WARNING,
+			"reason", "memory_pressure",
+			"memory", mem,
+			"threshold", w.cfg.Threshold,
+			"level", w.cfg.Level)
+		setLevelLimit(w.cfg.Level)
+	} else if w.degraded && mem < w.cfg.RestoreThreshold {
+		w.degraded = false
+		resetLevelLimit()
+		xlogger.KV(WARNING,
+			"reason", "memory_restored",
+			"memory", mem,
+			"threshold", w.cfg.RestoreThreshold)
+	}
+	return w.degraded
+}
+
+// Started returns true if the watchdog is running in the background
+func (w *MemoryWatchdog) Started() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.stop != nil
+}
+
+func (w *MemoryWatchdog) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+	defer TrackGoroutine("xlog.MemoryWatchdog")()
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// runtimeMemory returns the memory mapped by the Go runtime,
+// not released to the OS
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+
+	var total, released uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		total = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		released = samples[1].Value.Uint64()
+	}
+	return total - released
+}
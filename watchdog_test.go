@@ -0,0 +1,95 @@
+package xlog_test
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryWatchdog(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	var mem atomic.Uint64
+	mem.Store(100)
+	w := xlog.NewMemoryWatchdog(xlog.MemoryWatchdogConfig{
+		Threshold: 1000,
+		Level:     xlog.WARNING,
+		MemoryFn:  mem.Load,
+	})
+	defer w.Stop()
+
+	assert.False(t, w.Check())
+	assert.True(t, logger.LevelAt(xlog.INFO))
+
+	mem.Store(2000)
+	assert.True(t, w.Check())
+	assert.True(t, w.Degraded())
+	assert.False(t, logger.LevelAt(xlog.INFO))
+	assert.True(t, logger.LevelAt(xlog.WARNING))
+	assert.Equal(t, "level=W pkg=xlog reason=\"memory_pressure\" memory=2000 threshold=1000 level=\"WARNING\"\n", b.String())
+	b.Reset()
+
+	logger.KV(xlog.INFO, "dropped", true)
+	assert.Empty(t, b.String())
+
+	// above the restore threshold
+	mem.Store(950)
+	assert.True(t, w.Check())
+
+	mem.Store(100)
+	assert.False(t, w.Check())
+	assert.Equal(t, "level=W pkg=xlog reason=\"memory_restored\" memory=100 threshold=900\n", b.String())
+	b.Reset()
+
+	logger.KV(xlog.INFO, "logged", true)
+	assert.Equal(t, "level=I pkg=xlog_test logged=true\n", b.String())
+
+	mem.Store(2000)
+	w2 := xlog.NewMemoryWatchdog(xlog.MemoryWatchdogConfig{
+		Threshold: 1000,
+		Interval:  time.Millisecond,
+		MemoryFn:  mem.Load,
+	})
+	w2.Start()
+	w2.Start()
+	assert.Eventually(t, w2.Degraded, time.Second, time.Millisecond)
+	w2.Stop()
+	assert.False(t, w2.Degraded())
+	assert.True(t, logger.LevelAt(xlog.INFO))
+
+	assert.False(t, xlog.NewMemoryWatchdog(xlog.MemoryWatchdogConfig{Threshold: 1 << 50}).Check())
+}
+
+func Test_MemoryWatchdogDefaultLevel(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	var mem atomic.Uint64
+	mem.Store(2000)
+	// the zero value limits the level to ERROR,
+	// the notice at WARNING is logged before the limit
+	w := xlog.NewMemoryWatchdog(xlog.MemoryWatchdogConfig{
+		Threshold: 1000,
+		MemoryFn:  mem.Load,
+	})
+	defer w.Stop()
+
+	assert.True(t, w.Check())
+	assert.Equal(t, "level=W pkg=xlog reason=\"memory_pressure\" memory=2000 threshold=1000 level=\"ERROR\"\n", b.String())
+	b.Reset()
+
+	logger.KV(xlog.WARNING, "dropped", true)
+	assert.Empty(t, b.String())
+
+	mem.Store(100)
+	assert.False(t, w.Check())
+	assert.Equal(t, "level=W pkg=xlog reason=\"memory_restored\" memory=100 threshold=900\n", b.String())
+}
@@ -0,0 +1,352 @@
+// Package webhook provides the writer, that posts batched NDJSON entries
+// to HTTP endpoint, such as Splunk HEC or a generic webhook.
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// ErrorPkg is the package reported to xlog.OnError callback on delivery errors
+const ErrorPkg = "webhook"
+
+// Config specifies configuration for Writer
+type Config struct {
+	// Endpoint specifies the URL to post the entries
+	Endpoint string
+	// Authorization specifies the value of Authorization header,
+	// such as "Bearer <token>" or "Splunk <token>"
+	Authorization string
+	// Headers specifies extra headers
+	Headers map[string]string
+	// ContentType of the body, "application/x-ndjson" by default
+	ContentType string
+	// Encode returns the body of the batch,
+	// the entries are separated by newline by default
+	Encode func(batch [][]byte) ([]byte, error)
+	// Gzip specifies to compress the body
+	Gzip bool
+	// HTTPClient specifies the client, http.DefaultClient by default
+	HTTPClient *http.Client
+	// BatchSize specifies the maximum number of entries in a post, 500 by default
+	BatchSize int
+	// BatchBytes specifies the maximum size of a post, 1MB by default
+	BatchBytes int
+	// FlushInterval specifies the interval to post batched entries,
+	// 1 second by default
+	FlushInterval time.Duration
+	// QueueSize specifies the number of entries queued for posting,
+	// 1000 by default
+	QueueSize int
+	// Overflow specifies the behavior when the queue is full,
+	// OverflowBlock and OverflowDropNewest are supported
+	Overflow xlog.OverflowPolicy
+	// MaxRetries specifies the number of retries of failed posts, 3 by default
+	MaxRetries int
+	// Backoff specifies the initial delay between retries,
+	// doubled on each retry, 200ms by default
+	Backoff time.Duration
+	// OnError is called when the entries failed to deliver,
+	// in addition to xlog.OnError callback with ErrorPkg
+	OnError func(err error, batch [][]byte)
+}
+
+// Writer is io.Writer, that batches entries for HTTP endpoint asynchronously,
+// each Write call is a single entry.
+// The batch is posted when it is full, on the flush interval,
+// or on Flush and Close calls.
+type Writer struct {
+	cfg Config
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan []byte
+	flush  chan chan struct{}
+	done   chan struct{}
+
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// NewWriter returns Writer
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+	if cfg.Overflow == xlog.OverflowDropOldest {
+		return nil, errors.Errorf("unsupported overflow policy: %s", cfg.Overflow)
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/x-ndjson"
+	}
+	if cfg.Encode == nil {
+		cfg.Encode = NDJSON
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = 1024 * 1024
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+
+	w := &Writer{
+		cfg:   cfg,
+		queue: make(chan []byte, cfg.QueueSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	done := xlog.TrackGoroutine("webhook.Writer")
+	go w.run(done)
+	return w, nil
+}
+
+// NDJSON returns the entries separated by newline
+func NDJSON(batch [][]byte) ([]byte, error) {
+	size := 0
+	for _, entry := range batch {
+		size += len(entry) + 1
+	}
+	body := make([]byte, 0, size)
+	for _, entry := range batch {
+		body = append(body, entry...)
+		body = append(body, '\n')
+	}
+	return body, nil
+}
+
+// Write queues the entry, the trailing newline is removed
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return 0, errors.New("webhook writer is closed")
+	}
+	entry := bytes.TrimRight(p, "\n")
+	if len(entry) == 0 {
+		return len(p), nil
+	}
+	entry = append([]byte{}, entry...)
+	if w.cfg.Overflow == xlog.OverflowDropNewest {
+		select {
+		case w.queue <- entry:
+		default:
+			w.dropped.Add(1)
+		}
+	} else {
+		w.queue <- entry
+	}
+	return len(p), nil
+}
+
+// Flush posts the queued entries, and waits for the delivery
+func (w *Writer) Flush(ctx context.Context) error {
+	w.lock.RLock()
+	closed := w.closed
+	w.lock.RUnlock()
+	if closed {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case w.flush <- flushed:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, and drains the queue
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// Dropped returns the number of entries dropped on the full queue
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Failed returns the number of entries failed to deliver
+func (w *Writer) Failed() uint64 {
+	return w.failed.Load()
+}
+
+func (w *Writer) run(done func()) {
+	defer func() {
+		done()
+		close(w.done)
+	}()
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	size := 0
+	send := func() {
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = nil
+			size = 0
+		}
+	}
+	add := func(entry []byte) {
+		if size+len(entry)+1 > w.cfg.BatchBytes {
+			send()
+		}
+		batch = append(batch, entry)
+		size += len(entry) + 1
+		if len(batch) >= w.cfg.BatchSize {
+			send()
+		}
+	}
+
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				send()
+				return
+			}
+			add(entry)
+		case <-ticker.C:
+			send()
+		case flushed := <-w.flush:
+			// drain the entries queued before the flush
+			for n := len(w.queue); n > 0; n-- {
+				entry, ok := <-w.queue
+				if !ok {
+					break
+				}
+				add(entry)
+			}
+			send()
+			close(flushed)
+		}
+	}
+}
+
+// send the batch, and reports delivery errors
+func (w *Writer) send(batch [][]byte) {
+	err := w.post(context.Background(), batch)
+	if err == nil {
+		return
+	}
+	w.failed.Add(uint64(len(batch)))
+	xlog.ReportError(ErrorPkg)
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(err, batch)
+	} else {
+		fmt.Fprintf(os.Stderr, "xlog: webhook: %v\n", err)
+	}
+}
+
+// post the batch, retrying with backoff
+// on network, throttling and server errors
+func (w *Writer) post(ctx context.Context, batch [][]byte) error {
+	body, err := w.cfg.Encode(batch)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to encode %d entries", len(batch))
+	}
+	if w.cfg.Gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		_, _ = zw.Write(body)
+		if err = zw.Close(); err != nil {
+			return errors.WithMessagef(err, "failed to compress %d entries", len(batch))
+		}
+		body = b.Bytes()
+	}
+
+	delay := w.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = w.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithMessagef(ctx.Err(), "failed to post %d entries", len(batch))
+		}
+		delay *= 2
+	}
+	return errors.WithMessagef(err, "failed to post %d entries", len(batch))
+}
+
+// do sends the request, and returns true if it can be retried
+func (w *Writer) do(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", w.cfg.ContentType)
+	if w.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if w.cfg.Authorization != "" {
+		req.Header.Set("Authorization", w.cfg.Authorization)
+	}
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, errors.Errorf("unexpected status: %s", resp.Status)
+}
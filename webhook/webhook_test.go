@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Writer(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Splunk token", r.Header.Get("Authorization"))
+		assert.Equal(t, "app", r.Header.Get("X-Source"))
+
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(zr)
+
+		bodies = append(bodies, string(body))
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status = statuses[0]
+			statuses = statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	_, err := NewWriter(Config{})
+	assert.EqualError(t, err, "endpoint is required")
+	_, err = NewWriter(Config{Endpoint: srv.URL, Overflow: xlog.OverflowDropOldest})
+	assert.EqualError(t, err, "unsupported overflow policy: drop-oldest")
+
+	w, err := NewWriter(Config{
+		Endpoint:      srv.URL,
+		Authorization: "Splunk token",
+		Headers:       map[string]string{"X-Source": "app"},
+		Gzip:          true,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Backoff:       time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for _, entry := range []string{`{"msg":"1"}` + "\n", `{"msg":"2"}` + "\n", "\n", `{"msg":"3"}` + "\n"} {
+		_, err = w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Flush(context.Background()))
+
+	lock.Lock()
+	assert.Equal(t, []string{
+		"{\"msg\":\"1\"}\n{\"msg\":\"2\"}\n",
+		"{\"msg\":\"1\"}\n{\"msg\":\"2\"}\n",
+		"{\"msg\":\"3\"}\n",
+	}, bodies)
+	lock.Unlock()
+	assert.Equal(t, uint64(0), w.Failed())
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Flush(context.Background()))
+	_, err = w.Write([]byte("x"))
+	assert.EqualError(t, err, "webhook writer is closed")
+}
+
+func Test_WriterErrors(t *testing.T) {
+	var reported []string
+	xlog.OnError(func(pkg string) {
+		reported = append(reported, pkg)
+	})
+	defer xlog.OnError(nil)
+
+	var count int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var failed [][]byte
+	var failedErr error
+	w, err := NewWriter(Config{
+		Endpoint:      srv.URL,
+		FlushInterval: time.Hour,
+		Encode: func(batch [][]byte) ([]byte, error) {
+			return append([]byte(`{"streams":[`), append(bytes.Join(batch, []byte(",")), ']', '}')...), nil
+		},
+		OnError: func(err error, batch [][]byte) {
+			failedErr = err
+			failed = batch
+		},
+	})
+	require.NoError(t, err)
+
+	_, _ = w.Write([]byte(`{"msg":"1"}`))
+	require.NoError(t, w.Close())
+
+	// client errors are not retried
+	assert.Equal(t, 1, count)
+	assert.Equal(t, uint64(1), w.Failed())
+	assert.Equal(t, [][]byte{[]byte(`{"msg":"1"}`)}, failed)
+	assert.EqualError(t, failedErr, "failed to post 1 entries: unexpected status: 400 Bad Request")
+	assert.Equal(t, []string{ErrorPkg}, reported)
+}
+
+func Test_WriterDropNewest(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(Config{
+		Endpoint:      srv.URL,
+		BatchSize:     1,
+		QueueSize:     1,
+		FlushInterval: time.Hour,
+		Overflow:      xlog.OverflowDropNewest,
+	})
+	require.NoError(t, err)
+
+	_, _ = w.Write([]byte("1"))
+	// wait until the first entry is being posted
+	require.Eventually(t, func() bool { return len(w.queue) == 0 }, time.Second, time.Millisecond)
+	_, _ = w.Write([]byte("2"))
+	_, _ = w.Write([]byte("3"))
+	assert.Equal(t, uint64(1), w.Dropped())
+
+	close(release)
+	require.NoError(t, w.Close())
+	assert.Equal(t, uint64(0), w.Failed())
+}
+
+func Test_NDJSON(t *testing.T) {
+	body, err := NDJSON([][]byte{[]byte("a"), []byte("b")})
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(body))
+}
@@ -40,6 +40,41 @@ type KeyValueLogger interface {
 	WithValues(keysAndValues ...any) KeyValueLogger
 }
 
+// ExtendedKeyValueLogger is implemented by PackageLogger and NilLogger,
+// KeyValueLogger can be type-asserted to it:
+//
+//	if l, ok := kvLogger.(xlog.ExtendedKeyValueLogger); ok {
+//		kvLogger = l.WithTags("audit")
+//	}
+type ExtendedKeyValueLogger interface {
+	KeyValueLogger
+
+	// WithTags adds tags to a logger.
+	// Tags are low-cardinality labels, separate from key-value pairs.
+	WithTags(tags ...string) ExtendedKeyValueLogger
+
+	// WithPrefix returns a logger that emits subsequent keys
+	// in "prefix.key" format.
+	WithPrefix(prefix string) ExtendedKeyValueLogger
+
+	// WithName returns a child logger with the name joined to the parent name,
+	// emitted as "logger" field.
+	WithName(name string) ExtendedKeyValueLogger
+
+	// WithLevel returns a child logger with own minimum level,
+	// independent of the package level.
+	WithLevel(l LogLevel) ExtendedKeyValueLogger
+
+	// Deprecated logs the use of the deprecated feature once per process,
+	// at WARNING level with standardized keys
+	Deprecated(feature string, entries ...any)
+}
+
+var (
+	_ ExtendedKeyValueLogger = (*PackageLogger)(nil)
+	_ ExtendedKeyValueLogger = (*NilLogger)(nil)
+)
+
 // StdLogger interface for generic logger
 type StdLogger interface {
 	Fatal(args ...any)
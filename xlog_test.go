@@ -507,6 +507,31 @@ func Test_WithJSONError(t *testing.T) {
 	assert.Contains(t, result, `"func":"Test_WithJSONError","level":"E","number":1,"obj":{"A":"A","C":1234567},"pkg":"xlog_test","src":"xlog_test.go:503","time":"2021-04-01T00:00:00Z"}`)
 }
 
+func Test_WithJSONOrderedKeys(t *testing.T) {
+	assert.Equal(t, "WithOrderedKeys", xlog.FormatWithOrderedKeys.String())
+	o, err := xlog.ParseFormatterOption("WithOrderedKeys")
+	require.NoError(t, err)
+	assert.Equal(t, xlog.FormatWithOrderedKeys, o)
+
+	var b bytes.Buffer
+	writer := bufio.NewWriter(&b)
+
+	xlog.SetFormatter(xlog.NewJSONFormatter(writer).Options(xlog.FormatWithOrderedKeys))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "z", 1, "a", "<b>", xlog.Group("y", "b", 2, "a", 1))
+	logger.Infof("Test Info")
+	xlog.SetFormatter(xlog.ApplySettings(xlog.NewJSONFormatter(writer).Options(xlog.FormatWithOrderedKeys), xlog.FormatterSettings{PkgKey: "component"}))
+	logger.KV(xlog.ERROR, "k", 1)
+	writer.Flush()
+
+	assert.Equal(t,
+		`{"time":"2021-04-01T00:00:00Z","level":"I","pkg":"xlog_test","func":"Test_WithJSONOrderedKeys","a":"<b>","y":{"a":1,"b":2},"z":1}`+"\n"+
+			`{"time":"2021-04-01T00:00:00Z","level":"I","pkg":"xlog_test","func":"Test_WithJSONOrderedKeys","msg":"Test Info"}`+"\n"+
+			`{"time":"2021-04-01T00:00:00Z","level":"E","component":"xlog_test","src":"xlog_test.go:525","func":"Test_WithJSONOrderedKeys","k":1}`+"\n",
+		b.String())
+}
+
 func Test_NilFormatter(t *testing.T) {
 	f := xlog.NewNilFormatter()
 	f.FormatKV("pkg", xlog.DEBUG, 1)
@@ -564,10 +589,26 @@ func TestEscapedString(t *testing.T) {
 		{"err", errToTest.Error(), `"issue: some error"`},
 		{"goerrors", goerrors.New("goerrors"), `"goerrors"`},
 		{"stringer", xlog.TRACE, `"TRACE"`},
+		{"float", 3.1415, "3.1415"},
+		{"float32", float32(0.5), "0.5"},
+		{"small", 1e-7, "1e-7"},
+		{"int32", int32(-5), "-5"},
+		{"int8", int8(-5), "-5"},
+		{"escaped", "line1\nline2 \"quoted\"", `"line1\nline2 \"quoted\""`},
 	}
 
+	var b bytes.Buffer
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime, xlog.FormatSkipLevel))
+	defer xlog.SetFormatter(xlog.NewNilFormatter())
+
 	for _, tc := range tcases {
 		assert.Equal(t, tc.exp, xlog.EscapedString(tc.val), tc.name)
+
+		// the text formatters append the values without EscapedString
+		b.Reset()
+		logger.KV(xlog.INFO, "k", tc.val)
+		assert.Equal(t, "pkg=xlog_test k="+tc.exp+"\n", b.String(), tc.name)
 	}
 }
 
@@ -607,6 +648,23 @@ func TestCaller(t *testing.T) {
 	}
 	assert.Equal(t, "bogus.genericFunc", f(1))
 	assert.Equal(t, "bogus.genericFunc", b.genericFunc("bogus", 1))
+
+	// cached by the call site
+	var lines []int
+	for i := 0; i < 2; i++ {
+		name, file, line := xlog.Caller(1)
+		assert.Equal(t, "TestCaller", name)
+		assert.Equal(t, "xlog_test.go", file)
+		lines = append(lines, line)
+	}
+	_, _, line := xlog.Caller(1)
+	assert.Equal(t, lines[0], lines[1])
+	assert.Equal(t, lines[0]+5, line)
+
+	name, file, line := xlog.Caller(100)
+	assert.Equal(t, "func", name)
+	assert.Equal(t, "???", file)
+	assert.Equal(t, 1, line)
 }
 
 func f1(stack bool) error {
@@ -0,0 +1,79 @@
+// Package xlogadmin provides gRPC LevelService to control the log levels at runtime,
+// mirroring xlog.Handler, so the tooling can adjust verbosity across a fleet.
+//
+// The service is defined in xlogadmin.proto, and the Server is registered
+// with grpc.Server by RegisterLevelServiceServer.
+// The package is a separate module, so the core module does not depend on gRPC.
+package xlogadmin
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative xlogadmin.proto
+
+import (
+	"context"
+	"sort"
+
+	"github.com/effective-security/xlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements LevelServiceServer
+type Server struct {
+	UnimplementedLevelServiceServer
+
+	initial []xlog.RepoLogLevel
+}
+
+var _ LevelServiceServer = (*Server)(nil)
+
+// NewServer returns the Server, the current levels are captured
+// to be restored by ResetLevels.
+// The server must be protected by the caller, as it changes the logging of the process.
+func NewServer() *Server {
+	initial := xlog.GetRepoLevels()
+	// the repo levels are restored before the package levels
+	sort.SliceStable(initial, func(i, j int) bool {
+		return initial[i].Package == "*" && initial[j].Package != "*"
+	})
+	return &Server{initial: initial}
+}
+
+// ListLevels returns the current levels of the packages
+func (s *Server) ListLevels(_ context.Context, _ *ListLevelsRequest) (*LevelsResponse, error) {
+	return currentLevels(), nil
+}
+
+// SetLevel sets the level of the package, repo, or the global level,
+// and returns the updated levels
+func (s *Server) SetLevel(_ context.Context, req *SetLevelRequest) (*LevelsResponse, error) {
+	levels, err := xlog.ValidateRepoLevels([]xlog.RepoLogLevel{
+		{Repo: req.GetRepo(), Package: req.GetPackage(), Level: req.GetLevel()},
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	xlog.SetRepoLevels(levels)
+	return currentLevels(), nil
+}
+
+// ResetLevels restores the levels captured by NewServer,
+// and returns the updated levels
+func (s *Server) ResetLevels(_ context.Context, _ *ResetLevelsRequest) (*LevelsResponse, error) {
+	xlog.SetRepoLevels(s.initial)
+	return currentLevels(), nil
+}
+
+func currentLevels() *LevelsResponse {
+	levels := xlog.GetRepoLevels()
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].Repo != levels[j].Repo {
+			return levels[i].Repo < levels[j].Repo
+		}
+		return levels[i].Package < levels[j].Package
+	})
+	res := &LevelsResponse{Levels: make([]*RepoLogLevel, 0, len(levels))}
+	for _, ll := range levels {
+		res.Levels = append(res.Levels, &RepoLogLevel{Repo: ll.Repo, Package: ll.Package, Level: ll.Level})
+	}
+	return res
+}
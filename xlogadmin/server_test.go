@@ -0,0 +1,90 @@
+package xlogadmin
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const repo = "github.com/effective-security/xlog"
+
+var logger = xlog.NewPackageLogger(repo, "xlogadmin")
+
+// newClient returns the client connected to the Server served by grpc.Server
+func newClient(t *testing.T) LevelServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterLevelServiceServer(s, NewServer())
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewLevelServiceClient(conn)
+}
+
+func levelOf(res *LevelsResponse, pkg string) string {
+	for _, ll := range res.GetLevels() {
+		if ll.GetRepo() == repo && ll.GetPackage() == pkg {
+			return ll.GetLevel()
+		}
+	}
+	return ""
+}
+
+func TestServer(t *testing.T) {
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	defer xlog.SetGlobalLogLevel(xlog.INFO)
+
+	ctx := context.Background()
+	client := newClient(t)
+
+	res, err := client.ListLevels(ctx, &ListLevelsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "INFO", levelOf(res, "xlogadmin"))
+
+	res, err = client.SetLevel(ctx, &SetLevelRequest{Repo: repo, Package: "xlogadmin", Level: "debug"})
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG", levelOf(res, "xlogadmin"))
+	assert.True(t, logger.LevelAt(xlog.DEBUG))
+
+	tcases := []struct {
+		req *SetLevelRequest
+		msg string
+	}{
+		{&SetLevelRequest{Repo: "*", Level: "LOUD"}, "unable to parse log level: LOUD"},
+		{&SetLevelRequest{Repo: "unknown", Level: "INFO"}, `unknown repo: "unknown"`},
+		{&SetLevelRequest{Repo: repo, Package: "unknown", Level: "INFO"}, `unknown package: "unknown" in repo "` + repo + `"`},
+	}
+	for _, tc := range tcases {
+		_, err := client.SetLevel(ctx, tc.req)
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, tc.msg, st.Message())
+	}
+
+	res, err = client.SetLevel(ctx, &SetLevelRequest{Repo: "*", Level: "W"})
+	require.NoError(t, err)
+	assert.Equal(t, "WARNING", levelOf(res, "xlogadmin"))
+
+	res, err = client.ResetLevels(ctx, &ResetLevelsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "INFO", levelOf(res, "xlogadmin"))
+	assert.False(t, logger.LevelAt(xlog.DEBUG))
+}
@@ -0,0 +1,346 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: xlogadmin.proto
+
+package xlogadmin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RepoLogLevel struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Repo specifies the repo name, or "*" for all repos
+	Repo string `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	// Package specifies the package name, or "*" for all packages of the repo
+	Package string `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+	// Level specifies the log level
+	Level         string `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepoLogLevel) Reset() {
+	*x = RepoLogLevel{}
+	mi := &file_xlogadmin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepoLogLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepoLogLevel) ProtoMessage() {}
+
+func (x *RepoLogLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_xlogadmin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepoLogLevel.ProtoReflect.Descriptor instead.
+func (*RepoLogLevel) Descriptor() ([]byte, []int) {
+	return file_xlogadmin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RepoLogLevel) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *RepoLogLevel) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *RepoLogLevel) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type ListLevelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLevelsRequest) Reset() {
+	*x = ListLevelsRequest{}
+	mi := &file_xlogadmin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLevelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLevelsRequest) ProtoMessage() {}
+
+func (x *ListLevelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xlogadmin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLevelsRequest.ProtoReflect.Descriptor instead.
+func (*ListLevelsRequest) Descriptor() ([]byte, []int) {
+	return file_xlogadmin_proto_rawDescGZIP(), []int{1}
+}
+
+type SetLevelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Repo specifies the repo name, or "*" for all repos
+	Repo string `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	// Package specifies the package name, or "*" for all packages of the repo
+	Package string `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+	// Level specifies the log level
+	Level         string `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLevelRequest) Reset() {
+	*x = SetLevelRequest{}
+	mi := &file_xlogadmin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLevelRequest) ProtoMessage() {}
+
+func (x *SetLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xlogadmin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLevelRequest.ProtoReflect.Descriptor instead.
+func (*SetLevelRequest) Descriptor() ([]byte, []int) {
+	return file_xlogadmin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetLevelRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *SetLevelRequest) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *SetLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type ResetLevelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetLevelsRequest) Reset() {
+	*x = ResetLevelsRequest{}
+	mi := &file_xlogadmin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetLevelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetLevelsRequest) ProtoMessage() {}
+
+func (x *ResetLevelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xlogadmin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetLevelsRequest.ProtoReflect.Descriptor instead.
+func (*ResetLevelsRequest) Descriptor() ([]byte, []int) {
+	return file_xlogadmin_proto_rawDescGZIP(), []int{3}
+}
+
+type LevelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Levels        []*RepoLogLevel        `protobuf:"bytes,1,rep,name=levels,proto3" json:"levels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LevelsResponse) Reset() {
+	*x = LevelsResponse{}
+	mi := &file_xlogadmin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LevelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LevelsResponse) ProtoMessage() {}
+
+func (x *LevelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xlogadmin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LevelsResponse.ProtoReflect.Descriptor instead.
+func (*LevelsResponse) Descriptor() ([]byte, []int) {
+	return file_xlogadmin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LevelsResponse) GetLevels() []*RepoLogLevel {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+var File_xlogadmin_proto protoreflect.FileDescriptor
+
+const file_xlogadmin_proto_rawDesc = "" +
+	"\n" +
+	"\x0fxlogadmin.proto\x12\txlogadmin\"R\n" +
+	"\fRepoLogLevel\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x18\n" +
+	"\apackage\x18\x02 \x01(\tR\apackage\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\"\x13\n" +
+	"\x11ListLevelsRequest\"U\n" +
+	"\x0fSetLevelRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x18\n" +
+	"\apackage\x18\x02 \x01(\tR\apackage\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\"\x14\n" +
+	"\x12ResetLevelsRequest\"A\n" +
+	"\x0eLevelsResponse\x12/\n" +
+	"\x06levels\x18\x01 \x03(\v2\x17.xlogadmin.RepoLogLevelR\x06levels2\xe1\x01\n" +
+	"\fLevelService\x12E\n" +
+	"\n" +
+	"ListLevels\x12\x1c.xlogadmin.ListLevelsRequest\x1a\x19.xlogadmin.LevelsResponse\x12A\n" +
+	"\bSetLevel\x12\x1a.xlogadmin.SetLevelRequest\x1a\x19.xlogadmin.LevelsResponse\x12G\n" +
+	"\vResetLevels\x12\x1d.xlogadmin.ResetLevelsRequest\x1a\x19.xlogadmin.LevelsResponseB8Z6github.com/effective-security/xlog/xlogadmin;xlogadminb\x06proto3"
+
+var (
+	file_xlogadmin_proto_rawDescOnce sync.Once
+	file_xlogadmin_proto_rawDescData []byte
+)
+
+func file_xlogadmin_proto_rawDescGZIP() []byte {
+	file_xlogadmin_proto_rawDescOnce.Do(func() {
+		file_xlogadmin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_xlogadmin_proto_rawDesc), len(file_xlogadmin_proto_rawDesc)))
+	})
+	return file_xlogadmin_proto_rawDescData
+}
+
+var file_xlogadmin_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_xlogadmin_proto_goTypes = []any{
+	(*RepoLogLevel)(nil),       // 0: xlogadmin.RepoLogLevel
+	(*ListLevelsRequest)(nil),  // 1: xlogadmin.ListLevelsRequest
+	(*SetLevelRequest)(nil),    // 2: xlogadmin.SetLevelRequest
+	(*ResetLevelsRequest)(nil), // 3: xlogadmin.ResetLevelsRequest
+	(*LevelsResponse)(nil),     // 4: xlogadmin.LevelsResponse
+}
+var file_xlogadmin_proto_depIdxs = []int32{
+	0, // 0: xlogadmin.LevelsResponse.levels:type_name -> xlogadmin.RepoLogLevel
+	1, // 1: xlogadmin.LevelService.ListLevels:input_type -> xlogadmin.ListLevelsRequest
+	2, // 2: xlogadmin.LevelService.SetLevel:input_type -> xlogadmin.SetLevelRequest
+	3, // 3: xlogadmin.LevelService.ResetLevels:input_type -> xlogadmin.ResetLevelsRequest
+	4, // 4: xlogadmin.LevelService.ListLevels:output_type -> xlogadmin.LevelsResponse
+	4, // 5: xlogadmin.LevelService.SetLevel:output_type -> xlogadmin.LevelsResponse
+	4, // 6: xlogadmin.LevelService.ResetLevels:output_type -> xlogadmin.LevelsResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_xlogadmin_proto_init() }
+func file_xlogadmin_proto_init() {
+	if File_xlogadmin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_xlogadmin_proto_rawDesc), len(file_xlogadmin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_xlogadmin_proto_goTypes,
+		DependencyIndexes: file_xlogadmin_proto_depIdxs,
+		MessageInfos:      file_xlogadmin_proto_msgTypes,
+	}.Build()
+	File_xlogadmin_proto = out.File
+	file_xlogadmin_proto_goTypes = nil
+	file_xlogadmin_proto_depIdxs = nil
+}
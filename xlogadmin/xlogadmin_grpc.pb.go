@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: xlogadmin.proto
+
+package xlogadmin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LevelService_ListLevels_FullMethodName  = "/xlogadmin.LevelService/ListLevels"
+	LevelService_SetLevel_FullMethodName    = "/xlogadmin.LevelService/SetLevel"
+	LevelService_ResetLevels_FullMethodName = "/xlogadmin.LevelService/ResetLevels"
+)
+
+// LevelServiceClient is the client API for LevelService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LevelService controls the log levels of the process at runtime
+type LevelServiceClient interface {
+	// ListLevels returns the current levels of the packages
+	ListLevels(ctx context.Context, in *ListLevelsRequest, opts ...grpc.CallOption) (*LevelsResponse, error)
+	// SetLevel sets the level of the package, repo, or the global level,
+	// and returns the updated levels
+	SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*LevelsResponse, error)
+	// ResetLevels restores the levels captured when the server was created,
+	// and returns the updated levels
+	ResetLevels(ctx context.Context, in *ResetLevelsRequest, opts ...grpc.CallOption) (*LevelsResponse, error)
+}
+
+type levelServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLevelServiceClient(cc grpc.ClientConnInterface) LevelServiceClient {
+	return &levelServiceClient{cc}
+}
+
+func (c *levelServiceClient) ListLevels(ctx context.Context, in *ListLevelsRequest, opts ...grpc.CallOption) (*LevelsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LevelsResponse)
+	err := c.cc.Invoke(ctx, LevelService_ListLevels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelServiceClient) SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*LevelsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LevelsResponse)
+	err := c.cc.Invoke(ctx, LevelService_SetLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelServiceClient) ResetLevels(ctx context.Context, in *ResetLevelsRequest, opts ...grpc.CallOption) (*LevelsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LevelsResponse)
+	err := c.cc.Invoke(ctx, LevelService_ResetLevels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LevelServiceServer is the server API for LevelService service.
+// All implementations must embed UnimplementedLevelServiceServer
+// for forward compatibility.
+//
+// LevelService controls the log levels of the process at runtime
+type LevelServiceServer interface {
+	// ListLevels returns the current levels of the packages
+	ListLevels(context.Context, *ListLevelsRequest) (*LevelsResponse, error)
+	// SetLevel sets the level of the package, repo, or the global level,
+	// and returns the updated levels
+	SetLevel(context.Context, *SetLevelRequest) (*LevelsResponse, error)
+	// ResetLevels restores the levels captured when the server was created,
+	// and returns the updated levels
+	ResetLevels(context.Context, *ResetLevelsRequest) (*LevelsResponse, error)
+	mustEmbedUnimplementedLevelServiceServer()
+}
+
+// UnimplementedLevelServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLevelServiceServer struct{}
+
+func (UnimplementedLevelServiceServer) ListLevels(context.Context, *ListLevelsRequest) (*LevelsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListLevels not implemented")
+}
+func (UnimplementedLevelServiceServer) SetLevel(context.Context, *SetLevelRequest) (*LevelsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetLevel not implemented")
+}
+func (UnimplementedLevelServiceServer) ResetLevels(context.Context, *ResetLevelsRequest) (*LevelsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ResetLevels not implemented")
+}
+func (UnimplementedLevelServiceServer) mustEmbedUnimplementedLevelServiceServer() {}
+func (UnimplementedLevelServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeLevelServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LevelServiceServer will
+// result in compilation errors.
+type UnsafeLevelServiceServer interface {
+	mustEmbedUnimplementedLevelServiceServer()
+}
+
+func RegisterLevelServiceServer(s grpc.ServiceRegistrar, srv LevelServiceServer) {
+	// If the following call panics, it indicates UnimplementedLevelServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LevelService_ServiceDesc, srv)
+}
+
+func _LevelService_ListLevels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLevelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelServiceServer).ListLevels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LevelService_ListLevels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelServiceServer).ListLevels(ctx, req.(*ListLevelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LevelService_SetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelServiceServer).SetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LevelService_SetLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelServiceServer).SetLevel(ctx, req.(*SetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LevelService_ResetLevels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetLevelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelServiceServer).ResetLevels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LevelService_ResetLevels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelServiceServer).ResetLevels(ctx, req.(*ResetLevelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LevelService_ServiceDesc is the grpc.ServiceDesc for LevelService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LevelService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xlogadmin.LevelService",
+	HandlerType: (*LevelServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListLevels",
+			Handler:    _LevelService_ListLevels_Handler,
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    _LevelService_SetLevel_Handler,
+		},
+		{
+			MethodName: "ResetLevels",
+			Handler:    _LevelService_ResetLevels_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "xlogadmin.proto",
+}
@@ -0,0 +1,234 @@
+// Package xlogbench drives synthetic load through the live logging pipeline,
+// and reports throughput, allocation and drop statistics,
+// to size buffers and sampling before production rollout.
+package xlogbench
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/pkg/errors"
+)
+
+// DefaultRepo is the default repo of the benchmark logger
+const DefaultRepo = "github.com/effective-security/xlog"
+
+// DefaultPkg is the default package of the benchmark logger
+const DefaultPkg = "xlogbench"
+
+// Config specifies the synthetic load
+type Config struct {
+	// Repo and Pkg of the benchmark logger,
+	// so the package level, rate limits and formatters apply as configured.
+	// DefaultRepo and DefaultPkg by default.
+	Repo string
+	Pkg  string
+	// Level of the entries, INFO by default
+	Level xlog.LogLevel
+	// Rate specifies the entries per second across all workers,
+	// zero means no limit
+	Rate int
+	// Duration of the run, 1 second by default
+	Duration time.Duration
+	// Entries limits the number of entries, zero means no limit
+	Entries int
+	// Concurrency specifies the number of workers, 1 by default
+	Concurrency int
+	// Keys specifies the number of key-value pairs per entry, 4 by default
+	Keys int
+	// Cardinality specifies the number of distinct values per key, 100 by default
+	Cardinality int
+	// ValueSize specifies the size of the values, 16 bytes by default
+	ValueSize int
+	// Dropped returns the number of entries dropped by the pipeline,
+	// for example AsyncWriter.Dropped or Sampler.Dropped
+	Dropped func() uint64
+}
+
+// Result provides statistics of the run
+type Result struct {
+	// Entries is the number of entries logged
+	Entries uint64 `json:"entries"`
+	// Duration of the run
+	Duration time.Duration `json:"duration"`
+	// Throughput is the number of entries per second
+	Throughput float64 `json:"throughput"`
+	// AvgLatency is the average time of a log call
+	AvgLatency time.Duration `json:"avg_latency"`
+	// AllocsPerEntry is the average number of heap allocations per entry
+	AllocsPerEntry float64 `json:"allocs_per_entry"`
+	// BytesPerEntry is the average number of bytes allocated per entry
+	BytesPerEntry float64 `json:"bytes_per_entry"`
+	// Written is the size of formatted entries,
+	// if entry size metrics are enabled
+	Written uint64 `json:"written"`
+	// RateLimited is the number of entries dropped by rate limits
+	RateLimited uint64 `json:"rate_limited"`
+	// Dropped is the number of entries dropped by the pipeline,
+	// as reported by Config.Dropped
+	Dropped uint64 `json:"dropped"`
+}
+
+// String returns the report of the run
+func (r *Result) String() string {
+	return fmt.Sprintf("entries=%d duration=%v throughput=%.0f/s latency=%v allocs/entry=%.1f bytes/entry=%.0f written=%d rate_limited=%d dropped=%d",
+		r.Entries, r.Duration, r.Throughput, r.AvgLatency, r.AllocsPerEntry, r.BytesPerEntry, r.Written, r.RateLimited, r.Dropped)
+}
+
+// Run drives the load until the duration elapses,
+// the entries limit is reached, or ctx is done
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if cfg.Rate < 0 || cfg.Duration < 0 || cfg.Entries < 0 || cfg.Concurrency < 0 ||
+		cfg.Keys < 0 || cfg.Cardinality < 0 || cfg.ValueSize < 0 {
+		return nil, errors.New("invalid configuration: negative values")
+	}
+	if cfg.Repo == "" {
+		cfg.Repo = DefaultRepo
+	}
+	if cfg.Pkg == "" {
+		cfg.Pkg = DefaultPkg
+	}
+	if cfg.Level == 0 {
+		cfg.Level = xlog.INFO
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = time.Second
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Keys == 0 {
+		cfg.Keys = 4
+	}
+	if cfg.Cardinality == 0 {
+		cfg.Cardinality = 100
+	}
+	if cfg.ValueSize == 0 {
+		cfg.ValueSize = 16
+	}
+
+	logger := xlog.NewPackageLogger(cfg.Repo, cfg.Pkg)
+	entries := generate(cfg)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	before := snapshot(cfg)
+	var count atomic.Uint64
+	var elapsed atomic.Int64
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			var interval time.Duration
+			if cfg.Rate > 0 {
+				interval = time.Duration(float64(time.Second) * float64(cfg.Concurrency) / float64(cfg.Rate))
+			}
+			for i := 0; ; i++ {
+				if ctx.Err() != nil {
+					return
+				}
+				if cfg.Entries > 0 && count.Add(1) > uint64(cfg.Entries) {
+					count.Add(^uint64(0))
+					return
+				}
+				if interval > 0 {
+					if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+						select {
+						case <-time.After(wait):
+						case <-ctx.Done():
+							if cfg.Entries > 0 {
+								count.Add(^uint64(0))
+							}
+							return
+						}
+					}
+				}
+
+				t := time.Now()
+				logger.KV(cfg.Level, entries[(w+i*cfg.Concurrency)%len(entries)]...)
+				elapsed.Add(int64(time.Since(t)))
+				if cfg.Entries == 0 {
+					count.Add(1)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	logger.Flush()
+	duration := time.Since(start)
+	after := snapshot(cfg)
+
+	r := &Result{
+		Entries:     count.Load(),
+		Duration:    duration,
+		Written:     after.written - before.written,
+		RateLimited: after.rateLimited - before.rateLimited,
+		Dropped:     after.dropped - before.dropped,
+	}
+	if r.Entries > 0 {
+		n := float64(r.Entries)
+		r.Throughput = n / duration.Seconds()
+		r.AvgLatency = time.Duration(elapsed.Load() / int64(r.Entries))
+		r.AllocsPerEntry = float64(after.mallocs-before.mallocs) / n
+		r.BytesPerEntry = float64(after.allocated-before.allocated) / n
+	}
+	return r, nil
+}
+
+// generate returns the entries with the configured cardinality,
+// created before the run, so the generator does not affect allocations
+func generate(cfg Config) [][]any {
+	list := make([][]any, cfg.Cardinality)
+	for i := range list {
+		kv := make([]any, 0, cfg.Keys*2)
+		for k := 0; k < cfg.Keys; k++ {
+			v := strconv.Itoa(i)
+			if len(v) < cfg.ValueSize {
+				v += strings.Repeat("x", cfg.ValueSize-len(v))
+			}
+			kv = append(kv, "key"+strconv.Itoa(k), v)
+		}
+		list[i] = kv
+	}
+	return list
+}
+
+type stats struct {
+	mallocs     uint64
+	allocated   uint64
+	written     uint64
+	rateLimited uint64
+	dropped     uint64
+}
+
+func snapshot(cfg Config) stats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	s := stats{
+		mallocs:   ms.Mallocs,
+		allocated: ms.TotalAlloc,
+		written:   xlog.EntrySizes()[cfg.Pkg].Sum,
+	}
+	for _, rl := range xlog.GetRateLimits() {
+		if rl.Repo == cfg.Repo && rl.Pkg == cfg.Pkg {
+			s.rateLimited += rl.Dropped
+		}
+	}
+	if cfg.Dropped != nil {
+		s.dropped = cfg.Dropped()
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package xlogbench
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Run(t *testing.T) {
+	xlog.SetFormatter(xlog.NewJSONFormatter(io.Discard))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+	xlog.SetEntrySizeMetrics(true)
+	defer xlog.SetEntrySizeMetrics(false)
+
+	r, err := Run(context.Background(), Config{
+		Entries:     100,
+		Concurrency: 4,
+		Keys:        2,
+		Cardinality: 10,
+		ValueSize:   32,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), r.Entries)
+	assert.Greater(t, r.Throughput, float64(0))
+	assert.Greater(t, r.Written, uint64(100*64))
+	assert.Contains(t, r.String(), "entries=100 ")
+
+	// rate limited by the pipeline
+	xlog.WithRateLimit(DefaultRepo, DefaultPkg, xlog.INFO, 10)
+	defer xlog.WithRateLimit(DefaultRepo, DefaultPkg, xlog.INFO, 0)
+	var dropped uint64
+	r, err = Run(context.Background(), Config{
+		Rate:     500,
+		Duration: 100 * time.Millisecond,
+		Dropped: func() uint64 {
+			dropped += 5
+			return dropped
+		},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 50, float64(r.Entries), 25)
+	// the run may cross the rate limit window
+	assert.GreaterOrEqual(t, r.RateLimited, r.Entries-20)
+	assert.LessOrEqual(t, r.RateLimited, r.Entries-10)
+	assert.Equal(t, uint64(5), r.Dropped)
+
+	// disabled level
+	r, err = Run(context.Background(), Config{
+		Level:   xlog.DEBUG,
+		Entries: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), r.Entries)
+	assert.Zero(t, r.RateLimited)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, err = Run(ctx, Config{})
+	require.NoError(t, err)
+	assert.Zero(t, r.Entries)
+
+	_, err = Run(context.Background(), Config{Rate: -1})
+	assert.EqualError(t, err, "invalid configuration: negative values")
+}
@@ -0,0 +1,216 @@
+// Package xloggrpc provides gRPC client and server interceptors, that log the calls
+// as key-value entries, and seed the request context for downstream handlers.
+//
+// The package is a separate module, so the core module does not depend on gRPC.
+package xloggrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "xloggrpc")
+
+// Keys of the call entries
+const (
+	KeyMethod = "grpc_method"
+	KeyCode   = "grpc_code"
+	KeyPeer   = "peer"
+)
+
+// DefaultLevel returns the level of the call with the code:
+// ERROR for the server faults, WARNING for the conditions
+// the operators should be aware of, and INFO otherwise
+func DefaultLevel(c codes.Code) xlog.LogLevel {
+	switch c {
+	case codes.Unknown, codes.Unimplemented, codes.Internal, codes.DataLoss:
+		return xlog.ERROR
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return xlog.WARNING
+	default:
+		return xlog.INFO
+	}
+}
+
+// DefaultPeer returns the address of the peer from the context
+func DefaultPeer(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// Config specifies configuration for the interceptors
+type Config struct {
+	// Logger specifies the logger of the calls,
+	// the xloggrpc package logger by default
+	Logger xlog.KeyValueLogger
+	// Level returns the level of the call with the code, DefaultLevel by default
+	Level func(codes.Code) xlog.LogLevel
+	// Peer returns the address of the peer, DefaultPeer by default
+	Peer func(context.Context) string
+	// RequestID returns the request ID of the server call, such as from the metadata,
+	// the ID is generated if not specified or empty
+	RequestID func(context.Context) string
+	// SkipMethods specifies the full methods not to be logged, such as health checks
+	SkipMethods []string
+}
+
+// Interceptor logs method, code, duration and peer of the calls
+type Interceptor struct {
+	cfg  Config
+	skip map[string]bool
+}
+
+var (
+	_ grpc.UnaryServerInterceptor  = (*Interceptor)(nil).UnaryServer
+	_ grpc.StreamServerInterceptor = (*Interceptor)(nil).StreamServer
+	_ grpc.UnaryClientInterceptor  = (*Interceptor)(nil).UnaryClient
+	_ grpc.StreamClientInterceptor = (*Interceptor)(nil).StreamClient
+)
+
+// New returns the interceptor
+func New(cfg Config) *Interceptor {
+	if cfg.Logger == nil {
+		cfg.Logger = logger
+	}
+	if cfg.Level == nil {
+		cfg.Level = DefaultLevel
+	}
+	if cfg.Peer == nil {
+		cfg.Peer = DefaultPeer
+	}
+	skip := make(map[string]bool, len(cfg.SkipMethods))
+	for _, m := range cfg.SkipMethods {
+		skip[m] = true
+	}
+	return &Interceptor{
+		cfg:  cfg,
+		skip: skip,
+	}
+}
+
+// UnaryServer is grpc.UnaryServerInterceptor.
+// The context of the handler is seeded by xlog.ContextWithKV with the request ID and method,
+// so the entries of the handler logged with ContextKV have them.
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(i.UnaryServer))
+func (i *Interceptor) UnaryServer(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if i.skip[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	started := xlog.TimeNowFn()
+	ctx = i.serverContext(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	i.log(ctx, started, err)
+	return resp, err
+}
+
+// StreamServer is grpc.StreamServerInterceptor,
+// the context of the stream is seeded as in UnaryServer.
+//
+//	grpc.NewServer(grpc.ChainStreamInterceptor(i.StreamServer))
+func (i *Interceptor) StreamServer(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if i.skip[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	started := xlog.TimeNowFn()
+	ctx := i.serverContext(ss.Context(), info.FullMethod)
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	i.log(ctx, started, err)
+	return err
+}
+
+// UnaryClient is grpc.UnaryClientInterceptor.
+// The entry is logged with ContextKV, so it has the values of the caller's request.
+//
+//	grpc.NewClient(target, grpc.WithChainUnaryInterceptor(i.UnaryClient))
+func (i *Interceptor) UnaryClient(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if i.skip[method] {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	started := xlog.TimeNowFn()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	// the method is not added to the context of the caller,
+	// as ContextWithKV appends to the existing values
+	i.log(ctx, started, err, KeyMethod, method)
+	return err
+}
+
+// StreamClient is grpc.StreamClientInterceptor,
+// it logs the creation of the stream, see UnaryClient
+//
+//	grpc.NewClient(target, grpc.WithChainStreamInterceptor(i.StreamClient))
+func (i *Interceptor) StreamClient(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if i.skip[method] {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	started := xlog.TimeNowFn()
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	i.log(ctx, started, err, KeyMethod, method)
+	return cs, err
+}
+
+// serverStream is grpc.ServerStream with the seeded context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// serverContext returns the context with the request ID and method
+func (i *Interceptor) serverContext(ctx context.Context, method string) context.Context {
+	var id string
+	if i.cfg.RequestID != nil {
+		id = i.cfg.RequestID(ctx)
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	return xlog.ContextWithKV(ctx,
+		xlog.KeyRequestID, id,
+		KeyMethod, method)
+}
+
+// codeOf returns the status code of the error,
+// the context errors returned by the handlers are Canceled and DeadlineExceeded
+func codeOf(err error) codes.Code {
+	code := status.Code(err)
+	if code == codes.Unknown {
+		code = status.FromContextError(err).Code()
+	}
+	return code
+}
+
+func (i *Interceptor) log(ctx context.Context, started time.Time, err error, kvList ...any) {
+	code := codeOf(err)
+	kvList = append(kvList,
+		KeyCode, code.String(),
+		xlog.Elapsed(started))
+	if peer := i.cfg.Peer(ctx); peer != "" {
+		kvList = append(kvList, KeyPeer, peer)
+	}
+	if err != nil {
+		kvList = append(kvList, xlog.Err(err))
+	}
+	i.cfg.Logger.ContextKV(ctx, i.cfg.Level(code), kvList...)
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,189 @@
+package xloggrpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer fails the checks of "fail" and "cancel" services,
+// and logs the checks with the context of the handler
+type healthServer struct {
+	*health.Server
+	now *time.Time
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	*s.now = s.now.Add(time.Second)
+	logger.ContextKV(ctx, xlog.INFO, "check", req.GetService())
+	switch req.GetService() {
+	case "fail":
+		return nil, status.Error(codes.Internal, "check failed")
+	case "cancel":
+		return nil, context.Canceled
+	}
+	return s.Server.Check(ctx, req)
+}
+
+func (s *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	logger.ContextKV(stream.Context(), xlog.INFO, "watch", req.GetService())
+	return status.Error(codes.Unavailable, "not serving")
+}
+
+// newClient returns the health client connected to the server with the options
+func newClient(t *testing.T, serverOpts []grpc.ServerOption, clientOpts ...grpc.DialOption) healthpb.HealthClient {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	xlog.TimeNowFn = func() time.Time { return now }
+	t.Cleanup(func() { xlog.TimeNowFn = time.Now })
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(s, &healthServer{Server: health.NewServer(), now: &now})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	clientOpts = append(clientOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", clientOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryServer(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	i := New(Config{
+		RequestID: func(ctx context.Context) string {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if ids := md.Get("x-request-id"); len(ids) > 0 {
+				return ids[0]
+			}
+			return ""
+		},
+		SkipMethods: []string{"/grpc.health.v1.Health/Watch"},
+	})
+	client := newClient(t, []grpc.ServerOption{grpc.ChainUnaryInterceptor(i.UnaryServer), grpc.ChainStreamInterceptor(i.StreamServer)})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "r1")
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "level=I pkg=xloggrpc request_id=\"r1\" grpc_method=\"/grpc.health.v1.Health/Check\"\n"+
+		"level=I pkg=xloggrpc request_id=\"r1\" grpc_method=\"/grpc.health.v1.Health/Check\" grpc_code=\"OK\" duration=1s peer=\"bufconn\"\n",
+		b.String())
+
+	tcases := []struct {
+		service string
+		code    codes.Code
+		exp     string
+	}{
+		{"unknown", codes.NotFound, `level=I pkg=xloggrpc request_id="r1" grpc_method="/grpc.health.v1.Health/Check" grpc_code="NotFound" duration=1s peer="bufconn" err="rpc error: code = NotFound desc = unknown service"`},
+		{"fail", codes.Internal, `level=E pkg=xloggrpc request_id="r1" grpc_method="/grpc.health.v1.Health/Check" grpc_code="Internal" duration=1s peer="bufconn" err="rpc error: code = Internal desc = check failed"`},
+		{"cancel", codes.Canceled, `level=I pkg=xloggrpc request_id="r1" grpc_method="/grpc.health.v1.Health/Check" grpc_code="Canceled" duration=1s peer="bufconn" err="context canceled"`},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.service, func(t *testing.T) {
+			b.Reset()
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: tc.service})
+			assert.Equal(t, tc.code, status.Code(err))
+			assert.Equal(t, "level=I pkg=xloggrpc request_id=\"r1\" grpc_method=\"/grpc.health.v1.Health/Check\" check=\""+tc.service+"\"\n"+
+				tc.exp+"\n", b.String())
+		})
+	}
+
+	// the skipped method is not logged, and the context is not seeded
+	b.Reset()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "level=I pkg=xloggrpc watch=\"svc\"\n", b.String())
+}
+
+func TestStreamServer(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	i := New(Config{Peer: func(context.Context) string { return "" }})
+	client := newClient(t, []grpc.ServerOption{grpc.ChainStreamInterceptor(i.StreamServer)})
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Regexp(t, `^level=I pkg=xloggrpc request_id="[0-9a-f]{16}" grpc_method="/grpc.health.v1.Health/Watch" watch="svc"\n`+
+		`level=W pkg=xloggrpc request_id="[0-9a-f]{16}" grpc_method="/grpc.health.v1.Health/Watch" grpc_code="Unavailable" duration=0s err="rpc error: code = Unavailable desc = not serving"\n$`,
+		b.String())
+}
+
+func TestClient(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	i := New(Config{Peer: func(context.Context) string { return "" }})
+	client := newClient(t, nil, grpc.WithChainUnaryInterceptor(i.UnaryClient), grpc.WithChainStreamInterceptor(i.StreamClient))
+	ctx := xlog.ContextWithRequestID(context.Background(), "r2")
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "fail"})
+	require.Error(t, err)
+	assert.Equal(t, "level=I pkg=xloggrpc check=\"fail\"\n"+
+		"level=E pkg=xloggrpc request_id=\"r2\" grpc_method=\"/grpc.health.v1.Health/Check\" grpc_code=\"Internal\" duration=1s err=\"rpc error: code = Internal desc = check failed\"\n",
+		b.String())
+
+	b.Reset()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "level=I pkg=xloggrpc request_id=\"r2\" grpc_method=\"/grpc.health.v1.Health/Watch\" grpc_code=\"OK\" duration=0s\n"+
+		"level=I pkg=xloggrpc watch=\"svc\"\n",
+		b.String())
+}
+
+func TestDefaultLevel(t *testing.T) {
+	tcases := []struct {
+		code  codes.Code
+		level xlog.LogLevel
+	}{
+		{codes.OK, xlog.INFO},
+		{codes.NotFound, xlog.INFO},
+		{codes.Unauthenticated, xlog.INFO},
+		{codes.Canceled, xlog.INFO},
+		{codes.DeadlineExceeded, xlog.WARNING},
+		{codes.Unavailable, xlog.WARNING},
+		{codes.Unknown, xlog.ERROR},
+		{codes.Internal, xlog.ERROR},
+		{codes.DataLoss, xlog.ERROR},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.level, DefaultLevel(tc.code), tc.code.String())
+	}
+
+	assert.Equal(t, codes.OK, codeOf(nil))
+	assert.Equal(t, codes.DeadlineExceeded, codeOf(context.DeadlineExceeded))
+	assert.Equal(t, codes.NotFound, codeOf(status.Error(codes.NotFound, "not found")))
+	assert.Equal(t, codes.Unknown, codeOf(assert.AnError))
+	assert.Empty(t, DefaultPeer(context.Background()))
+}
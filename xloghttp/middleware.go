@@ -0,0 +1,167 @@
+// Package xloghttp provides HTTP server middleware, that logs the requests
+// as key-value entries, and seeds the request context for downstream handlers.
+package xloghttp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "xloghttp")
+
+// Keys of the request entries
+const (
+	KeyMethod     = "method"
+	KeyPath       = "path"
+	KeyStatus     = "status"
+	KeyBytes      = "bytes"
+	KeyRemoteAddr = "remote_addr"
+)
+
+// DefaultRequestIDHeader is the header of the request ID
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// Config specifies configuration for the middleware
+type Config struct {
+	// Logger specifies the logger of the requests,
+	// the xloghttp package logger by default
+	Logger xlog.KeyValueLogger
+	// Level specifies the level of the requests,
+	// the responses with 5xx status are logged at ERROR level.
+	// INFO by default
+	Level xlog.LogLevel
+	// SkipPaths specifies the paths not to be logged, such as health checks
+	SkipPaths []string
+	// SampleSuccess specifies that only every N-th response with 2xx status is logged,
+	// zero or one logs all the responses
+	SampleSuccess int
+	// RequestIDHeader specifies the header of the request ID,
+	// X-Request-ID by default. If the request does not have the header,
+	// the ID is generated, and returned in the response header.
+	RequestIDHeader string
+}
+
+// NewMiddleware returns the middleware, that logs method, path, status, bytes,
+// duration, remote address and request ID of the requests.
+// The request context is seeded by xlog.ContextWithKV with the request ID,
+// method and path, so the entries of downstream handlers logged with ContextKV have them.
+func NewMiddleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Logger == nil {
+		cfg.Logger = logger
+	}
+	if cfg.Level == 0 {
+		cfg.Level = xlog.INFO
+	}
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = DefaultRequestIDHeader
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+	m := &middleware{
+		cfg:  cfg,
+		skip: skip,
+	}
+	return m.handler
+}
+
+type middleware struct {
+	cfg     Config
+	skip    map[string]bool
+	success atomic.Uint64
+}
+
+func (m *middleware) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.skip[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		started := time.Now()
+		id := r.Header.Get(m.cfg.RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(m.cfg.RequestIDHeader, id)
+
+		ctx := xlog.ContextWithKV(r.Context(),
+			xlog.KeyRequestID, id,
+			KeyMethod, r.Method,
+			KeyPath, r.URL.Path)
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := m.cfg.Level
+		if status >= http.StatusInternalServerError {
+			level = xlog.ERROR
+		} else if status < http.StatusMultipleChoices && !m.sampled() {
+			return
+		}
+		m.cfg.Logger.ContextKV(ctx, level,
+			KeyStatus, status,
+			KeyBytes, sw.bytes,
+			xlog.KeyDuration, time.Since(started),
+			KeyRemoteAddr, r.RemoteAddr)
+	})
+}
+
+// sampled returns true if the successful response must be logged
+func (m *middleware) sampled() bool {
+	if m.cfg.SampleSuccess <= 1 {
+		return true
+	}
+	return (m.success.Add(1)-1)%uint64(m.cfg.SampleSuccess) == 0
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// statusWriter captures the status and the number of bytes of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
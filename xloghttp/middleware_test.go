@@ -0,0 +1,106 @@
+package xloghttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var durationRegex = regexp.MustCompile(`duration=[^ ]+`)
+
+func serve(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	for k, v := range header {
+		r.Header.Set(k, v[0])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.ContextKV(r.Context(), xlog.INFO, "msg", "handler")
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	})
+	h := NewMiddleware(Config{SkipPaths: []string{"/healthz"}})(handler)
+
+	w := serve(h, "/api", http.Header{DefaultRequestIDHeader: {"r1"}})
+	assert.Equal(t, "r1", w.Header().Get(DefaultRequestIDHeader))
+	assert.Equal(t, "level=I pkg=xloghttp request_id=\"r1\" method=\"GET\" path=\"/api\" msg=\"handler\"\n"+
+		"level=I pkg=xloghttp request_id=\"r1\" method=\"GET\" path=\"/api\" status=200 bytes=5 duration=X remote_addr=\"10.0.0.1:1234\"\n",
+		durationRegex.ReplaceAllString(b.String(), "duration=X"))
+
+	b.Reset()
+	w = serve(h, "/fail", nil)
+	id := w.Header().Get(DefaultRequestIDHeader)
+	require.Len(t, id, 16)
+	assert.Contains(t, b.String(), "level=E pkg=xloghttp request_id=\""+id+"\" method=\"GET\" path=\"/fail\" status=500 bytes=5 ")
+
+	b.Reset()
+	serve(h, "/missing", nil)
+	assert.Contains(t, b.String(), "status=404 bytes=19 ")
+
+	b.Reset()
+	serve(h, "/healthz", nil)
+	assert.Equal(t, "level=I pkg=xloghttp msg=\"handler\"\n", b.String())
+}
+
+func TestMiddleware_Sampling(t *testing.T) {
+	var b bytes.Buffer
+	xlog.SetFormatter(xlog.NewStringFormatter(&b).Options(xlog.FormatNoCaller, xlog.FormatSkipTime))
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	l := xlog.NewPackageLogger("github.com/effective-security/xlog", "xloghttp_test")
+	h := NewMiddleware(Config{
+		Logger:          l,
+		Level:           xlog.NOTICE,
+		SampleSuccess:   3,
+		RequestIDHeader: "X-Trace",
+	})(handler)
+
+	for i := 0; i < 6; i++ {
+		serve(h, "/ok", nil)
+	}
+	serve(h, "/fail", nil)
+
+	lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Contains(t, string(lines[0]), "level=N pkg=xloghttp_test request_id=")
+	assert.Contains(t, string(lines[1]), "status=200")
+	assert.Contains(t, string(lines[2]), "status=400")
+}
+
+func TestStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &statusWriter{ResponseWriter: rec}
+	w.WriteHeader(http.StatusAccepted)
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+	assert.Equal(t, http.StatusAccepted, w.status)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, rec, w.Unwrap())
+}
@@ -0,0 +1,144 @@
+// Package xlogprom provides the collector of the log entries counts,
+// exposed in Prometheus text format without Prometheus client dependency:
+//
+//	xlog_entries_total{pkg="...",level="..."}
+//	xlog_dropped_total{pkg="...",level="..."}
+//	xlog_sink_dropped_total{sink="..."}
+package xlogprom
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/effective-security/xlog"
+)
+
+// ContentType is the content type of Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector counts the log entries per package and level,
+// it implements xlog.MetricsSink and http.Handler serving the metrics
+type Collector struct {
+	lock    sync.RWMutex
+	entries map[counterKey]*atomic.Uint64
+	dropped map[counterKey]*atomic.Uint64
+	sinks   map[string]func() uint64
+}
+
+type counterKey struct {
+	pkg   string
+	level xlog.LogLevel
+}
+
+// NewCollector returns the collector
+func NewCollector() *Collector {
+	return &Collector{
+		entries: make(map[counterKey]*atomic.Uint64),
+		dropped: make(map[counterKey]*atomic.Uint64),
+		sinks:   make(map[string]func() uint64),
+	}
+}
+
+// Register creates the collector, and sets it as xlog.MetricsSink
+func Register() *Collector {
+	c := NewCollector()
+	xlog.SetMetricsSink(c)
+	return c
+}
+
+// EntryLogged implements xlog.MetricsSink
+func (c *Collector) EntryLogged(pkg string, level xlog.LogLevel) {
+	c.counter(c.entries, pkg, level).Add(1)
+}
+
+// EntryDropped implements xlog.MetricsSink
+func (c *Collector) EntryDropped(pkg string, level xlog.LogLevel) {
+	c.counter(c.dropped, pkg, level).Add(1)
+}
+
+// AddSink exposes the number of entries dropped by the sink,
+// such as xlog.AsyncWriter.Dropped, as xlog_sink_dropped_total
+func (c *Collector) AddSink(name string, dropped func() uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sinks[name] = dropped
+}
+
+func (c *Collector) counter(m map[counterKey]*atomic.Uint64, pkg string, level xlog.LogLevel) *atomic.Uint64 {
+	key := counterKey{pkg: pkg, level: level}
+	c.lock.RLock()
+	v, ok := m[key]
+	c.lock.RUnlock()
+	if ok {
+		return v
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if v, ok = m[key]; !ok {
+		v = new(atomic.Uint64)
+		m[key] = v
+	}
+	return v
+}
+
+// WriteTo writes the metrics in Prometheus text format
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	c.lock.RLock()
+	writeCounters(&b, "xlog_entries_total", "Number of log entries written by package and level.", c.entries)
+	writeCounters(&b, "xlog_dropped_total", "Number of log entries dropped by package and level.", c.dropped)
+	if len(c.sinks) > 0 {
+		names := make([]string, 0, len(c.sinks))
+		for name := range c.sinks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("# HELP xlog_sink_dropped_total Number of log entries dropped by sink.\n")
+		b.WriteString("# TYPE xlog_sink_dropped_total counter\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "xlog_sink_dropped_total{sink=\"%s\"} %d\n", escape(name), c.sinks[name]())
+		}
+	}
+	c.lock.RUnlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics in Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = c.WriteTo(w)
+}
+
+func writeCounters(b *strings.Builder, name, help string, m map[counterKey]*atomic.Uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s counter\n", name)
+
+	keys := make([]counterKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pkg == keys[j].pkg {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].pkg < keys[j].pkg
+	})
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s{pkg=\"%s\",level=\"%s\"} %d\n", name, escape(k.pkg), k.level.String(), m[k].Load())
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape returns the label value escaped for the text format
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,54 @@
+package xlogprom_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/xlogprom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "xlogprom_test")
+
+func TestCollector(t *testing.T) {
+	c := xlogprom.Register()
+	defer xlog.SetMetricsSink(nil)
+
+	xlog.SetFormatter(xlog.NewNilFormatter())
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	logger.KV(xlog.INFO, "k", 1)
+	logger.KV(xlog.INFO, "k", 2)
+	logger.KV(xlog.ERROR, "k", 3)
+	logger.KV(xlog.DEBUG, "k", 4)
+	xlog.ReportDropped("with\"quote", xlog.WARNING)
+
+	aw := xlog.NewAsyncWriter(io.Discard, xlog.AsyncConfig{})
+	defer aw.Close()
+	c.AddSink("async", aw.Dropped)
+
+	var b bytes.Buffer
+	_, err := c.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP xlog_entries_total Number of log entries written by package and level.
+# TYPE xlog_entries_total counter
+xlog_entries_total{pkg="xlogprom_test",level="ERROR"} 1
+xlog_entries_total{pkg="xlogprom_test",level="INFO"} 2
+# HELP xlog_dropped_total Number of log entries dropped by package and level.
+# TYPE xlog_dropped_total counter
+xlog_dropped_total{pkg="with\"quote",level="WARNING"} 1
+# HELP xlog_sink_dropped_total Number of log entries dropped by sink.
+# TYPE xlog_sink_dropped_total counter
+xlog_sink_dropped_total{sink="async"} 0
+`, b.String())
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, xlogprom.ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, b.String(), w.Body.String())
+}
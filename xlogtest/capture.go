@@ -0,0 +1,124 @@
+// Package xlogtest provides the formatter, that captures structured entries in memory,
+// so the tests assert the logged level, package, message and fields
+// instead of parsing the formatted strings.
+package xlogtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/xlog"
+)
+
+// Capture is the formatter, that records the entries in memory
+type Capture struct {
+	f    xlog.Formatter
+	logs *xlog.ObservedLogs
+}
+
+// NewCapture returns an instance of Capture
+func NewCapture() *Capture {
+	f, logs := xlog.NewObserver(xlog.DEBUG)
+	return &Capture{
+		f:    f,
+		logs: logs,
+	}
+}
+
+// Start sets Capture as the formatter of all logs,
+// the previous formatter is restored on the test cleanup
+func Start(t testing.TB) *Capture {
+	c := NewCapture()
+	old := xlog.GetFormatter()
+	xlog.SetFormatter(c)
+	t.Cleanup(func() {
+		xlog.SetFormatter(old)
+	})
+	return c
+}
+
+// Options allows to configure formatter behavior
+func (c *Capture) Options(ops ...xlog.FormatterOption) xlog.Formatter {
+	c.f.Options(ops...)
+	return c
+}
+
+// FormatKV records the entry, the "msg" value is recorded as the message
+func (c *Capture) FormatKV(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	c.f.FormatKV(pkg, l, depth+1, entries...)
+}
+
+// Format records the entry
+func (c *Capture) Format(pkg string, l xlog.LogLevel, depth int, entries ...any) {
+	c.f.Format(pkg, l, depth+1, entries...)
+}
+
+// Flush does nothing
+func (c *Capture) Flush() {}
+
+// Logs returns the observed entries, that can be filtered
+func (c *Capture) Logs() *xlog.ObservedLogs {
+	return c.logs
+}
+
+// Entries returns a copy of the recorded entries
+func (c *Capture) Entries() []xlog.Entry {
+	return c.logs.All()
+}
+
+// Len returns the number of the recorded entries
+func (c *Capture) Len() int {
+	return c.logs.Len()
+}
+
+// LastEntry returns the last recorded entry,
+// false is returned if there are no entries
+func (c *Capture) LastEntry() (xlog.Entry, bool) {
+	entries := c.logs.All()
+	if len(entries) == 0 {
+		return xlog.Entry{}, false
+	}
+	return entries[len(entries)-1], true
+}
+
+// Reset removes the recorded entries
+func (c *Capture) Reset() {
+	c.logs.TakeAll()
+}
+
+// ContainsEntry returns true if an entry with the level contains the message,
+// and has the key/value fields. The values are compared by their string representation,
+// so int and int64 values are equal. Empty message matches any message.
+func (c *Capture) ContainsEntry(level xlog.LogLevel, msg string, fields ...any) bool {
+	entries := c.logs.All()
+	for i := range entries {
+		if Match(&entries[i], level, msg, fields...) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the entry has the level, contains the message,
+// and has the key/value fields, see ContainsEntry
+func Match(e *xlog.Entry, level xlog.LogLevel, msg string, fields ...any) bool {
+	if e.Level != level || !strings.Contains(e.Message, msg) {
+		return false
+	}
+	for i := 0; i < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		v, ok := e.Field(key)
+		if !ok {
+			return false
+		}
+		var want any
+		if i+1 < len(fields) {
+			want = fields[i+1]
+		}
+		if fmt.Sprint(v) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
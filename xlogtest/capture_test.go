@@ -0,0 +1,69 @@
+package xlogtest_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/xlog"
+	"github.com/effective-security/xlog/xlogtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/xlog", "xlogtest_test")
+
+func TestCapture(t *testing.T) {
+	c := xlogtest.Start(t)
+	xlog.SetGlobalLogLevel(xlog.INFO)
+
+	_, ok := c.LastEntry()
+	assert.False(t, ok)
+
+	logger.Infof("hello %s", "world")
+	logger.KV(xlog.WARNING, "msg", "retrying", "attempt", 2, "err", errors.New("timeout"))
+	logger.Debug("not logged")
+
+	assert.Equal(t, 2, c.Len())
+	assert.True(t, c.ContainsEntry(xlog.INFO, "hello world"))
+	assert.True(t, c.ContainsEntry(xlog.INFO, "hello"))
+	assert.False(t, c.ContainsEntry(xlog.ERROR, "hello"))
+	assert.True(t, c.ContainsEntry(xlog.WARNING, "retrying", "attempt", int64(2)))
+	assert.True(t, c.ContainsEntry(xlog.WARNING, "", "err", "timeout"))
+	assert.False(t, c.ContainsEntry(xlog.WARNING, "retrying", "attempt", 3))
+	assert.False(t, c.ContainsEntry(xlog.WARNING, "retrying", "missing", 2))
+	assert.False(t, c.ContainsEntry(xlog.DEBUG, "not logged"))
+
+	e, ok := c.LastEntry()
+	require.True(t, ok)
+	assert.Equal(t, xlog.WARNING, e.Level)
+	assert.Equal(t, "xlogtest_test", e.Pkg)
+	assert.Equal(t, "retrying", e.Message)
+	v, ok := e.Field("attempt")
+	require.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.False(t, e.Time.IsZero())
+
+	entries := c.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "hello world", entries[0].Message)
+	assert.Empty(t, entries[0].Fields)
+
+	c.Reset()
+	assert.Equal(t, 0, c.Len())
+	assert.False(t, c.ContainsEntry(xlog.INFO, "hello"))
+}
+
+func TestCaptureOptions(t *testing.T) {
+	c := xlogtest.NewCapture()
+	c.Options(xlog.FormatWithCaller, xlog.FormatSkipTime)
+	xlog.SetPackageFormatter("github.com/effective-security/xlog", "xlogtest_test", c)
+	defer xlog.SetPackageFormatter("github.com/effective-security/xlog", "xlogtest_test", nil)
+
+	logger.Info("with caller")
+
+	e, ok := c.LastEntry()
+	require.True(t, ok)
+	assert.True(t, e.Time.IsZero())
+	assert.Contains(t, e.Caller, "TestCaptureOptions")
+	assert.Contains(t, e.Source, "capture_test.go:")
+}